@@ -2,24 +2,80 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	"github.com/vaberof/auth-grpc/internal/domain/authorization"
+	"github.com/vaberof/auth-grpc/internal/domain/mfa"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
 	"github.com/vaberof/auth-grpc/pkg/config"
 	"github.com/vaberof/auth-grpc/pkg/database/postgres"
 	"github.com/vaberof/auth-grpc/pkg/database/redis"
 	"github.com/vaberof/auth-grpc/pkg/grpc/grpcclient"
 	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
+	"github.com/vaberof/auth-grpc/pkg/http/httpserver"
 	"os"
+	"slices"
+	"strings"
 )
 
 type AppConfig struct {
-	Server      grpcserver.ServerConfig
-	AuthService auth.Config
-	Postgres    postgres.Config
-	Redis       redis.Config
+	Server               grpcserver.ServerConfig
+	HttpServer           httpserver.ServerConfig
+	AuthService          auth.Config
+	AuthorizationService authorization.Config
+	MfaService           mfa.Config
+	Postgres             postgres.Config
+	Redis                redis.Config
+
+	InMemoryStorage InMemoryStorageConfig
 
 	NotificationService grpcclient.NotificationServiceClientConfig
 }
 
+const (
+	inMemoryStorageTypeRedis = "redis"
+	inMemoryStorageTypeLocal = "local"
+)
+
+// InMemoryStorageConfig selects the backend of auth.InMemoryStorage,
+// 'local' keeps the data in the process memory and is meant for a local development only
+type InMemoryStorageConfig struct {
+	Type string `yaml:"type"`
+}
+
+// identityProviderSecretVariable returns the name of the environment variable with the client secret
+// of the identity provider, e.g. IDENTITY_PROVIDER_GOOGLE_CLIENT_SECRET
+func identityProviderSecretVariable(providerName string) string {
+	name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(providerName))
+	return "IDENTITY_PROVIDER_" + name + "_CLIENT_SECRET"
+}
+
+// publishedEncryptionKeys were committed to the sample configs once, data encrypted by them is not protected
+var publishedEncryptionKeys = []string{"mtP8mcaz+5HQDxscLtGp0ooKIsuilt3bYkCJgs2SPhs="}
+
+// validateEncryptionKey rejects unset and published keys of the environment variable
+func validateEncryptionKey(variable string, key string) error {
+	if key == "" {
+		return fmt.Errorf("%s must be set, generate it by 'openssl rand -base64 32'", variable)
+	}
+	if slices.Contains(publishedEncryptionKeys, key) {
+		return fmt.Errorf("%s is a published sample key, generate a new one by 'openssl rand -base64 32'", variable)
+	}
+	return nil
+}
+
+// validateOAuthIssuer requires the issuer of the authorization server served by the HTTP server,
+// ID tokens of the same issuer as access tokens would pass the issuer check of access tokens
+func validateOAuthIssuer(authConfig *auth.Config) error {
+	if authConfig.OAuth.Issuer == "" {
+		return errors.New("app.auth-service.oauth.issuer must be set to the public url of the HTTP server")
+	}
+	if authConfig.OAuth.Issuer == authConfig.TokenIssuer {
+		return errors.New("app.auth-service.oauth.issuer must differ from app.auth-service.token-issuer")
+	}
+	return nil
+}
+
 func mustGetAppConfig(sources ...string) AppConfig {
 	config, err := tryGetAppConfig(sources...)
 	if err != nil {
@@ -46,11 +102,56 @@ func tryGetAppConfig(sources ...string) (*AppConfig, error) {
 		return nil, err
 	}
 
+	var httpServerConfig httpserver.ServerConfig
+	err = config.ParseConfig(provider, "app.http.server", &httpServerConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	var authConfig auth.Config
 	err = config.ParseConfig(provider, "app.auth-service", &authConfig)
 	if err != nil {
 		return nil, err
 	}
+	if authConfig.TokenKey.Algorithm == "" {
+		authConfig.TokenKey.Algorithm = accesstoken.AlgorithmHS256
+		authConfig.TokenKey.SecretKey = accesstoken.SecretKey(authConfig.TokenSecretKey)
+	}
+	if len(authConfig.TokenKeys) == 0 {
+		authConfig.TokenKeys = []accesstoken.KeyConfig{authConfig.TokenKey}
+	}
+	for i := range authConfig.IdentityProviders {
+		identityProvider := &authConfig.IdentityProviders[i]
+		identityProvider.ClientSecret = os.Getenv(identityProviderSecretVariable(identityProvider.Name))
+	}
+	if httpServerConfig.Enabled {
+		err = validateOAuthIssuer(&authConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+	authConfig.SigningKeyEncryptionKey = os.Getenv("SIGNING_KEY_ENCRYPTION_KEY")
+	err = validateEncryptionKey("SIGNING_KEY_ENCRYPTION_KEY", authConfig.SigningKeyEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var authorizationConfig authorization.Config
+	err = config.ParseConfig(provider, "app.authorization-service", &authorizationConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var mfaConfig mfa.Config
+	err = config.ParseConfig(provider, "app.mfa-service", &mfaConfig)
+	if err != nil {
+		return nil, err
+	}
+	mfaConfig.SecretEncryptionKey = os.Getenv("MFA_SECRET_ENCRYPTION_KEY")
+	err = validateEncryptionKey("MFA_SECRET_ENCRYPTION_KEY", mfaConfig.SecretEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
 
 	var postgresConfig postgres.Config
 	err = config.ParseConfig(provider, "app.postgres", &postgresConfig)
@@ -66,6 +167,18 @@ func tryGetAppConfig(sources ...string) (*AppConfig, error) {
 		return nil, err
 	}
 
+	var inMemoryStorageConfig InMemoryStorageConfig
+	err = config.ParseConfig(provider, "app.in-memory-storage", &inMemoryStorageConfig)
+	if err != nil {
+		return nil, err
+	}
+	if inMemoryStorageConfig.Type == "" {
+		inMemoryStorageConfig.Type = inMemoryStorageTypeRedis
+	}
+	if inMemoryStorageConfig.Type != inMemoryStorageTypeRedis && inMemoryStorageConfig.Type != inMemoryStorageTypeLocal {
+		return nil, fmt.Errorf("unknown in-memory storage type %q", inMemoryStorageConfig.Type)
+	}
+
 	var notificationServiceConfig grpcclient.NotificationServiceClientConfig
 	err = config.ParseConfig(provider, "app.grpc.client.notification-service", &notificationServiceConfig)
 	if err != nil {
@@ -73,11 +186,15 @@ func tryGetAppConfig(sources ...string) (*AppConfig, error) {
 	}
 
 	appConfig := AppConfig{
-		Server:              serverConfig,
-		AuthService:         authConfig,
-		Postgres:            postgresConfig,
-		Redis:               redisConfig,
-		NotificationService: notificationServiceConfig,
+		Server:               serverConfig,
+		HttpServer:           httpServerConfig,
+		AuthService:          authConfig,
+		AuthorizationService: authorizationConfig,
+		MfaService:           mfaConfig,
+		Postgres:             postgresConfig,
+		Redis:                redisConfig,
+		InMemoryStorage:      inMemoryStorageConfig,
+		NotificationService:  notificationServiceConfig,
 	}
 
 	return &appConfig, nil
@@ -2,23 +2,53 @@ package main
 
 import (
 	"flag"
-	"fmt"
 	"github.com/joho/godotenv"
 	"github.com/vaberof/auth-grpc/internal/app/entrypoint/grpc/auth"
+	"github.com/vaberof/auth-grpc/internal/app/entrypoint/grpc/authorization"
+	"github.com/vaberof/auth-grpc/internal/app/entrypoint/http/oauth"
+	"github.com/vaberof/auth-grpc/internal/app/entrypoint/http/wellknown"
 	authservice "github.com/vaberof/auth-grpc/internal/domain/auth"
+	authorizationservice "github.com/vaberof/auth-grpc/internal/domain/authorization"
+	identityservice "github.com/vaberof/auth-grpc/internal/domain/identity"
+	mfaservice "github.com/vaberof/auth-grpc/internal/domain/mfa"
+	oauthservice "github.com/vaberof/auth-grpc/internal/domain/oauth"
+	passkeyservice "github.com/vaberof/auth-grpc/internal/domain/passkey"
+	roleservice "github.com/vaberof/auth-grpc/internal/domain/role"
+	serviceaccountservice "github.com/vaberof/auth-grpc/internal/domain/serviceaccount"
+	signingkeyservice "github.com/vaberof/auth-grpc/internal/domain/signingkey"
+	tenantservice "github.com/vaberof/auth-grpc/internal/domain/tenant"
 	userservice "github.com/vaberof/auth-grpc/internal/domain/user"
 	"github.com/vaberof/auth-grpc/internal/infra/integration/grpc/notificationservice"
+	memorystorage "github.com/vaberof/auth-grpc/internal/infra/storage/memory"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgidentity"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgmfa"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgoauth"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgpasskey"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgrelationship"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgrole"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgserviceaccount"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgsigningkey"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pgtenant"
 	"github.com/vaberof/auth-grpc/internal/infra/storage/postgres/pguser"
 	redisstorage "github.com/vaberof/auth-grpc/internal/infra/storage/redis"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
 	"github.com/vaberof/auth-grpc/pkg/database/postgres"
 	"github.com/vaberof/auth-grpc/pkg/database/redis"
 	"github.com/vaberof/auth-grpc/pkg/grpc/grpcclient"
 	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
+	"github.com/vaberof/auth-grpc/pkg/http/httpserver"
 	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+	"github.com/vaberof/auth-grpc/pkg/webauthn"
+	"github.com/vaberof/auth-grpc/pkg/xcipher"
+	"github.com/vaberof/auth-grpc/pkg/xpassword"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 var appConfigPaths = flag.String("config.files", "not-found.yaml", "List of application config files separated by comma")
@@ -35,52 +65,159 @@ func main() {
 
 	appConfig := mustGetAppConfig(*appConfigPaths)
 
-	fmt.Printf("%+v\n", appConfig)
-
 	postgresManagedDb, err := postgres.New(&appConfig.Postgres)
 	if err != nil {
 		panic(err)
 	}
 
-	redisManagedDb, err := redis.New(&appConfig.Redis)
+	notificationServiceGrpcClient, err := grpcclient.New(&appConfig.NotificationService)
 	if err != nil {
 		panic(err)
 	}
 
-	notificationServiceGrpcClient, err := grpcclient.New(&appConfig.NotificationService)
-	if err != nil {
-		panic(err)
+	var inMemoryStorage authservice.InMemoryStorage
+	var revocationListStorage authservice.RevocationListStorage
+	switch appConfig.InMemoryStorage.Type {
+	case inMemoryStorageTypeLocal:
+		inMemoryStorage = memorystorage.NewMemoryStorage()
+		revocationListStorage = memorystorage.NewRevocationListStorage()
+	default:
+		redisManagedDb, err := redis.New(&appConfig.Redis)
+		if err != nil {
+			panic(err)
+		}
+
+		inMemoryStorage = redisstorage.NewRedisStorage(redisManagedDb.RedisDb)
+		revocationListStorage = redisstorage.NewRevocationListStorage(redisManagedDb.RedisDb)
 	}
 
-	redisStorage := redisstorage.NewRedisStorage(redisManagedDb.RedisDb)
 	pgUserStorage := pguser.NewPgUserStorage(postgresManagedDb.PostgresDb)
+	pgRoleStorage := pgrole.NewPgRoleStorage(postgresManagedDb.PostgresDb)
+	pgTenantStorage := pgtenant.NewPgTenantStorage(postgresManagedDb.PostgresDb)
+	pgMfaStorage := pgmfa.NewPgMfaStorage(postgresManagedDb.PostgresDb)
+	pgPasskeyStorage := pgpasskey.NewPgPasskeyStorage(postgresManagedDb.PostgresDb)
+	pgIdentityStorage := pgidentity.NewPgIdentityStorage(postgresManagedDb.PostgresDb)
+	pgOAuthStorage := pgoauth.NewPgOAuthStorage(postgresManagedDb.PostgresDb)
+	pgServiceAccountStorage := pgserviceaccount.NewPgServiceAccountStorage(postgresManagedDb.PostgresDb)
+	pgSigningKeyStorage := pgsigningkey.NewPgSigningKeyStorage(postgresManagedDb.PostgresDb)
+	pgRelationshipStorage := pgrelationship.NewPgRelationshipStorage(postgresManagedDb.PostgresDb)
 
 	notificationService := notificationservice.New(notificationServiceGrpcClient, logger)
 	userService := userservice.NewUserService(pgUserStorage, logger)
+	roleService := roleservice.NewRoleService(pgRoleStorage, logger)
+	tenantService := tenantservice.NewTenantService(pgTenantStorage, logger)
+
+	mfaSecretCipher, err := xcipher.NewFromBase64(appConfig.MfaService.SecretEncryptionKey)
+	if err != nil {
+		panic(err)
+	}
+
+	mfaService := mfaservice.NewMfaService(&appConfig.MfaService, pgMfaStorage, mfaSecretCipher, logger)
+	passkeyService := passkeyservice.NewPasskeyService(pgPasskeyStorage, logger)
+	identityService := identityservice.NewIdentityService(pgIdentityStorage, logger)
+	oauthService := oauthservice.NewOAuthService(pgOAuthStorage, logger)
+	serviceAccountService := serviceaccountservice.NewServiceAccountService(pgServiceAccountStorage, logger)
+
+	signingKeyCipher, err := xcipher.NewFromBase64(appConfig.AuthService.SigningKeyEncryptionKey)
+	if err != nil {
+		panic(err)
+	}
+
+	signingKeyService := signingkeyservice.NewSigningKeyService(pgSigningKeyStorage, signingKeyCipher, logger)
+
+	relyingParty, err := webauthn.NewRelyingParty(&appConfig.AuthService.Passkeys)
+	if err != nil {
+		panic(err)
+	}
+
+	identityProviders, err := oidc.NewProviders(appConfig.AuthService.IdentityProviders)
+	if err != nil {
+		panic(err)
+	}
+
+	tokenKeyRing, err := accesstoken.NewKeyRingFromConfig(appConfig.AuthService.TokenKeys)
+	if err != nil {
+		panic(err)
+	}
+
+	passwordPolicy, err := passwordpolicy.NewFromConfig(&appConfig.AuthService.PasswordPolicy)
+	if err != nil {
+		panic(err)
+	}
+
+	passwordHasher, err := xpassword.NewFromConfig(&appConfig.AuthService.PasswordHashing)
+	if err != nil {
+		panic(err)
+	}
+
+	authService := authservice.NewAuthService(&appConfig.AuthService, tokenKeyRing, userService, roleService, tenantService, mfaService, passkeyService, relyingParty, identityService, identityProviders, oauthService, serviceAccountService, signingKeyService, notificationService, inMemoryStorage, revocationListStorage, passwordPolicy, passwordHasher, logger)
+
+	err = authService.LoadSigningKeys()
+	if err != nil {
+		panic(err)
+	}
+
+	go reloadSigningKeys(authService)
+
+	authorizationSchema, err := rebac.ParseSchema(appConfig.AuthorizationService.Schema)
+	if err != nil {
+		panic(err)
+	}
 
-	authService := authservice.NewAuthService(&appConfig.AuthService, userService, notificationService, redisStorage, logger)
+	authorizationService := authorizationservice.NewAuthorizationService(authorizationSchema, pgRelationshipStorage, logger)
 
-	grpcServer := grpcserver.New(&appConfig.Server, logger)
+	grpcServer := grpcserver.New(&appConfig.Server, logger,
+		grpcserver.WithAuthentication(auth.NewTokenVerifier(authService), auth.PublicMethods...),
+		grpcserver.WithTenantResolution(auth.NewTenantResolver(tenantService)),
+		grpcserver.WithAuthorization(auth.MethodRules),
+		grpcserver.WithAuthorization(authorization.MethodRules),
+	)
 
-	auth.Register(grpcServer.Server, authService)
+	auth.Register(grpcServer.Server, authService, roleService, mfaService)
+	authorization.Register(grpcServer.Server, authorizationService)
 
 	grpcServerErrorCh := grpcServer.StartAsync()
 
+	var httpServer *httpserver.AppServer
+	var httpServerErrorCh <-chan error
+	if appConfig.HttpServer.Enabled {
+		httpServer = httpserver.New(&appConfig.HttpServer, logger)
+
+		wellknown.Register(httpServer.Mux, authService)
+		oauth.Register(httpServer.Mux, authService)
+
+		httpServerErrorCh = httpServer.StartAsync()
+	}
+
 	quitCh := make(chan os.Signal, 1)
 	signal.Notify(quitCh, syscall.SIGTERM, syscall.SIGINT)
 
 	select {
 	case signalValue := <-quitCh:
 		logger.GetLogger().Info("stopping application", slog.String("signal", signalValue.String()))
-
-		grpcServer.Shutdown()
 	case err = <-grpcServerErrorCh:
 		logger.GetLogger().Info("stopping application", slog.String("gRPC server error", err.Error()))
+	case err = <-httpServerErrorCh:
+		logger.GetLogger().Info("stopping application", slog.String("HTTP server error", err.Error()))
+	}
 
-		grpcServer.Shutdown()
+	grpcServer.Shutdown()
+
+	if httpServer != nil {
+		httpServer.Shutdown()
 	}
 }
 
 func loadEnvironmentVariables() error {
 	return godotenv.Load(*environmentVariablesPath)
 }
+
+// reloadSigningKeys picks up signing keys rotated by other instances, errors are logged by the service
+func reloadSigningKeys(authService authservice.AuthService) {
+	ticker := time.NewTicker(authService.SigningKeysReloadInterval())
+	defer ticker.Stop()
+
+	for range ticker.C {
+		_ = authService.LoadSigningKeys()
+	}
+}
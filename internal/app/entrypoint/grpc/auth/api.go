@@ -2,54 +2,670 @@ package auth
 
 import (
 	"context"
+	"encoding/base64"
 	pb "github.com/vaberof/auth-grpc/genproto/auth_service"
 	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	pkgauth "github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
 	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"time"
 )
 
 type serverAPI struct {
 	pb.UnimplementedAuthServiceServer
 	authService AuthService
+	roleService RoleService
+	mfaService  MfaService
 }
 
-func Register(gRPC *grpc.Server, authService AuthService) {
-	pb.RegisterAuthServiceServer(gRPC, &serverAPI{authService: authService})
+// Register registers auth service API. Permissions required by the methods are listed in MethodRules
+func Register(gRPC *grpc.Server, authService AuthService, roleService RoleService, mfaService MfaService) {
+	pb.RegisterAuthServiceServer(gRPC, &serverAPI{authService: authService, roleService: roleService, mfaService: mfaService})
 }
 
-// TODO: check all returned errors and send a corresponding status
-
 func (s *serverAPI) Register(ctx context.Context, req *pb.RegisterRequest) (*emptypb.Empty, error) {
-	err := s.authService.Register(domain.Email(req.Email), domain.Password(req.Password))
+	err := validateRegisterRequest(req)
 	if err != nil {
-		return &emptypb.Empty{}, status.Errorf(codes.Internal, "Internal server error: %v", err)
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.Register(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email), domain.Password(req.Password))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
 
 func (s *serverAPI) Login(ctx context.Context, req *pb.LoginRequest) (*pb.AuthResponse, error) {
-	accessToken, err := s.authService.Login(domain.Email(req.Email), domain.Password(req.Password))
+	err := validateLoginRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	loginResult, err := s.authService.Login(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email), domain.Password(req.Password))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toLoginResponse(loginResult), nil
+}
+
+func (s *serverAPI) StartEmailLogin(ctx context.Context, req *pb.StartEmailLoginRequest) (*emptypb.Empty, error) {
+	err := validateStartEmailLoginRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.StartEmailLogin(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) CompleteEmailLogin(ctx context.Context, req *pb.CompleteEmailLoginRequest) (*pb.AuthResponse, error) {
+	err := validateCompleteEmailLoginRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	loginResult, err := s.authService.CompleteEmailLogin(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email), domain.Code(req.Code))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toLoginResponse(loginResult), nil
+}
+
+func (s *serverAPI) CompleteMfa(ctx context.Context, req *pb.CompleteMfaRequest) (*pb.AuthResponse, error) {
+	err := validateCompleteMfaRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	tokens, err := s.authService.CompleteMfa(auth.MfaToken(req.MfaToken), req.Code)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toAuthResponse(tokens), nil
+}
+
+func (s *serverAPI) EnrollTotp(ctx context.Context, req *emptypb.Empty) (*pb.EnrollTotpResponse, error) {
+	claims := pkgauth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	enrollment, err := s.mfaService.EnrollTotp(claims.UserId, claims.Email.String())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.EnrollTotpResponse{
+		Secret:     enrollment.Secret,
+		OtpauthUri: enrollment.Uri,
+		QrCode:     enrollment.QrCode,
+	}, nil
+}
+
+func (s *serverAPI) ConfirmTotp(ctx context.Context, req *pb.ConfirmTotpRequest) (*pb.ConfirmTotpResponse, error) {
+	userId := pkgauth.UserIdFromContext(ctx)
+	if userId == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	err := validateConfirmTotpRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	recoveryCodes, err := s.mfaService.ConfirmTotp(*userId, req.Code)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.ConfirmTotpResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *serverAPI) BeginPasskeyRegistration(ctx context.Context, req *emptypb.Empty) (*pb.BeginPasskeyRegistrationResponse, error) {
+	userId := pkgauth.UserIdFromContext(ctx)
+	if userId == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	optionsJson, err := s.authService.BeginPasskeyRegistration(*userId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.BeginPasskeyRegistrationResponse{OptionsJson: string(optionsJson)}, nil
+}
+
+func (s *serverAPI) FinishPasskeyRegistration(ctx context.Context, req *pb.FinishPasskeyRegistrationRequest) (*pb.FinishPasskeyRegistrationResponse, error) {
+	userId := pkgauth.UserIdFromContext(ctx)
+	if userId == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	err := validateFinishPasskeyRegistrationRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	userPasskey, err := s.authService.FinishPasskeyRegistration(*userId, req.Name, []byte(req.CredentialJson))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.FinishPasskeyRegistrationResponse{
+		CredentialId: base64.RawURLEncoding.EncodeToString(userPasskey.Id),
+	}, nil
+}
+
+func (s *serverAPI) BeginPasskeyLogin(ctx context.Context, req *emptypb.Empty) (*pb.BeginPasskeyLoginResponse, error) {
+	challenge, err := s.authService.BeginPasskeyLogin(pkgauth.TenantIdFromContext(ctx))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.BeginPasskeyLoginResponse{
+		SessionId:   string(challenge.SessionId),
+		OptionsJson: string(challenge.OptionsJson),
+		ExpiresAt:   timestamppb.New(challenge.ExpiresAt),
+	}, nil
+}
+
+func (s *serverAPI) FinishPasskeyLogin(ctx context.Context, req *pb.FinishPasskeyLoginRequest) (*pb.AuthResponse, error) {
+	err := validateFinishPasskeyLoginRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	loginResult, err := s.authService.FinishPasskeyLogin(auth.PasskeySessionId(req.SessionId), []byte(req.CredentialJson))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toLoginResponse(loginResult), nil
+}
+
+func (s *serverAPI) StartFederatedLogin(ctx context.Context, req *pb.StartFederatedLoginRequest) (*pb.StartFederatedLoginResponse, error) {
+	err := validateStartFederatedLoginRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	start, err := s.authService.StartFederatedLogin(pkgauth.TenantIdFromContext(ctx), req.Provider)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.StartFederatedLoginResponse{
+		AuthorizationUrl: start.AuthorizationUrl,
+		State:            start.State,
+		ExpiresAt:        timestamppb.New(start.ExpiresAt),
+	}, nil
+}
+
+func (s *serverAPI) CompleteFederatedLogin(ctx context.Context, req *pb.CompleteFederatedLoginRequest) (*pb.AuthResponse, error) {
+	err := validateCompleteFederatedLoginRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	loginResult, err := s.authService.CompleteFederatedLogin(req.State, req.Code)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toLoginResponse(loginResult), nil
+}
+
+func (s *serverAPI) RegisterOAuthClient(ctx context.Context, req *pb.RegisterOAuthClientRequest) (*pb.RegisterOAuthClientResponse, error) {
+	err := validateRegisterOAuthClientRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	registered, err := s.authService.RegisterOAuthClient(pkgauth.TenantIdFromContext(ctx), &auth.OAuthClientRegistration{
+		Name:         req.Name,
+		RedirectUris: req.RedirectUris,
+		GrantTypes:   req.GrantTypes,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.RegisterOAuthClientResponse{
+		ClientId:     registered.Client.ClientId,
+		ClientSecret: registered.ClientSecret,
+		CreatedAt:    timestamppb.New(registered.Client.CreatedAt),
+	}, nil
+}
+
+func (s *serverAPI) GetAuthorization(ctx context.Context, req *pb.GetAuthorizationRequest) (*pb.GetAuthorizationResponse, error) {
+	userId := pkgauth.UserIdFromContext(ctx)
+	if userId == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	err := validateGetAuthorizationRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	authorization, err := s.authService.GetAuthorization(*userId, req.RequestId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.GetAuthorizationResponse{
+		ClientId:       authorization.ClientId,
+		ClientName:     authorization.ClientName,
+		Scopes:         authorization.Scopes,
+		ConsentGranted: authorization.ConsentGranted,
+		ExpiresAt:      timestamppb.New(authorization.ExpiresAt),
+	}, nil
+}
+
+func (s *serverAPI) CompleteAuthorization(ctx context.Context, req *pb.CompleteAuthorizationRequest) (*pb.CompleteAuthorizationResponse, error) {
+	userId := pkgauth.UserIdFromContext(ctx)
+	if userId == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	err := validateCompleteAuthorizationRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	redirectUri, err := s.authService.CompleteAuthorization(*userId, req.RequestId, req.Approved)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.CompleteAuthorizationResponse{RedirectUri: redirectUri}, nil
+}
+
+func (s *serverAPI) CreateServiceAccount(ctx context.Context, req *pb.CreateServiceAccountRequest) (*pb.CreateServiceAccountResponse, error) {
+	err := validateCreateServiceAccountRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	created, err := s.authService.CreateServiceAccount(pkgauth.TenantIdFromContext(ctx), req.Name, req.Scopes)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.CreateServiceAccountResponse{
+		ClientId:     created.ServiceAccount.ClientId,
+		ClientSecret: created.ClientSecret,
+		CreatedAt:    timestamppb.New(created.ServiceAccount.CreatedAt),
+	}, nil
+}
+
+func (s *serverAPI) RotateServiceAccountSecret(ctx context.Context, req *pb.RotateServiceAccountSecretRequest) (*pb.RotateServiceAccountSecretResponse, error) {
+	err := validateRotateServiceAccountSecretRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	rotated, err := s.authService.RotateServiceAccountSecret(pkgauth.TenantIdFromContext(ctx), req.ClientId, req.RevokePrevious)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.RotateServiceAccountSecretResponse{
+		ClientSecret:            rotated.ClientSecret,
+		PreviousSecretsExpireAt: timestamppb.New(rotated.PreviousSecretsExpireAt),
+	}, nil
+}
+
+func (s *serverAPI) DisableServiceAccount(ctx context.Context, req *pb.DisableServiceAccountRequest) (*emptypb.Empty, error) {
+	err := validateDisableServiceAccountRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	err = s.authService.DisableServiceAccount(pkgauth.TenantIdFromContext(ctx), req.ClientId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) DeleteServiceAccount(ctx context.Context, req *pb.DeleteServiceAccountRequest) (*emptypb.Empty, error) {
+	err := validateDeleteServiceAccountRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	err = s.authService.DeleteServiceAccount(pkgauth.TenantIdFromContext(ctx), req.ClientId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) IssueServiceToken(ctx context.Context, req *pb.IssueServiceTokenRequest) (*pb.IssueServiceTokenResponse, error) {
+	err := validateIssueServiceTokenRequest(req)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "Internal server error: %v", err)
+		return nil, toStatusError(err)
 	}
-	return &pb.AuthResponse{AccessToken: string(*accessToken)}, nil
+
+	serviceToken, err := s.authService.IssueServiceToken(req.ClientId, req.ClientSecret, req.Scopes, peerAddress(ctx))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &pb.IssueServiceTokenResponse{
+		AccessToken: string(serviceToken.AccessToken),
+		ExpiresAt:   timestamppb.New(serviceToken.ExpiresAt),
+		Scopes:      serviceToken.Scopes,
+	}, nil
 }
 
 func (s *serverAPI) Verify(ctx context.Context, req *pb.VerifyRequest) (*emptypb.Empty, error) {
-	err := s.authService.Verify(domain.Email(req.Email), domain.Code(req.Code))
+	err := validateVerifyRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.Verify(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email), domain.Code(req.Code))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*pb.TokenInfo, error) {
+	err := validateVerifyTokenRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	tokenInfo, err := s.authService.VerifyToken(auth.AccessToken(req.Token))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toTokenInfoResponse(tokenInfo), nil
+}
+
+func (s *serverAPI) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.AuthResponse, error) {
+	err := validateRefreshRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	tokens, err := s.authService.Refresh(auth.RefreshToken(req.RefreshToken))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return toAuthResponse(tokens), nil
+}
+
+func (s *serverAPI) Logout(ctx context.Context, req *pb.LogoutRequest) (*emptypb.Empty, error) {
+	err := validateLogoutRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.Logout(auth.AccessToken(req.AccessToken), auth.RefreshToken(req.RefreshToken))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) RevokeToken(ctx context.Context, req *pb.RevokeTokenRequest) (*emptypb.Empty, error) {
+	err := validateRevokeTokenRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.RevokeToken(auth.AccessToken(req.Token), req.RevokeAllSessions)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) RevokeUserSessions(ctx context.Context, req *pb.RevokeUserSessionsRequest) (*emptypb.Empty, error) {
+	err := validateRevokeUserSessionsRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.RevokeUserSessions(pkgauth.TenantIdFromContext(ctx), domain.UserId(req.UserId))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) GetJwks(ctx context.Context, req *emptypb.Empty) (*pb.Jwks, error) {
+	jwks := s.authService.GetJwks()
+
+	keys := make([]*pb.Jwk, len(jwks.Keys))
+	for i, jwk := range jwks.Keys {
+		keys[i] = &pb.Jwk{
+			Kty: jwk.Kty,
+			Kid: jwk.Kid,
+			Use: jwk.Use,
+			Alg: jwk.Alg,
+			N:   jwk.N,
+			E:   jwk.E,
+			Crv: jwk.Crv,
+			X:   jwk.X,
+			Y:   jwk.Y,
+		}
+	}
+
+	return &pb.Jwks{Keys: keys}, nil
+}
+
+func (s *serverAPI) RotateSigningKey(ctx context.Context, req *pb.RotateSigningKeyRequest) (*pb.RotateSigningKeyResponse, error) {
+	keyId, err := s.authService.RotateSigningKey(accesstoken.Algorithm(req.Algorithm), req.KeyId)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.RotateSigningKeyResponse{KeyId: keyId}, nil
+}
+
+func (s *serverAPI) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	err := validateRequestPasswordResetRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.RequestPasswordReset(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email))
 	if err != nil {
-		return &emptypb.Empty{}, status.Errorf(codes.Internal, "Internal server error: %v", err)
+		return &emptypb.Empty{}, toStatusError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
 
-func (s *serverAPI) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*emptypb.Empty, error) {
-	err := s.authService.VerifyToken(auth.AccessToken(req.Token))
+func (s *serverAPI) ConfirmPasswordReset(ctx context.Context, req *pb.ConfirmPasswordResetRequest) (*emptypb.Empty, error) {
+	err := validateConfirmPasswordResetRequest(req)
 	if err != nil {
-		return &emptypb.Empty{}, status.Errorf(codes.Internal, "Internal server error: %v", err)
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.ConfirmPasswordReset(pkgauth.TenantIdFromContext(ctx), domain.Email(req.Email), domain.Code(req.Code), domain.Password(req.NewPassword))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
 	}
 	return &emptypb.Empty{}, nil
 }
+
+func (s *serverAPI) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.AuthResponse, error) {
+	userId := pkgauth.UserIdFromContext(ctx)
+	if userId == nil {
+		return nil, grpcserver.NewStatusError(codes.Unauthenticated, ReasonTokenInvalid, "access token is required")
+	}
+
+	err := validateChangePasswordRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	tokens, err := s.authService.ChangePassword(*userId, domain.Password(req.CurrentPassword), domain.Password(req.NewPassword), req.RevokeOtherSessions)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	if tokens == nil {
+		return &pb.AuthResponse{}, nil
+	}
+	return toAuthResponse(tokens), nil
+}
+
+func (s *serverAPI) GetRevocationList(ctx context.Context, req *pb.GetRevocationListRequest) (*pb.RevocationList, error) {
+	var since time.Time
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+
+	revocationList, err := s.authService.GetRevocationList(since)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	tokens := make([]*pb.RevokedToken, len(revocationList.Tokens))
+	for i, token := range revocationList.Tokens {
+		tokens[i] = &pb.RevokedToken{
+			TokenId:   token.TokenId,
+			ExpiresAt: timestamppb.New(token.ExpiresAt),
+		}
+	}
+
+	users := make([]*pb.RevokedUser, len(revocationList.Users))
+	for i, revokedUser := range revocationList.Users {
+		users[i] = &pb.RevokedUser{
+			UserId:    int64(revokedUser.UserId),
+			RevokedAt: timestamppb.New(revokedUser.RevokedAt),
+		}
+	}
+
+	clients := make([]*pb.RevokedClient, len(revocationList.Clients))
+	for i, revokedClient := range revocationList.Clients {
+		clients[i] = &pb.RevokedClient{
+			ClientId:  revokedClient.ClientId,
+			RevokedAt: timestamppb.New(revokedClient.RevokedAt),
+		}
+	}
+
+	return &pb.RevocationList{
+		Tokens:      tokens,
+		Users:       users,
+		Clients:     clients,
+		GeneratedAt: timestamppb.New(revocationList.GeneratedAt),
+	}, nil
+}
+
+func (s *serverAPI) AssignRole(ctx context.Context, req *pb.AssignRoleRequest) (*emptypb.Empty, error) {
+	err := validateAssignRoleRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.AssignRole(pkgauth.TenantIdFromContext(ctx), domain.UserId(req.UserId), req.Role)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) RevokeRole(ctx context.Context, req *pb.RevokeRoleRequest) (*emptypb.Empty, error) {
+	err := validateRevokeRoleRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authService.RevokeRole(pkgauth.TenantIdFromContext(ctx), domain.UserId(req.UserId), req.Role)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) ListRoles(ctx context.Context, req *pb.ListRolesRequest) (*pb.ListRolesResponse, error) {
+	var roles []*role.Role
+	var err error
+
+	if req.UserId != 0 {
+		roles, err = s.authService.ListUserRoles(pkgauth.TenantIdFromContext(ctx), domain.UserId(req.UserId))
+	} else {
+		roles, err = s.roleService.ListRoles()
+	}
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	pbRoles := make([]*pb.Role, len(roles))
+	for i, domainRole := range roles {
+		pbRoles[i] = &pb.Role{
+			Name:        domainRole.Name,
+			Description: domainRole.Description,
+			Permissions: domainRole.Permissions,
+		}
+	}
+
+	return &pb.ListRolesResponse{Roles: pbRoles}, nil
+}
+
+// peerAddress returns the host of the peer address, it is empty if the peer is unknown
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func toAuthResponse(tokens *auth.Tokens) *pb.AuthResponse {
+	return &pb.AuthResponse{
+		AccessToken:  string(tokens.AccessToken),
+		RefreshToken: string(tokens.RefreshToken),
+	}
+}
+
+func toLoginResponse(loginResult *auth.LoginResult) *pb.AuthResponse {
+	if loginResult.MfaChallenge != nil {
+		return &pb.AuthResponse{
+			MfaRequired:       true,
+			MfaToken:          string(loginResult.MfaChallenge.MfaToken),
+			MfaTokenExpiresAt: timestamppb.New(loginResult.MfaChallenge.ExpiresAt),
+		}
+	}
+	return toAuthResponse(loginResult.Tokens)
+}
+
+func toTokenInfoResponse(tokenInfo *auth.TokenInfo) *pb.TokenInfo {
+	return &pb.TokenInfo{
+		UserId:        int64(tokenInfo.UserId),
+		ClientId:      tokenInfo.ClientId,
+		PrincipalType: tokenInfo.PrincipalType,
+		TenantId:      int64(tokenInfo.TenantId),
+		Email:         string(tokenInfo.Email),
+		Roles:         tokenInfo.Roles,
+		Permissions:   tokenInfo.Permissions,
+		IssuedAt:      timestamppb.New(tokenInfo.IssuedAt),
+		ExpiresAt:     timestamppb.New(tokenInfo.ExpiresAt),
+		TokenId:       tokenInfo.TokenId,
+		Scopes:        tokenInfo.Scopes,
+	}
+}
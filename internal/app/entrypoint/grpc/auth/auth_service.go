@@ -2,12 +2,46 @@ package auth
 
 import (
 	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	"github.com/vaberof/auth-grpc/internal/domain/passkey"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
 	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
 )
 
 type AuthService interface {
-	Register(email domain.Email, password domain.Password) error
-	Login(email domain.Email, password domain.Password) (*auth.AccessToken, error)
-	Verify(email domain.Email, code domain.Code) error
-	VerifyToken(token auth.AccessToken) error
+	Register(tenantId domain.TenantId, email domain.Email, password domain.Password) error
+	Login(tenantId domain.TenantId, email domain.Email, password domain.Password) (*auth.LoginResult, error)
+	CompleteMfa(mfaToken auth.MfaToken, code string) (*auth.Tokens, error)
+	StartEmailLogin(tenantId domain.TenantId, email domain.Email) error
+	CompleteEmailLogin(tenantId domain.TenantId, email domain.Email, code domain.Code) (*auth.LoginResult, error)
+	BeginPasskeyRegistration(userId domain.UserId) ([]byte, error)
+	FinishPasskeyRegistration(userId domain.UserId, name string, credentialJson []byte) (*passkey.Passkey, error)
+	BeginPasskeyLogin(tenantId domain.TenantId) (*auth.PasskeyLoginChallenge, error)
+	FinishPasskeyLogin(sessionId auth.PasskeySessionId, credentialJson []byte) (*auth.LoginResult, error)
+	StartFederatedLogin(tenantId domain.TenantId, providerName string) (*auth.FederatedLoginStart, error)
+	CompleteFederatedLogin(state string, code string) (*auth.LoginResult, error)
+	Verify(tenantId domain.TenantId, email domain.Email, code domain.Code) error
+	VerifyToken(token auth.AccessToken) (*auth.TokenInfo, error)
+	Refresh(refreshToken auth.RefreshToken) (*auth.Tokens, error)
+	Logout(accessToken auth.AccessToken, refreshToken auth.RefreshToken) error
+	RevokeToken(token auth.AccessToken, revokeAllSessions bool) error
+	RevokeUserSessions(tenantId domain.TenantId, userId domain.UserId) error
+	AssignRole(tenantId domain.TenantId, userId domain.UserId, roleName string) error
+	RevokeRole(tenantId domain.TenantId, userId domain.UserId, roleName string) error
+	ListUserRoles(tenantId domain.TenantId, userId domain.UserId) ([]*role.Role, error)
+	GetJwks() *accesstoken.Jwks
+	RotateSigningKey(algorithm accesstoken.Algorithm, keyId string) (string, error)
+	RequestPasswordReset(tenantId domain.TenantId, email domain.Email) error
+	ConfirmPasswordReset(tenantId domain.TenantId, email domain.Email, code domain.Code, newPassword domain.Password) error
+	ChangePassword(userId domain.UserId, currentPassword domain.Password, newPassword domain.Password, revokeOtherSessions bool) (*auth.Tokens, error)
+	GetRevocationList(since time.Time) (*auth.RevocationList, error)
+	RegisterOAuthClient(tenantId domain.TenantId, registration *auth.OAuthClientRegistration) (*auth.RegisteredOAuthClient, error)
+	GetAuthorization(userId domain.UserId, requestId string) (*auth.PendingAuthorization, error)
+	CompleteAuthorization(userId domain.UserId, requestId string, approved bool) (string, error)
+	CreateServiceAccount(tenantId domain.TenantId, name string, scopes []string) (*auth.CreatedServiceAccount, error)
+	RotateServiceAccountSecret(tenantId domain.TenantId, clientId string, revokePrevious bool) (*auth.RotatedServiceAccountSecret, error)
+	DisableServiceAccount(tenantId domain.TenantId, clientId string) error
+	DeleteServiceAccount(tenantId domain.TenantId, clientId string) error
+	IssueServiceToken(clientId string, clientSecret string, scopes []string, peerAddress string) (*auth.ServiceToken, error)
 }
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
+)
+
+// Permissions granted by roles, the admin role seeded by migrations has all of them
+const (
+	PermissionRolesRead             = "roles.read"
+	PermissionRolesAssign           = "roles.assign"
+	PermissionSigningKeysRotate     = "signing_keys.rotate"
+	PermissionSessionsRevoke        = "sessions.revoke"
+	PermissionOAuthClientsManage    = "oauth_clients.manage"
+	PermissionServiceAccountsManage = "service_accounts.manage"
+	PermissionRevocationsRead       = "revocations.read"
+)
+
+// Scopes granted to service accounts, they are checked by ServiceAccountMethods
+const (
+	ScopeRevocationsRead = "revocations.read"
+)
+
+// MethodRules are enforced by grpcserver authorization interceptors, methods without a rule
+// are available to any authenticated user. Roles are shared by all tenants, so signing keys and
+// revocations, which are not scoped to a tenant, are available in the default tenant only
+var MethodRules = map[string]grpcserver.AuthorizationRule{
+	"/genproto.AuthService/RotateSigningKey": grpcserver.RequireAll(
+		grpcserver.RequireTenant(domain.DefaultTenantId),
+		grpcserver.RequirePermission(PermissionSigningKeysRotate),
+	),
+	"/genproto.AuthService/RevokeUserSessions":         grpcserver.RequirePermission(PermissionSessionsRevoke),
+	"/genproto.AuthService/AssignRole":                 grpcserver.RequirePermission(PermissionRolesAssign),
+	"/genproto.AuthService/RevokeRole":                 grpcserver.RequirePermission(PermissionRolesAssign),
+	"/genproto.AuthService/ListRoles":                  grpcserver.RequirePermission(PermissionRolesRead),
+	"/genproto.AuthService/RegisterOAuthClient":        grpcserver.RequirePermission(PermissionOAuthClientsManage),
+	"/genproto.AuthService/CreateServiceAccount":       grpcserver.RequirePermission(PermissionServiceAccountsManage),
+	"/genproto.AuthService/RotateServiceAccountSecret": grpcserver.RequirePermission(PermissionServiceAccountsManage),
+	"/genproto.AuthService/DisableServiceAccount":      grpcserver.RequirePermission(PermissionServiceAccountsManage),
+	"/genproto.AuthService/DeleteServiceAccount":       grpcserver.RequirePermission(PermissionServiceAccountsManage),
+	"/genproto.AuthService/GetRevocationList": grpcserver.RequireAll(
+		grpcserver.RequireTenant(domain.DefaultTenantId),
+		grpcserver.RequireAny(
+			grpcserver.RequirePermission(PermissionRevocationsRead),
+			grpcserver.RequireScope(ScopeRevocationsRead),
+		),
+	),
+}
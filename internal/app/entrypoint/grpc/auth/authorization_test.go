@@ -0,0 +1,63 @@
+package auth
+
+import (
+	pkgauth "github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestMethodRulesRestrictGlobalPermissionsToDefaultTenant(t *testing.T) {
+	const otherTenantId domain.TenantId = 2
+
+	admin := func(tenantId domain.TenantId) *pkgauth.JwtPayload {
+		return &pkgauth.JwtPayload{
+			PrincipalType: pkgauth.PrincipalTypeUser,
+			UserId:        1,
+			TenantId:      tenantId,
+			Roles:         []string{"admin"},
+			Permissions: []string{
+				PermissionRolesRead,
+				PermissionRolesAssign,
+				PermissionSigningKeysRotate,
+				PermissionSessionsRevoke,
+				PermissionRevocationsRead,
+			},
+		}
+	}
+	serviceAccount := func(tenantId domain.TenantId) *pkgauth.JwtPayload {
+		return &pkgauth.JwtPayload{
+			PrincipalType: pkgauth.PrincipalTypeService,
+			ClientId:      "service",
+			TenantId:      tenantId,
+			Scopes:        []string{ScopeRevocationsRead},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		claims *pkgauth.JwtPayload
+		want   codes.Code
+	}{
+		{name: "rotate signing key in default tenant", method: "/genproto.AuthService/RotateSigningKey", claims: admin(domain.DefaultTenantId), want: codes.OK},
+		{name: "rotate signing key in other tenant", method: "/genproto.AuthService/RotateSigningKey", claims: admin(otherTenantId), want: codes.PermissionDenied},
+		{name: "read revocations in default tenant", method: "/genproto.AuthService/GetRevocationList", claims: admin(domain.DefaultTenantId), want: codes.OK},
+		{name: "read revocations in other tenant", method: "/genproto.AuthService/GetRevocationList", claims: admin(otherTenantId), want: codes.PermissionDenied},
+		{name: "service account of default tenant", method: "/genproto.AuthService/GetRevocationList", claims: serviceAccount(domain.DefaultTenantId), want: codes.OK},
+		{name: "service account of other tenant", method: "/genproto.AuthService/GetRevocationList", claims: serviceAccount(otherTenantId), want: codes.PermissionDenied},
+		// roles of users are checked against the tenant by AuthService
+		{name: "assign role in other tenant", method: "/genproto.AuthService/AssignRole", claims: admin(otherTenantId), want: codes.OK},
+		{name: "revoke sessions in other tenant", method: "/genproto.AuthService/RevokeUserSessions", claims: admin(otherTenantId), want: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := MethodRules[tt.method](tt.claims)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("rule of %s error = %v, want code %v", tt.method, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	"github.com/vaberof/auth-grpc/internal/domain/mfa"
+	"github.com/vaberof/auth-grpc/internal/domain/oauth"
+	"github.com/vaberof/auth-grpc/internal/domain/passkey"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	"github.com/vaberof/auth-grpc/internal/domain/serviceaccount"
+	"github.com/vaberof/auth-grpc/internal/domain/tenant"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
+	"google.golang.org/grpc/codes"
+)
+
+// Reasons are stable error codes sent in google.rpc.ErrorInfo details, clients may rely on them
+const (
+	ReasonInternal                = "INTERNAL"
+	ReasonInvalidArgument         = "INVALID_ARGUMENT"
+	ReasonUserAlreadyExists       = "USER_ALREADY_EXISTS"
+	ReasonInvalidCredentials      = "INVALID_CREDENTIALS"
+	ReasonInvalidCurrentPassword  = "INVALID_CURRENT_PASSWORD"
+	ReasonVerificationCodeExpired = "VERIFICATION_CODE_EXPIRED"
+	ReasonInvalidVerificationCode = "INVALID_VERIFICATION_CODE"
+	ReasonTooManyAttempts         = "TOO_MANY_ATTEMPTS"
+	ReasonTooManyRequests         = "TOO_MANY_REQUESTS"
+	ReasonTokenExpired            = "TOKEN_EXPIRED"
+	ReasonTokenInvalid            = "TOKEN_INVALID"
+	ReasonTokenRevoked            = "TOKEN_REVOKED"
+	ReasonRefreshTokenInvalid     = "REFRESH_TOKEN_INVALID"
+	ReasonRefreshTokenReused      = "REFRESH_TOKEN_REUSED"
+	ReasonSigningKeyNotFound      = "SIGNING_KEY_NOT_FOUND"
+	ReasonUnsupportedAlgorithm    = "UNSUPPORTED_ALGORITHM"
+	ReasonVerificationOnlyKey     = "VERIFICATION_ONLY_KEY"
+	ReasonRoleNotFound            = "ROLE_NOT_FOUND"
+	ReasonUserNotFound            = "USER_NOT_FOUND"
+	ReasonTenantNotFound          = "TENANT_NOT_FOUND"
+	ReasonMfaTokenInvalid         = "MFA_TOKEN_INVALID"
+	ReasonInvalidMfaCode          = "INVALID_MFA_CODE"
+	ReasonTotpAlreadyEnabled      = "TOTP_ALREADY_ENABLED"
+	ReasonTotpNotEnrolled         = "TOTP_NOT_ENROLLED"
+	ReasonPasskeySessionInvalid   = "PASSKEY_SESSION_INVALID"
+	ReasonPasskeyInvalid          = "PASSKEY_INVALID"
+	ReasonPasskeyAlreadyExists    = "PASSKEY_ALREADY_EXISTS"
+	ReasonUnknownProvider         = "UNKNOWN_IDENTITY_PROVIDER"
+	ReasonFederatedStateInvalid   = "FEDERATED_LOGIN_STATE_INVALID"
+	ReasonFederatedLoginFailed    = "FEDERATED_LOGIN_FAILED"
+	ReasonFederatedEmailRequired  = "FEDERATED_EMAIL_REQUIRED"
+	ReasonInvalidClientMetadata   = "INVALID_CLIENT_METADATA"
+	ReasonAuthorizationInvalid    = "AUTHORIZATION_REQUEST_INVALID"
+	ReasonOAuthClientNotFound     = "OAUTH_CLIENT_NOT_FOUND"
+	ReasonInvalidScope            = "INVALID_SCOPE"
+	ReasonServiceAccountInvalid   = "SERVICE_ACCOUNT_CREDENTIALS_INVALID"
+	ReasonServiceAccountNotFound  = "SERVICE_ACCOUNT_NOT_FOUND"
+)
+
+type errorStatus struct {
+	err     error
+	code    codes.Code
+	reason  string
+	message string
+}
+
+// errorStatuses maps domain errors to gRPC statuses, the first matching error wins
+var errorStatuses = []errorStatus{
+	{auth.ErrUserAlreadyExists, codes.AlreadyExists, ReasonUserAlreadyExists, "user with specified email already exists"},
+	{auth.ErrInvalidEmailOrPassword, codes.Unauthenticated, ReasonInvalidCredentials, "invalid email or password"},
+	{auth.ErrInvalidCurrentPassword, codes.PermissionDenied, ReasonInvalidCurrentPassword, "current password is incorrect"},
+	{auth.ErrVerificationCodeExpired, codes.FailedPrecondition, ReasonVerificationCodeExpired, "verification code has expired"},
+	{auth.ErrInvalidVerificationCode, codes.InvalidArgument, ReasonInvalidVerificationCode, "invalid verification code"},
+	{auth.ErrTooManyAttempts, codes.ResourceExhausted, ReasonTooManyAttempts, "too many attempts, request a new code"},
+	{auth.ErrTooManyRequests, codes.ResourceExhausted, ReasonTooManyRequests, "too many requests, try again later"},
+	{auth.ErrTokenExpired, codes.Unauthenticated, ReasonTokenExpired, "token has expired"},
+	{auth.ErrInvalidToken, codes.Unauthenticated, ReasonTokenInvalid, "token is invalid"},
+	{auth.ErrTokenRevoked, codes.Unauthenticated, ReasonTokenRevoked, "token has been revoked"},
+	{auth.ErrInvalidRefreshToken, codes.Unauthenticated, ReasonRefreshTokenInvalid, "refresh token is invalid"},
+	{auth.ErrRefreshTokenReused, codes.Unauthenticated, ReasonRefreshTokenReused, "refresh token has already been used"},
+	{auth.ErrSigningKeyNotFound, codes.NotFound, ReasonSigningKeyNotFound, "signing key not found"},
+	{user.ErrUserNotFound, codes.NotFound, ReasonUserNotFound, "user not found"},
+	{role.ErrRoleNotFound, codes.NotFound, ReasonRoleNotFound, "role not found"},
+	{role.ErrUserNotFound, codes.NotFound, ReasonUserNotFound, "user not found"},
+	{tenant.ErrTenantNotFound, codes.NotFound, ReasonTenantNotFound, "tenant not found"},
+	{auth.ErrInvalidMfaToken, codes.Unauthenticated, ReasonMfaTokenInvalid, "mfa token is invalid or has expired, log in again"},
+	{mfa.ErrInvalidCode, codes.Unauthenticated, ReasonInvalidMfaCode, "invalid mfa code"},
+	{mfa.ErrTotpAlreadyEnabled, codes.FailedPrecondition, ReasonTotpAlreadyEnabled, "totp is already enabled"},
+	{mfa.ErrTotpNotEnrolled, codes.FailedPrecondition, ReasonTotpNotEnrolled, "totp is not enrolled"},
+	{mfa.ErrUserNotFound, codes.NotFound, ReasonUserNotFound, "user not found"},
+	{auth.ErrInvalidPasskeySession, codes.FailedPrecondition, ReasonPasskeySessionInvalid, "passkey session is invalid or has expired, begin again"},
+	{auth.ErrInvalidPasskeyRegistration, codes.InvalidArgument, ReasonPasskeyInvalid, "passkey registration response is invalid"},
+	{auth.ErrInvalidPasskeyAssertion, codes.Unauthenticated, ReasonPasskeyInvalid, "passkey is invalid"},
+	{passkey.ErrPasskeyAlreadyExists, codes.AlreadyExists, ReasonPasskeyAlreadyExists, "passkey is already registered"},
+	{passkey.ErrUserNotFound, codes.NotFound, ReasonUserNotFound, "user not found"},
+	{auth.ErrUnknownIdentityProvider, codes.InvalidArgument, ReasonUnknownProvider, "identity provider is not configured"},
+	{auth.ErrInvalidFederatedLoginState, codes.FailedPrecondition, ReasonFederatedStateInvalid, "federated login state is invalid or has expired, start again"},
+	{auth.ErrFederatedLoginFailed, codes.Unauthenticated, ReasonFederatedLoginFailed, "identity provider rejected the login"},
+	{auth.ErrFederatedEmailRequired, codes.FailedPrecondition, ReasonFederatedEmailRequired, "identity provider did not return a verified email"},
+	{auth.ErrInvalidOAuthClientMetadata, codes.InvalidArgument, ReasonInvalidClientMetadata, "oauth client metadata is invalid"},
+	{auth.ErrAuthorizationRequestExpired, codes.FailedPrecondition, ReasonAuthorizationInvalid, "authorization request is invalid or has expired, start again"},
+	{oauth.ErrClientNotFound, codes.NotFound, ReasonOAuthClientNotFound, "oauth client not found"},
+	{oauth.ErrTenantNotFound, codes.NotFound, ReasonTenantNotFound, "tenant not found"},
+	{oauth.ErrUserNotFound, codes.NotFound, ReasonUserNotFound, "user not found"},
+	{auth.ErrInvalidScope, codes.InvalidArgument, ReasonInvalidScope, "requested scope is not allowed"},
+	{auth.ErrInvalidServiceAccountCredentials, codes.Unauthenticated, ReasonServiceAccountInvalid, "invalid client id or secret"},
+	{serviceaccount.ErrServiceAccountNotFound, codes.NotFound, ReasonServiceAccountNotFound, "service account not found"},
+	{serviceaccount.ErrTenantNotFound, codes.NotFound, ReasonTenantNotFound, "tenant not found"},
+	{accesstoken.ErrUnsupportedAlgorithm, codes.InvalidArgument, ReasonUnsupportedAlgorithm, "unsupported signing algorithm"},
+	{accesstoken.ErrVerificationOnlyKey, codes.FailedPrecondition, ReasonVerificationOnlyKey, "key can only be used for verification"},
+}
+
+// toStatusError converts an error returned by the domain to gRPC status error with
+// google.rpc.ErrorInfo details. Unknown errors are reported as Internal without the error text,
+// validation errors additionally carry google.rpc.BadRequest details with field violations
+func toStatusError(err error) error {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return grpcserver.NewValidationStatusError(ReasonInvalidArgument, validationErr)
+	}
+
+	for _, errStatus := range errorStatuses {
+		if errors.Is(err, errStatus.err) {
+			return grpcserver.NewStatusError(errStatus.code, errStatus.reason, errStatus.message)
+		}
+	}
+	return grpcserver.NewStatusError(codes.Internal, ReasonInternal, "internal server error")
+}
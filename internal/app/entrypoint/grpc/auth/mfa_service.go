@@ -0,0 +1,11 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/mfa"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type MfaService interface {
+	EnrollTotp(userId domain.UserId, accountName string) (*mfa.TotpEnrollment, error)
+	ConfirmTotp(userId domain.UserId, code string) ([]string, error)
+}
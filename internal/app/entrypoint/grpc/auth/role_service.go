@@ -0,0 +1,11 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+)
+
+// RoleService lists the roles shared by all tenants, roles of users are managed by AuthService,
+// which checks that the users belong to the tenant of the request
+type RoleService interface {
+	ListRoles() ([]*role.Role, error)
+}
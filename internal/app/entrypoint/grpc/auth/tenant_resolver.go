@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"github.com/vaberof/auth-grpc/internal/domain/tenant"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"strconv"
+)
+
+type TenantService interface {
+	GetById(id domain.TenantId) (*tenant.Tenant, error)
+	GetByName(name string) (*tenant.Tenant, error)
+}
+
+// TenantResolver adapts TenantService to grpcserver.TenantResolver,
+// a tenant is identified either by its numeric id or by its name
+type TenantResolver struct {
+	tenantService TenantService
+}
+
+func NewTenantResolver(tenantService TenantService) *TenantResolver {
+	return &TenantResolver{tenantService: tenantService}
+}
+
+func (r *TenantResolver) ResolveTenant(ctx context.Context, value string) (domain.TenantId, error) {
+	var domainTenant *tenant.Tenant
+	var err error
+
+	if id, parseErr := strconv.ParseInt(value, 10, 64); parseErr == nil {
+		domainTenant, err = r.tenantService.GetById(domain.TenantId(id))
+	} else {
+		domainTenant, err = r.tenantService.GetByName(value)
+	}
+	if err != nil {
+		return 0, toStatusError(err)
+	}
+
+	return domainTenant.Id, nil
+}
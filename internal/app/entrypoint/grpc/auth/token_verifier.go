@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	pkgauth "github.com/vaberof/auth-grpc/pkg/auth"
+	"google.golang.org/grpc"
+	"slices"
+)
+
+// PublicMethods do not require an access token, the other methods are authenticated
+// by grpcserver auth interceptors. Tokens of some public methods are passed in requests
+var PublicMethods = []string{
+	"/genproto.AuthService/Register",
+	"/genproto.AuthService/Login",
+	"/genproto.AuthService/CompleteMfa",
+	"/genproto.AuthService/StartEmailLogin",
+	"/genproto.AuthService/CompleteEmailLogin",
+	"/genproto.AuthService/BeginPasskeyLogin",
+	"/genproto.AuthService/FinishPasskeyLogin",
+	"/genproto.AuthService/StartFederatedLogin",
+	"/genproto.AuthService/CompleteFederatedLogin",
+	"/genproto.AuthService/Verify",
+	"/genproto.AuthService/VerifyToken",
+	"/genproto.AuthService/Refresh",
+	"/genproto.AuthService/Logout",
+	"/genproto.AuthService/RevokeToken",
+	"/genproto.AuthService/GetJwks",
+	"/genproto.AuthService/RequestPasswordReset",
+	"/genproto.AuthService/ConfirmPasswordReset",
+	"/genproto.AuthService/IssueServiceToken",
+}
+
+// ServiceAccountMethods accept tokens of service accounts, they are authorized by scopes of the tokens
+var ServiceAccountMethods = []string{
+	"/genproto.AuthService/GetRevocationList",
+}
+
+// TokenVerifier adapts AuthService to grpcserver.TokenVerifier,
+// unlike offline verification it also checks token revocation.
+// Tokens issued to OAuth clients are rejected, they are valid only for the resource servers they are issued for.
+// Tokens of service accounts are accepted by ServiceAccountMethods only
+type TokenVerifier struct {
+	authService AuthService
+}
+
+func NewTokenVerifier(authService AuthService) *TokenVerifier {
+	return &TokenVerifier{authService: authService}
+}
+
+func (v *TokenVerifier) VerifyToken(ctx context.Context, token string) (*pkgauth.JwtPayload, error) {
+	tokenInfo, err := v.authService.VerifyToken(auth.AccessToken(token))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	if tokenInfo.ClientId != "" && !isServiceAccountCall(ctx, tokenInfo) {
+		return nil, toStatusError(auth.ErrInvalidToken)
+	}
+
+	return &pkgauth.JwtPayload{
+		TokenId:       tokenInfo.TokenId,
+		PrincipalType: tokenInfo.PrincipalType,
+		UserId:        tokenInfo.UserId,
+		TenantId:      tokenInfo.TenantId,
+		Email:         tokenInfo.Email,
+		Roles:         tokenInfo.Roles,
+		Permissions:   tokenInfo.Permissions,
+		Scopes:        tokenInfo.Scopes,
+		IssuedAt:      tokenInfo.IssuedAt,
+		ExpiredAt:     tokenInfo.ExpiresAt,
+	}, nil
+}
+
+func isServiceAccountCall(ctx context.Context, tokenInfo *auth.TokenInfo) bool {
+	if tokenInfo.PrincipalType != pkgauth.PrincipalTypeService {
+		return false
+	}
+
+	method, ok := grpc.Method(ctx)
+
+	return ok && slices.Contains(ServiceAccountMethods, method)
+}
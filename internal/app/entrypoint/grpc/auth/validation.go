@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	pb "github.com/vaberof/auth-grpc/genproto/auth_service"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"unicode/utf8"
+)
+
+const verificationCodeLength = 6
+
+const totpCodeLength = 6
+
+// maxPasswordLength guards password hashing from huge inputs, password policy is checked by the domain
+const maxPasswordLength = 1024
+
+// maxMfaCodeLength is enough for both authenticator app codes and recovery codes
+const maxMfaCodeLength = 32
+
+// maxCredentialJsonLength guards parsing of WebAuthn responses, real ones are a few KiB
+const maxCredentialJsonLength = 64 * 1024
+
+const maxPasskeyNameLength = 64
+
+const maxIdentityProviderNameLength = 64
+
+// maxAuthorizationCodeLength is generous, providers do not limit the length of their codes
+const (
+	maxFederatedLoginStateLength = 256
+	maxAuthorizationCodeLength   = 2048
+)
+
+const (
+	maxOAuthClientNameLength        = 128
+	maxRedirectUriLength            = 2048
+	maxRedirectUris                 = 16
+	maxScopeLength                  = 128
+	maxScopes                       = 64
+	maxAuthorizationRequestIdLength = 64
+)
+
+const (
+	maxServiceAccountNameLength = 128
+	maxClientIdLength           = 64
+	maxClientSecretLength       = 128
+)
+
+func validateRegisterRequest(req *pb.RegisterRequest) error {
+	email := domain.Email(req.Email)
+	password := domain.Password(req.Password)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Field("password", password.ValidateLength(0, maxPasswordLength)).
+		Err()
+}
+
+func validateLoginRequest(req *pb.LoginRequest) error {
+	email := domain.Email(req.Email)
+	password := domain.Password(req.Password)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Field("password", password.ValidateLength(0, maxPasswordLength)).
+		Err()
+}
+
+func validateVerifyRequest(req *pb.VerifyRequest) error {
+	email := domain.Email(req.Email)
+	code := domain.Code(req.Code)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Field("code", code.Validate(verificationCodeLength)).
+		Err()
+}
+
+func validateVerifyTokenRequest(req *pb.VerifyTokenRequest) error {
+	return domain.NewValidator().
+		Field("token", required(req.Token)).
+		Err()
+}
+
+func validateRefreshRequest(req *pb.RefreshRequest) error {
+	return domain.NewValidator().
+		Field("refresh_token", required(req.RefreshToken)).
+		Err()
+}
+
+func validateLogoutRequest(req *pb.LogoutRequest) error {
+	return domain.NewValidator().
+		Field("access_token", required(req.AccessToken)).
+		Err()
+}
+
+func validateRevokeTokenRequest(req *pb.RevokeTokenRequest) error {
+	return domain.NewValidator().
+		Field("token", required(req.Token)).
+		Err()
+}
+
+func validateRevokeUserSessionsRequest(req *pb.RevokeUserSessionsRequest) error {
+	return domain.NewValidator().
+		Field("user_id", positive(req.UserId)).
+		Err()
+}
+
+func validateRequestPasswordResetRequest(req *pb.RequestPasswordResetRequest) error {
+	email := domain.Email(req.Email)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Err()
+}
+
+func validateConfirmPasswordResetRequest(req *pb.ConfirmPasswordResetRequest) error {
+	email := domain.Email(req.Email)
+	code := domain.Code(req.Code)
+	newPassword := domain.Password(req.NewPassword)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Field("code", code.Validate(verificationCodeLength)).
+		Field("new_password", newPassword.ValidateLength(0, maxPasswordLength)).
+		Err()
+}
+
+func validateChangePasswordRequest(req *pb.ChangePasswordRequest) error {
+	currentPassword := domain.Password(req.CurrentPassword)
+	newPassword := domain.Password(req.NewPassword)
+
+	return domain.NewValidator().
+		Field("current_password", currentPassword.ValidateLength(0, maxPasswordLength)).
+		Field("new_password", newPassword.ValidateLength(0, maxPasswordLength)).
+		Err()
+}
+
+func validateAssignRoleRequest(req *pb.AssignRoleRequest) error {
+	return domain.NewValidator().
+		Field("user_id", positive(req.UserId)).
+		Field("role", required(req.Role)).
+		Err()
+}
+
+func validateRevokeRoleRequest(req *pb.RevokeRoleRequest) error {
+	return domain.NewValidator().
+		Field("user_id", positive(req.UserId)).
+		Field("role", required(req.Role)).
+		Err()
+}
+
+func validateStartEmailLoginRequest(req *pb.StartEmailLoginRequest) error {
+	email := domain.Email(req.Email)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Err()
+}
+
+func validateCompleteEmailLoginRequest(req *pb.CompleteEmailLoginRequest) error {
+	email := domain.Email(req.Email)
+	code := domain.Code(req.Code)
+
+	return domain.NewValidator().
+		Field("email", email.Validate()).
+		Field("code", code.Validate(verificationCodeLength)).
+		Err()
+}
+
+func validateCompleteMfaRequest(req *pb.CompleteMfaRequest) error {
+	return domain.NewValidator().
+		Field("mfa_token", required(req.MfaToken)).
+		Field("code", requiredMaxLength(req.Code, maxMfaCodeLength)).
+		Err()
+}
+
+func validateConfirmTotpRequest(req *pb.ConfirmTotpRequest) error {
+	code := domain.Code(req.Code)
+
+	return domain.NewValidator().
+		Field("code", code.Validate(totpCodeLength)).
+		Err()
+}
+
+func validateFinishPasskeyRegistrationRequest(req *pb.FinishPasskeyRegistrationRequest) error {
+	return domain.NewValidator().
+		Field("credential_json", requiredMaxLength(req.CredentialJson, maxCredentialJsonLength)).
+		Field("name", maxLength(req.Name, maxPasskeyNameLength)).
+		Err()
+}
+
+func validateFinishPasskeyLoginRequest(req *pb.FinishPasskeyLoginRequest) error {
+	return domain.NewValidator().
+		Field("session_id", required(req.SessionId)).
+		Field("credential_json", requiredMaxLength(req.CredentialJson, maxCredentialJsonLength)).
+		Err()
+}
+
+func required(value string) error {
+	if value == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func requiredMaxLength(value string, length int) error {
+	if value == "" {
+		return errors.New("must not be empty")
+	}
+	return maxLength(value, length)
+}
+
+// maxLength counts characters like the password policy and VARCHAR columns do
+func maxLength(value string, length int) error {
+	if utf8.RuneCountInString(value) > length {
+		return fmt.Errorf("must not be longer than %d characters", length)
+	}
+	return nil
+}
+
+func positive(value int64) error {
+	if value <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+func validateStartFederatedLoginRequest(req *pb.StartFederatedLoginRequest) error {
+	return domain.NewValidator().
+		Field("provider", requiredMaxLength(req.Provider, maxIdentityProviderNameLength)).
+		Err()
+}
+
+func validateCompleteFederatedLoginRequest(req *pb.CompleteFederatedLoginRequest) error {
+	return domain.NewValidator().
+		Field("state", requiredMaxLength(req.State, maxFederatedLoginStateLength)).
+		Field("code", requiredMaxLength(req.Code, maxAuthorizationCodeLength)).
+		Err()
+}
+
+func validateRegisterOAuthClientRequest(req *pb.RegisterOAuthClientRequest) error {
+	return domain.NewValidator().
+		Field("name", requiredMaxLength(req.Name, maxOAuthClientNameLength)).
+		Field("redirect_uris", maxItems(req.RedirectUris, maxRedirectUris, maxRedirectUriLength)).
+		Field("grant_types", requiredItems(req.GrantTypes)).
+		Field("scopes", maxItems(req.Scopes, maxScopes, maxScopeLength)).
+		Err()
+}
+
+func validateGetAuthorizationRequest(req *pb.GetAuthorizationRequest) error {
+	return domain.NewValidator().
+		Field("request_id", requiredMaxLength(req.RequestId, maxAuthorizationRequestIdLength)).
+		Err()
+}
+
+func validateCompleteAuthorizationRequest(req *pb.CompleteAuthorizationRequest) error {
+	return domain.NewValidator().
+		Field("request_id", requiredMaxLength(req.RequestId, maxAuthorizationRequestIdLength)).
+		Err()
+}
+
+func validateCreateServiceAccountRequest(req *pb.CreateServiceAccountRequest) error {
+	return domain.NewValidator().
+		Field("name", requiredMaxLength(req.Name, maxServiceAccountNameLength)).
+		Field("scopes", requiredMaxItems(req.Scopes, maxScopes, maxScopeLength)).
+		Err()
+}
+
+func validateRotateServiceAccountSecretRequest(req *pb.RotateServiceAccountSecretRequest) error {
+	return domain.NewValidator().
+		Field("client_id", requiredMaxLength(req.ClientId, maxClientIdLength)).
+		Err()
+}
+
+func validateDisableServiceAccountRequest(req *pb.DisableServiceAccountRequest) error {
+	return domain.NewValidator().
+		Field("client_id", requiredMaxLength(req.ClientId, maxClientIdLength)).
+		Err()
+}
+
+func validateDeleteServiceAccountRequest(req *pb.DeleteServiceAccountRequest) error {
+	return domain.NewValidator().
+		Field("client_id", requiredMaxLength(req.ClientId, maxClientIdLength)).
+		Err()
+}
+
+func validateIssueServiceTokenRequest(req *pb.IssueServiceTokenRequest) error {
+	return domain.NewValidator().
+		Field("client_id", requiredMaxLength(req.ClientId, maxClientIdLength)).
+		Field("client_secret", requiredMaxLength(req.ClientSecret, maxClientSecretLength)).
+		Field("scopes", maxItems(req.Scopes, maxScopes, maxScopeLength)).
+		Err()
+}
+
+func requiredItems(values []string) error {
+	if len(values) == 0 {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func requiredMaxItems(values []string, count int, length int) error {
+	err := requiredItems(values)
+	if err != nil {
+		return err
+	}
+	return maxItems(values, count, length)
+}
+
+func maxItems(values []string, count int, length int) error {
+	if len(values) > count {
+		return fmt.Errorf("must not have more than %d items", count)
+	}
+	for i, value := range values {
+		err := requiredMaxLength(value, length)
+		if err != nil {
+			return fmt.Errorf("item %d %w", i, err)
+		}
+	}
+	return nil
+}
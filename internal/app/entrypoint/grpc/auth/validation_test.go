@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"errors"
+	pb "github.com/vaberof/auth-grpc/genproto/auth_service"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+func TestValidationErrorToBadRequest(t *testing.T) {
+	err := validateRegisterRequest(&pb.RegisterRequest{Email: "not an email", Password: ""})
+
+	st, ok := status.FromError(toStatusError(err))
+	if !ok {
+		t.Fatalf("toStatusError() = %v, want status error", err)
+	}
+	if st.Code() != codes.InvalidArgument {
+		t.Errorf("Code() = %v, want %v", st.Code(), codes.InvalidArgument)
+	}
+
+	var errorInfo *errdetails.ErrorInfo
+	var badRequest *errdetails.BadRequest
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			errorInfo = detail
+		case *errdetails.BadRequest:
+			badRequest = detail
+		}
+	}
+
+	if errorInfo == nil || errorInfo.Reason != ReasonInvalidArgument {
+		t.Errorf("ErrorInfo = %v, want reason %s", errorInfo, ReasonInvalidArgument)
+	}
+	if badRequest == nil {
+		t.Fatalf("BadRequest details are missing")
+	}
+
+	var fields []string
+	for _, violation := range badRequest.FieldViolations {
+		fields = append(fields, violation.Field)
+	}
+	if strings.Join(fields, ",") != "email,password" {
+		t.Errorf("violated fields = %v, want [email password]", fields)
+	}
+}
+
+func TestMaxLengthCountsCharacters(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{name: "ascii within limit", value: "abcd"},
+		{name: "ascii over limit", value: "abcde", wantErr: true},
+		// 4 characters take 8 bytes
+		{name: "multibyte within limit", value: "абвг"},
+		{name: "multibyte over limit", value: "абвгд", wantErr: true},
+		{name: "emoji within limit", value: "🔑🔑🔑🔑"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := maxLength(tt.value, 4)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("maxLength() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			err = requiredMaxLength(tt.value, 4)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("requiredMaxLength() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantFields []string
+	}{
+		{
+			name: "valid create service account",
+			err:  validateCreateServiceAccountRequest(&pb.CreateServiceAccountRequest{Name: "billing", Scopes: []string{"revocations.read"}}),
+		},
+		{
+			name:       "service account name of multibyte characters over limit",
+			err:        validateCreateServiceAccountRequest(&pb.CreateServiceAccountRequest{Name: strings.Repeat("я", maxServiceAccountNameLength+1), Scopes: []string{"revocations.read"}}),
+			wantFields: []string{"name"},
+		},
+		{
+			name: "service account name of multibyte characters within limit",
+			err:  validateCreateServiceAccountRequest(&pb.CreateServiceAccountRequest{Name: strings.Repeat("я", maxServiceAccountNameLength), Scopes: []string{"revocations.read"}}),
+		},
+		{
+			name:       "missing scopes",
+			err:        validateCreateServiceAccountRequest(&pb.CreateServiceAccountRequest{Name: "billing"}),
+			wantFields: []string{"scopes"},
+		},
+		{
+			name:       "empty scope",
+			err:        validateIssueServiceTokenRequest(&pb.IssueServiceTokenRequest{ClientId: "client", ClientSecret: "secret", Scopes: []string{""}}),
+			wantFields: []string{"scopes"},
+		},
+		{
+			name:       "missing client credentials",
+			err:        validateIssueServiceTokenRequest(&pb.IssueServiceTokenRequest{}),
+			wantFields: []string{"client_id", "client_secret"},
+		},
+		{
+			name:       "password over limit",
+			err:        validateLoginRequest(&pb.LoginRequest{Email: "user@example.com", Password: strings.Repeat("a", maxPasswordLength+1)}),
+			wantFields: []string{"password"},
+		},
+		{
+			name:       "verification code of letters",
+			err:        validateVerifyRequest(&pb.VerifyRequest{Email: "user@example.com", Code: "abcdef"}),
+			wantFields: []string{"code"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.wantFields == nil {
+				if tt.err != nil {
+					t.Errorf("validation error = %v, want nil", tt.err)
+				}
+				return
+			}
+
+			var validationErr *domain.ValidationError
+			if !errors.As(tt.err, &validationErr) {
+				t.Fatalf("validation error = %v, want *domain.ValidationError", tt.err)
+			}
+
+			var fields []string
+			for _, violation := range validationErr.Violations {
+				fields = append(fields, violation.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.wantFields, ",") {
+				t.Errorf("violated fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package authorization
+
+import (
+	"context"
+	pb "github.com/vaberof/auth-grpc/genproto/authorization_service"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type serverAPI struct {
+	pb.UnimplementedAuthorizationServiceServer
+	authorizationService AuthorizationService
+}
+
+// Register registers authorization service API. Permissions required by the methods are listed in MethodRules
+func Register(gRPC *grpc.Server, authorizationService AuthorizationService) {
+	pb.RegisterAuthorizationServiceServer(gRPC, &serverAPI{authorizationService: authorizationService})
+}
+
+func (s *serverAPI) Check(ctx context.Context, req *pb.CheckRequest) (*pb.CheckResponse, error) {
+	err := validateCheckRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	allowed, err := s.authorizationService.Check(toObject(req.Resource), req.Permission, toSubject(req.Subject))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.CheckResponse{Allowed: allowed}, nil
+}
+
+func (s *serverAPI) WriteRelationships(ctx context.Context, req *pb.WriteRelationshipsRequest) (*emptypb.Empty, error) {
+	err := validateWriteRelationshipsRequest(req)
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+
+	err = s.authorizationService.WriteRelationships(toRelationships(req.Writes), toRelationships(req.Deletes))
+	if err != nil {
+		return &emptypb.Empty{}, toStatusError(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *serverAPI) ListObjects(ctx context.Context, req *pb.ListObjectsRequest) (*pb.ListObjectsResponse, error) {
+	err := validateListObjectsRequest(req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	ids, err := s.authorizationService.ListObjects(req.ResourceType, req.Permission, toSubject(req.Subject))
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return &pb.ListObjectsResponse{ResourceIds: ids}, nil
+}
+
+func toObject(object *pb.ObjectReference) rebac.Object {
+	return rebac.Object{
+		Type: object.GetType(),
+		Id:   object.GetId(),
+	}
+}
+
+func toSubject(subject *pb.SubjectReference) rebac.Subject {
+	return rebac.Subject{
+		Object:   toObject(subject.GetObject()),
+		Relation: subject.GetRelation(),
+	}
+}
+
+func toRelationships(relationships []*pb.Relationship) []rebac.Relationship {
+	result := make([]rebac.Relationship, len(relationships))
+	for i, relationship := range relationships {
+		result[i] = rebac.Relationship{
+			Resource: toObject(relationship.Resource),
+			Relation: relationship.Relation,
+			Subject:  toSubject(relationship.Subject),
+		}
+	}
+	return result
+}
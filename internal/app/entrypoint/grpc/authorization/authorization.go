@@ -0,0 +1,18 @@
+package authorization
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
+)
+
+// Permissions granted by roles, the admin role seeded by migrations has all of them
+const (
+	PermissionRelationshipsRead  = "relationships.read"
+	PermissionRelationshipsWrite = "relationships.write"
+)
+
+// MethodRules are enforced by grpcserver authorization interceptors
+var MethodRules = map[string]grpcserver.AuthorizationRule{
+	"/genproto.AuthorizationService/Check":              grpcserver.RequirePermission(PermissionRelationshipsRead),
+	"/genproto.AuthorizationService/ListObjects":        grpcserver.RequirePermission(PermissionRelationshipsRead),
+	"/genproto.AuthorizationService/WriteRelationships": grpcserver.RequirePermission(PermissionRelationshipsWrite),
+}
@@ -0,0 +1,11 @@
+package authorization
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+)
+
+type AuthorizationService interface {
+	Check(resource rebac.Object, permission string, subject rebac.Subject) (bool, error)
+	WriteRelationships(writes []rebac.Relationship, deletes []rebac.Relationship) error
+	ListObjects(resourceType string, permission string, subject rebac.Subject) ([]string, error)
+}
@@ -0,0 +1,62 @@
+package authorization
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/grpc/grpcserver"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+	"google.golang.org/grpc/codes"
+)
+
+// Reasons are stable error codes sent in google.rpc.ErrorInfo details, clients may rely on them
+const (
+	ReasonInternal              = "INTERNAL"
+	ReasonInvalidArgument       = "INVALID_ARGUMENT"
+	ReasonInvalidRelationship   = "INVALID_RELATIONSHIP"
+	ReasonUnknownType           = "UNKNOWN_TYPE"
+	ReasonUnknownRelation       = "UNKNOWN_RELATION"
+	ReasonSubjectTypeNotAllowed = "SUBJECT_TYPE_NOT_ALLOWED"
+	ReasonTooManyRelationships  = "TOO_MANY_RELATIONSHIPS"
+	ReasonMaxCheckDepthExceeded = "MAX_CHECK_DEPTH_EXCEEDED"
+)
+
+type errorStatus struct {
+	err    error
+	code   codes.Code
+	reason string
+}
+
+// errorStatuses maps rebac errors to gRPC statuses, the first matching error wins.
+// Messages of the errors are sent as is, because they point to the invalid part of the request
+var errorStatuses = []errorStatus{
+	{rebac.ErrInvalidRelationship, codes.InvalidArgument, ReasonInvalidRelationship},
+	{rebac.ErrUnknownType, codes.InvalidArgument, ReasonUnknownType},
+	{rebac.ErrUnknownRelation, codes.InvalidArgument, ReasonUnknownRelation},
+	{rebac.ErrSubjectTypeNotAllowed, codes.InvalidArgument, ReasonSubjectTypeNotAllowed},
+	{rebac.ErrTooManyRelationships, codes.InvalidArgument, ReasonTooManyRelationships},
+	{rebac.ErrMaxDepthExceeded, codes.FailedPrecondition, ReasonMaxCheckDepthExceeded},
+}
+
+// toStatusError converts an error returned by the domain to gRPC status error with
+// google.rpc.ErrorInfo details. Unknown errors are reported as Internal without the error text
+func toStatusError(err error) error {
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		return grpcserver.NewValidationStatusError(ReasonInvalidArgument, validationErr)
+	}
+
+	for _, errStatus := range errorStatuses {
+		if errors.Is(err, errStatus.err) {
+			return grpcserver.NewStatusError(errStatus.code, errStatus.reason, domainErrorMessage(err))
+		}
+	}
+	return grpcserver.NewStatusError(codes.Internal, ReasonInternal, "internal server error")
+}
+
+// domainErrorMessage strips the operation the domain has wrapped the error with
+func domainErrorMessage(err error) string {
+	if unwrapped := errors.Unwrap(err); unwrapped != nil {
+		return unwrapped.Error()
+	}
+	return err.Error()
+}
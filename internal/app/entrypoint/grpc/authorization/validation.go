@@ -0,0 +1,76 @@
+package authorization
+
+import (
+	"errors"
+	"fmt"
+	pb "github.com/vaberof/auth-grpc/genproto/authorization_service"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+func validateCheckRequest(req *pb.CheckRequest) error {
+	validator := domain.NewValidator().
+		Field("permission", required(req.Permission))
+
+	validateObject(validator, "resource", req.Resource)
+	validateSubject(validator, "subject", req.Subject)
+
+	return validator.Err()
+}
+
+func validateWriteRelationshipsRequest(req *pb.WriteRelationshipsRequest) error {
+	validator := domain.NewValidator()
+
+	if len(req.Writes) == 0 && len(req.Deletes) == 0 {
+		validator.Violation("writes", "writes or deletes must not be empty")
+	}
+
+	for i, relationship := range req.Writes {
+		validateRelationship(validator, fmt.Sprintf("writes[%d]", i), relationship)
+	}
+	for i, relationship := range req.Deletes {
+		validateRelationship(validator, fmt.Sprintf("deletes[%d]", i), relationship)
+	}
+
+	return validator.Err()
+}
+
+func validateListObjectsRequest(req *pb.ListObjectsRequest) error {
+	validator := domain.NewValidator().
+		Field("resource_type", required(req.ResourceType)).
+		Field("permission", required(req.Permission))
+
+	validateSubject(validator, "subject", req.Subject)
+
+	return validator.Err()
+}
+
+func validateRelationship(validator *domain.Validator, field string, relationship *pb.Relationship) {
+	validateObject(validator, field+".resource", relationship.Resource)
+	validator.Field(field+".relation", required(relationship.Relation))
+	validateSubject(validator, field+".subject", relationship.Subject)
+}
+
+func validateSubject(validator *domain.Validator, field string, subject *pb.SubjectReference) {
+	if subject == nil {
+		validator.Violation(field, "must not be empty")
+		return
+	}
+	validateObject(validator, field+".object", subject.Object)
+}
+
+func validateObject(validator *domain.Validator, field string, object *pb.ObjectReference) {
+	if object == nil {
+		validator.Violation(field, "must not be empty")
+		return
+	}
+	validator.
+		Field(field+".type", required(object.Type)).
+		Field(field+".id", required(object.Id))
+}
+
+func required(value string) error {
+	if value == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,362 @@
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	"github.com/vaberof/auth-grpc/internal/domain/oauth"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxRequestBodySize limits form bodies of the token, introspection and revocation endpoints
+const maxRequestBodySize = 64 * 1024
+
+// paths of the endpoints published in the discovery document
+const (
+	authorizationPath = "/oauth2/authorize"
+	tokenPath         = "/oauth2/token"
+	introspectionPath = "/oauth2/introspect"
+	revocationPath    = "/oauth2/revoke"
+	userInfoPath      = "/oauth2/userinfo"
+	jwksPath          = "/.well-known/jwks.json"
+)
+
+const discoveryCacheControl = "public, max-age=300"
+
+type handlerAPI struct {
+	authService AuthService
+}
+
+// Register registers the endpoints of the OAuth2 authorization server and the OpenID provider. Clients authenticate
+// to the token, introspection and revocation endpoints with client_secret_basic or client_secret_post methods
+func Register(mux *http.ServeMux, authService AuthService) {
+	api := &handlerAPI{authService: authService}
+
+	mux.HandleFunc("GET "+authorizationPath, api.Authorize)
+	mux.HandleFunc("POST "+tokenPath, api.Token)
+	mux.HandleFunc("POST "+introspectionPath, api.Introspect)
+	mux.HandleFunc("POST "+revocationPath, api.Revoke)
+	mux.HandleFunc("GET "+userInfoPath, api.UserInfo)
+	mux.HandleFunc("POST "+userInfoPath, api.UserInfo)
+	mux.HandleFunc("GET /.well-known/openid-configuration", api.GetOpenIdConfiguration)
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IdToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// introspectionResponse is the response of RFC 7662 2.2, 'sub' is the user id or the client id
+// of client credentials tokens
+type introspectionResponse struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope,omitempty"`
+	ClientId  string   `json:"client_id,omitempty"`
+	TokenType string   `json:"token_type,omitempty"`
+	Subject   string   `json:"sub,omitempty"`
+	TenantId  int64    `json:"tenant_id,omitempty"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  []string `json:"aud,omitempty"`
+	TokenId   string   `json:"jti,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+// openIdConfiguration is the discovery document of OpenID Connect Discovery 1.0
+type openIdConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksUri                           string   `json:"jwks_uri"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+type userInfoResponse struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// Authorize redirects the user to the consent page. Errors are redirected to the redirect uri of the client
+// unless the client or the redirect uri itself is invalid
+func (h *handlerAPI) Authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	request := &auth.AuthorizationRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientId:            query.Get("client_id"),
+		RedirectUri:         query.Get("redirect_uri"),
+		Scopes:              strings.Fields(query.Get("scope")),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+		Nonce:               query.Get("nonce"),
+	}
+
+	if request.ClientId == "" || request.RedirectUri == "" {
+		writeErrorResponse(w, http.StatusBadRequest, errorInvalidRequest, "client_id and redirect_uri are required")
+		return
+	}
+
+	consentPageUrl, err := h.authService.StartAuthorization(request)
+	if err != nil {
+		errStatus := toErrorStatus(err)
+		if errors.Is(err, auth.ErrInvalidOAuthClient) {
+			writeErrorResponse(w, http.StatusBadRequest, errorInvalidRequest, "client is not registered")
+			return
+		}
+		if errors.Is(err, auth.ErrInvalidRedirectUri) || errStatus.statusCode == http.StatusInternalServerError {
+			writeError(w, err)
+			return
+		}
+
+		redirectUri, err := errorRedirectUri(request.RedirectUri, errStatus, request.State)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		http.Redirect(w, r, redirectUri, http.StatusFound)
+		return
+	}
+
+	http.Redirect(w, r, consentPageUrl, http.StatusFound)
+}
+
+func (h *handlerAPI) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	clientId, clientSecret, ok := parseClientRequest(w, r)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.authService.ExchangeToken(&auth.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Code:         r.PostForm.Get("code"),
+		RedirectUri:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scopes:       strings.Fields(r.PostForm.Get("scope")),
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, &tokenResponse{
+		AccessToken:  string(tokens.AccessToken),
+		TokenType:    tokens.TokenType,
+		ExpiresIn:    int64(tokens.ExpiresIn / time.Second),
+		RefreshToken: string(tokens.RefreshToken),
+		IdToken:      tokens.IdToken,
+		Scope:        strings.Join(tokens.Scopes, " "),
+	})
+}
+
+func (h *handlerAPI) Introspect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	clientId, clientSecret, ok := parseClientRequest(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeErrorResponse(w, http.StatusBadRequest, errorInvalidRequest, "token is required")
+		return
+	}
+
+	introspection, err := h.authService.IntrospectToken(clientId, clientSecret, token, r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeJson(w, http.StatusOK, toIntrospectionResponse(introspection))
+}
+
+// Revoke responds with 200 to unknown tokens and tokens of other clients as RFC 7009 2.2 requires
+func (h *handlerAPI) Revoke(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret, ok := parseClientRequest(w, r)
+	if !ok {
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	if token == "" {
+		writeErrorResponse(w, http.StatusBadRequest, errorInvalidRequest, "token is required")
+		return
+	}
+
+	err := h.authService.RevokeOAuthToken(clientId, clientSecret, token)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// UserInfo returns the claims of the user, the access token is sent in 'Authorization' header as RFC 6750 2.1 defines
+func (h *handlerAPI) UserInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="oauth"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	userInfo, err := h.authService.GetUserInfo(auth.AccessToken(token))
+	if err != nil {
+		writeBearerError(w, err)
+		return
+	}
+
+	response := &userInfoResponse{Subject: userInfo.UserId.String()}
+	if userInfo.Email != "" {
+		response.Email = userInfo.Email.String()
+		response.EmailVerified = &userInfo.EmailVerified
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+// GetOpenIdConfiguration returns the discovery document, the endpoints are relative to the issuer
+func (h *handlerAPI) GetOpenIdConfiguration(w http.ResponseWriter, r *http.Request) {
+	metadata := h.authService.GetOpenIdProviderMetadata()
+	issuer := strings.TrimSuffix(metadata.Issuer, "/")
+
+	w.Header().Set("Cache-Control", discoveryCacheControl)
+
+	writeJson(w, http.StatusOK, &openIdConfiguration{
+		Issuer:                            metadata.Issuer,
+		AuthorizationEndpoint:             issuer + authorizationPath,
+		TokenEndpoint:                     issuer + tokenPath,
+		UserInfoEndpoint:                  issuer + userInfoPath,
+		JwksUri:                           issuer + jwksPath,
+		IntrospectionEndpoint:             issuer + introspectionPath,
+		RevocationEndpoint:                issuer + revocationPath,
+		ScopesSupported:                   metadata.Scopes,
+		ResponseTypesSupported:            []string{auth.ResponseTypeCode},
+		GrantTypesSupported:               []string{oauth.GrantTypeAuthorizationCode, oauth.GrantTypeRefreshToken, oauth.GrantTypeClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  metadata.IdTokenSigningAlgorithms,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{oidc.CodeChallengeMethod},
+		ClaimsSupported:                   metadata.Claims,
+	})
+}
+
+// parseClientRequest parses the form and the client credentials, the client authenticates with either
+// the basic scheme or the form parameters but not both
+func parseClientRequest(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodySize)
+
+	err := r.ParseForm()
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, errorInvalidRequest, "request body must be a form")
+		return "", "", false
+	}
+
+	basicClientId, basicClientSecret, basic := r.BasicAuth()
+	if !basic {
+		return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), true
+	}
+
+	if r.PostForm.Has("client_secret") {
+		writeErrorResponse(w, http.StatusBadRequest, errorInvalidRequest, "client must use only one authentication method")
+		return "", "", false
+	}
+
+	// credentials are form-urlencoded before they are put to the basic scheme, RFC 6749 2.3.1
+	clientId, err := url.QueryUnescape(basicClientId)
+	if err != nil {
+		writeError(w, auth.ErrInvalidOAuthClient)
+		return "", "", false
+	}
+	clientSecret, err := url.QueryUnescape(basicClientSecret)
+	if err != nil {
+		writeError(w, auth.ErrInvalidOAuthClient)
+		return "", "", false
+	}
+
+	return clientId, clientSecret, true
+}
+
+func toIntrospectionResponse(introspection *auth.TokenIntrospection) *introspectionResponse {
+	if !introspection.Active {
+		return &introspectionResponse{Active: false}
+	}
+
+	response := &introspectionResponse{
+		Active:    true,
+		Scope:     strings.Join(introspection.Scopes, " "),
+		ClientId:  introspection.ClientId,
+		Subject:   introspection.ClientId,
+		TenantId:  int64(introspection.TenantId),
+		Issuer:    introspection.Issuer,
+		Audience:  introspection.Audience,
+		TokenId:   introspection.TokenId,
+		IssuedAt:  introspection.IssuedAt.Unix(),
+		ExpiresAt: introspection.ExpiresAt.Unix(),
+	}
+
+	if introspection.UserId != 0 {
+		response.Subject = introspection.UserId.String()
+	}
+
+	if introspection.TokenType == auth.TokenTypeHintAccessToken {
+		response.TokenType = auth.BearerTokenType
+	}
+
+	return response
+}
+
+func errorRedirectUri(redirectUri string, errStatus errorStatus, state string) (string, error) {
+	parsed, err := url.Parse(redirectUri)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsed.Query()
+	query.Set("error", errStatus.code)
+	query.Set("error_description", errStatus.message)
+	if state != "" {
+		query.Set("state", state)
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,14 @@
+package oauth
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/auth"
+)
+
+type AuthService interface {
+	StartAuthorization(request *auth.AuthorizationRequest) (string, error)
+	ExchangeToken(request *auth.TokenRequest) (*auth.OAuthTokens, error)
+	IntrospectToken(clientId string, clientSecret string, token string, tokenTypeHint string) (*auth.TokenIntrospection, error)
+	RevokeOAuthToken(clientId string, clientSecret string, token string) error
+	GetOpenIdProviderMetadata() *auth.OpenIdProviderMetadata
+	GetUserInfo(token auth.AccessToken) (*auth.UserInfo, error)
+}
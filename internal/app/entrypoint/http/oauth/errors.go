@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/domain/auth"
+	"net/http"
+)
+
+// error codes of RFC 6749 5.2 and 4.1.2.1
+const (
+	errorInvalidRequest          = "invalid_request"
+	errorInvalidClient           = "invalid_client"
+	errorInvalidGrant            = "invalid_grant"
+	errorUnauthorizedClient      = "unauthorized_client"
+	errorUnsupportedGrantType    = "unsupported_grant_type"
+	errorUnsupportedResponseType = "unsupported_response_type"
+	errorInvalidScope            = "invalid_scope"
+	errorServerError             = "server_error"
+)
+
+type errorStatus struct {
+	err        error
+	statusCode int
+	code       string
+	message    string
+}
+
+// errorStatuses maps domain errors to OAuth error responses, the first matching error wins
+var errorStatuses = []errorStatus{
+	{auth.ErrInvalidOAuthClient, http.StatusUnauthorized, errorInvalidClient, "client authentication failed"},
+	{auth.ErrInvalidRedirectUri, http.StatusBadRequest, errorInvalidRequest, "redirect uri is not registered for the client"},
+	{auth.ErrInvalidGrant, http.StatusBadRequest, errorInvalidGrant, "authorization grant is invalid, expired or revoked"},
+	{auth.ErrUnauthorizedClient, http.StatusBadRequest, errorUnauthorizedClient, "client is not allowed to use the grant"},
+	{auth.ErrUnsupportedGrantType, http.StatusBadRequest, errorUnsupportedGrantType, "grant type is not supported"},
+	{auth.ErrUnsupportedResponseType, http.StatusBadRequest, errorUnsupportedResponseType, "response type is not supported"},
+	{auth.ErrInvalidAuthorizationRequest, http.StatusBadRequest, errorInvalidRequest, "code_challenge with S256 method is required"},
+	{auth.ErrInvalidScope, http.StatusBadRequest, errorInvalidScope, "requested scope is invalid"},
+}
+
+// errorResponse is the error response of RFC 6749 5.2
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+func toErrorStatus(err error) errorStatus {
+	for _, errStatus := range errorStatuses {
+		if errors.Is(err, errStatus.err) {
+			return errStatus
+		}
+	}
+	return errorStatus{err: err, statusCode: http.StatusInternalServerError, code: errorServerError, message: "internal server error"}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	errStatus := toErrorStatus(err)
+
+	if errStatus.statusCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+
+	writeErrorResponse(w, errStatus.statusCode, errStatus.code, errStatus.message)
+}
+
+// writeBearerError writes the error of a protected resource as RFC 6750 3.1 defines
+func writeBearerError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInsufficientScope):
+		w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrTokenExpired) || errors.Is(err, auth.ErrTokenRevoked):
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		writeError(w, err)
+	}
+}
+
+func writeErrorResponse(w http.ResponseWriter, statusCode int, code string, description string) {
+	writeJson(w, statusCode, &errorResponse{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,31 @@
+package wellknown
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const jwksCacheControl = "public, max-age=300"
+
+type handlerAPI struct {
+	authService AuthService
+}
+
+func Register(mux *http.ServeMux, authService AuthService) {
+	api := &handlerAPI{authService: authService}
+
+	mux.HandleFunc("GET /.well-known/jwks.json", api.GetJwks)
+}
+
+func (h *handlerAPI) GetJwks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", jwksCacheControl)
+
+	writeJson(w, http.StatusOK, h.authService.GetJwks())
+}
+
+func writeJson(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,9 @@
+package wellknown
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+)
+
+type AuthService interface {
+	GetJwks() *accesstoken.Jwks
+}
@@ -1,3 +1,11 @@
 package auth
 
 type AccessToken string
+
+type RefreshToken string
+
+// Tokens is a pair of tokens issued to a user on a successful authentication
+type Tokens struct {
+	AccessToken  AccessToken
+	RefreshToken RefreshToken
+}
@@ -4,19 +4,37 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/passkey"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
 	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/auth"
 	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
 	"github.com/vaberof/auth-grpc/pkg/domain"
 	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"github.com/vaberof/auth-grpc/pkg/webauthn"
 	"github.com/vaberof/auth-grpc/pkg/xpassword"
 	"github.com/vaberof/auth-grpc/pkg/xrand"
 	"log/slog"
+	"sync"
 	"time"
 )
 
 const (
-	userEmailKey    = "user_email_"
-	registerCodeKey = "register_code_"
+	userEmailKey              = "user_email_"
+	registerCodeKey           = "register_code_"
+	passwordResetCodeKey      = "password_reset_code_"
+	passwordResetAttemptsKey  = "password_reset_attempts_"
+	passwordResetCooldownKey  = "password_reset_cooldown_"
+	passwordResetRequestsKey  = "password_reset_requests_"
+	emailLoginCodeKey         = "email_login_code_"
+	emailLoginAttemptsKey     = "email_login_attempts_"
+	emailLoginCooldownKey     = "email_login_cooldown_"
+	emailLoginRequestsKey     = "email_login_requests_"
+	changePasswordAttemptsKey = "change_password_attempts_"
+	tenantKeyPrefix           = "tenant_"
 )
 
 const (
@@ -24,61 +42,206 @@ const (
 	verificationCodeCacheExpireTime = 2 * time.Minute
 )
 
+// dummyPassword is checked instead of a password or a secret of a principal that does not exist,
+// so the response time does not reveal whether the email or the client id is registered
+const dummyPassword = "dummy-password"
+
+const (
+	defaultPasswordResetCodeTtl = 15 * time.Minute
+	defaultEmailLoginCodeTtl    = 10 * time.Minute
+)
+
+// maxVerificationCodeAttempts limits guessing of password reset and email login codes,
+// the code is invalidated after that. Attempts are counted for verificationCodeWindow
+// and are not reset by requesting a new code
+const maxVerificationCodeAttempts = 5
+
+// ChangePassword checks the current password at most maxChangePasswordAttempts times per
+// changePasswordAttemptsWindow, so a stolen access token does not allow guessing the password
+const (
+	maxChangePasswordAttempts    = 5
+	changePasswordAttemptsWindow = time.Hour
+)
+
+// a new password reset or email login code may be requested once per verificationCodeResendCooldown
+// and at most maxVerificationCodesPerWindow times per verificationCodeWindow
+const (
+	verificationCodeResendCooldown = time.Minute
+	verificationCodeWindow         = time.Hour
+	maxVerificationCodesPerWindow  = 5
+)
+
 const verificationCodeLength = 6
 
+const signingKeyIdLength = 12
+
 var (
 	ErrUserAlreadyExists      = errors.New("user with specified email already exists")
 	ErrInvalidEmailOrPassword = errors.New("invalid email or password")
+	ErrInvalidCurrentPassword = errors.New("current password is incorrect")
 
 	ErrInvalidVerificationCode = errors.New("invalid verification code")
 	ErrVerificationCodeExpired = errors.New("verification code has expired")
+	ErrTooManyAttempts         = errors.New("too many attempts")
+	ErrTooManyRequests         = errors.New("too many requests")
 
 	ErrTokenExpired = errors.New("token has expired")
 	ErrInvalidToken = errors.New("token is invalid")
+
+	ErrSigningKeyNotFound = errors.New("signing key not found")
 )
 
 type AuthService interface {
-	Register(email domain.Email, password domain.Password) error
-	Login(email domain.Email, password domain.Password) (*AccessToken, error)
-	Verify(email domain.Email, code domain.Code) error
-	VerifyToken(token AccessToken) error
+	Register(tenantId domain.TenantId, email domain.Email, password domain.Password) error
+	Login(tenantId domain.TenantId, email domain.Email, password domain.Password) (*LoginResult, error)
+	CompleteMfa(mfaToken MfaToken, code string) (*Tokens, error)
+	StartEmailLogin(tenantId domain.TenantId, email domain.Email) error
+	CompleteEmailLogin(tenantId domain.TenantId, email domain.Email, code domain.Code) (*LoginResult, error)
+	BeginPasskeyRegistration(userId domain.UserId) ([]byte, error)
+	FinishPasskeyRegistration(userId domain.UserId, name string, credentialJson []byte) (*passkey.Passkey, error)
+	BeginPasskeyLogin(tenantId domain.TenantId) (*PasskeyLoginChallenge, error)
+	FinishPasskeyLogin(sessionId PasskeySessionId, credentialJson []byte) (*LoginResult, error)
+	StartFederatedLogin(tenantId domain.TenantId, providerName string) (*FederatedLoginStart, error)
+	CompleteFederatedLogin(state string, code string) (*LoginResult, error)
+	Verify(tenantId domain.TenantId, email domain.Email, code domain.Code) error
+	VerifyToken(token AccessToken) (*TokenInfo, error)
+	Refresh(refreshToken RefreshToken) (*Tokens, error)
+	Logout(accessToken AccessToken, refreshToken RefreshToken) error
+	RevokeToken(token AccessToken, revokeAllSessions bool) error
+	RevokeUserSessions(tenantId domain.TenantId, userId domain.UserId) error
+	AssignRole(tenantId domain.TenantId, userId domain.UserId, roleName string) error
+	RevokeRole(tenantId domain.TenantId, userId domain.UserId, roleName string) error
+	ListUserRoles(tenantId domain.TenantId, userId domain.UserId) ([]*role.Role, error)
+	GetJwks() *accesstoken.Jwks
+	RotateSigningKey(algorithm accesstoken.Algorithm, keyId string) (string, error)
+	LoadSigningKeys() error
+	SigningKeysReloadInterval() time.Duration
+	RequestPasswordReset(tenantId domain.TenantId, email domain.Email) error
+	ConfirmPasswordReset(tenantId domain.TenantId, email domain.Email, code domain.Code, newPassword domain.Password) error
+	ChangePassword(userId domain.UserId, currentPassword domain.Password, newPassword domain.Password, revokeOtherSessions bool) (*Tokens, error)
+	GetRevocationList(since time.Time) (*RevocationList, error)
+	RegisterOAuthClient(tenantId domain.TenantId, registration *OAuthClientRegistration) (*RegisteredOAuthClient, error)
+	StartAuthorization(request *AuthorizationRequest) (string, error)
+	GetAuthorization(userId domain.UserId, requestId string) (*PendingAuthorization, error)
+	CompleteAuthorization(userId domain.UserId, requestId string, approved bool) (string, error)
+	ExchangeToken(request *TokenRequest) (*OAuthTokens, error)
+	IntrospectToken(clientId string, clientSecret string, token string, tokenTypeHint string) (*TokenIntrospection, error)
+	RevokeOAuthToken(clientId string, clientSecret string, token string) error
+	GetOpenIdProviderMetadata() *OpenIdProviderMetadata
+	GetUserInfo(token AccessToken) (*UserInfo, error)
+	CreateServiceAccount(tenantId domain.TenantId, name string, scopes []string) (*CreatedServiceAccount, error)
+	RotateServiceAccountSecret(tenantId domain.TenantId, clientId string, revokePrevious bool) (*RotatedServiceAccountSecret, error)
+	DisableServiceAccount(tenantId domain.TenantId, clientId string) error
+	DeleteServiceAccount(tenantId domain.TenantId, clientId string) error
+	IssueServiceToken(clientId string, clientSecret string, scopes []string, peerAddress string) (*ServiceToken, error)
 }
 
+// Config of the auth service. TokenKeys form a key ring, TokenKey is used when the ring is empty
+// and TokenSecretKey is used with HS256 algorithm when TokenKey is not set.
+// Tokens issued before 'sub' claim was introduced are accepted until LegacyTokensAcceptedUntil.
+// PasswordResetLink and EmailLoginLink are links sent along with a password reset code and an email login code,
+// '{tenant_id}', '{email}' and '{code}' are replaced in them. Token ttls and the password policy may be overridden by tenants.
+// MfaChallengeTtl limits the time between the password step of a login and CompleteMfa.
+// Passkeys configure the WebAuthn relying party, its timeout is the ttl of passkey challenges.
+// IdentityProviders are the providers of federated logins, FederatedLoginTtl limits the time the user spends at a provider.
+// OAuth configures the authorization server of third-party clients.
+// ServiceAccountSecretOverlap is the time previous secrets of a service account stay valid after a rotation.
+// SigningKeyEncryptionKey is a base64 encoded AES-256 key encrypting generated signing keys in the storage,
+// stored keys are reloaded every SigningKeysReloadInterval
 type Config struct {
-	TokenTtl       time.Duration `yaml:"token-ttl"`
-	TokenSecretKey string        `yaml:"token-secret-key"`
+	TokenTtl                    time.Duration           `yaml:"token-ttl"`
+	TokenSecretKey              string                  `yaml:"token-secret-key"`
+	TokenKey                    accesstoken.KeyConfig   `yaml:"token-key"`
+	TokenKeys                   []accesstoken.KeyConfig `yaml:"token-keys"`
+	TokenIssuer                 string                  `yaml:"token-issuer"`
+	TokenAudience               []string                `yaml:"token-audience"`
+	TokenLeeway                 time.Duration           `yaml:"token-leeway"`
+	LegacyTokensAcceptedUntil   time.Time               `yaml:"legacy-tokens-accepted-until"`
+	RefreshTokenTtl             time.Duration           `yaml:"refresh-token-ttl"`
+	PasswordPolicy              passwordpolicy.Config   `yaml:"password-policy"`
+	PasswordHashing             xpassword.Config        `yaml:"password-hashing"`
+	PasswordResetCodeTtl        time.Duration           `yaml:"password-reset-code-ttl"`
+	PasswordResetLink           string                  `yaml:"password-reset-link"`
+	EmailLoginCodeTtl           time.Duration           `yaml:"email-login-code-ttl"`
+	EmailLoginLink              string                  `yaml:"email-login-link"`
+	MfaChallengeTtl             time.Duration           `yaml:"mfa-challenge-ttl"`
+	Passkeys                    webauthn.Config         `yaml:"passkeys"`
+	IdentityProviders           []oidc.Config           `yaml:"identity-providers"`
+	FederatedLoginTtl           time.Duration           `yaml:"federated-login-ttl"`
+	OAuth                       OAuthConfig             `yaml:"oauth"`
+	ServiceAccountSecretOverlap time.Duration           `yaml:"service-account-secret-overlap"`
+	SigningKeyEncryptionKey     string                  `yaml:"signing-key-encryption-key"`
+	SigningKeysReloadInterval   time.Duration           `yaml:"signing-keys-reload-interval"`
 }
 
 type authServiceImpl struct {
-	config              *Config
-	userService         UserService
-	notificationService NotificationService
-	inMemoryStorage     InMemoryStorage
+	config                *Config
+	keyRing               *accesstoken.KeyRing
+	userService           UserService
+	roleService           RoleService
+	tenantService         TenantService
+	mfaService            MfaService
+	passkeyService        PasskeyService
+	relyingParty          *webauthn.RelyingParty
+	identityService       IdentityService
+	identityProviders     *oidc.Providers
+	oauthService          OAuthService
+	serviceAccountService ServiceAccountService
+	signingKeyService     SigningKeyService
+	notificationService   NotificationService
+	inMemoryStorage       InMemoryStorage
+	revocationListStorage RevocationListStorage
+	passwordPolicy        PasswordPolicy
+	passwordHasher        PasswordHasher
+
+	signingKeysMu         sync.Mutex
+	signingKeysReloadedAt time.Time
+
+	// dummyPasswordHash is computed once with the configured password hashing
+	dummyPasswordHash func() (string, error)
 
 	logger *slog.Logger
 }
 
-func NewAuthService(config *Config, userService UserService, notificationService NotificationService, inMemoryStorage InMemoryStorage, logs *logs.Logs) AuthService {
+func NewAuthService(config *Config, keyRing *accesstoken.KeyRing, userService UserService, roleService RoleService, tenantService TenantService, mfaService MfaService, passkeyService PasskeyService, relyingParty *webauthn.RelyingParty, identityService IdentityService, identityProviders *oidc.Providers, oauthService OAuthService, serviceAccountService ServiceAccountService, signingKeyService SigningKeyService, notificationService NotificationService, inMemoryStorage InMemoryStorage, revocationListStorage RevocationListStorage, passwordPolicy PasswordPolicy, passwordHasher PasswordHasher, logs *logs.Logs) AuthService {
 	logger := logs.WithName("domain.auth.service")
 	return &authServiceImpl{
-		config:              config,
-		userService:         userService,
-		notificationService: notificationService,
-		inMemoryStorage:     inMemoryStorage,
-		logger:              logger,
+		config:                config,
+		keyRing:               keyRing,
+		userService:           userService,
+		roleService:           roleService,
+		tenantService:         tenantService,
+		mfaService:            mfaService,
+		passkeyService:        passkeyService,
+		relyingParty:          relyingParty,
+		identityService:       identityService,
+		identityProviders:     identityProviders,
+		oauthService:          oauthService,
+		serviceAccountService: serviceAccountService,
+		signingKeyService:     signingKeyService,
+		notificationService:   notificationService,
+		inMemoryStorage:       inMemoryStorage,
+		revocationListStorage: revocationListStorage,
+		passwordPolicy:        passwordPolicy,
+		passwordHasher:        passwordHasher,
+		dummyPasswordHash: sync.OnceValues(func() (string, error) {
+			return passwordHasher.Hash(dummyPassword)
+		}),
+		logger: logger,
 	}
 }
 
-func (a *authServiceImpl) Register(email domain.Email, password domain.Password) error {
+func (a *authServiceImpl) Register(tenantId domain.TenantId, email domain.Email, password domain.Password) error {
 	const operation = "Register"
 
 	log := a.logger.With(
 		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
 		slog.String("email", email.String()))
 
 	log.Info("registering a user")
 
-	exists, err := a.userService.ExistsByEmail(email)
+	exists, err := a.userService.ExistsByEmail(tenantId, email)
 	if err != nil {
 		log.Error("failed to get info about existing/non-existing email")
 
@@ -91,7 +254,14 @@ func (a *authServiceImpl) Register(email domain.Email, password domain.Password)
 		return fmt.Errorf("%s: %w", operation, ErrUserAlreadyExists)
 	}
 
-	passwordHash, err := xpassword.Hash(password.String())
+	err = a.checkPasswordPolicy(tenantId, "password", email, password)
+	if err != nil {
+		log.Warn("password does not satisfy password policy", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passwordHash, err := a.passwordHasher.Hash(password.String())
 	if err != nil {
 		log.Error("failed to hash password", "error", err)
 
@@ -99,6 +269,7 @@ func (a *authServiceImpl) Register(email domain.Email, password domain.Password)
 	}
 
 	domainUser := &user.User{
+		TenantId: tenantId,
 		Email:    email,
 		Password: domain.Password(passwordHash),
 	}
@@ -110,7 +281,7 @@ func (a *authServiceImpl) Register(email domain.Email, password domain.Password)
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
-	err = a.inMemoryStorage.Set(userEmailKey+email.String(), string(userData), userDataCacheExpireTime)
+	err = a.inMemoryStorage.Set(tenantEmailKey(userEmailKey, tenantId, email), string(userData), userDataCacheExpireTime)
 	if err != nil {
 		log.Error("failed to set a userData to cache", "error", err)
 
@@ -120,7 +291,7 @@ func (a *authServiceImpl) Register(email domain.Email, password domain.Password)
 	go func() {
 		log.Info("send verification code to email")
 
-		err := a.sendVerificationCode(registerCodeKey, email)
+		err := a.sendVerificationCode(registerCodeKey, tenantId, email, registrationEmail)
 		if err != nil {
 			log.Error("failed to send verification code", "error", err)
 		}
@@ -129,20 +300,29 @@ func (a *authServiceImpl) Register(email domain.Email, password domain.Password)
 	return nil
 }
 
-func (a *authServiceImpl) Login(email domain.Email, password domain.Password) (*AccessToken, error) {
+// Login issues tokens if the password is correct, an MFA challenge is returned instead if the user has enabled MFA
+func (a *authServiceImpl) Login(tenantId domain.TenantId, email domain.Email, password domain.Password) (*LoginResult, error) {
 	const operation = "Login"
 
 	log := a.logger.With(
 		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
 		slog.String("email", email.String()))
 
 	log.Info("logging a user")
 
-	domainUser, err := a.userService.GetByEmail(email)
+	domainUser, err := a.userService.GetByEmail(tenantId, email)
 	if err != nil {
 		if errors.Is(err, user.ErrUserNotFound) {
 			log.Error("user not found", "error", err)
 
+			err = a.checkDummyPassword(password.String())
+			if err != nil {
+				log.Error("failed to check dummy password", "error", err)
+
+				return nil, fmt.Errorf("%s: %w", operation, err)
+			}
+
 			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidEmailOrPassword)
 		}
 
@@ -151,39 +331,43 @@ func (a *authServiceImpl) Login(email domain.Email, password domain.Password) (*
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	err = xpassword.Check(password.String(), domainUser.Password.String())
+	err = a.passwordHasher.Check(password.String(), domainUser.Password.String())
 	if err != nil {
-		log.Error("incorrect password", err)
+		log.Error("incorrect password", "error", err)
 
 		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidEmailOrPassword)
 	}
 
-	token, err := accesstoken.Create(domainUser.Id, a.config.TokenTtl, accesstoken.SecretKey(a.config.TokenSecretKey))
-	if err != nil {
-		log.Error("failed to create an access token", "error", err)
+	if a.passwordHasher.NeedsRehash(domainUser.Password.String()) {
+		a.rehashPassword(log, domainUser, password)
+	}
 
+	loginResult, err := a.completeLogin(log, domainUser)
+	if err != nil {
 		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 
-	accessToken := AccessToken(token)
-
-	log.Info("user logged in")
-
-	return &accessToken, nil
+	return loginResult, nil
 }
 
-func (a *authServiceImpl) Verify(email domain.Email, code domain.Code) error {
+func (a *authServiceImpl) Verify(tenantId domain.TenantId, email domain.Email, code domain.Code) error {
 	const operation = "Verify"
 
 	log := a.logger.With(
 		slog.String("operation", operation),
-		slog.String("email", email.String()),
-		slog.String("code", code.String()))
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("email", email.String()))
 
 	log.Info("verifying an email")
 
-	userData, err := a.inMemoryStorage.Get(userEmailKey + email.String())
+	userData, err := a.inMemoryStorage.Get(tenantEmailKey(userEmailKey, tenantId, email))
 	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			log.Error("registration data has expired", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, ErrVerificationCodeExpired)
+		}
+
 		log.Error("failed to get user data from cache", "error", err)
 
 		return fmt.Errorf("%s: %w", operation, err)
@@ -197,7 +381,7 @@ func (a *authServiceImpl) Verify(email domain.Email, code domain.Code) error {
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
-	cachedCode, err := a.inMemoryStorage.Get(registerCodeKey + domainUser.Email.String())
+	cachedCode, err := a.inMemoryStorage.Get(tenantEmailKey(registerCodeKey, tenantId, email))
 	if err != nil {
 		log.Error("failed to get verification code from cache", "error", err)
 
@@ -210,7 +394,7 @@ func (a *authServiceImpl) Verify(email domain.Email, code domain.Code) error {
 		return fmt.Errorf("%s: %w", operation, ErrInvalidVerificationCode)
 	}
 
-	_, err = a.userService.Create(domainUser.Email, domainUser.Password)
+	_, err = a.userService.Create(tenantId, domainUser.Email, domainUser.Password)
 	if err != nil {
 		log.Error("failed to create user", "error", err)
 
@@ -222,50 +406,588 @@ func (a *authServiceImpl) Verify(email domain.Email, code domain.Code) error {
 	return nil
 }
 
-func (a *authServiceImpl) VerifyToken(token AccessToken) error {
+func (a *authServiceImpl) VerifyToken(token AccessToken) (*TokenInfo, error) {
 	const operation = "VerifyToken"
 
+	log := a.logger.With(slog.String("operation", operation))
+
+	log.Info("verifying a token")
+
+	payload, err := a.verifyAccessToken(token, auth.TokenUseAccess)
+	if err != nil {
+		log.Error("failed to verify access token", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("successfully verified a token")
+
+	return toTokenInfo(payload), nil
+}
+
+func (a *authServiceImpl) Refresh(refreshToken RefreshToken) (*Tokens, error) {
+	const operation = "Refresh"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	log.Info("refreshing tokens")
+
+	tokenData, err := a.rotateRefreshToken(refreshToken, "")
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			log.Warn("refresh token reuse detected, token family revoked", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrRefreshTokenReused)
+		}
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			log.Error("invalid refresh token", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidRefreshToken)
+		}
+
+		log.Error("failed to rotate refresh token", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	domainUser, err := a.userService.GetById(tokenData.UserId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			log.Error("owner of refresh token not found", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidRefreshToken)
+		}
+
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tokens, err := a.issueTokens(domainUser, tokenData.FamilyId)
+	if err != nil {
+		log.Error("failed to issue tokens", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("tokens refreshed", slog.String("user_id", tokenData.UserId.String()))
+
+	return tokens, nil
+}
+
+func (a *authServiceImpl) Logout(accessToken AccessToken, refreshToken RefreshToken) error {
+	const operation = "Logout"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	log.Info("logging out a user")
+
+	payload, err := a.verifyAccessToken(accessToken, auth.TokenUseAccess)
+	if err != nil && !errors.Is(err, ErrTokenExpired) {
+		log.Error("failed to verify access token", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if payload != nil {
+		err = a.revokeAccessToken(payload)
+		if err != nil {
+			log.Error("failed to revoke access token", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	if refreshToken != "" {
+		tokenData, err := a.getRefreshTokenData(refreshToken)
+		if err != nil {
+			log.Error("failed to get refresh token data", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+
+		if payload != nil && payload.UserId != tokenData.UserId {
+			log.Error("refresh token belongs to another user")
+
+			return fmt.Errorf("%s: %w", operation, ErrInvalidRefreshToken)
+		}
+
+		err = a.revokeRefreshTokenFamily(tokenData.FamilyId)
+		if err != nil {
+			log.Error("failed to revoke refresh token family", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	log.Info("user logged out")
+
+	return nil
+}
+
+func (a *authServiceImpl) RevokeToken(token AccessToken, revokeAllSessions bool) error {
+	const operation = "RevokeToken"
+
 	log := a.logger.With(
 		slog.String("operation", operation),
-		slog.String("token", string(token)))
+		slog.Bool("revoke_all_sessions", revokeAllSessions))
 
-	log.Info("verifying a token")
+	log.Info("revoking a token")
+
+	payload, err := a.verifyAccessToken(token, auth.TokenUseAccess)
+	if err != nil {
+		log.Error("failed to verify access token", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
 
-	_, err := accesstoken.Verify(string(token), accesstoken.SecretKey(a.config.TokenSecretKey))
+	err = a.revokeAccessToken(payload)
 	if err != nil {
-		if errors.Is(err, accesstoken.ErrInvalidToken) {
-			log.Error("invalid access token", "error", err)
+		log.Error("failed to revoke access token", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if revokeAllSessions {
+		err = a.revokeUserTokens(payload.UserId)
+		if err != nil {
+			log.Error("failed to revoke user tokens", "error", err)
 
-			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+			return fmt.Errorf("%s: %w", operation, err)
 		}
-		if errors.Is(err, accesstoken.ErrExpiredToken) {
-			log.Error("access token has expired", "error", err)
+	}
+
+	log.Info("token revoked", slog.String("user_id", payload.UserId.String()))
+
+	return nil
+}
+
+// RevokeUserSessions revokes all the access and refresh tokens of the user of the tenant,
+// it lets administrators log out users whose accounts are compromised
+func (a *authServiceImpl) RevokeUserSessions(tenantId domain.TenantId, userId domain.UserId) error {
+	const operation = "RevokeUserSessions"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("user_id", userId.String()))
+
+	log.Info("revoking sessions of a user")
+
+	domainUser, err := a.getTenantUser(log, tenantId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.revokeUserTokens(domainUser.Id)
+	if err != nil {
+		log.Error("failed to revoke user tokens", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("sessions of a user revoked")
+
+	return nil
+}
+
+// GetJwks returns public keys which verify access tokens, symmetric keys are never published
+func (a *authServiceImpl) GetJwks() *accesstoken.Jwks {
+	jwks := &accesstoken.Jwks{Keys: []accesstoken.Jwk{}}
+
+	for _, key := range a.keyRing.VerificationKeys() {
+		jwk, ok := key.Jwk()
+		if ok {
+			jwks.Keys = append(jwks.Keys, *jwk)
+		}
+	}
+
+	return jwks
+}
+
+// RotateSigningKey starts signing tokens with a configured key with the given id or, if keyId is empty,
+// with a newly generated key. Previous signing key keeps verifying tokens for a token ttl.
+// The ring is stored, generated keys are stored encrypted, so other instances pick up the rotation
+// with LoadSigningKeys and the rotation survives a restart
+func (a *authServiceImpl) RotateSigningKey(algorithm accesstoken.Algorithm, keyId string) (string, error) {
+	const operation = "RotateSigningKey"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("algorithm", string(algorithm)),
+		slog.String("key_id", keyId))
+
+	log.Info("rotating signing key")
+
+	var key *accesstoken.Key
+	var generatedKeyId string
+	var err error
+
+	if keyId != "" {
+		key, err = a.keyRing.LookupKey(keyId)
+		if err != nil {
+			log.Error("failed to find a key", "error", err)
+
+			return "", fmt.Errorf("%s: %w", operation, ErrSigningKeyNotFound)
+		}
+	} else {
+		if algorithm == "" {
+			currentKey, err := a.keyRing.SigningKey()
+			if err != nil {
+				log.Error("failed to get current signing key", "error", err)
+
+				return "", fmt.Errorf("%s: %w", operation, err)
+			}
+			algorithm = currentKey.Algorithm
+		}
+
+		newKeyId, err := xrand.GenerateRandomToken(signingKeyIdLength)
+		if err != nil {
+			log.Error("failed to generate key id", "error", err)
+
+			return "", fmt.Errorf("%s: %w", operation, err)
+		}
+
+		key, err = accesstoken.GenerateKey(newKeyId, algorithm)
+		if err != nil {
+			log.Error("failed to generate a key", "error", err)
 
-			return fmt.Errorf("%s: %w", operation, ErrTokenExpired)
+			return "", fmt.Errorf("%s: %w", operation, err)
 		}
-		if errors.Is(err, accesstoken.ErrInvalidSigningMethod) {
-			log.Error("access token has invalid signing method", "error", err)
+		generatedKeyId = key.Id
+	}
+
+	a.signingKeysMu.Lock()
+	defer a.signingKeysMu.Unlock()
+
+	err = a.keyRing.Rotate(key, a.config.TokenTtl)
+	if err != nil {
+		log.Error("failed to rotate signing key", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.signingKeyService.Save(a.keyRing, generatedKeyId)
+	if err != nil {
+		log.Error("failed to save signing keys", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("signing key rotated", slog.String("new_key_id", key.Id))
+
+	return key.Id, nil
+}
 
-			return fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+// RequestPasswordReset sends a password reset code to the email. It succeeds for unknown emails
+// as well, so the RPC can not be used to find out registered emails
+func (a *authServiceImpl) RequestPasswordReset(tenantId domain.TenantId, email domain.Email) error {
+	const operation = "RequestPasswordReset"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("email", email.String()))
+
+	log.Info("requesting a password reset")
+
+	err := a.limitVerificationCodes(passwordResetCooldownKey, passwordResetRequestsKey, tenantId, email)
+	if err != nil {
+		if errors.Is(err, ErrTooManyRequests) {
+			log.Warn("too many password reset requests")
+		} else {
+			log.Error("failed to check password reset requests", "error", err)
 		}
 
-		log.Error("unexpected error from 'accesstoken' package", "error", err)
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	exists, err := a.userService.ExistsByEmail(tenantId, email)
+	if err != nil {
+		log.Error("failed to get info about existing/non-existing email", "error", err)
 
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
-	log.Info("successfully verified a token")
+	if !exists {
+		log.Warn("password reset requested for not registered email")
+
+		return nil
+	}
+
+	go func() {
+		log.Info("send password reset code to email")
+
+		err := a.sendVerificationCode(passwordResetCodeKey, tenantId, email, a.passwordResetEmail())
+		if err != nil {
+			log.Error("failed to send password reset code", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// ConfirmPasswordReset sets a new password if the code is correct. The code can be used only once,
+// all the sessions of the user are revoked on success
+func (a *authServiceImpl) ConfirmPasswordReset(tenantId domain.TenantId, email domain.Email, code domain.Code, newPassword domain.Password) error {
+	const operation = "ConfirmPasswordReset"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("email", email.String()))
+
+	log.Info("confirming a password reset")
+
+	err := a.checkPasswordPolicy(tenantId, "new_password", email, newPassword)
+	if err != nil {
+		log.Warn("password does not satisfy password policy", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.checkVerificationCode(passwordResetCodeKey, passwordResetAttemptsKey, tenantId, email, code, a.passwordResetEmail())
+	if err != nil {
+		log.Error("failed to check password reset code", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	domainUser, err := a.userService.GetByEmail(tenantId, email)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			log.Error("user not found", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, ErrVerificationCodeExpired)
+		}
+
+		log.Error("failed to get user by email", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passwordHash, err := a.passwordHasher.Hash(newPassword.String())
+	if err != nil {
+		log.Error("failed to hash password", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.userService.UpdatePassword(domainUser.Id, domain.Password(passwordHash))
+	if err != nil {
+		log.Error("failed to update password", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.revokeUserTokens(domainUser.Id)
+	if err != nil {
+		log.Error("failed to revoke user tokens", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("password has been reset")
 
 	return nil
 }
 
-func (a *authServiceImpl) sendVerificationCode(key string, email domain.Email) error {
+// ChangePassword sets a new password of the user if the current one is correct. If revokeOtherSessions
+// is set, all the tokens of the user are revoked and fresh tokens are returned to keep the caller
+// signed in, otherwise returned tokens are nil
+func (a *authServiceImpl) ChangePassword(userId domain.UserId, currentPassword domain.Password, newPassword domain.Password, revokeOtherSessions bool) (*Tokens, error) {
+	const operation = "ChangePassword"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()),
+		slog.Bool("revoke_other_sessions", revokeOtherSessions))
+
+	log.Info("changing a password")
+
+	domainUser, err := a.userService.GetById(userId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			log.Error("user not found", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	attemptsKey := changePasswordAttemptsKey + userId.String()
+
+	attempts, err := a.inMemoryStorage.Increment(attemptsKey, changePasswordAttemptsWindow)
+	if err != nil {
+		log.Error("failed to count change password attempts", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if attempts > maxChangePasswordAttempts {
+		log.Warn("too many change password attempts")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrTooManyRequests)
+	}
+
+	err = a.passwordHasher.Check(currentPassword.String(), domainUser.Password.String())
+	if err != nil {
+		log.Error("incorrect current password", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidCurrentPassword)
+	}
+
+	_, err = a.inMemoryStorage.Delete(attemptsKey)
+	if err != nil {
+		log.Error("failed to reset change password attempts", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if currentPassword == newPassword {
+		log.Warn("new password is the same as the current one")
+
+		return nil, fmt.Errorf("%s: %w", operation,
+			domain.NewValidator().Violation("new_password", "must differ from the current password").Err())
+	}
+
+	err = a.checkPasswordPolicy(domainUser.TenantId, "new_password", domainUser.Email, newPassword)
+	if err != nil {
+		log.Warn("password does not satisfy password policy", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passwordHash, err := a.passwordHasher.Hash(newPassword.String())
+	if err != nil {
+		log.Error("failed to hash password", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.userService.UpdatePassword(userId, domain.Password(passwordHash))
+	if err != nil {
+		log.Error("failed to update password", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !revokeOtherSessions {
+		log.Info("password changed")
+
+		return nil, nil
+	}
+
+	err = a.revokeUserTokens(userId)
+	if err != nil {
+		log.Error("failed to revoke user tokens", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tokens, err := a.issueTokens(domainUser, "")
+	if err != nil {
+		log.Error("failed to issue tokens", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("password changed, other sessions revoked")
+
+	return tokens, nil
+}
+
+// verifyAccessToken checks the token signature, expiration time, use and revocation
+func (a *authServiceImpl) verifyAccessToken(token AccessToken, tokenUses ...string) (*auth.JwtPayload, error) {
+	payload, err := accesstoken.Verify(string(token), signingKeySet{authService: a}, &accesstoken.VerifyOptions{
+		Issuer:                    a.config.TokenIssuer,
+		Audience:                  a.config.TokenAudience,
+		TokenUses:                 tokenUses,
+		Leeway:                    a.config.TokenLeeway,
+		LegacyTokensAcceptedUntil: a.config.LegacyTokensAcceptedUntil,
+	})
+	if err != nil {
+		if errors.Is(err, accesstoken.ErrExpiredToken) {
+			return nil, ErrTokenExpired
+		}
+		if errors.Is(err, accesstoken.ErrInvalidToken) ||
+			errors.Is(err, accesstoken.ErrInvalidSigningMethod) ||
+			errors.Is(err, accesstoken.ErrInvalidIssuer) ||
+			errors.Is(err, accesstoken.ErrInvalidAudience) ||
+			errors.Is(err, accesstoken.ErrInvalidTokenUse) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	revoked, err := a.isAccessTokenRevoked(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	return payload, nil
+}
+
+// issueTokens creates an access token and a refresh token belonging to the given
+// refresh token family, a new family is started if familyId is empty
+func (a *authServiceImpl) issueTokens(domainUser *user.User, familyId string) (*Tokens, error) {
+	const operation = "issueTokens"
+
+	signingKey, err := a.keyRing.SigningKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	domainTenant, err := a.tenantService.GetById(domainUser.TenantId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	payload := auth.NewPayload(domainUser.Id, a.tokenTtl(domainTenant))
+	payload.TenantId = domainUser.TenantId
+	payload.Email = domainUser.Email
+	payload.Issuer = a.config.TokenIssuer
+	payload.Audience = a.config.TokenAudience
+
+	userPermissions, err := a.roleService.GetUserPermissions(domainUser.Id)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+	payload.Roles = userPermissions.Roles
+	payload.Permissions = userPermissions.Permissions
+
+	accessToken, err := accesstoken.Create(payload, signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	refreshToken, err := a.createRefreshToken(&refreshTokenData{UserId: domainUser.Id, FamilyId: familyId}, a.refreshTokenTtl(domainTenant))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &Tokens{
+		AccessToken:  AccessToken(accessToken),
+		RefreshToken: refreshToken,
+	}, nil
+}
+
+func (a *authServiceImpl) sendVerificationCode(key string, tenantId domain.TenantId, email domain.Email, verificationEmail *verificationEmail) error {
 	const operation = "sendVerificationCode"
 
 	log := a.logger.With(
 		slog.String("operation", operation),
 		slog.String("key", key),
-		slog.String("email", email.String()))
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("email", email.String()),
+		slog.String("email_type", verificationEmail.Type))
 
 	code, err := xrand.GenerateRandomCode(verificationCodeLength)
 	if err != nil {
@@ -276,16 +998,21 @@ func (a *authServiceImpl) sendVerificationCode(key string, email domain.Email) e
 
 	log.Info("random code generated")
 
-	err = a.inMemoryStorage.Set(key+email.String(), code, verificationCodeCacheExpireTime)
+	err = a.inMemoryStorage.Set(tenantEmailKey(key, tenantId, email), code, verificationEmail.CodeTtl)
 	if err != nil {
 		log.Error("failed to cache verification code", "error", err)
 
 		return fmt.Errorf("%s: %w", operation, err)
 	}
 
-	log.Info("code saved in memory storage", "code", code)
+	log.Info("code saved in memory storage")
+
+	body := map[string]string{"code": code}
+	if verificationEmail.Link != "" {
+		body["link"] = verificationEmail.link(tenantId, email, code)
+	}
 
-	err = a.notificationService.SendEmail(email.String(), "verification_email", "Verification email", map[string]string{"code": code})
+	err = a.notificationService.SendEmail(email.String(), verificationEmail.Type, verificationEmail.Subject, body)
 	if err != nil {
 		log.Error("failed to send verification code", "error", err)
 
@@ -296,3 +1023,151 @@ func (a *authServiceImpl) sendVerificationCode(key string, email domain.Email) e
 
 	return nil
 }
+
+// rehashPassword upgrades an outdated password hash of the user. Login does not fail if the upgrade fails,
+// the hash is upgraded on the next login then
+func (a *authServiceImpl) rehashPassword(log *slog.Logger, domainUser *user.User, password domain.Password) {
+	log.Info("password hash is outdated, rehashing password")
+
+	passwordHash, err := a.passwordHasher.Hash(password.String())
+	if err != nil {
+		log.Error("failed to rehash password", "error", err)
+
+		return
+	}
+
+	err = a.userService.UpdatePassword(domainUser.Id, domain.Password(passwordHash))
+	if err != nil {
+		log.Error("failed to update rehashed password", "error", err)
+
+		return
+	}
+
+	domainUser.Password = domain.Password(passwordHash)
+}
+
+// checkDummyPassword spends the time of a password check against the dummy hash, the result of the check is ignored
+func (a *authServiceImpl) checkDummyPassword(password string) error {
+	dummyPasswordHash, err := a.dummyPasswordHash()
+	if err != nil {
+		return err
+	}
+
+	_ = a.passwordHasher.Check(password, dummyPasswordHash)
+
+	return nil
+}
+
+// completeLogin issues tokens to the user who passed the first factor,
+// an MFA challenge is returned instead if the user has enabled MFA
+func (a *authServiceImpl) completeLogin(log *slog.Logger, domainUser *user.User) (*LoginResult, error) {
+	mfaEnabled, err := a.mfaService.IsTotpEnabled(domainUser.Id)
+	if err != nil {
+		log.Error("failed to check whether mfa is enabled", "error", err)
+
+		return nil, err
+	}
+
+	if mfaEnabled {
+		mfaChallenge, err := a.createMfaChallenge(domainUser.Id)
+		if err != nil {
+			log.Error("failed to create mfa challenge", "error", err)
+
+			return nil, err
+		}
+
+		log.Info("mfa is required")
+
+		return &LoginResult{MfaChallenge: mfaChallenge}, nil
+	}
+
+	tokens, err := a.issueTokens(domainUser, "")
+	if err != nil {
+		log.Error("failed to issue tokens", "error", err)
+
+		return nil, err
+	}
+
+	log.Info("user logged in")
+
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// checkVerificationCode consumes the code stored under the key if it is correct. The code is invalidated
+// after maxVerificationCodeAttempts failed attempts counted under attemptsKey
+func (a *authServiceImpl) checkVerificationCode(key string, attemptsKey string, tenantId domain.TenantId, email domain.Email, code domain.Code, verificationEmail *verificationEmail) error {
+	codeKey := tenantEmailKey(key, tenantId, email)
+	attemptsKey = tenantEmailKey(attemptsKey, tenantId, email)
+
+	cachedCode, err := a.inMemoryStorage.Get(codeKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return ErrVerificationCodeExpired
+		}
+		return err
+	}
+
+	attempts, err := a.inMemoryStorage.Increment(attemptsKey, max(verificationEmail.CodeTtl, verificationCodeWindow))
+	if err != nil {
+		return err
+	}
+
+	if attempts > maxVerificationCodeAttempts {
+		_, err = a.inMemoryStorage.Delete(codeKey)
+		if err != nil {
+			return err
+		}
+		return ErrTooManyAttempts
+	}
+
+	if code.String() != cachedCode {
+		return ErrInvalidVerificationCode
+	}
+
+	deleted, err := a.inMemoryStorage.Delete(codeKey)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		// the code has been consumed by a concurrent request
+		return ErrVerificationCodeExpired
+	}
+
+	_, err = a.inMemoryStorage.Delete(attemptsKey)
+
+	return err
+}
+
+// limitVerificationCodes checks the resend cooldown and the number of codes requested for the email.
+// Requests for not registered emails are limited as well, so the limits do not reveal registered emails
+func (a *authServiceImpl) limitVerificationCodes(cooldownKey string, requestsKey string, tenantId domain.TenantId, email domain.Email) error {
+	cooledDown, err := a.inMemoryStorage.SetIfNotExists(tenantEmailKey(cooldownKey, tenantId, email), "1", verificationCodeResendCooldown)
+	if err != nil {
+		return err
+	}
+
+	if !cooledDown {
+		return ErrTooManyRequests
+	}
+
+	requests, err := a.inMemoryStorage.Increment(tenantEmailKey(requestsKey, tenantId, email), verificationCodeWindow)
+	if err != nil {
+		return err
+	}
+
+	if requests > maxVerificationCodesPerWindow {
+		return ErrTooManyRequests
+	}
+
+	return nil
+}
+
+// tenantEmailKey scopes a key of the email to the tenant, because the same email may be registered
+// in several tenants. Keys of the default tenant are kept unscoped as they were before tenants,
+// the prefix of the scoped keys differs from all the unscoped ones, so they never collide
+func tenantEmailKey(key string, tenantId domain.TenantId, email domain.Email) string {
+	if tenantId == domain.DefaultTenantId {
+		return key + email.String()
+	}
+	return tenantKeyPrefix + tenantId.String() + "_" + key + email.String()
+}
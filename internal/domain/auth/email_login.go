@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"log/slog"
+)
+
+// StartEmailLogin sends a one-time login code to the email of a registered user. It succeeds for unknown
+// emails as well, so the RPC can not be used to find out registered emails
+func (a *authServiceImpl) StartEmailLogin(tenantId domain.TenantId, email domain.Email) error {
+	const operation = "StartEmailLogin"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("email", email.String()))
+
+	log.Info("starting an email login")
+
+	err := a.limitVerificationCodes(emailLoginCooldownKey, emailLoginRequestsKey, tenantId, email)
+	if err != nil {
+		if errors.Is(err, ErrTooManyRequests) {
+			log.Warn("too many email login requests")
+		} else {
+			log.Error("failed to check email login requests", "error", err)
+		}
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	exists, err := a.userService.ExistsByEmail(tenantId, email)
+	if err != nil {
+		log.Error("failed to get info about existing/non-existing email", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !exists {
+		log.Warn("email login requested for not registered email")
+
+		return nil
+	}
+
+	go func() {
+		log.Info("send email login code to email")
+
+		err := a.sendVerificationCode(emailLoginCodeKey, tenantId, email, a.emailLoginEmail())
+		if err != nil {
+			log.Error("failed to send email login code", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// CompleteEmailLogin logs the user in if the code sent by StartEmailLogin is correct, the code can be used
+// only once. Like Login it returns an MFA challenge instead of tokens if the user has enabled MFA
+func (a *authServiceImpl) CompleteEmailLogin(tenantId domain.TenantId, email domain.Email, code domain.Code) (*LoginResult, error) {
+	const operation = "CompleteEmailLogin"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("email", email.String()))
+
+	log.Info("completing an email login")
+
+	err := a.checkVerificationCode(emailLoginCodeKey, emailLoginAttemptsKey, tenantId, email, code, a.emailLoginEmail())
+	if err != nil {
+		log.Error("failed to check email login code", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	domainUser, err := a.userService.GetByEmail(tenantId, email)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			log.Error("user not found", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrVerificationCodeExpired)
+		}
+
+		log.Error("failed to get user by email", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	loginResult, err := a.completeLogin(log, domainUser)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return loginResult, nil
+}
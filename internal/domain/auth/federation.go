@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/identity"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"log/slog"
+	"time"
+)
+
+const federatedLoginKey = "federated_login_"
+
+const (
+	federatedLoginStateLength = 32
+	federatedLoginNonceLength = 32
+)
+
+const defaultFederatedLoginTtl = 10 * time.Minute
+
+// federatedUserPasswordLength is the length of the random password of users created by a federated login,
+// they can set their own password with a password reset
+const federatedUserPasswordLength = 32
+
+var (
+	ErrUnknownIdentityProvider    = errors.New("unknown identity provider")
+	ErrInvalidFederatedLoginState = errors.New("federated login state is invalid or has expired")
+	ErrFederatedLoginFailed       = errors.New("identity provider rejected the login")
+	ErrFederatedEmailRequired     = errors.New("identity provider did not return a verified email")
+)
+
+type IdentityService interface {
+	Create(identity *identity.Identity) error
+	GetByProviderSubject(tenantId domain.TenantId, provider string, subject string) (*identity.Identity, error)
+	ListByUserId(userId domain.UserId) ([]*identity.Identity, error)
+}
+
+// FederatedLoginStart holds the url of the identity provider the user is redirected to.
+// The provider redirects the user back with the state and a code passed to CompleteFederatedLogin
+type FederatedLoginStart struct {
+	AuthorizationUrl string
+	State            string
+	ExpiresAt        time.Time
+}
+
+type federatedLoginSession struct {
+	Provider     string          `json:"provider"`
+	CodeVerifier string          `json:"code_verifier"`
+	Nonce        string          `json:"nonce"`
+	TenantId     domain.TenantId `json:"tenant_id"`
+}
+
+// StartFederatedLogin starts an authorization code flow with PKCE at the identity provider
+func (a *authServiceImpl) StartFederatedLogin(tenantId domain.TenantId, providerName string) (*FederatedLoginStart, error) {
+	const operation = "StartFederatedLogin"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("provider", providerName))
+
+	log.Info("starting a federated login")
+
+	provider, ok := a.identityProviders.Get(providerName)
+	if !ok {
+		log.Warn("identity provider is not configured")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrUnknownIdentityProvider)
+	}
+
+	state, err := xrand.GenerateRandomToken(federatedLoginStateLength)
+	if err != nil {
+		log.Error("failed to generate state", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	nonce, err := xrand.GenerateRandomToken(federatedLoginNonceLength)
+	if err != nil {
+		log.Error("failed to generate nonce", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		log.Error("failed to generate code verifier", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	authorizationUrl, err := provider.AuthorizationUrl(context.Background(), state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		log.Error("failed to build authorization url", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	session, err := json.Marshal(&federatedLoginSession{
+		Provider:     provider.Name(),
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+		TenantId:     tenantId,
+	})
+	if err != nil {
+		log.Error("failed to marshal federated login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	ttl := a.federatedLoginTtl()
+
+	err = a.inMemoryStorage.Set(federatedLoginKey+hashToken(state), string(session), ttl)
+	if err != nil {
+		log.Error("failed to cache federated login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("federated login started")
+
+	return &FederatedLoginStart{
+		AuthorizationUrl: authorizationUrl,
+		State:            state,
+		ExpiresAt:        time.Now().Add(ttl),
+	}, nil
+}
+
+// CompleteFederatedLogin redeems the code at the identity provider and logs in the user linked to the external identity.
+// An identity which is not linked yet is linked to the user of the tenant with the same verified email, a new user
+// is created if there is no such user. Like Login it returns an MFA challenge instead of tokens if the user has enabled MFA.
+// The state is consumed on the first attempt
+func (a *authServiceImpl) CompleteFederatedLogin(state string, code string) (*LoginResult, error) {
+	const operation = "CompleteFederatedLogin"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	log.Info("completing a federated login")
+
+	sessionValue, err := a.consumeValue(federatedLoginKey+hashToken(state), ErrInvalidFederatedLoginState)
+	if err != nil {
+		log.Warn("failed to consume federated login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var session federatedLoginSession
+	err = json.Unmarshal([]byte(sessionValue), &session)
+	if err != nil {
+		log.Error("invalid federated login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidFederatedLoginState)
+	}
+
+	log = log.With(
+		slog.String("tenant_id", session.TenantId.String()),
+		slog.String("provider", session.Provider))
+
+	provider, ok := a.identityProviders.Get(session.Provider)
+	if !ok {
+		log.Warn("identity provider is not configured anymore")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrUnknownIdentityProvider)
+	}
+
+	externalIdentity, err := provider.Exchange(context.Background(), code, session.CodeVerifier, session.Nonce)
+	if err != nil {
+		log.Warn("failed to exchange authorization code", "error", err)
+
+		return nil, fmt.Errorf("%s: %w: %w", operation, ErrFederatedLoginFailed, err)
+	}
+
+	domainUser, err := a.federatedUser(log, session.TenantId, provider.Name(), externalIdentity)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	loginResult, err := a.completeLogin(log.With(slog.String("user_id", domainUser.Id.String())), domainUser)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return loginResult, nil
+}
+
+// federatedUser returns the user linked to the external identity, the identity is linked if it is not yet
+func (a *authServiceImpl) federatedUser(log *slog.Logger, tenantId domain.TenantId, providerName string, externalIdentity *oidc.Identity) (*user.User, error) {
+	linkedIdentity, err := a.identityService.GetByProviderSubject(tenantId, providerName, externalIdentity.Subject)
+	if err == nil {
+		return a.userService.GetById(linkedIdentity.UserId)
+	}
+	if !errors.Is(err, identity.ErrIdentityNotFound) {
+		log.Error("failed to get identity", "error", err)
+
+		return nil, err
+	}
+
+	if externalIdentity.Email == "" || !externalIdentity.EmailVerified {
+		log.Warn("identity provider did not return a verified email")
+
+		return nil, ErrFederatedEmailRequired
+	}
+
+	email := domain.Email(externalIdentity.Email)
+
+	domainUser, err := a.userService.GetByEmail(tenantId, email)
+	if errors.Is(err, user.ErrUserNotFound) {
+		domainUser, err = a.createFederatedUser(tenantId, email)
+	}
+	if err != nil {
+		log.Error("failed to get or create user", "error", err)
+
+		return nil, err
+	}
+
+	err = a.identityService.Create(&identity.Identity{
+		UserId:   domainUser.Id,
+		TenantId: tenantId,
+		Provider: providerName,
+		Subject:  externalIdentity.Subject,
+		Email:    externalIdentity.Email,
+	})
+	if err != nil {
+		if !errors.Is(err, identity.ErrIdentityAlreadyLinked) {
+			log.Error("failed to link identity", "error", err)
+
+			return nil, err
+		}
+
+		// a concurrent login has linked the identity
+		linkedIdentity, err = a.identityService.GetByProviderSubject(tenantId, providerName, externalIdentity.Subject)
+		if err != nil {
+			log.Error("failed to get identity", "error", err)
+
+			return nil, err
+		}
+		return a.userService.GetById(linkedIdentity.UserId)
+	}
+
+	log.Info("identity linked to user", slog.String("user_id", domainUser.Id.String()))
+
+	return domainUser, nil
+}
+
+// createFederatedUser creates a user with a random password, the email is verified by the identity provider
+func (a *authServiceImpl) createFederatedUser(tenantId domain.TenantId, email domain.Email) (*user.User, error) {
+	password, err := xrand.GenerateRandomToken(federatedUserPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := a.passwordHasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := a.userService.Create(tenantId, email, domain.Password(passwordHash))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.userService.GetById(userId)
+}
+
+func (a *authServiceImpl) federatedLoginTtl() time.Duration {
+	if a.config.FederatedLoginTtl > 0 {
+		return a.config.FederatedLoginTtl
+	}
+	return defaultFederatedLoginTtl
+}
@@ -0,0 +1,257 @@
+package auth
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/domain/identity"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/memory"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/oidc/oidctest"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+const testProviderName = "idp"
+
+type fakeUserService struct {
+	users []*user.User
+}
+
+func (s *fakeUserService) Create(tenantId domain.TenantId, email domain.Email, password domain.Password) (domain.UserId, error) {
+	id := domain.UserId(len(s.users) + 1)
+	s.users = append(s.users, &user.User{Id: id, TenantId: tenantId, Email: email, Password: password})
+	return id, nil
+}
+
+func (s *fakeUserService) GetByEmail(tenantId domain.TenantId, email domain.Email) (*user.User, error) {
+	for _, u := range s.users {
+		if u.TenantId == tenantId && u.Email == email {
+			return u, nil
+		}
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (s *fakeUserService) GetById(id domain.UserId) (*user.User, error) {
+	for _, u := range s.users {
+		if u.Id == id {
+			return u, nil
+		}
+	}
+	return nil, user.ErrUserNotFound
+}
+
+func (s *fakeUserService) ExistsByEmail(tenantId domain.TenantId, email domain.Email) (bool, error) {
+	_, err := s.GetByEmail(tenantId, email)
+	return err == nil, nil
+}
+
+func (s *fakeUserService) UpdatePassword(id domain.UserId, password domain.Password) error {
+	u, err := s.GetById(id)
+	if err != nil {
+		return err
+	}
+	u.Password = password
+	return nil
+}
+
+type fakeIdentityService struct {
+	identities []*identity.Identity
+}
+
+func (s *fakeIdentityService) Create(newIdentity *identity.Identity) error {
+	_, err := s.GetByProviderSubject(newIdentity.TenantId, newIdentity.Provider, newIdentity.Subject)
+	if err == nil {
+		return identity.ErrIdentityAlreadyLinked
+	}
+	s.identities = append(s.identities, newIdentity)
+	return nil
+}
+
+func (s *fakeIdentityService) GetByProviderSubject(tenantId domain.TenantId, provider string, subject string) (*identity.Identity, error) {
+	for _, i := range s.identities {
+		if i.TenantId == tenantId && i.Provider == provider && i.Subject == subject {
+			return i, nil
+		}
+	}
+	return nil, identity.ErrIdentityNotFound
+}
+
+func (s *fakeIdentityService) ListByUserId(userId domain.UserId) ([]*identity.Identity, error) {
+	var identities []*identity.Identity
+	for _, i := range s.identities {
+		if i.UserId == userId {
+			identities = append(identities, i)
+		}
+	}
+	return identities, nil
+}
+
+// fakeMfaService enables MFA of all users, so logins end with an MFA challenge instead of issued tokens
+type fakeMfaService struct{}
+
+func (s *fakeMfaService) IsTotpEnabled(domain.UserId) (bool, error) {
+	return true, nil
+}
+
+func (s *fakeMfaService) Verify(domain.UserId, string) error {
+	return nil
+}
+
+func newFederationTestService(t *testing.T) (*authServiceImpl, *oidctest.Provider) {
+	t.Helper()
+
+	fakeProvider, err := oidctest.NewProvider(oidc.ProviderTypeOidc, "client", "secret")
+	if err != nil {
+		t.Fatalf("oidctest.NewProvider() error = %v", err)
+	}
+	t.Cleanup(fakeProvider.Close)
+
+	providers, err := oidc.NewProviders([]oidc.Config{fakeProvider.Config(testProviderName, "https://auth.example.com/callback")})
+	if err != nil {
+		t.Fatalf("NewProviders() error = %v", err)
+	}
+
+	service := &authServiceImpl{
+		config:            &Config{},
+		userService:       &fakeUserService{},
+		mfaService:        &fakeMfaService{},
+		identityService:   &fakeIdentityService{},
+		identityProviders: providers,
+		inMemoryStorage:   memory.NewMemoryStorage(),
+		logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	return service, fakeProvider
+}
+
+// authorize starts a federated login and signs the user in to the provider
+func authorize(t *testing.T, service *authServiceImpl, fakeProvider *oidctest.Provider, providerUser oidctest.User) (code string, state string) {
+	t.Helper()
+
+	start, err := service.StartFederatedLogin(domain.DefaultTenantId, testProviderName)
+	if err != nil {
+		t.Fatalf("StartFederatedLogin() error = %v", err)
+	}
+
+	fakeProvider.SetUser(providerUser)
+
+	code, state, err = fakeProvider.Authorize(start.AuthorizationUrl)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if state != start.State {
+		t.Fatalf("Authorize() state = %q, want %q", state, start.State)
+	}
+
+	return code, state
+}
+
+func TestCompleteFederatedLoginLinksVerifiedEmail(t *testing.T) {
+	service, fakeProvider := newFederationTestService(t)
+
+	existingId, _ := service.userService.Create(domain.DefaultTenantId, "user@example.com", "hash")
+
+	code, state := authorize(t, service, fakeProvider, oidctest.User{Subject: "1234", Email: "user@example.com", EmailVerified: true})
+
+	result, err := service.CompleteFederatedLogin(state, code)
+	if err != nil {
+		t.Fatalf("CompleteFederatedLogin() error = %v", err)
+	}
+	if result.MfaChallenge == nil {
+		t.Errorf("CompleteFederatedLogin() MfaChallenge = nil, want a challenge")
+	}
+
+	linked, err := service.identityService.GetByProviderSubject(domain.DefaultTenantId, testProviderName, "1234")
+	if err != nil {
+		t.Fatalf("GetByProviderSubject() error = %v", err)
+	}
+	if linked.UserId != existingId {
+		t.Errorf("linked UserId = %v, want %v", linked.UserId, existingId)
+	}
+}
+
+func TestCompleteFederatedLoginUnverifiedEmail(t *testing.T) {
+	tests := []struct {
+		name         string
+		providerUser oidctest.User
+	}{
+		{name: "unverified email of an existing user", providerUser: oidctest.User{Subject: "1234", Email: "user@example.com"}},
+		{name: "unverified email of a new user", providerUser: oidctest.User{Subject: "1234", Email: "new@example.com"}},
+		{name: "no email", providerUser: oidctest.User{Subject: "1234", EmailVerified: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, fakeProvider := newFederationTestService(t)
+
+			_, _ = service.userService.Create(domain.DefaultTenantId, "user@example.com", "hash")
+
+			code, state := authorize(t, service, fakeProvider, tt.providerUser)
+
+			_, err := service.CompleteFederatedLogin(state, code)
+			if !errors.Is(err, ErrFederatedEmailRequired) {
+				t.Fatalf("CompleteFederatedLogin() error = %v, want %v", err, ErrFederatedEmailRequired)
+			}
+
+			_, err = service.identityService.GetByProviderSubject(domain.DefaultTenantId, testProviderName, "1234")
+			if !errors.Is(err, identity.ErrIdentityNotFound) {
+				t.Errorf("GetByProviderSubject() error = %v, want %v", err, identity.ErrIdentityNotFound)
+			}
+
+			users := service.userService.(*fakeUserService).users
+			if len(users) != 1 {
+				t.Errorf("users = %d, want 1", len(users))
+			}
+		})
+	}
+}
+
+func TestCompleteFederatedLoginStateReuse(t *testing.T) {
+	providerUser := oidctest.User{Subject: "1234", Email: "user@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name      string
+		firstCode func(code string) string
+		wantErr   error
+	}{
+		{name: "after a successful login", firstCode: func(code string) string { return code }},
+		{name: "after a failed login", firstCode: func(string) string { return "invalid" }, wantErr: ErrFederatedLoginFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, fakeProvider := newFederationTestService(t)
+
+			_, _ = service.userService.Create(domain.DefaultTenantId, "user@example.com", "hash")
+
+			code, state := authorize(t, service, fakeProvider, providerUser)
+
+			_, err := service.CompleteFederatedLogin(state, tt.firstCode(code))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompleteFederatedLogin() error = %v, want %v", err, tt.wantErr)
+			}
+
+			// a new code of another authorization must not be accepted with the consumed state either
+			secondCode, _ := authorize(t, service, fakeProvider, providerUser)
+
+			_, err = service.CompleteFederatedLogin(state, secondCode)
+			if !errors.Is(err, ErrInvalidFederatedLoginState) {
+				t.Errorf("CompleteFederatedLogin() error = %v, want %v", err, ErrInvalidFederatedLoginState)
+			}
+		})
+	}
+}
+
+func TestCompleteFederatedLoginUnknownState(t *testing.T) {
+	service, fakeProvider := newFederationTestService(t)
+
+	code, _ := authorize(t, service, fakeProvider, oidctest.User{Subject: "1234", Email: "user@example.com", EmailVerified: true})
+
+	_, err := service.CompleteFederatedLogin("unknown", code)
+	if !errors.Is(err, ErrInvalidFederatedLoginState) {
+		t.Errorf("CompleteFederatedLogin() error = %v, want %v", err, ErrInvalidFederatedLoginState)
+	}
+}
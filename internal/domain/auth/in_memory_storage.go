@@ -6,5 +6,10 @@ import (
 
 type InMemoryStorage interface {
 	Set(key, value string, exp time.Duration) error
+	SetIfNotExists(key, value string, exp time.Duration) (bool, error)
 	Get(key string) (string, error)
+	// Delete reports whether the key existed, so only one of concurrent callers consumes a value
+	Delete(key string) (bool, error)
+	// Increment increments an integer value of the key, a new key expires after exp
+	Increment(key string, exp time.Duration) (int64, error)
 }
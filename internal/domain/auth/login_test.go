@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/memory"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/xpassword"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newLoginTestService(t *testing.T, algorithm xpassword.Algorithm) (*authServiceImpl, *fakeUserService) {
+	t.Helper()
+
+	passwordHasher, err := xpassword.NewFromConfig(&xpassword.Config{
+		Algorithm: algorithm,
+		Argon2id:  xpassword.Argon2idConfig{Memory: 64, Time: 1},
+		Bcrypt:    xpassword.BcryptConfig{Cost: 4},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+
+	userService := &fakeUserService{}
+
+	service := &authServiceImpl{
+		config:          &Config{},
+		userService:     userService,
+		mfaService:      &fakeMfaService{},
+		inMemoryStorage: memory.NewMemoryStorage(),
+		passwordHasher:  passwordHasher,
+		dummyPasswordHash: sync.OnceValues(func() (string, error) {
+			return passwordHasher.Hash(dummyPassword)
+		}),
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	return service, userService
+}
+
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	bcryptService, _ := newLoginTestService(t, xpassword.AlgorithmBcrypt)
+	bcryptHash, err := bcryptService.passwordHasher.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	service, userService := newLoginTestService(t, xpassword.AlgorithmArgon2id)
+	userId, _ := userService.Create(domain.DefaultTenantId, "user@example.com", domain.Password(bcryptHash))
+
+	_, err = service.Login(domain.DefaultTenantId, "user@example.com", "password")
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	domainUser, _ := userService.GetById(userId)
+	if !strings.HasPrefix(domainUser.Password.String(), "$argon2id$") {
+		t.Fatalf("password hash = %q, want argon2id hash", domainUser.Password)
+	}
+
+	_, err = service.Login(domain.DefaultTenantId, "user@example.com", "password")
+	if err != nil {
+		t.Errorf("Login() with upgraded hash error = %v", err)
+	}
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	service, userService := newLoginTestService(t, xpassword.AlgorithmArgon2id)
+
+	passwordHash, err := service.passwordHasher.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	_, _ = userService.Create(domain.DefaultTenantId, "user@example.com", domain.Password(passwordHash))
+
+	tests := []struct {
+		name     string
+		email    domain.Email
+		password domain.Password
+	}{
+		{name: "wrong password", email: "user@example.com", password: "wrong"},
+		{name: "unknown email", email: "unknown@example.com", password: "password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Login(domain.DefaultTenantId, tt.email, tt.password)
+			if !errors.Is(err, ErrInvalidEmailOrPassword) {
+				t.Errorf("Login() error = %v, want %v", err, ErrInvalidEmailOrPassword)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const (
+	mfaChallengeKey         = "mfa_challenge_"
+	mfaChallengeAttemptsKey = "mfa_challenge_attempts_"
+)
+
+const mfaTokenLength = 32
+
+const defaultMfaChallengeTtl = 5 * time.Minute
+
+// maxMfaAttempts limits guessing of codes with a single challenge, the challenge is invalidated after that
+const maxMfaAttempts = 5
+
+var ErrInvalidMfaToken = errors.New("mfa token is invalid or has expired")
+
+type MfaToken string
+
+type MfaService interface {
+	IsTotpEnabled(userId domain.UserId) (bool, error)
+	Verify(userId domain.UserId, code string) error
+}
+
+// MfaChallenge is returned by Login instead of tokens if the user has enabled MFA,
+// the tokens are issued by CompleteMfa with the challenge token and a code
+type MfaChallenge struct {
+	MfaToken  MfaToken
+	ExpiresAt time.Time
+}
+
+// LoginResult holds either tokens or an MFA challenge to be completed
+type LoginResult struct {
+	Tokens       *Tokens
+	MfaChallenge *MfaChallenge
+}
+
+// CompleteMfa issues tokens to the user who passed the password step of the challenge if the code
+// of the authenticator app or a recovery code is valid. The challenge is consumed on success
+func (a *authServiceImpl) CompleteMfa(mfaToken MfaToken, code string) (*Tokens, error) {
+	const operation = "CompleteMfa"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	log.Info("completing mfa")
+
+	challengeKey := mfaChallengeKey + hashToken(string(mfaToken))
+	attemptsKey := mfaChallengeAttemptsKey + hashToken(string(mfaToken))
+
+	userIdValue, err := a.inMemoryStorage.Get(challengeKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			log.Warn("mfa challenge not found")
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidMfaToken)
+		}
+
+		log.Error("failed to get mfa challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	parsedUserId, err := strconv.ParseInt(userIdValue, 10, 64)
+	if err != nil {
+		log.Error("invalid mfa challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidMfaToken)
+	}
+
+	userId := domain.UserId(parsedUserId)
+	log = log.With(slog.String("user_id", userId.String()))
+
+	attempts, err := a.inMemoryStorage.Increment(attemptsKey, a.mfaChallengeTtl())
+	if err != nil {
+		log.Error("failed to count mfa attempts", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if attempts > maxMfaAttempts {
+		log.Warn("too many mfa attempts")
+
+		_, err = a.inMemoryStorage.Delete(challengeKey)
+		if err != nil {
+			log.Error("failed to delete mfa challenge", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrTooManyAttempts)
+	}
+
+	err = a.mfaService.Verify(userId, code)
+	if err != nil {
+		log.Warn("mfa verification failed", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	deleted, err := a.inMemoryStorage.Delete(challengeKey)
+	if err != nil {
+		log.Error("failed to delete mfa challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !deleted {
+		log.Warn("mfa challenge has been completed by a concurrent request")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidMfaToken)
+	}
+
+	_, err = a.inMemoryStorage.Delete(attemptsKey)
+	if err != nil {
+		log.Error("failed to delete mfa attempts", "error", err)
+	}
+
+	domainUser, err := a.userService.GetById(userId)
+	if err != nil {
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	tokens, err := a.issueTokens(domainUser, "")
+	if err != nil {
+		log.Error("failed to issue tokens", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("mfa completed")
+
+	return tokens, nil
+}
+
+func (a *authServiceImpl) createMfaChallenge(userId domain.UserId) (*MfaChallenge, error) {
+	const operation = "createMfaChallenge"
+
+	mfaToken, err := xrand.GenerateRandomToken(mfaTokenLength)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	ttl := a.mfaChallengeTtl()
+
+	err = a.inMemoryStorage.Set(mfaChallengeKey+hashToken(mfaToken), userId.String(), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &MfaChallenge{
+		MfaToken:  MfaToken(mfaToken),
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+func (a *authServiceImpl) mfaChallengeTtl() time.Duration {
+	if a.config.MfaChallengeTtl > 0 {
+		return a.config.MfaChallengeTtl
+	}
+	return defaultMfaChallengeTtl
+}
@@ -0,0 +1,1019 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/oauth"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	oauthAuthorizationRequestKey = "oauth_authorization_request_"
+	oauthAuthorizationCodeKey    = "oauth_authorization_code_"
+)
+
+const (
+	oauthClientIdLength             = 16
+	oauthClientSecretLength         = 32
+	oauthAuthorizationRequestLength = 32
+	oauthAuthorizationCodeLength    = 32
+)
+
+const (
+	defaultAuthorizationRequestTtl = 10 * time.Minute
+	defaultAuthorizationCodeTtl    = time.Minute
+)
+
+// ResponseTypeCode is the only response type of the authorization endpoint, implicit grant is not supported
+const ResponseTypeCode = "code"
+
+// BearerTokenType is token_type of access tokens issued by the token endpoint
+const BearerTokenType = "Bearer"
+
+// token type hints of RFC 7009 and RFC 7662
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// introspectedTokenUses are the uses of access tokens the introspection endpoint describes,
+// resource servers of the tenant may be called with tokens of either
+var introspectedTokenUses = []string{auth.TokenUseAccess, auth.TokenUseOAuth}
+
+var (
+	ErrInvalidOAuthClient          = errors.New("oauth client authentication failed")
+	ErrInvalidOAuthClientMetadata  = errors.New("oauth client metadata is invalid")
+	ErrInvalidRedirectUri          = errors.New("redirect uri is not registered for the client")
+	ErrUnsupportedResponseType     = errors.New("response type is not supported")
+	ErrInvalidAuthorizationRequest = errors.New("authorization request is invalid")
+	ErrAuthorizationRequestExpired = errors.New("authorization request is invalid or has expired")
+	ErrUnauthorizedClient          = errors.New("client is not allowed to use the grant")
+	ErrUnsupportedGrantType        = errors.New("grant type is not supported")
+	ErrInvalidGrant                = errors.New("authorization grant is invalid")
+	ErrInvalidScope                = errors.New("requested scope is invalid")
+)
+
+// OAuthConfig of the authorization server. The authorization endpoint redirects users to ConsentPageUrl
+// with 'request_id' query parameter, the page authenticates the user and completes the request with gRPC API.
+// Issuer is the public url of the HTTP server, it is 'iss' claim of ID tokens and the base url of the endpoints
+// in the discovery document. It must differ from the token issuer, so ID tokens are never taken for access tokens
+type OAuthConfig struct {
+	Issuer                  string        `yaml:"issuer"`
+	ConsentPageUrl          string        `yaml:"consent-page-url"`
+	AuthorizationRequestTtl time.Duration `yaml:"authorization-request-ttl"`
+	AuthorizationCodeTtl    time.Duration `yaml:"authorization-code-ttl"`
+}
+
+type OAuthService interface {
+	CreateClient(client *oauth.Client) error
+	GetClient(clientId string) (*oauth.Client, error)
+	SaveConsent(userId domain.UserId, clientId string, scopes []string) error
+	GetConsent(userId domain.UserId, clientId string) (*oauth.Consent, error)
+}
+
+// OAuthClientRegistration is the metadata of a client to register. Confidential clients get a secret,
+// only they can use the client credentials grant
+type OAuthClientRegistration struct {
+	Name         string
+	RedirectUris []string
+	GrantTypes   []string
+	Scopes       []string
+	Confidential bool
+}
+
+// RegisteredOAuthClient holds the client secret, it is not stored and can not be read again
+type RegisteredOAuthClient struct {
+	Client       *oauth.Client
+	ClientSecret string
+}
+
+// AuthorizationRequest is a request to the authorization endpoint, PKCE with S256 method is required.
+// Nonce is put to the ID token issued for the request
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientId            string
+	RedirectUri         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+}
+
+// PendingAuthorization is an authorization request waiting for the user consent,
+// ConsentGranted is true if the user has already granted the scopes to the client
+type PendingAuthorization struct {
+	ClientId       string
+	ClientName     string
+	Scopes         []string
+	ConsentGranted bool
+	ExpiresAt      time.Time
+}
+
+// TokenRequest is a request to the token endpoint. The client secret is empty for public clients
+type TokenRequest struct {
+	GrantType    string
+	ClientId     string
+	ClientSecret string
+	Code         string
+	RedirectUri  string
+	CodeVerifier string
+	RefreshToken string
+	Scopes       []string
+}
+
+// OAuthTokens is a successful response of the token endpoint, RefreshToken is empty
+// if the client is not allowed to refresh tokens and IdToken is empty if 'openid' scope is not granted
+type OAuthTokens struct {
+	AccessToken  AccessToken
+	RefreshToken RefreshToken
+	IdToken      string
+	TokenType    string
+	ExpiresIn    time.Duration
+	Scopes       []string
+}
+
+// TokenIntrospection describes a token as RFC 7662 defines, only Active is set for inactive tokens
+type TokenIntrospection struct {
+	Active    bool
+	TokenType string
+	ClientId  string
+	UserId    domain.UserId
+	TenantId  domain.TenantId
+	Scopes    []string
+	TokenId   string
+	Issuer    string
+	Audience  []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+type authorizationRequestData struct {
+	ClientId      string    `json:"client_id"`
+	RedirectUri   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes"`
+	State         string    `json:"state"`
+	CodeChallenge string    `json:"code_challenge"`
+	Nonce         string    `json:"nonce,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type authorizationCodeData struct {
+	ClientId      string        `json:"client_id"`
+	RedirectUri   string        `json:"redirect_uri"`
+	UserId        domain.UserId `json:"user_id"`
+	Scopes        []string      `json:"scopes"`
+	CodeChallenge string        `json:"code_challenge"`
+	Nonce         string        `json:"nonce,omitempty"`
+}
+
+// RegisterOAuthClient registers a client of the tenant and generates its credentials
+func (a *authServiceImpl) RegisterOAuthClient(tenantId domain.TenantId, registration *OAuthClientRegistration) (*RegisteredOAuthClient, error) {
+	const operation = "RegisterOAuthClient"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("name", registration.Name))
+
+	log.Info("registering an oauth client")
+
+	err := validateOAuthClientRegistration(registration)
+	if err != nil {
+		log.Warn("invalid oauth client metadata", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	clientId, err := xrand.GenerateRandomToken(oauthClientIdLength)
+	if err != nil {
+		log.Error("failed to generate client id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var clientSecret, secretHash string
+	if registration.Confidential {
+		clientSecret, err = xrand.GenerateRandomToken(oauthClientSecretLength)
+		if err != nil {
+			log.Error("failed to generate client secret", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+		secretHash = hashToken(clientSecret)
+	}
+
+	client := &oauth.Client{
+		ClientId:     clientId,
+		TenantId:     tenantId,
+		Name:         registration.Name,
+		SecretHash:   secretHash,
+		RedirectUris: registration.RedirectUris,
+		GrantTypes:   registration.GrantTypes,
+		Scopes:       registration.Scopes,
+	}
+
+	err = a.oauthService.CreateClient(client)
+	if err != nil {
+		log.Error("failed to create oauth client", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("oauth client registered", slog.String("client_id", clientId))
+
+	return &RegisteredOAuthClient{Client: client, ClientSecret: clientSecret}, nil
+}
+
+// StartAuthorization validates the request to the authorization endpoint and returns the url of the consent page.
+// ErrInvalidOAuthClient and ErrInvalidRedirectUri must not be redirected to the redirect uri,
+// other errors are redirected to it as RFC 6749 4.1.2.1 requires
+func (a *authServiceImpl) StartAuthorization(request *AuthorizationRequest) (string, error) {
+	const operation = "StartAuthorization"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", request.ClientId))
+
+	log.Info("starting an authorization")
+
+	client, err := a.oauthService.GetClient(request.ClientId)
+	if err != nil {
+		if errors.Is(err, oauth.ErrClientNotFound) {
+			log.Warn("oauth client not found")
+
+			return "", fmt.Errorf("%s: %w", operation, ErrInvalidOAuthClient)
+		}
+
+		log.Error("failed to get oauth client", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !client.AllowsRedirectUri(request.RedirectUri) {
+		log.Warn("redirect uri is not registered", slog.String("redirect_uri", request.RedirectUri))
+
+		return "", fmt.Errorf("%s: %w", operation, ErrInvalidRedirectUri)
+	}
+
+	if request.ResponseType != ResponseTypeCode {
+		return "", fmt.Errorf("%s: %w", operation, ErrUnsupportedResponseType)
+	}
+
+	if !client.AllowsGrantType(oauth.GrantTypeAuthorizationCode) {
+		return "", fmt.Errorf("%s: %w", operation, ErrUnauthorizedClient)
+	}
+
+	if request.CodeChallengeMethod != oidc.CodeChallengeMethod || request.CodeChallenge == "" {
+		return "", fmt.Errorf("%s: %w: S256 code challenge is required", operation, ErrInvalidAuthorizationRequest)
+	}
+
+	scopes, err := grantedScopes(request.Scopes, client.Scopes)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	// the code would be consumed by the token request failing to issue the ID token
+	if slices.Contains(scopes, ScopeOpenId) && !a.idTokensSupported() {
+		log.Warn("id tokens can not be issued with the symmetric signing key")
+
+		return "", fmt.Errorf("%s: %w: %q requires an asymmetric signing key", operation, ErrInvalidScope, ScopeOpenId)
+	}
+
+	requestId, err := xrand.GenerateRandomToken(oauthAuthorizationRequestLength)
+	if err != nil {
+		log.Error("failed to generate authorization request id", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	ttl := a.authorizationRequestTtl()
+
+	data, err := json.Marshal(&authorizationRequestData{
+		ClientId:      client.ClientId,
+		RedirectUri:   request.RedirectUri,
+		Scopes:        scopes,
+		State:         request.State,
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+		ExpiresAt:     time.Now().Add(ttl),
+	})
+	if err != nil {
+		log.Error("failed to marshal authorization request", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.inMemoryStorage.Set(oauthAuthorizationRequestKey+hashToken(requestId), string(data), ttl)
+	if err != nil {
+		log.Error("failed to cache authorization request", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	consentPageUrl, err := withQuery(a.config.OAuth.ConsentPageUrl, url.Values{"request_id": {requestId}})
+	if err != nil {
+		log.Error("invalid consent page url", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("authorization started")
+
+	return consentPageUrl, nil
+}
+
+// GetAuthorization returns the pending authorization request shown to the user on the consent page
+func (a *authServiceImpl) GetAuthorization(userId domain.UserId, requestId string) (*PendingAuthorization, error) {
+	const operation = "GetAuthorization"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	data, err := a.getAuthorizationRequest(requestId)
+	if err != nil {
+		log.Warn("failed to get authorization request", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	client, err := a.oauthService.GetClient(data.ClientId)
+	if err != nil {
+		log.Error("failed to get oauth client", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	consentGranted := false
+
+	consent, err := a.oauthService.GetConsent(userId, client.ClientId)
+	if err == nil {
+		consentGranted = consent.Covers(data.Scopes)
+	} else if !errors.Is(err, oauth.ErrConsentNotFound) {
+		log.Error("failed to get consent", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return &PendingAuthorization{
+		ClientId:       client.ClientId,
+		ClientName:     client.Name,
+		Scopes:         data.Scopes,
+		ConsentGranted: consentGranted,
+		ExpiresAt:      data.ExpiresAt,
+	}, nil
+}
+
+// CompleteAuthorization records the decision of the user and returns the redirect uri of the client with
+// an authorization code or with 'access_denied' error. The request is consumed on the first attempt
+func (a *authServiceImpl) CompleteAuthorization(userId domain.UserId, requestId string, approved bool) (string, error) {
+	const operation = "CompleteAuthorization"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()),
+		slog.Bool("approved", approved))
+
+	log.Info("completing an authorization")
+
+	rawData, err := a.consumeValue(oauthAuthorizationRequestKey+hashToken(requestId), ErrAuthorizationRequestExpired)
+	if err != nil {
+		log.Warn("failed to consume authorization request", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var data authorizationRequestData
+	err = json.Unmarshal([]byte(rawData), &data)
+	if err != nil {
+		log.Error("invalid authorization request", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, ErrAuthorizationRequestExpired)
+	}
+
+	log = log.With(slog.String("client_id", data.ClientId))
+
+	if !approved {
+		log.Info("authorization denied by user")
+
+		return a.authorizationErrorRedirect(log, &data, "access_denied")
+	}
+
+	client, err := a.oauthService.GetClient(data.ClientId)
+	if err != nil {
+		log.Error("failed to get oauth client", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	domainUser, err := a.userService.GetById(userId)
+	if err != nil {
+		log.Error("failed to get user by id", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if domainUser.TenantId != client.TenantId {
+		log.Warn("user does not belong to the tenant of the client")
+
+		return a.authorizationErrorRedirect(log, &data, "access_denied")
+	}
+
+	err = a.oauthService.SaveConsent(userId, client.ClientId, data.Scopes)
+	if err != nil {
+		log.Error("failed to save consent", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	code, err := xrand.GenerateRandomToken(oauthAuthorizationCodeLength)
+	if err != nil {
+		log.Error("failed to generate authorization code", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	codeData, err := json.Marshal(&authorizationCodeData{
+		ClientId:      client.ClientId,
+		RedirectUri:   data.RedirectUri,
+		UserId:        userId,
+		Scopes:        data.Scopes,
+		CodeChallenge: data.CodeChallenge,
+		Nonce:         data.Nonce,
+	})
+	if err != nil {
+		log.Error("failed to marshal authorization code", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.inMemoryStorage.Set(oauthAuthorizationCodeKey+hashToken(code), string(codeData), a.authorizationCodeTtl())
+	if err != nil {
+		log.Error("failed to cache authorization code", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	query := url.Values{"code": {code}}
+	if data.State != "" {
+		query.Set("state", data.State)
+	}
+
+	redirectUrl, err := withQuery(data.RedirectUri, query)
+	if err != nil {
+		log.Error("invalid redirect uri", "error", err)
+
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("authorization code issued")
+
+	return redirectUrl, nil
+}
+
+// ExchangeToken handles a request to the token endpoint
+func (a *authServiceImpl) ExchangeToken(request *TokenRequest) (*OAuthTokens, error) {
+	const operation = "ExchangeToken"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", request.ClientId),
+		slog.String("grant_type", request.GrantType))
+
+	log.Info("exchanging a grant for tokens")
+
+	client, err := a.authenticateOAuthClient(request.ClientId, request.ClientSecret)
+	if err != nil {
+		log.Warn("failed to authenticate oauth client", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if request.GrantType != oauth.GrantTypeAuthorizationCode &&
+		request.GrantType != oauth.GrantTypeRefreshToken &&
+		request.GrantType != oauth.GrantTypeClientCredentials {
+		return nil, fmt.Errorf("%s: %w", operation, ErrUnsupportedGrantType)
+	}
+
+	if !client.AllowsGrantType(request.GrantType) {
+		log.Warn("grant type is not allowed for the client")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrUnauthorizedClient)
+	}
+
+	var tokens *OAuthTokens
+
+	switch request.GrantType {
+	case oauth.GrantTypeAuthorizationCode:
+		tokens, err = a.exchangeAuthorizationCode(client, request)
+	case oauth.GrantTypeRefreshToken:
+		tokens, err = a.exchangeRefreshToken(client, request)
+	case oauth.GrantTypeClientCredentials:
+		tokens, err = a.exchangeClientCredentials(client, request)
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidGrant) || errors.Is(err, ErrInvalidScope) || errors.Is(err, ErrUnauthorizedClient) {
+			log.Warn("grant rejected", "error", err)
+		} else {
+			log.Error("failed to exchange grant", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("tokens issued to oauth client")
+
+	return tokens, nil
+}
+
+// IntrospectToken describes the token to a confidential client as RFC 7662 defines. Access tokens of the tenant
+// of the client are described to any its client, refresh tokens are described only to the client they were issued to
+func (a *authServiceImpl) IntrospectToken(clientId string, clientSecret string, token string, tokenTypeHint string) (*TokenIntrospection, error) {
+	const operation = "IntrospectToken"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", clientId))
+
+	client, err := a.authenticateOAuthClient(clientId, clientSecret)
+	if err != nil {
+		log.Warn("failed to authenticate oauth client", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !client.IsConfidential() {
+		log.Warn("public clients can not introspect tokens")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidOAuthClient)
+	}
+
+	introspect := []func(*oauth.Client, string) (*TokenIntrospection, error){a.introspectAccessToken, a.introspectRefreshToken}
+	if tokenTypeHint == TokenTypeHintRefreshToken {
+		slices.Reverse(introspect)
+	}
+
+	for _, introspectToken := range introspect {
+		introspection, err := introspectToken(client, token)
+		if err != nil {
+			log.Error("failed to introspect token", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+		if introspection.Active {
+			return introspection, nil
+		}
+	}
+
+	return &TokenIntrospection{Active: false}, nil
+}
+
+// RevokeOAuthToken revokes an access token or a refresh token family issued to the client as RFC 7009 defines.
+// Invalid tokens and tokens of other clients are ignored
+func (a *authServiceImpl) RevokeOAuthToken(clientId string, clientSecret string, token string) error {
+	const operation = "RevokeOAuthToken"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", clientId))
+
+	client, err := a.authenticateOAuthClient(clientId, clientSecret)
+	if err != nil {
+		log.Warn("failed to authenticate oauth client", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	payload, err := a.verifyAccessToken(AccessToken(token), auth.TokenUseOAuth)
+	if err == nil {
+		if payload.ClientId != client.ClientId {
+			log.Warn("access token was issued to another client")
+
+			return nil
+		}
+
+		err = a.revokeAccessToken(payload)
+		if err != nil {
+			log.Error("failed to revoke access token", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+
+		log.Info("access token revoked")
+
+		return nil
+	}
+
+	data, err := a.getRefreshTokenData(RefreshToken(token))
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return nil
+		}
+
+		log.Error("failed to get refresh token data", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if data.ClientId != client.ClientId {
+		log.Warn("refresh token was issued to another client")
+
+		return nil
+	}
+
+	err = a.revokeRefreshTokenFamily(data.FamilyId)
+	if err != nil {
+		log.Error("failed to revoke refresh token family", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("refresh token revoked")
+
+	return nil
+}
+
+func (a *authServiceImpl) exchangeAuthorizationCode(client *oauth.Client, request *TokenRequest) (*OAuthTokens, error) {
+	rawData, err := a.consumeValue(oauthAuthorizationCodeKey+hashToken(request.Code), ErrInvalidGrant)
+	if err != nil {
+		return nil, err
+	}
+
+	var data authorizationCodeData
+	err = json.Unmarshal([]byte(rawData), &data)
+	if err != nil {
+		return nil, ErrInvalidGrant
+	}
+
+	if data.ClientId != client.ClientId {
+		return nil, fmt.Errorf("%w: code was issued to another client", ErrInvalidGrant)
+	}
+	if data.RedirectUri != request.RedirectUri {
+		return nil, fmt.Errorf("%w: redirect uri does not match", ErrInvalidGrant)
+	}
+	if subtle.ConstantTimeCompare([]byte(oidc.CodeChallenge(request.CodeVerifier)), []byte(data.CodeChallenge)) != 1 {
+		return nil, fmt.Errorf("%w: code verifier does not match", ErrInvalidGrant)
+	}
+
+	domainUser, err := a.userService.GetById(data.UserId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	return a.issueOAuthTokens(client, domainUser, data.Scopes, data.Scopes, "", data.Nonce)
+}
+
+// exchangeRefreshToken rotates the refresh token, the requested scopes narrow the scopes of the access token only
+func (a *authServiceImpl) exchangeRefreshToken(client *oauth.Client, request *TokenRequest) (*OAuthTokens, error) {
+	data, err := a.rotateRefreshToken(RefreshToken(request.RefreshToken), client.ClientId)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) || errors.Is(err, ErrRefreshTokenReused) {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidGrant, err)
+		}
+		return nil, err
+	}
+
+	scopes, err := grantedScopes(request.Scopes, data.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	domainUser, err := a.userService.GetById(data.UserId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return nil, err
+	}
+
+	return a.issueOAuthTokens(client, domainUser, scopes, data.Scopes, data.FamilyId, "")
+}
+
+// exchangeClientCredentials issues an access token to the client itself, refresh tokens are not issued
+func (a *authServiceImpl) exchangeClientCredentials(client *oauth.Client, request *TokenRequest) (*OAuthTokens, error) {
+	if !client.IsConfidential() {
+		return nil, fmt.Errorf("%w: public clients can not use client credentials", ErrUnauthorizedClient)
+	}
+
+	scopes, err := grantedScopes(request.Scopes, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := a.keyRing.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	domainTenant, err := a.tenantService.GetById(client.TenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := a.tokenTtl(domainTenant)
+
+	payload := auth.NewPayload(0, ttl)
+	payload.PrincipalType = auth.PrincipalTypeService
+	payload.TokenUse = auth.TokenUseOAuth
+	payload.ClientId = client.ClientId
+	payload.TenantId = client.TenantId
+	payload.Scopes = scopes
+	payload.Issuer = a.config.TokenIssuer
+	payload.Audience = a.config.TokenAudience
+
+	accessToken, err := accesstoken.Create(payload, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthTokens{
+		AccessToken: AccessToken(accessToken),
+		TokenType:   BearerTokenType,
+		ExpiresIn:   ttl,
+		Scopes:      scopes,
+	}, nil
+}
+
+// issueOAuthTokens issues tokens delegated by the user to the client. Unlike tokens of the gRPC API they carry
+// the granted scopes instead of roles and permissions of the user and their use is auth.TokenUseOAuth, so they
+// are not accepted as tokens of the user by the gRPC API and verifiers. The refresh token keeps the scopes
+// of the original grant, which may be wider than the scopes of the access token.
+// ID token is issued along with them if 'openid' scope is granted, refreshed ID tokens have no nonce
+func (a *authServiceImpl) issueOAuthTokens(client *oauth.Client, domainUser *user.User, scopes []string, grantScopes []string, familyId string, nonce string) (*OAuthTokens, error) {
+	signingKey, err := a.keyRing.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	domainTenant, err := a.tenantService.GetById(domainUser.TenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := a.tokenTtl(domainTenant)
+
+	payload := auth.NewPayload(domainUser.Id, ttl)
+	payload.TokenUse = auth.TokenUseOAuth
+	payload.ClientId = client.ClientId
+	payload.TenantId = domainUser.TenantId
+	payload.Scopes = scopes
+	payload.Issuer = a.config.TokenIssuer
+	payload.Audience = a.config.TokenAudience
+
+	accessToken, err := accesstoken.Create(payload, signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := &OAuthTokens{
+		AccessToken: AccessToken(accessToken),
+		TokenType:   BearerTokenType,
+		ExpiresIn:   ttl,
+		Scopes:      scopes,
+	}
+
+	if slices.Contains(scopes, ScopeOpenId) {
+		tokens.IdToken, err = a.createIdToken(client, domainUser, scopes, nonce, signingKey, payload)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if client.AllowsGrantType(oauth.GrantTypeRefreshToken) {
+		tokens.RefreshToken, err = a.createRefreshToken(&refreshTokenData{
+			UserId:   domainUser.Id,
+			FamilyId: familyId,
+			ClientId: client.ClientId,
+			Scopes:   grantScopes,
+		}, a.refreshTokenTtl(domainTenant))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tokens, nil
+}
+
+func (a *authServiceImpl) introspectAccessToken(client *oauth.Client, token string) (*TokenIntrospection, error) {
+	payload, err := a.verifyAccessToken(AccessToken(token), introspectedTokenUses...)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) || errors.Is(err, ErrTokenExpired) || errors.Is(err, ErrTokenRevoked) {
+			return &TokenIntrospection{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	if payload.TenantId != client.TenantId {
+		return &TokenIntrospection{Active: false}, nil
+	}
+
+	return &TokenIntrospection{
+		Active:    true,
+		TokenType: TokenTypeHintAccessToken,
+		ClientId:  payload.ClientId,
+		UserId:    payload.UserId,
+		TenantId:  payload.TenantId,
+		Scopes:    payload.Scopes,
+		TokenId:   payload.TokenId,
+		Issuer:    payload.Issuer,
+		Audience:  payload.Audience,
+		IssuedAt:  payload.IssuedAt,
+		ExpiresAt: payload.ExpiredAt,
+	}, nil
+}
+
+// introspectRefreshToken reports rotated refresh tokens as inactive
+func (a *authServiceImpl) introspectRefreshToken(client *oauth.Client, token string) (*TokenIntrospection, error) {
+	data, err := a.getRefreshTokenData(RefreshToken(token))
+	if err != nil {
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return &TokenIntrospection{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	if data.ClientId != client.ClientId || time.Now().After(data.ExpiresAt) {
+		return &TokenIntrospection{Active: false}, nil
+	}
+
+	revoked, err := a.isRefreshTokenRevoked(data)
+	if err != nil {
+		return nil, err
+	}
+
+	used, err := a.isRefreshTokenUsed(RefreshToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked || used {
+		return &TokenIntrospection{Active: false}, nil
+	}
+
+	return &TokenIntrospection{
+		Active:    true,
+		TokenType: TokenTypeHintRefreshToken,
+		ClientId:  data.ClientId,
+		UserId:    data.UserId,
+		TenantId:  client.TenantId,
+		Scopes:    data.Scopes,
+		Issuer:    a.config.TokenIssuer,
+		IssuedAt:  data.IssuedAt,
+		ExpiresAt: data.ExpiresAt,
+	}, nil
+}
+
+// authenticateOAuthClient checks the secret of confidential clients, public clients must not send a secret
+func (a *authServiceImpl) authenticateOAuthClient(clientId string, clientSecret string) (*oauth.Client, error) {
+	if clientId == "" {
+		return nil, ErrInvalidOAuthClient
+	}
+
+	client, err := a.oauthService.GetClient(clientId)
+	if err != nil {
+		if errors.Is(err, oauth.ErrClientNotFound) {
+			return nil, ErrInvalidOAuthClient
+		}
+		return nil, err
+	}
+
+	if !client.IsConfidential() {
+		if clientSecret != "" {
+			return nil, ErrInvalidOAuthClient
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+		return nil, ErrInvalidOAuthClient
+	}
+
+	return client, nil
+}
+
+func (a *authServiceImpl) getAuthorizationRequest(requestId string) (*authorizationRequestData, error) {
+	rawData, err := a.inMemoryStorage.Get(oauthAuthorizationRequestKey + hashToken(requestId))
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return nil, ErrAuthorizationRequestExpired
+		}
+		return nil, err
+	}
+
+	var data authorizationRequestData
+	err = json.Unmarshal([]byte(rawData), &data)
+	if err != nil {
+		return nil, ErrAuthorizationRequestExpired
+	}
+
+	return &data, nil
+}
+
+func (a *authServiceImpl) authorizationErrorRedirect(log *slog.Logger, data *authorizationRequestData, errorCode string) (string, error) {
+	query := url.Values{"error": {errorCode}}
+	if data.State != "" {
+		query.Set("state", data.State)
+	}
+
+	redirectUrl, err := withQuery(data.RedirectUri, query)
+	if err != nil {
+		log.Error("invalid redirect uri", "error", err)
+
+		return "", err
+	}
+
+	return redirectUrl, nil
+}
+
+func (a *authServiceImpl) authorizationRequestTtl() time.Duration {
+	if a.config.OAuth.AuthorizationRequestTtl > 0 {
+		return a.config.OAuth.AuthorizationRequestTtl
+	}
+	return defaultAuthorizationRequestTtl
+}
+
+func (a *authServiceImpl) authorizationCodeTtl() time.Duration {
+	if a.config.OAuth.AuthorizationCodeTtl > 0 {
+		return a.config.OAuth.AuthorizationCodeTtl
+	}
+	return defaultAuthorizationCodeTtl
+}
+
+// grantedScopes returns the requested scopes if all of them are allowed, the allowed scopes are granted
+// if no scopes are requested
+func grantedScopes(requested []string, allowed []string) ([]string, error) {
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	for _, scope := range requested {
+		if !slices.Contains(allowed, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+
+	return requested, nil
+}
+
+func validateOAuthClientRegistration(registration *OAuthClientRegistration) error {
+	if len(registration.GrantTypes) == 0 {
+		return fmt.Errorf("%w: at least one grant type is required", ErrInvalidOAuthClientMetadata)
+	}
+
+	for _, grantType := range registration.GrantTypes {
+		switch grantType {
+		case oauth.GrantTypeAuthorizationCode, oauth.GrantTypeRefreshToken:
+		case oauth.GrantTypeClientCredentials:
+			if !registration.Confidential {
+				return fmt.Errorf("%w: client credentials grant requires a confidential client", ErrInvalidOAuthClientMetadata)
+			}
+		default:
+			return fmt.Errorf("%w: unknown grant type %q", ErrInvalidOAuthClientMetadata, grantType)
+		}
+	}
+
+	if slices.Contains(registration.GrantTypes, oauth.GrantTypeAuthorizationCode) && len(registration.RedirectUris) == 0 {
+		return fmt.Errorf("%w: authorization code grant requires a redirect uri", ErrInvalidOAuthClientMetadata)
+	}
+
+	for _, redirectUri := range registration.RedirectUris {
+		parsed, err := url.Parse(redirectUri)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Fragment != "" {
+			return fmt.Errorf("%w: redirect uri %q must be an absolute uri without a fragment", ErrInvalidOAuthClientMetadata, redirectUri)
+		}
+	}
+
+	return nil
+}
+
+// withQuery adds the parameters to the query of the uri, the existing parameters are kept
+func withQuery(uri string, params url.Values) (string, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsed.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
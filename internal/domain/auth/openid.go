@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/oauth"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"log/slog"
+	"slices"
+)
+
+// scopes of OpenID Connect, 'openid' scope makes an authorization request an OpenID Connect request
+// and 'email' scope grants access to the email claims
+const (
+	ScopeOpenId = "openid"
+	ScopeEmail  = "email"
+)
+
+var ErrInsufficientScope = errors.New("token does not have the required scope")
+
+// OpenIdProviderMetadata describes the OpenID provider in the discovery document
+type OpenIdProviderMetadata struct {
+	Issuer                   string
+	IdTokenSigningAlgorithms []string
+	Scopes                   []string
+	Claims                   []string
+}
+
+// UserInfo holds the claims of the userinfo endpoint, email claims are set only if 'email' scope is granted.
+// Emails are verified before users are created, so EmailVerified is always true for them
+type UserInfo struct {
+	UserId        domain.UserId
+	Email         domain.Email
+	EmailVerified bool
+}
+
+// GetOpenIdProviderMetadata returns the metadata of the discovery document, ID tokens are signed
+// with asymmetric keys of the key ring only. 'openid' scope is not supported while the signing key is symmetric,
+// then the list of the algorithms is empty
+func (a *authServiceImpl) GetOpenIdProviderMetadata() *OpenIdProviderMetadata {
+	algorithms := []string{}
+	scopes := []string{ScopeEmail}
+
+	if a.idTokensSupported() {
+		for _, key := range a.keyRing.VerificationKeys() {
+			algorithm := string(key.Algorithm)
+			if !key.IsSymmetric() && !slices.Contains(algorithms, algorithm) {
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+		scopes = []string{ScopeOpenId, ScopeEmail}
+	}
+
+	return &OpenIdProviderMetadata{
+		Issuer:                   a.config.OAuth.Issuer,
+		IdTokenSigningAlgorithms: algorithms,
+		Scopes:                   scopes,
+		Claims:                   []string{"iss", "sub", "aud", "exp", "iat", "nonce", "email", "email_verified"},
+	}
+}
+
+// GetUserInfo returns the claims of the user who granted the access token to a client, the token must have 'openid' scope.
+// Tokens of the gRPC API and of the client credentials grant are not accepted
+func (a *authServiceImpl) GetUserInfo(token AccessToken) (*UserInfo, error) {
+	const operation = "GetUserInfo"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	payload, err := a.verifyAccessToken(token, auth.TokenUseOAuth)
+	if err != nil {
+		log.Warn("failed to verify access token", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log = log.With(
+		slog.String("user_id", payload.UserId.String()),
+		slog.String("client_id", payload.ClientId))
+
+	if payload.ClientId == "" || payload.UserId == 0 {
+		log.Warn("access token was not delegated to a client by a user")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+	}
+
+	if !slices.Contains(payload.Scopes, ScopeOpenId) {
+		log.Warn("access token does not have openid scope")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInsufficientScope)
+	}
+
+	domainUser, err := a.userService.GetById(payload.UserId)
+	if err != nil {
+		if errors.Is(err, user.ErrUserNotFound) {
+			log.Warn("owner of access token not found")
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidToken)
+		}
+
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	userInfo := &UserInfo{UserId: domainUser.Id}
+	if slices.Contains(payload.Scopes, ScopeEmail) {
+		userInfo.Email = domainUser.Email
+		userInfo.EmailVerified = true
+	}
+
+	return userInfo, nil
+}
+
+// createIdToken creates ID token of the user for the client, it expires along with the access token
+func (a *authServiceImpl) createIdToken(client *oauth.Client, domainUser *user.User, scopes []string, nonce string, signingKey *accesstoken.Key, payload *auth.JwtPayload) (string, error) {
+	idToken := &accesstoken.IdToken{
+		Issuer:    a.config.OAuth.Issuer,
+		UserId:    domainUser.Id,
+		ClientId:  client.ClientId,
+		Nonce:     nonce,
+		IssuedAt:  payload.IssuedAt,
+		ExpiredAt: payload.ExpiredAt,
+	}
+
+	if slices.Contains(scopes, ScopeEmail) {
+		idToken.Email = domainUser.Email
+		idToken.EmailVerified = true
+	}
+
+	return accesstoken.CreateIdToken(idToken, signingKey)
+}
+
+// idTokensSupported reports whether ID tokens can be issued, they have the issuer of the authorization server
+// and are signed with asymmetric keys only
+func (a *authServiceImpl) idTokensSupported() bool {
+	if a.config.OAuth.Issuer == "" {
+		return false
+	}
+
+	signingKey, err := a.keyRing.SigningKey()
+	return err == nil && !signingKey.IsSymmetric()
+}
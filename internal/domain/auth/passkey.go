@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/passkey"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/webauthn"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"log/slog"
+	"time"
+)
+
+const (
+	passkeyRegistrationKey = "passkey_registration_"
+	passkeyLoginKey        = "passkey_login_"
+)
+
+const passkeySessionIdLength = 32
+
+const defaultPasskeyName = "Passkey"
+
+var (
+	ErrInvalidPasskeySession      = errors.New("passkey session is invalid or has expired")
+	ErrInvalidPasskeyRegistration = errors.New("passkey registration response is invalid")
+	ErrInvalidPasskeyAssertion    = errors.New("passkey assertion is invalid")
+)
+
+type PasskeySessionId string
+
+type PasskeyService interface {
+	Create(passkey *passkey.Passkey) error
+	GetById(id []byte) (*passkey.Passkey, error)
+	ListByUserId(userId domain.UserId) ([]*passkey.Passkey, error)
+	UpdateSignCount(id []byte, signCount uint32) error
+}
+
+// PasskeyLoginChallenge holds options passed to navigator.credentials.get() on the client,
+// SessionId is sent back along with the assertion to FinishPasskeyLogin
+type PasskeyLoginChallenge struct {
+	SessionId   PasskeySessionId
+	OptionsJson []byte
+	ExpiresAt   time.Time
+}
+
+type passkeyLoginSession struct {
+	Challenge []byte          `json:"challenge"`
+	TenantId  domain.TenantId `json:"tenant_id"`
+}
+
+// BeginPasskeyRegistration returns options passed to navigator.credentials.create() on the client,
+// a previous not finished registration of the user is replaced
+func (a *authServiceImpl) BeginPasskeyRegistration(userId domain.UserId) ([]byte, error) {
+	const operation = "BeginPasskeyRegistration"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	log.Info("beginning a passkey registration")
+
+	domainUser, err := a.userService.GetById(userId)
+	if err != nil {
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	passkeys, err := a.passkeyService.ListByUserId(userId)
+	if err != nil {
+		log.Error("failed to list passkeys", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		log.Error("failed to generate challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.inMemoryStorage.Set(passkeyRegistrationKey+userId.String(), encodeChallenge(challenge), a.relyingParty.Timeout())
+	if err != nil {
+		log.Error("failed to cache passkey registration challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	excludeCredentials := make([]webauthn.CredentialDescriptor, len(passkeys))
+	for i, userPasskey := range passkeys {
+		excludeCredentials[i] = credentialDescriptor(userPasskey)
+	}
+
+	userEntity := webauthn.UserEntity{
+		Id:          userHandle(userId),
+		Name:        domainUser.Email.String(),
+		DisplayName: domainUser.Email.String(),
+	}
+
+	optionsJson, err := json.Marshal(a.relyingParty.CreationOptions(challenge, userEntity, excludeCredentials))
+	if err != nil {
+		log.Error("failed to marshal creation options", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("passkey registration begun")
+
+	return optionsJson, nil
+}
+
+// FinishPasskeyRegistration verifies the response of navigator.credentials.create() and stores the passkey,
+// the challenge is consumed whether the response is valid or not
+func (a *authServiceImpl) FinishPasskeyRegistration(userId domain.UserId, name string, credentialJson []byte) (*passkey.Passkey, error) {
+	const operation = "FinishPasskeyRegistration"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	log.Info("finishing a passkey registration")
+
+	challenge, err := a.consumeChallenge(passkeyRegistrationKey + userId.String())
+	if err != nil {
+		log.Warn("failed to consume passkey registration challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	credential, err := a.relyingParty.VerifyRegistration(challenge, credentialJson)
+	if err != nil {
+		log.Warn("invalid passkey registration response", "error", err)
+
+		return nil, fmt.Errorf("%s: %w: %w", operation, ErrInvalidPasskeyRegistration, err)
+	}
+
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	userPasskey := &passkey.Passkey{
+		Id:                credential.Id,
+		UserId:            userId,
+		Name:              name,
+		PublicKey:         credential.PublicKey,
+		SignCount:         credential.SignCount,
+		Aaguid:            credential.Aaguid,
+		Transports:        credential.Transports,
+		AttestationFormat: credential.AttestationFormat,
+	}
+
+	err = a.passkeyService.Create(userPasskey)
+	if err != nil {
+		log.Error("failed to create passkey", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("passkey registered")
+
+	return userPasskey, nil
+}
+
+// BeginPasskeyLogin starts a login with a discoverable passkey of any user of the tenant
+func (a *authServiceImpl) BeginPasskeyLogin(tenantId domain.TenantId) (*PasskeyLoginChallenge, error) {
+	const operation = "BeginPasskeyLogin"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()))
+
+	log.Info("beginning a passkey login")
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		log.Error("failed to generate challenge", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	sessionId, err := xrand.GenerateRandomToken(passkeySessionIdLength)
+	if err != nil {
+		log.Error("failed to generate session id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	session, err := json.Marshal(&passkeyLoginSession{Challenge: challenge, TenantId: tenantId})
+	if err != nil {
+		log.Error("failed to marshal passkey login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	ttl := a.relyingParty.Timeout()
+
+	err = a.inMemoryStorage.Set(passkeyLoginKey+hashToken(sessionId), string(session), ttl)
+	if err != nil {
+		log.Error("failed to cache passkey login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	optionsJson, err := json.Marshal(a.relyingParty.RequestOptions(challenge, nil))
+	if err != nil {
+		log.Error("failed to marshal request options", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("passkey login begun")
+
+	return &PasskeyLoginChallenge{
+		SessionId:   PasskeySessionId(sessionId),
+		OptionsJson: optionsJson,
+		ExpiresAt:   time.Now().Add(ttl),
+	}, nil
+}
+
+// FinishPasskeyLogin verifies the response of navigator.credentials.get() and logs the user in.
+// A passkey verifying the user is a multi-factor credential by itself, otherwise like Login it returns
+// an MFA challenge instead of tokens if the user has enabled MFA. The session is consumed on the first attempt
+func (a *authServiceImpl) FinishPasskeyLogin(sessionId PasskeySessionId, credentialJson []byte) (*LoginResult, error) {
+	const operation = "FinishPasskeyLogin"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	log.Info("finishing a passkey login")
+
+	sessionValue, err := a.consumeValue(passkeyLoginKey+hashToken(string(sessionId)), ErrInvalidPasskeySession)
+	if err != nil {
+		log.Warn("failed to consume passkey login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var session passkeyLoginSession
+	err = json.Unmarshal([]byte(sessionValue), &session)
+	if err != nil {
+		log.Error("invalid passkey login session", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidPasskeySession)
+	}
+
+	assertion, err := webauthn.ParseAssertion(credentialJson)
+	if err != nil {
+		log.Warn("invalid passkey assertion", "error", err)
+
+		return nil, fmt.Errorf("%s: %w: %w", operation, ErrInvalidPasskeyAssertion, err)
+	}
+
+	userPasskey, err := a.passkeyService.GetById(assertion.RawId)
+	if err != nil {
+		if errors.Is(err, passkey.ErrPasskeyNotFound) {
+			log.Warn("passkey not found")
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidPasskeyAssertion)
+		}
+
+		log.Error("failed to get passkey", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log = log.With(slog.String("user_id", userPasskey.UserId.String()))
+
+	handle := assertion.Response.UserHandle
+	if handle != nil && string(handle) != string(userHandle(userPasskey.UserId)) {
+		log.Warn("user handle does not match the passkey")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidPasskeyAssertion)
+	}
+
+	domainUser, err := a.userService.GetById(userPasskey.UserId)
+	if err != nil {
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if domainUser.TenantId != session.TenantId {
+		log.Warn("passkey belongs to a user of another tenant")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidPasskeyAssertion)
+	}
+
+	result, err := a.relyingParty.VerifyAssertion(session.Challenge, assertion, userPasskey.PublicKey, userPasskey.SignCount)
+	if err != nil {
+		log.Warn("passkey assertion verification failed", "error", err)
+
+		return nil, fmt.Errorf("%s: %w: %w", operation, ErrInvalidPasskeyAssertion, err)
+	}
+
+	err = a.passkeyService.UpdateSignCount(userPasskey.Id, result.SignCount)
+	if err != nil {
+		if errors.Is(err, passkey.ErrSignCountNotIncreased) {
+			log.Warn("passkey sign count has been used by a concurrent login")
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrInvalidPasskeyAssertion)
+		}
+
+		log.Error("failed to update passkey sign count", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !result.UserVerified {
+		loginResult, err := a.completeLogin(log, domainUser)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		return loginResult, nil
+	}
+
+	tokens, err := a.issueTokens(domainUser, "")
+	if err != nil {
+		log.Error("failed to issue tokens", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("user logged in with passkey")
+
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+func (a *authServiceImpl) consumeChallenge(key string) ([]byte, error) {
+	value, err := a.consumeValue(key, ErrInvalidPasskeySession)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidPasskeySession
+	}
+
+	return challenge, nil
+}
+
+// consumeValue gets and deletes the value of the key, so only one of concurrent callers gets it.
+// errNotFound is returned if the key does not exist or has been consumed
+func (a *authServiceImpl) consumeValue(key string, errNotFound error) (string, error) {
+	value, err := a.inMemoryStorage.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return "", errNotFound
+		}
+		return "", err
+	}
+
+	deleted, err := a.inMemoryStorage.Delete(key)
+	if err != nil {
+		return "", err
+	}
+
+	if !deleted {
+		return "", errNotFound
+	}
+
+	return value, nil
+}
+
+func encodeChallenge(challenge []byte) string {
+	return base64.RawURLEncoding.EncodeToString(challenge)
+}
+
+// userHandle is the user id as 8 bytes big endian, it identifies the user to authenticators
+// without any personal information
+func userHandle(userId domain.UserId) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userId))
+}
+
+func credentialDescriptor(userPasskey *passkey.Passkey) webauthn.CredentialDescriptor {
+	return webauthn.CredentialDescriptor{
+		Type:       webauthn.CredentialType,
+		Id:         userPasskey.Id,
+		Transports: userPasskey.Transports,
+	}
+}
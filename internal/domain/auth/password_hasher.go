@@ -0,0 +1,9 @@
+package auth
+
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Check(password string, hashedPassword string) error
+	NeedsRehash(hashedPassword string) bool
+	// MaxPasswordLength is the maximum length of passwords in bytes, zero means that the length is not limited
+	MaxPasswordLength() int
+}
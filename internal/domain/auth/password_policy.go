@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+)
+
+type PasswordPolicy interface {
+	Check(password string, email string) ([]passwordpolicy.Violation, error)
+	CheckWithConfig(config *passwordpolicy.Config, password string, email string) ([]passwordpolicy.Violation, error)
+}
+
+// checkPasswordPolicy returns *domain.ValidationError with a violation of the field per broken rule.
+// The policy of the tenant is checked instead of the configured one if the tenant has it,
+// the length in bytes limited by the password hashing algorithm is checked regardless of the policy
+func (a *authServiceImpl) checkPasswordPolicy(tenantId domain.TenantId, field string, email domain.Email, password domain.Password) error {
+	domainTenant, err := a.tenantService.GetById(tenantId)
+	if err != nil {
+		return err
+	}
+
+	var violations []passwordpolicy.Violation
+	if domainTenant.Settings.PasswordPolicy != nil {
+		violations, err = a.passwordPolicy.CheckWithConfig(domainTenant.Settings.PasswordPolicy, password.String(), email.String())
+	} else {
+		violations, err = a.passwordPolicy.Check(password.String(), email.String())
+	}
+	if err != nil {
+		return err
+	}
+
+	validator := domain.NewValidator()
+	for _, violation := range violations {
+		validator.Violation(field, violation.Description)
+	}
+
+	// the policy counts characters, while the hashing algorithm may limit the length in bytes
+	maxPasswordLength := a.passwordHasher.MaxPasswordLength()
+	if maxPasswordLength > 0 && len(password.String()) > maxPasswordLength {
+		validator.Violation(field, fmt.Sprintf("must not be longer than %d bytes", maxPasswordLength))
+	}
+
+	return validator.Err()
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"time"
+)
+
+const (
+	refreshTokenKey       = "refresh_token_"
+	refreshTokenUsedKey   = "refresh_token_used_"
+	refreshTokenFamilyKey = "refresh_token_family_"
+)
+
+const (
+	refreshTokenLength         = 32
+	refreshTokenFamilyIdLength = 16
+)
+
+const revokedRefreshTokenFamily = "revoked"
+
+var (
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid")
+	ErrRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// refreshTokenData is stored in memory storage under the hash of an opaque refresh token.
+// Every refresh token belongs to a family started by a single login, so a replay of
+// an already rotated token revokes all the tokens issued after it. Tokens issued to OAuth clients
+// hold the client and the scopes granted to it, they can be refreshed only by the client
+type refreshTokenData struct {
+	UserId    domain.UserId `json:"user_id"`
+	FamilyId  string        `json:"family_id"`
+	ClientId  string        `json:"client_id,omitempty"`
+	Scopes    []string      `json:"scopes,omitempty"`
+	IssuedAt  time.Time     `json:"issued_at"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// createRefreshToken stores the data of a new refresh token, a new family is started if the family id is empty
+func (a *authServiceImpl) createRefreshToken(data *refreshTokenData, ttl time.Duration) (RefreshToken, error) {
+	const operation = "createRefreshToken"
+
+	familyId := data.FamilyId
+	if familyId == "" {
+		newFamilyId, err := xrand.GenerateRandomToken(refreshTokenFamilyIdLength)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", operation, err)
+		}
+		familyId = newFamilyId
+	}
+
+	token, err := xrand.GenerateRandomToken(refreshTokenLength)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	now := time.Now().UTC()
+
+	rawData, err := json.Marshal(&refreshTokenData{
+		UserId:    data.UserId,
+		FamilyId:  familyId,
+		ClientId:  data.ClientId,
+		Scopes:    data.Scopes,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.inMemoryStorage.Set(refreshTokenKey+hashToken(token), string(rawData), ttl)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return RefreshToken(token), nil
+}
+
+// rotateRefreshToken marks the refresh token as used and returns its data.
+// A second attempt to use the same token revokes the whole token family. The token must have been
+// issued to the OAuth client, clientId is empty for tokens issued by the gRPC API. Tokens of other clients
+// are rejected before they are marked as used, so they can not be used to revoke a family
+func (a *authServiceImpl) rotateRefreshToken(refreshToken RefreshToken, clientId string) (*refreshTokenData, error) {
+	const operation = "rotateRefreshToken"
+
+	tokenHash := hashToken(string(refreshToken))
+
+	data, err := a.getRefreshTokenData(refreshToken)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if data.ClientId != clientId {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidRefreshToken)
+	}
+
+	revoked, err := a.isRefreshTokenRevoked(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if revoked {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidRefreshToken)
+	}
+
+	ttl := time.Until(data.ExpiresAt)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%s: %w", operation, ErrInvalidRefreshToken)
+	}
+
+	firstUse, err := a.inMemoryStorage.SetIfNotExists(refreshTokenUsedKey+tokenHash, time.Now().UTC().Format(time.RFC3339), ttl)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !firstUse {
+		err = a.revokeRefreshTokenFamily(data.FamilyId)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrRefreshTokenReused)
+	}
+
+	return data, nil
+}
+
+func (a *authServiceImpl) revokeRefreshTokenFamily(familyId string) error {
+	// any token of the family expires not later than refresh token ttl from now
+	return a.inMemoryStorage.Set(refreshTokenFamilyKey+familyId, revokedRefreshTokenFamily, a.config.RefreshTokenTtl)
+}
+
+func (a *authServiceImpl) isRefreshTokenFamilyRevoked(familyId string) (bool, error) {
+	value, err := a.inMemoryStorage.Get(refreshTokenFamilyKey + familyId)
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return value == revokedRefreshTokenFamily, nil
+}
+
+// isRefreshTokenUsed reports whether the refresh token has already been rotated
+func (a *authServiceImpl) isRefreshTokenUsed(refreshToken RefreshToken) (bool, error) {
+	_, err := a.inMemoryStorage.Get(refreshTokenUsedKey + hashToken(string(refreshToken)))
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (a *authServiceImpl) getRefreshTokenData(refreshToken RefreshToken) (*refreshTokenData, error) {
+	rawData, err := a.inMemoryStorage.Get(refreshTokenKey + hashToken(string(refreshToken)))
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	var data refreshTokenData
+	err = json.Unmarshal([]byte(rawData), &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal refresh token data: %w", err)
+	}
+
+	return &data, nil
+}
+
+// hashToken hashes an opaque token before it is used as a key of the memory storage
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
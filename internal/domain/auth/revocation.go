@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+const (
+	revokedAccessTokenKey      = "revoked_access_token_"
+	userTokensRevokedKey       = "user_tokens_revoked_at_"
+	userAccessTokensRevokedKey = "user_access_tokens_revoked_at_"
+	clientTokensRevokedKey     = "client_tokens_revoked_at_"
+)
+
+const revokedAccessToken = "revoked"
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// revokeAccessToken puts the token id to the denylist and the revocation list until the token expires.
+// Legacy tokens have no id, they can only be revoked along with all tokens of the user
+func (a *authServiceImpl) revokeAccessToken(payload *auth.JwtPayload) error {
+	ttl := time.Until(payload.ExpiredAt)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if payload.TokenId == "" {
+		return a.revokeUserTokens(payload.UserId)
+	}
+
+	err := a.inMemoryStorage.Set(revokedAccessTokenKey+payload.TokenId, revokedAccessToken, ttl)
+	if err != nil {
+		return err
+	}
+
+	return a.addToRevocationList(&revocationEntry{
+		TokenId:   payload.TokenId,
+		ExpiresAt: payload.ExpiredAt,
+		RevokedAt: time.Now().UTC(),
+	})
+}
+
+// revokeUserTokens invalidates all the access and refresh tokens of the user issued before now
+func (a *authServiceImpl) revokeUserTokens(userId domain.UserId) error {
+	revokedAt := time.Now().UTC()
+
+	ttl := max(a.config.TokenTtl, a.config.RefreshTokenTtl)
+	err := a.inMemoryStorage.Set(userTokensRevokedKey+userId.String(), revokedAt.Format(time.RFC3339Nano), ttl)
+	if err != nil {
+		return err
+	}
+
+	return a.addToRevocationList(&revocationEntry{
+		UserId:    userId,
+		RevokedAt: revokedAt,
+	})
+}
+
+// revokeUserAccessTokens invalidates the access tokens of the user issued before now, refresh tokens
+// stay valid, so the next refresh issues tokens with the current roles and permissions of the user
+func (a *authServiceImpl) revokeUserAccessTokens(userId domain.UserId) error {
+	revokedAt := time.Now().UTC()
+
+	err := a.inMemoryStorage.Set(userAccessTokensRevokedKey+userId.String(), revokedAt.Format(time.RFC3339Nano), a.config.TokenTtl+a.config.TokenLeeway)
+	if err != nil {
+		return err
+	}
+
+	return a.addToRevocationList(&revocationEntry{
+		UserId:    userId,
+		RevokedAt: revokedAt,
+	})
+}
+
+// revokeClientTokens invalidates the access tokens of the service account issued before now
+func (a *authServiceImpl) revokeClientTokens(clientId string) error {
+	revokedAt := time.Now().UTC()
+
+	err := a.inMemoryStorage.Set(clientTokensRevokedKey+clientId, revokedAt.Format(time.RFC3339Nano), a.config.TokenTtl+a.config.TokenLeeway)
+	if err != nil {
+		return err
+	}
+
+	return a.addToRevocationList(&revocationEntry{
+		ClientId:  clientId,
+		RevokedAt: revokedAt,
+	})
+}
+
+// userTokensRevokedAt returns zero time if tokens of the user have never been revoked
+func (a *authServiceImpl) userTokensRevokedAt(userId domain.UserId) (time.Time, error) {
+	return a.revocationTime(userTokensRevokedKey + userId.String())
+}
+
+// userAccessTokensRevokedAt returns the latest revocation of either all the tokens or only the access tokens of the user
+func (a *authServiceImpl) userAccessTokensRevokedAt(userId domain.UserId) (time.Time, error) {
+	tokensRevokedAt, err := a.userTokensRevokedAt(userId)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	accessTokensRevokedAt, err := a.revocationTime(userAccessTokensRevokedKey + userId.String())
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if accessTokensRevokedAt.After(tokensRevokedAt) {
+		return accessTokensRevokedAt, nil
+	}
+	return tokensRevokedAt, nil
+}
+
+// revocationTime returns zero time if the key is not set
+func (a *authServiceImpl) revocationTime(key string) (time.Time, error) {
+	value, err := a.inMemoryStorage.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, value)
+}
+
+// isAccessTokenRevoked checks the token denylist and the revocation of all user tokens, or of all
+// service account tokens for tokens with a client id. 'iat' claim has a precision of seconds, so a token
+// issued within the same second as revocation of all user tokens, but before it, is still considered valid
+func (a *authServiceImpl) isAccessTokenRevoked(payload *auth.JwtPayload) (bool, error) {
+	if payload.TokenId != "" {
+		_, err := a.inMemoryStorage.Get(revokedAccessTokenKey + payload.TokenId)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, storage.ErrRedisKeyNotFound) {
+			return false, err
+		}
+	}
+
+	revokedAt, err := a.userAccessTokensRevokedAt(payload.UserId)
+	if err != nil {
+		return false, err
+	}
+
+	if payload.ClientId != "" {
+		clientTokensRevokedAt, err := a.revocationTime(clientTokensRevokedKey + payload.ClientId)
+		if err != nil {
+			return false, err
+		}
+
+		if clientTokensRevokedAt.After(revokedAt) {
+			revokedAt = clientTokensRevokedAt
+		}
+	}
+
+	return payload.IssuedAt.Before(revokedAt.Truncate(time.Second)), nil
+}
+
+func (a *authServiceImpl) isRefreshTokenRevoked(data *refreshTokenData) (bool, error) {
+	revoked, err := a.isRefreshTokenFamilyRevoked(data.FamilyId)
+	if err != nil {
+		return false, err
+	}
+	if revoked {
+		return true, nil
+	}
+
+	revokedAt, err := a.userTokensRevokedAt(data.UserId)
+	if err != nil {
+		return false, err
+	}
+
+	return data.IssuedAt.Before(revokedAt), nil
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"log/slog"
+	"time"
+)
+
+// RevocationListStorage keeps serialized revocation entries ordered by revocation time
+type RevocationListStorage interface {
+	Add(entry string, revokedAt time.Time) error
+	Since(since time.Time) ([]string, error)
+	RemoveBefore(before time.Time) error
+}
+
+// RevokedToken is an access token revoked before its expiration
+type RevokedToken struct {
+	TokenId   string
+	ExpiresAt time.Time
+}
+
+// RevokedUser means that all the tokens of the user issued before RevokedAt are revoked
+type RevokedUser struct {
+	UserId    domain.UserId
+	RevokedAt time.Time
+}
+
+// RevokedClient means that all the tokens of the service account issued before RevokedAt are revoked
+type RevokedClient struct {
+	ClientId  string
+	RevokedAt time.Time
+}
+
+// RevocationList lets other services verify tokens offline and still reject revoked ones.
+// GeneratedAt should be passed as 'since' to get the next changes
+type RevocationList struct {
+	Tokens      []RevokedToken
+	Users       []RevokedUser
+	Clients     []RevokedClient
+	GeneratedAt time.Time
+}
+
+type revocationEntry struct {
+	TokenId   string        `json:"token_id,omitempty"`
+	ExpiresAt time.Time     `json:"expires_at,omitempty"`
+	UserId    domain.UserId `json:"user_id,omitempty"`
+	ClientId  string        `json:"client_id,omitempty"`
+	RevokedAt time.Time     `json:"revoked_at"`
+}
+
+// GetRevocationList returns tokens, users and service accounts revoked since the time. Revocations older than an access
+// token ttl are not returned, because all the tokens they affect have already expired
+func (a *authServiceImpl) GetRevocationList(since time.Time) (*RevocationList, error) {
+	const operation = "GetRevocationList"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.Time("since", since))
+
+	generatedAt := time.Now().UTC()
+
+	entries, err := a.revocationListStorage.Since(since)
+	if err != nil {
+		log.Error("failed to get revocation list", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	revocationList := &RevocationList{
+		Tokens:      []RevokedToken{},
+		Users:       []RevokedUser{},
+		Clients:     []RevokedClient{},
+		GeneratedAt: generatedAt,
+	}
+
+	for _, value := range entries {
+		var entry revocationEntry
+		err = json.Unmarshal([]byte(value), &entry)
+		if err != nil {
+			log.Error("failed to unmarshal revocation entry", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+
+		switch {
+		case entry.TokenId != "":
+			if entry.ExpiresAt.After(generatedAt) {
+				revocationList.Tokens = append(revocationList.Tokens, RevokedToken{TokenId: entry.TokenId, ExpiresAt: entry.ExpiresAt})
+			}
+		case entry.ClientId != "":
+			revocationList.Clients = append(revocationList.Clients, RevokedClient{ClientId: entry.ClientId, RevokedAt: entry.RevokedAt})
+		default:
+			revocationList.Users = append(revocationList.Users, RevokedUser{UserId: entry.UserId, RevokedAt: entry.RevokedAt})
+		}
+	}
+
+	return revocationList, nil
+}
+
+// addToRevocationList publishes the entry and removes entries which no longer affect any token
+func (a *authServiceImpl) addToRevocationList(entry *revocationEntry) error {
+	value, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = a.revocationListStorage.Add(string(value), entry.RevokedAt)
+	if err != nil {
+		return err
+	}
+
+	return a.revocationListStorage.RemoveBefore(entry.RevokedAt.Add(-a.revocationListRetention()))
+}
+
+func (a *authServiceImpl) revocationListRetention() time.Duration {
+	return a.config.TokenTtl + a.config.TokenLeeway
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"log/slog"
+)
+
+// AssignRole assigns the role to the user of the tenant. Roles are shared by all tenants,
+// permissions which are not scoped to a tenant are granted by them in the default tenant only
+func (a *authServiceImpl) AssignRole(tenantId domain.TenantId, userId domain.UserId, roleName string) error {
+	const operation = "AssignRole"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("user_id", userId.String()),
+		slog.String("role", roleName))
+
+	log.Info("assigning role to a user")
+
+	_, err := a.getTenantUser(log, tenantId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.roleService.AssignRole(userId, roleName)
+	if err != nil {
+		log.Error("failed to assign role", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("role assigned to a user")
+
+	return nil
+}
+
+// RevokeRole revokes the role from the user of the tenant and invalidates the access tokens of the user,
+// because they still carry the role and its permissions until they expire
+func (a *authServiceImpl) RevokeRole(tenantId domain.TenantId, userId domain.UserId, roleName string) error {
+	const operation = "RevokeRole"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("user_id", userId.String()),
+		slog.String("role", roleName))
+
+	log.Info("revoking role of a user")
+
+	_, err := a.getTenantUser(log, tenantId, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.roleService.RevokeRole(userId, roleName)
+	if err != nil {
+		log.Error("failed to revoke role", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.revokeUserAccessTokens(userId)
+	if err != nil {
+		log.Error("failed to revoke user access tokens", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("role of a user revoked")
+
+	return nil
+}
+
+// ListUserRoles returns the roles assigned to the user of the tenant
+func (a *authServiceImpl) ListUserRoles(tenantId domain.TenantId, userId domain.UserId) ([]*role.Role, error) {
+	const operation = "ListUserRoles"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("user_id", userId.String()))
+
+	_, err := a.getTenantUser(log, tenantId, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	roles, err := a.roleService.ListUserRoles(userId)
+	if err != nil {
+		log.Error("failed to list user roles", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return roles, nil
+}
+
+// getTenantUser returns the user if it belongs to the tenant, users of other tenants are reported as not found,
+// so administrators of a tenant can not find out ids of users of other tenants
+func (a *authServiceImpl) getTenantUser(log *slog.Logger, tenantId domain.TenantId, userId domain.UserId) (*user.User, error) {
+	domainUser, err := a.userService.GetById(userId)
+	if err != nil {
+		log.Error("failed to get user by id", "error", err)
+
+		return nil, err
+	}
+
+	if domainUser.TenantId != tenantId {
+		log.Warn("user belongs to another tenant")
+
+		return nil, user.ErrUserNotFound
+	}
+
+	return domainUser, nil
+}
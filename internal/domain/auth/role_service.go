@@ -0,0 +1,13 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type RoleService interface {
+	AssignRole(userId domain.UserId, roleName string) error
+	RevokeRole(userId domain.UserId, roleName string) error
+	ListUserRoles(userId domain.UserId) ([]*role.Role, error)
+	GetUserPermissions(userId domain.UserId) (*role.UserPermissions, error)
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	"github.com/vaberof/auth-grpc/internal/domain/user"
+	"github.com/vaberof/auth-grpc/internal/infra/storage/memory"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+)
+
+type fakeRoleService struct {
+	userRoles map[domain.UserId][]string
+}
+
+func (s *fakeRoleService) AssignRole(userId domain.UserId, roleName string) error {
+	if !slices.Contains(s.userRoles[userId], roleName) {
+		s.userRoles[userId] = append(s.userRoles[userId], roleName)
+	}
+	return nil
+}
+
+func (s *fakeRoleService) RevokeRole(userId domain.UserId, roleName string) error {
+	s.userRoles[userId] = slices.DeleteFunc(s.userRoles[userId], func(name string) bool {
+		return name == roleName
+	})
+	return nil
+}
+
+func (s *fakeRoleService) ListUserRoles(userId domain.UserId) ([]*role.Role, error) {
+	roles := []*role.Role{}
+	for _, name := range s.userRoles[userId] {
+		roles = append(roles, &role.Role{Name: name})
+	}
+	return roles, nil
+}
+
+func (s *fakeRoleService) GetUserPermissions(userId domain.UserId) (*role.UserPermissions, error) {
+	return &role.UserPermissions{Roles: s.userRoles[userId]}, nil
+}
+
+func newRoleTestService() (*authServiceImpl, *fakeUserService, *fakeRoleService) {
+	userService := &fakeUserService{}
+	roleService := &fakeRoleService{userRoles: map[domain.UserId][]string{}}
+
+	service := &authServiceImpl{
+		config:                &Config{TokenTtl: time.Hour},
+		userService:           userService,
+		roleService:           roleService,
+		inMemoryStorage:       memory.NewMemoryStorage(),
+		revocationListStorage: memory.NewRevocationListStorage(),
+		logger:                slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	return service, userService, roleService
+}
+
+func TestRolesOfUserOfTenant(t *testing.T) {
+	service, userService, roleService := newRoleTestService()
+	userId, _ := userService.Create(domain.DefaultTenantId, "user@example.com", "")
+
+	err := service.AssignRole(domain.DefaultTenantId, userId, "admin")
+	if err != nil {
+		t.Fatalf("AssignRole() error = %v", err)
+	}
+
+	roles, err := service.ListUserRoles(domain.DefaultTenantId, userId)
+	if err != nil || len(roles) != 1 || roles[0].Name != "admin" {
+		t.Fatalf("ListUserRoles() = %v, %v, want admin role", roles, err)
+	}
+
+	err = service.RevokeRole(domain.DefaultTenantId, userId, "admin")
+	if err != nil {
+		t.Fatalf("RevokeRole() error = %v", err)
+	}
+	if len(roleService.userRoles[userId]) != 0 {
+		t.Errorf("roles after RevokeRole() = %v, want none", roleService.userRoles[userId])
+	}
+
+	revokedAt, err := service.userAccessTokensRevokedAt(userId)
+	if err != nil || revokedAt.IsZero() {
+		t.Errorf("access tokens revoked at = %v, %v, want RevokeRole() to revoke them", revokedAt, err)
+	}
+}
+
+func TestRolesOfUserOfAnotherTenant(t *testing.T) {
+	const otherTenantId domain.TenantId = 2
+
+	service, userService, roleService := newRoleTestService()
+	userId, _ := userService.Create(domain.DefaultTenantId, "user@example.com", "")
+	roleService.userRoles[userId] = []string{"admin"}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{name: "AssignRole", call: func() error { return service.AssignRole(otherTenantId, userId, "viewer") }},
+		{name: "RevokeRole", call: func() error { return service.RevokeRole(otherTenantId, userId, "admin") }},
+		{name: "ListUserRoles", call: func() error {
+			_, err := service.ListUserRoles(otherTenantId, userId)
+			return err
+		}},
+		{name: "AssignRole to unknown user", call: func() error { return service.AssignRole(otherTenantId, 100, "viewer") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, user.ErrUserNotFound) {
+				t.Errorf("%s() error = %v, want %v", tt.name, err, user.ErrUserNotFound)
+			}
+		})
+	}
+
+	if !slices.Equal(roleService.userRoles[userId], []string{"admin"}) {
+		t.Errorf("roles = %v, want roles to be unchanged", roleService.userRoles[userId])
+	}
+}
@@ -0,0 +1,435 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/serviceaccount"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"log/slog"
+	"time"
+)
+
+const (
+	serviceAccountClientIdLength = 16
+	serviceAccountSecretLength   = 32
+)
+
+const defaultServiceAccountSecretOverlap = 24 * time.Hour
+
+const (
+	serviceTokenClientRequestsKey = "service_token_client_requests_"
+	serviceTokenPeerRequestsKey   = "service_token_peer_requests_"
+)
+
+// service tokens may be requested at most maxServiceTokenRequests times per serviceTokenRequestWindow
+// with the same client id and maxServiceTokenPeerRequests times from the same peer address,
+// several services may run behind the same address
+const (
+	serviceTokenRequestWindow   = time.Minute
+	maxServiceTokenRequests     = 30
+	maxServiceTokenPeerRequests = 300
+)
+
+var ErrInvalidServiceAccountCredentials = errors.New("invalid client id or secret of service account")
+
+type ServiceAccountService interface {
+	Create(serviceAccount *serviceaccount.ServiceAccount, secretHash string) error
+	GetByClientId(clientId string) (*serviceaccount.ServiceAccount, error)
+	ListActiveSecrets(serviceAccountId int64) ([]*serviceaccount.Secret, error)
+	RotateSecret(serviceAccountId int64, secretHash string, overlap time.Duration) error
+	Disable(serviceAccountId int64) error
+	Delete(serviceAccountId int64) error
+}
+
+// CreatedServiceAccount holds the secret of a created service account, it is returned only once
+type CreatedServiceAccount struct {
+	ServiceAccount *serviceaccount.ServiceAccount
+	ClientSecret   string
+}
+
+// RotatedServiceAccountSecret holds the new secret of a service account and the time the previous secrets expire at
+type RotatedServiceAccountSecret struct {
+	ClientSecret            string
+	PreviousSecretsExpireAt time.Time
+}
+
+// ServiceToken is an access token of a service account, it is not refreshed. Services issue a new one with the secret
+type ServiceToken struct {
+	AccessToken AccessToken
+	ExpiresAt   time.Time
+	Scopes      []string
+}
+
+// CreateServiceAccount creates a service account of the tenant allowed to get tokens with the given scopes
+func (a *authServiceImpl) CreateServiceAccount(tenantId domain.TenantId, name string, scopes []string) (*CreatedServiceAccount, error) {
+	const operation = "CreateServiceAccount"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("name", name))
+
+	log.Info("creating a service account")
+
+	clientId, err := xrand.GenerateRandomToken(serviceAccountClientIdLength)
+	if err != nil {
+		log.Error("failed to generate client id", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	clientSecret, secretHash, err := a.generateServiceAccountSecret()
+	if err != nil {
+		log.Error("failed to generate secret", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	serviceAccount := &serviceaccount.ServiceAccount{
+		ClientId: clientId,
+		TenantId: tenantId,
+		Name:     name,
+		Scopes:   scopes,
+	}
+
+	err = a.serviceAccountService.Create(serviceAccount, secretHash)
+	if err != nil {
+		log.Error("failed to create service account", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("service account created", slog.String("client_id", clientId))
+
+	return &CreatedServiceAccount{ServiceAccount: serviceAccount, ClientSecret: clientSecret}, nil
+}
+
+// RotateServiceAccountSecret generates a new secret of the service account. Previous secrets stay valid
+// for the configured overlap so that services can be redeployed with the new one. If revokePrevious is set,
+// they expire immediately and the tokens issued with them are revoked
+func (a *authServiceImpl) RotateServiceAccountSecret(tenantId domain.TenantId, clientId string, revokePrevious bool) (*RotatedServiceAccountSecret, error) {
+	const operation = "RotateServiceAccountSecret"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("client_id", clientId),
+		slog.Bool("revoke_previous", revokePrevious))
+
+	log.Info("rotating a secret of service account")
+
+	serviceAccount, err := a.serviceAccountService.GetByClientId(clientId)
+	if err != nil {
+		log.Error("failed to get service account", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if serviceAccount.TenantId != tenantId {
+		log.Warn("service account belongs to another tenant")
+
+		return nil, fmt.Errorf("%s: %w", operation, serviceaccount.ErrServiceAccountNotFound)
+	}
+
+	clientSecret, secretHash, err := a.generateServiceAccountSecret()
+	if err != nil {
+		log.Error("failed to generate secret", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	var overlap time.Duration
+	if !revokePrevious {
+		overlap = a.serviceAccountSecretOverlap()
+	}
+
+	err = a.serviceAccountService.RotateSecret(serviceAccount.Id, secretHash, overlap)
+	if err != nil {
+		log.Error("failed to rotate secret", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if revokePrevious {
+		err = a.revokeClientTokens(serviceAccount.ClientId)
+		if err != nil {
+			log.Error("failed to revoke tokens of service account", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, err)
+		}
+	}
+
+	log.Info("secret of service account rotated")
+
+	return &RotatedServiceAccountSecret{ClientSecret: clientSecret, PreviousSecretsExpireAt: time.Now().Add(overlap)}, nil
+}
+
+// DisableServiceAccount prevents the service account from issuing tokens and revokes the tokens it has issued,
+// the account and its secrets are kept
+func (a *authServiceImpl) DisableServiceAccount(tenantId domain.TenantId, clientId string) error {
+	const operation = "DisableServiceAccount"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("client_id", clientId))
+
+	log.Info("disabling a service account")
+
+	serviceAccount, err := a.serviceAccountService.GetByClientId(clientId)
+	if err != nil {
+		log.Error("failed to get service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if serviceAccount.TenantId != tenantId {
+		log.Warn("service account belongs to another tenant")
+
+		return fmt.Errorf("%s: %w", operation, serviceaccount.ErrServiceAccountNotFound)
+	}
+
+	err = a.serviceAccountService.Disable(serviceAccount.Id)
+	if err != nil {
+		log.Error("failed to disable service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.revokeClientTokens(serviceAccount.ClientId)
+	if err != nil {
+		log.Error("failed to revoke tokens of service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("service account disabled")
+
+	return nil
+}
+
+// DeleteServiceAccount deletes the service account along with its secrets and revokes the tokens it has issued
+func (a *authServiceImpl) DeleteServiceAccount(tenantId domain.TenantId, clientId string) error {
+	const operation = "DeleteServiceAccount"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("client_id", clientId))
+
+	log.Info("deleting a service account")
+
+	serviceAccount, err := a.serviceAccountService.GetByClientId(clientId)
+	if err != nil {
+		log.Error("failed to get service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if serviceAccount.TenantId != tenantId {
+		log.Warn("service account belongs to another tenant")
+
+		return fmt.Errorf("%s: %w", operation, serviceaccount.ErrServiceAccountNotFound)
+	}
+
+	err = a.serviceAccountService.Delete(serviceAccount.Id)
+	if err != nil {
+		log.Error("failed to delete service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = a.revokeClientTokens(serviceAccount.ClientId)
+	if err != nil {
+		log.Error("failed to revoke tokens of service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("service account deleted")
+
+	return nil
+}
+
+// IssueServiceToken issues an access token of the service account with the requested scopes,
+// all scopes of the account are granted if none are requested. Requests are limited per client id
+// and per peer address, peerAddress may be empty if it is unknown
+func (a *authServiceImpl) IssueServiceToken(clientId string, clientSecret string, scopes []string, peerAddress string) (*ServiceToken, error) {
+	const operation = "IssueServiceToken"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", clientId),
+		slog.String("peer_address", peerAddress))
+
+	log.Info("issuing a service token")
+
+	err := a.limitServiceTokenRequests(clientId, peerAddress)
+	if err != nil {
+		if errors.Is(err, ErrTooManyRequests) {
+			log.Warn("too many service token requests")
+		} else {
+			log.Error("failed to check service token requests", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	serviceAccount, err := a.authenticateServiceAccount(clientId, clientSecret)
+	if err != nil {
+		if errors.Is(err, ErrInvalidServiceAccountCredentials) {
+			log.Warn("service account authentication failed")
+		} else {
+			log.Error("failed to authenticate service account", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log = log.With(slog.String("tenant_id", serviceAccount.TenantId.String()))
+
+	tokenScopes, err := grantedScopes(scopes, serviceAccount.Scopes)
+	if err != nil {
+		log.Warn("requested scope is not allowed", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	signingKey, err := a.keyRing.SigningKey()
+	if err != nil {
+		log.Error("failed to get signing key", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	domainTenant, err := a.tenantService.GetById(serviceAccount.TenantId)
+	if err != nil {
+		log.Error("failed to get tenant", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	payload := auth.NewPayload(0, a.tokenTtl(domainTenant))
+	payload.PrincipalType = auth.PrincipalTypeService
+	payload.ClientId = serviceAccount.ClientId
+	payload.TenantId = serviceAccount.TenantId
+	payload.Scopes = tokenScopes
+	payload.Issuer = a.config.TokenIssuer
+	payload.Audience = a.config.TokenAudience
+
+	accessToken, err := accesstoken.Create(payload, signingKey)
+	if err != nil {
+		log.Error("failed to create access token", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("service token issued")
+
+	return &ServiceToken{
+		AccessToken: AccessToken(accessToken),
+		ExpiresAt:   payload.ExpiredAt,
+		Scopes:      tokenScopes,
+	}, nil
+}
+
+// limitServiceTokenRequests counts requests before the secret is checked, so the limits
+// also slow down guessing of secrets and client ids
+func (a *authServiceImpl) limitServiceTokenRequests(clientId string, peerAddress string) error {
+	if peerAddress != "" {
+		peerRequests, err := a.inMemoryStorage.Increment(serviceTokenPeerRequestsKey+peerAddress, serviceTokenRequestWindow)
+		if err != nil {
+			return err
+		}
+
+		if peerRequests > maxServiceTokenPeerRequests {
+			return ErrTooManyRequests
+		}
+	}
+
+	requests, err := a.inMemoryStorage.Increment(serviceTokenClientRequestsKey+hashToken(clientId), serviceTokenRequestWindow)
+	if err != nil {
+		return err
+	}
+
+	if requests > maxServiceTokenRequests {
+		return ErrTooManyRequests
+	}
+
+	return nil
+}
+
+// authenticateServiceAccount checks the secret against all active secrets of the account,
+// there are several of them during the overlap after a rotation. The secret is checked against
+// a dummy hash for unknown client ids and against the secrets of disabled accounts as well,
+// so the response time is the same for all of them
+func (a *authServiceImpl) authenticateServiceAccount(clientId string, clientSecret string) (*serviceaccount.ServiceAccount, error) {
+	if clientId == "" || clientSecret == "" {
+		return nil, ErrInvalidServiceAccountCredentials
+	}
+
+	serviceAccount, err := a.serviceAccountService.GetByClientId(clientId)
+	if err != nil {
+		if errors.Is(err, serviceaccount.ErrServiceAccountNotFound) {
+			return nil, a.checkServiceAccountDummySecret(clientSecret)
+		}
+		return nil, err
+	}
+
+	secrets, err := a.serviceAccountService.ListActiveSecrets(serviceAccount.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secrets) == 0 {
+		return nil, a.checkServiceAccountDummySecret(clientSecret)
+	}
+
+	authenticated := false
+	for _, secret := range secrets {
+		if a.passwordHasher.Check(clientSecret, secret.SecretHash) == nil {
+			authenticated = true
+			break
+		}
+	}
+
+	if !authenticated || serviceAccount.Disabled() {
+		return nil, ErrInvalidServiceAccountCredentials
+	}
+
+	return serviceAccount, nil
+}
+
+// checkServiceAccountDummySecret spends the time of a secret check and always returns ErrInvalidServiceAccountCredentials
+func (a *authServiceImpl) checkServiceAccountDummySecret(clientSecret string) error {
+	err := a.checkDummyPassword(clientSecret)
+	if err != nil {
+		return err
+	}
+
+	return ErrInvalidServiceAccountCredentials
+}
+
+func (a *authServiceImpl) generateServiceAccountSecret() (string, string, error) {
+	secret, err := xrand.GenerateRandomToken(serviceAccountSecretLength)
+	if err != nil {
+		return "", "", err
+	}
+
+	secretHash, err := a.passwordHasher.Hash(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	return secret, secretHash, nil
+}
+
+func (a *authServiceImpl) serviceAccountSecretOverlap() time.Duration {
+	if a.config.ServiceAccountSecretOverlap > 0 {
+		return a.config.ServiceAccountSecretOverlap
+	}
+	return defaultServiceAccountSecretOverlap
+}
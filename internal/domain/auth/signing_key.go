@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"log/slog"
+	"time"
+)
+
+const defaultSigningKeysReloadInterval = time.Minute
+
+// minSigningKeysReloadInterval limits reloads of the key ring caused by tokens with unknown 'kid'
+const minSigningKeysReloadInterval = 10 * time.Second
+
+type SigningKeyService interface {
+	Load(keyRing *accesstoken.KeyRing) error
+	Save(keyRing *accesstoken.KeyRing, generatedKeyId string) error
+}
+
+// LoadSigningKeys applies keys rotated by any instance of the service to the key ring.
+// It is called at startup and periodically, so all instances sign tokens with the same key
+func (a *authServiceImpl) LoadSigningKeys() error {
+	a.signingKeysMu.Lock()
+	defer a.signingKeysMu.Unlock()
+
+	return a.loadSigningKeys()
+}
+
+// SigningKeysReloadInterval is the interval LoadSigningKeys should be called with
+func (a *authServiceImpl) SigningKeysReloadInterval() time.Duration {
+	if a.config.SigningKeysReloadInterval > 0 {
+		return a.config.SigningKeysReloadInterval
+	}
+	return defaultSigningKeysReloadInterval
+}
+
+// loadSigningKeys must be called with signingKeysMu held
+func (a *authServiceImpl) loadSigningKeys() error {
+	const operation = "LoadSigningKeys"
+
+	log := a.logger.With(slog.String("operation", operation))
+
+	err := a.signingKeyService.Load(a.keyRing)
+	if err != nil {
+		log.Error("failed to load signing keys", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return nil
+}
+
+// signingKeySet verifies tokens with the key ring. The ring is reloaded if the key is unknown,
+// so tokens signed with a key just rotated by another instance are accepted
+type signingKeySet struct {
+	authService *authServiceImpl
+}
+
+func (s signingKeySet) LookupKey(kid string) (*accesstoken.Key, error) {
+	a := s.authService
+
+	key, err := a.keyRing.LookupKey(kid)
+	if err == nil || kid == "" || !errors.Is(err, accesstoken.ErrKeyNotFound) {
+		return key, err
+	}
+
+	a.signingKeysMu.Lock()
+	defer a.signingKeysMu.Unlock()
+
+	// concurrent lookups of the same key wait for a single reload
+	key, err = a.keyRing.LookupKey(kid)
+	if err == nil || time.Since(a.signingKeysReloadedAt) < minSigningKeysReloadInterval {
+		return key, err
+	}
+
+	a.signingKeysReloadedAt = time.Now()
+
+	if a.loadSigningKeys() != nil {
+		return nil, accesstoken.ErrKeyNotFound
+	}
+
+	return a.keyRing.LookupKey(kid)
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/tenant"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+type TenantService interface {
+	GetById(id domain.TenantId) (*tenant.Tenant, error)
+}
+
+// tokenTtl returns the access token ttl of the tenant. Tenants can only shorten the configured ttl,
+// because signing keys and revocation lists are retained for the configured ttl
+func (a *authServiceImpl) tokenTtl(domainTenant *tenant.Tenant) time.Duration {
+	if domainTenant.Settings.TokenTtl > 0 {
+		return min(domainTenant.Settings.TokenTtl, a.config.TokenTtl)
+	}
+	return a.config.TokenTtl
+}
+
+// refreshTokenTtl returns the refresh token ttl of the tenant. Tenants can only shorten the configured ttl,
+// because revoked refresh token families are remembered for the configured ttl
+func (a *authServiceImpl) refreshTokenTtl(domainTenant *tenant.Tenant) time.Duration {
+	if domainTenant.Settings.RefreshTokenTtl > 0 {
+		return min(domainTenant.Settings.RefreshTokenTtl, a.config.RefreshTokenTtl)
+	}
+	return a.config.RefreshTokenTtl
+}
@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+// TokenInfo describes an owner of a verified access token and the claims of the token.
+// PrincipalType tells users from service accounts and clients of the client credentials grant
+type TokenInfo struct {
+	TokenId       string
+	PrincipalType string
+	UserId        domain.UserId
+	ClientId      string
+	TenantId      domain.TenantId
+	Email         domain.Email
+	Roles         []string
+	Permissions   []string
+	Scopes        []string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+func toTokenInfo(payload *auth.JwtPayload) *TokenInfo {
+	return &TokenInfo{
+		TokenId:       payload.TokenId,
+		PrincipalType: payload.PrincipalType,
+		UserId:        payload.UserId,
+		ClientId:      payload.ClientId,
+		TenantId:      payload.TenantId,
+		Email:         payload.Email,
+		Roles:         payload.Roles,
+		Permissions:   payload.Permissions,
+		Scopes:        payload.Scopes,
+		IssuedAt:      payload.IssuedAt,
+		ExpiresAt:     payload.ExpiredAt,
+	}
+}
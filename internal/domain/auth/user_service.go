@@ -6,7 +6,9 @@ import (
 )
 
 type UserService interface {
-	Create(email domain.Email, password domain.Password) (domain.UserId, error)
-	GetByEmail(email domain.Email) (*user.User, error)
-	ExistsByEmail(email domain.Email) (bool, error)
+	Create(tenantId domain.TenantId, email domain.Email, password domain.Password) (domain.UserId, error)
+	GetByEmail(tenantId domain.TenantId, email domain.Email) (*user.User, error)
+	GetById(id domain.UserId) (*user.User, error)
+	ExistsByEmail(tenantId domain.TenantId, email domain.Email) (bool, error)
+	UpdatePassword(id domain.UserId, password domain.Password) error
 }
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	verificationEmailType  = "verification_email"
+	passwordResetEmailType = "password_reset"
+	emailLoginEmailType    = "email_login"
+)
+
+// verificationEmail describes an email carrying a verification code. Link is optional,
+// '{tenant_id}', '{email}' and '{code}' placeholders are replaced in it with query escaped values
+type verificationEmail struct {
+	Type    string
+	Subject string
+	CodeTtl time.Duration
+	Link    string
+}
+
+var registrationEmail = &verificationEmail{
+	Type:    verificationEmailType,
+	Subject: "Verification email",
+	CodeTtl: verificationCodeCacheExpireTime,
+}
+
+func (a *authServiceImpl) passwordResetEmail() *verificationEmail {
+	codeTtl := a.config.PasswordResetCodeTtl
+	if codeTtl == 0 {
+		codeTtl = defaultPasswordResetCodeTtl
+	}
+
+	return &verificationEmail{
+		Type:    passwordResetEmailType,
+		Subject: "Password reset",
+		CodeTtl: codeTtl,
+		Link:    a.config.PasswordResetLink,
+	}
+}
+
+func (a *authServiceImpl) emailLoginEmail() *verificationEmail {
+	codeTtl := a.config.EmailLoginCodeTtl
+	if codeTtl == 0 {
+		codeTtl = defaultEmailLoginCodeTtl
+	}
+
+	return &verificationEmail{
+		Type:    emailLoginEmailType,
+		Subject: "Sign-in code",
+		CodeTtl: codeTtl,
+		Link:    a.config.EmailLoginLink,
+	}
+}
+
+func (e *verificationEmail) link(tenantId domain.TenantId, email domain.Email, code string) string {
+	return strings.NewReplacer(
+		"{tenant_id}", url.QueryEscape(tenantId.String()),
+		"{email}", url.QueryEscape(email.String()),
+		"{code}", url.QueryEscape(code),
+	).Replace(e.Link)
+}
@@ -0,0 +1,92 @@
+package authorization
+
+import (
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+	"log/slog"
+)
+
+// Config holds the schema of object types, their relations and permissions, see package rebac
+type Config struct {
+	Schema string `yaml:"schema"`
+}
+
+type AuthorizationService interface {
+	Check(resource rebac.Object, permission string, subject rebac.Subject) (bool, error)
+	WriteRelationships(writes []rebac.Relationship, deletes []rebac.Relationship) error
+	ListObjects(resourceType string, permission string, subject rebac.Subject) ([]string, error)
+}
+
+type authorizationServiceImpl struct {
+	engine *rebac.Engine
+
+	logger *slog.Logger
+}
+
+func NewAuthorizationService(schema *rebac.Schema, tupleStore rebac.TupleStore, logs *logs.Logs) AuthorizationService {
+	logger := logs.WithName("domain.authorization.service")
+	return &authorizationServiceImpl{engine: rebac.NewEngine(schema, tupleStore), logger: logger}
+}
+
+func (a *authorizationServiceImpl) Check(resource rebac.Object, permission string, subject rebac.Subject) (bool, error) {
+	const operation = "Check"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("resource", resource.String()),
+		slog.String("permission", permission),
+		slog.String("subject", subject.String()))
+
+	allowed, err := a.engine.Check(resource, permission, subject)
+	if err != nil {
+		log.Error("failed to check permission", "error", err)
+
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("permission checked", slog.Bool("allowed", allowed))
+
+	return allowed, nil
+}
+
+func (a *authorizationServiceImpl) WriteRelationships(writes []rebac.Relationship, deletes []rebac.Relationship) error {
+	const operation = "WriteRelationships"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.Int("writes", len(writes)),
+		slog.Int("deletes", len(deletes)))
+
+	log.Info("writing relationships")
+
+	err := a.engine.WriteRelationships(writes, deletes)
+	if err != nil {
+		log.Error("failed to write relationships", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("relationships written")
+
+	return nil
+}
+
+func (a *authorizationServiceImpl) ListObjects(resourceType string, permission string, subject rebac.Subject) ([]string, error) {
+	const operation = "ListObjects"
+
+	log := a.logger.With(
+		slog.String("operation", operation),
+		slog.String("resource_type", resourceType),
+		slog.String("permission", permission),
+		slog.String("subject", subject.String()))
+
+	ids, err := a.engine.ListObjects(resourceType, permission, subject)
+	if err != nil {
+		log.Error("failed to list objects", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,18 @@
+package identity
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+// Identity links the user to an account of an external identity provider. Subject identifies
+// the account within the provider, Email is the email reported by the provider when the identity was linked
+type Identity struct {
+	Id        int64
+	UserId    domain.UserId
+	TenantId  domain.TenantId
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,107 @@
+package identity
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+)
+
+var (
+	ErrIdentityNotFound      = errors.New("identity not found")
+	ErrIdentityAlreadyLinked = errors.New("identity is already linked to a user")
+	ErrUserNotFound          = errors.New("user not found")
+)
+
+type IdentityService interface {
+	Create(identity *Identity) error
+	GetByProviderSubject(tenantId domain.TenantId, provider string, subject string) (*Identity, error)
+	ListByUserId(userId domain.UserId) ([]*Identity, error)
+}
+
+type identityServiceImpl struct {
+	identityStorage IdentityStorage
+
+	logger *slog.Logger
+}
+
+func NewIdentityService(identityStorage IdentityStorage, logs *logs.Logs) IdentityService {
+	logger := logs.WithName("domain.identity.service")
+	return &identityServiceImpl{
+		identityStorage: identityStorage,
+		logger:          logger,
+	}
+}
+
+// Create fails with ErrIdentityAlreadyLinked if the account of the provider is linked to a user of the tenant
+func (i *identityServiceImpl) Create(identity *Identity) error {
+	const operation = "Create"
+
+	log := i.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", identity.UserId.String()),
+		slog.String("provider", identity.Provider))
+
+	err := i.identityStorage.Create(identity)
+	if err != nil {
+		log.Error("failed to create identity", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("identity linked")
+
+	return nil
+}
+
+func (i *identityServiceImpl) GetByProviderSubject(tenantId domain.TenantId, provider string, subject string) (*Identity, error) {
+	const operation = "GetByProviderSubject"
+
+	log := i.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
+		slog.String("provider", provider))
+
+	identity, err := i.identityStorage.GetByProviderSubject(tenantId, provider, subject)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPostgresIdentityNotFound) {
+			log.Error("failed to get identity", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return identity, nil
+}
+
+func (i *identityServiceImpl) ListByUserId(userId domain.UserId) ([]*Identity, error) {
+	const operation = "ListByUserId"
+
+	log := i.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	identities, err := i.identityStorage.ListByUserId(userId)
+	if err != nil {
+		log.Error("failed to list identities", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return identities, nil
+}
+
+func toDomainError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrPostgresIdentityNotFound):
+		return ErrIdentityNotFound
+	case errors.Is(err, storage.ErrPostgresIdentityAlreadyExists):
+		return ErrIdentityAlreadyLinked
+	case errors.Is(err, storage.ErrPostgresUserNotFound):
+		return ErrUserNotFound
+	default:
+		return err
+	}
+}
@@ -0,0 +1,11 @@
+package identity
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type IdentityStorage interface {
+	Create(identity *Identity) error
+	GetByProviderSubject(tenantId domain.TenantId, provider string, subject string) (*Identity, error)
+	ListByUserId(userId domain.UserId) ([]*Identity, error)
+}
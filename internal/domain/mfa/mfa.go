@@ -0,0 +1,21 @@
+package mfa
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+// Totp is a TOTP authenticator of the user, it is used for login only after it is confirmed.
+// EncryptedSecret is bound to the user id, so it can not be moved to another user
+type Totp struct {
+	UserId          domain.UserId
+	EncryptedSecret []byte
+	Confirmed       bool
+}
+
+// TotpEnrollment is shown to the user to add the authenticator to an app, QrCode is a PNG image
+// of Uri. The secret can be typed manually if the QR code can not be scanned
+type TotpEnrollment struct {
+	Secret string
+	Uri    string
+	QrCode []byte
+}
@@ -0,0 +1,316 @@
+package mfa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"github.com/vaberof/auth-grpc/pkg/totp"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const qrCodeSize = 256
+
+const defaultRecoveryCodeCount = 10
+
+// recovery codes are 'xxxxx-xxxxx', the alphabet omits characters easily confused with each other
+const (
+	recoveryCodeAlphabet  = "abcdefghjkmnpqrstuvwxyz23456789"
+	recoveryCodeGroupSize = 5
+)
+
+var (
+	ErrTotpAlreadyEnabled = errors.New("totp is already enabled")
+	ErrTotpNotEnrolled    = errors.New("totp is not enrolled")
+	ErrInvalidCode        = errors.New("invalid mfa code")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+type MfaService interface {
+	EnrollTotp(userId domain.UserId, accountName string) (*TotpEnrollment, error)
+	ConfirmTotp(userId domain.UserId, code string) ([]string, error)
+	IsTotpEnabled(userId domain.UserId) (bool, error)
+	Verify(userId domain.UserId, code string) error
+}
+
+// Config of the mfa service. Issuer is shown by authenticator apps next to the account name,
+// SecretEncryptionKey is a base64 encoded AES-256 key encrypting TOTP secrets in the storage
+type Config struct {
+	Issuer              string `yaml:"issuer"`
+	SecretEncryptionKey string `yaml:"secret-encryption-key"`
+	RecoveryCodeCount   int    `yaml:"recovery-code-count"`
+}
+
+type mfaServiceImpl struct {
+	config       *Config
+	mfaStorage   MfaStorage
+	secretCipher SecretCipher
+
+	logger *slog.Logger
+}
+
+func NewMfaService(config *Config, mfaStorage MfaStorage, secretCipher SecretCipher, logs *logs.Logs) MfaService {
+	logger := logs.WithName("domain.mfa.service")
+	return &mfaServiceImpl{
+		config:       config,
+		mfaStorage:   mfaStorage,
+		secretCipher: secretCipher,
+		logger:       logger,
+	}
+}
+
+// EnrollTotp generates a new TOTP secret of the user replacing a not confirmed one,
+// the secret is used for login after it is confirmed with ConfirmTotp
+func (m *mfaServiceImpl) EnrollTotp(userId domain.UserId, accountName string) (*TotpEnrollment, error) {
+	const operation = "EnrollTotp"
+
+	log := m.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	log.Info("enrolling totp")
+
+	enabled, err := m.IsTotpEnabled(userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if enabled {
+		log.Warn("totp is already enabled")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrTotpAlreadyEnabled)
+	}
+
+	key, err := totp.Generate(m.config.Issuer, accountName)
+	if err != nil {
+		log.Error("failed to generate totp key", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	qrCode, err := totp.QrCode(key.Uri, qrCodeSize)
+	if err != nil {
+		log.Error("failed to generate qr code", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	encryptedSecret, err := m.secretCipher.Encrypt([]byte(key.Secret), secretAdditionalData(userId))
+	if err != nil {
+		log.Error("failed to encrypt totp secret", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = m.mfaStorage.SaveTotp(userId, encryptedSecret)
+	if err != nil {
+		log.Error("failed to save totp", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("totp enrolled")
+
+	return &TotpEnrollment{Secret: key.Secret, Uri: key.Uri, QrCode: qrCode}, nil
+}
+
+// ConfirmTotp enables the enrolled TOTP if the code is valid and returns new recovery codes,
+// they are not stored in plain text, so they can not be shown again
+func (m *mfaServiceImpl) ConfirmTotp(userId domain.UserId, code string) ([]string, error) {
+	const operation = "ConfirmTotp"
+
+	log := m.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	log.Info("confirming totp")
+
+	userTotp, err := m.mfaStorage.GetTotp(userId)
+	if err != nil {
+		log.Error("failed to get totp", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	if userTotp.Confirmed {
+		log.Warn("totp is already enabled")
+
+		return nil, fmt.Errorf("%s: %w", operation, ErrTotpAlreadyEnabled)
+	}
+
+	step, err := m.validateTotpCode(userTotp, code)
+	if err != nil {
+		log.Warn("invalid totp code", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	recoveryCodes, recoveryCodeHashes, err := m.generateRecoveryCodes()
+	if err != nil {
+		log.Error("failed to generate recovery codes", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	err = m.mfaStorage.ConfirmTotp(userId, step, recoveryCodeHashes)
+	if err != nil {
+		log.Error("failed to confirm totp", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("totp confirmed")
+
+	return recoveryCodes, nil
+}
+
+func (m *mfaServiceImpl) IsTotpEnabled(userId domain.UserId) (bool, error) {
+	const operation = "IsTotpEnabled"
+
+	log := m.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	userTotp, err := m.mfaStorage.GetTotp(userId)
+	if err != nil {
+		if errors.Is(err, storage.ErrPostgresTotpNotFound) {
+			return false, nil
+		}
+
+		log.Error("failed to get totp", "error", err)
+
+		return false, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return userTotp.Confirmed, nil
+}
+
+// Verify checks a code of the authenticator app or a recovery code of the user,
+// each of them is accepted only once
+func (m *mfaServiceImpl) Verify(userId domain.UserId, code string) error {
+	const operation = "Verify"
+
+	log := m.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	if len(code) != totp.CodeLength {
+		used, err := m.mfaStorage.UseRecoveryCode(userId, hashRecoveryCode(code))
+		if err != nil {
+			log.Error("failed to use recovery code", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, err)
+		}
+
+		if !used {
+			log.Warn("invalid recovery code")
+
+			return fmt.Errorf("%s: %w", operation, ErrInvalidCode)
+		}
+
+		log.Info("recovery code used")
+
+		return nil
+	}
+
+	userTotp, err := m.mfaStorage.GetTotp(userId)
+	if err != nil {
+		log.Error("failed to get totp", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	if !userTotp.Confirmed {
+		log.Warn("totp is not confirmed")
+
+		return fmt.Errorf("%s: %w", operation, ErrTotpNotEnrolled)
+	}
+
+	step, err := m.validateTotpCode(userTotp, code)
+	if err != nil {
+		log.Warn("invalid totp code", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	used, err := m.mfaStorage.UseTotpStep(userId, step)
+	if err != nil {
+		log.Error("failed to use totp step", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	if !used {
+		log.Warn("totp code has already been used")
+
+		return fmt.Errorf("%s: %w", operation, ErrInvalidCode)
+	}
+
+	return nil
+}
+
+func (m *mfaServiceImpl) validateTotpCode(userTotp *Totp, code string) (int64, error) {
+	secret, err := m.secretCipher.Decrypt(userTotp.EncryptedSecret, secretAdditionalData(userTotp.UserId))
+	if err != nil {
+		return 0, err
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now())
+	if !ok {
+		return 0, ErrInvalidCode
+	}
+
+	return step, nil
+}
+
+func (m *mfaServiceImpl) generateRecoveryCodes() ([]string, []string, error) {
+	count := m.config.RecoveryCodeCount
+	if count <= 0 {
+		count = defaultRecoveryCodeCount
+	}
+
+	recoveryCodes := make([]string, count)
+	recoveryCodeHashes := make([]string, count)
+	for i := range recoveryCodes {
+		value, err := xrand.GenerateRandomString(2*recoveryCodeGroupSize, recoveryCodeAlphabet)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		recoveryCodes[i] = value[:recoveryCodeGroupSize] + "-" + value[recoveryCodeGroupSize:]
+		recoveryCodeHashes[i] = hashRecoveryCode(recoveryCodes[i])
+	}
+
+	return recoveryCodes, recoveryCodeHashes, nil
+}
+
+// hashRecoveryCode ignores case and separators, recovery codes are random enough
+// for a fast hash to be sufficient
+func hashRecoveryCode(code string) string {
+	normalizedCode := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(normalizedCode))
+	return hex.EncodeToString(hash[:])
+}
+
+func secretAdditionalData(userId domain.UserId) []byte {
+	return []byte("totp:" + userId.String())
+}
+
+func toDomainError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrPostgresTotpNotFound):
+		return ErrTotpNotEnrolled
+	case errors.Is(err, storage.ErrPostgresTotpAlreadyConfirmed):
+		return ErrTotpAlreadyEnabled
+	case errors.Is(err, storage.ErrPostgresUserNotFound):
+		return ErrUserNotFound
+	default:
+		return err
+	}
+}
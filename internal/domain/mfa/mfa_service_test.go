@@ -0,0 +1,200 @@
+package mfa_test
+
+import (
+	"errors"
+	"github.com/pquerna/otp/totp"
+	"github.com/vaberof/auth-grpc/internal/domain/mfa"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeMfaStorage struct {
+	totps         map[domain.UserId]*mfa.Totp
+	lastUsedSteps map[domain.UserId]int64
+	recoveryCodes map[domain.UserId]map[string]bool
+}
+
+func newFakeMfaStorage() *fakeMfaStorage {
+	return &fakeMfaStorage{
+		totps:         map[domain.UserId]*mfa.Totp{},
+		lastUsedSteps: map[domain.UserId]int64{},
+		recoveryCodes: map[domain.UserId]map[string]bool{},
+	}
+}
+
+func (s *fakeMfaStorage) SaveTotp(userId domain.UserId, encryptedSecret []byte) error {
+	s.totps[userId] = &mfa.Totp{UserId: userId, EncryptedSecret: encryptedSecret}
+	return nil
+}
+
+func (s *fakeMfaStorage) GetTotp(userId domain.UserId) (*mfa.Totp, error) {
+	userTotp, ok := s.totps[userId]
+	if !ok {
+		return nil, storage.ErrPostgresTotpNotFound
+	}
+	return userTotp, nil
+}
+
+func (s *fakeMfaStorage) ConfirmTotp(userId domain.UserId, step int64, recoveryCodeHashes []string) error {
+	userTotp, ok := s.totps[userId]
+	if !ok {
+		return storage.ErrPostgresTotpNotFound
+	}
+	if userTotp.Confirmed {
+		return storage.ErrPostgresTotpAlreadyConfirmed
+	}
+
+	userTotp.Confirmed = true
+	s.lastUsedSteps[userId] = step
+	s.recoveryCodes[userId] = map[string]bool{}
+	for _, recoveryCodeHash := range recoveryCodeHashes {
+		s.recoveryCodes[userId][recoveryCodeHash] = true
+	}
+	return nil
+}
+
+func (s *fakeMfaStorage) UseTotpStep(userId domain.UserId, step int64) (bool, error) {
+	lastUsedStep, ok := s.lastUsedSteps[userId]
+	if ok && lastUsedStep >= step {
+		return false, nil
+	}
+	s.lastUsedSteps[userId] = step
+	return true, nil
+}
+
+func (s *fakeMfaStorage) UseRecoveryCode(userId domain.UserId, recoveryCodeHash string) (bool, error) {
+	if !s.recoveryCodes[userId][recoveryCodeHash] {
+		return false, nil
+	}
+	delete(s.recoveryCodes[userId], recoveryCodeHash)
+	return true, nil
+}
+
+// plainSecretCipher keeps secrets as they are, encryption is not a concern of these tests
+type plainSecretCipher struct{}
+
+func (c plainSecretCipher) Encrypt(plaintext []byte, additionalData []byte) ([]byte, error) {
+	return plaintext, nil
+}
+
+func (c plainSecretCipher) Decrypt(ciphertext []byte, additionalData []byte) ([]byte, error) {
+	return ciphertext, nil
+}
+
+// enableTotp enrolls and confirms TOTP of the user and returns the secret and recovery codes
+func enableTotp(t *testing.T, service mfa.MfaService, userId domain.UserId) (string, []string) {
+	t.Helper()
+
+	enrollment, err := service.EnrollTotp(userId, "user@example.com")
+	if err != nil {
+		t.Fatalf("EnrollTotp() error = %v", err)
+	}
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode() error = %v", err)
+	}
+
+	recoveryCodes, err := service.ConfirmTotp(userId, code)
+	if err != nil {
+		t.Fatalf("ConfirmTotp() error = %v", err)
+	}
+
+	return enrollment.Secret, recoveryCodes
+}
+
+func newTestMfaService() mfa.MfaService {
+	return mfa.NewMfaService(
+		&mfa.Config{Issuer: "auth-grpc", RecoveryCodeCount: 3},
+		newFakeMfaStorage(),
+		plainSecretCipher{},
+		logs.New(io.Discard, nil))
+}
+
+func TestVerifyRejectsReusedTotpStep(t *testing.T) {
+	service := newTestMfaService()
+	userId := domain.UserId(1)
+
+	secret, _ := enableTotp(t, service, userId)
+
+	// the code confirming the authenticator can not be used for login
+	code, _ := totp.GenerateCode(secret, time.Now())
+	err := service.Verify(userId, code)
+	if !errors.Is(err, mfa.ErrInvalidCode) {
+		t.Fatalf("Verify() with the confirmation code error = %v, want %v", err, mfa.ErrInvalidCode)
+	}
+
+	nextCode, _ := totp.GenerateCode(secret, time.Now().Add(30*time.Second))
+	err = service.Verify(userId, nextCode)
+	if err != nil {
+		t.Fatalf("Verify() with the next code error = %v", err)
+	}
+
+	err = service.Verify(userId, nextCode)
+	if !errors.Is(err, mfa.ErrInvalidCode) {
+		t.Errorf("Verify() with the reused code error = %v, want %v", err, mfa.ErrInvalidCode)
+	}
+}
+
+func TestVerifyRecoveryCodes(t *testing.T) {
+	service := newTestMfaService()
+	userId := domain.UserId(1)
+
+	_, recoveryCodes := enableTotp(t, service, userId)
+	if len(recoveryCodes) != 3 {
+		t.Fatalf("ConfirmTotp() returned %d recovery codes, want 3", len(recoveryCodes))
+	}
+
+	err := service.Verify(userId, recoveryCodes[0])
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	err = service.Verify(userId, recoveryCodes[0])
+	if !errors.Is(err, mfa.ErrInvalidCode) {
+		t.Errorf("Verify() with a used recovery code error = %v, want %v", err, mfa.ErrInvalidCode)
+	}
+
+	// recovery codes are accepted regardless of case and separators
+	err = service.Verify(userId, strings.ToUpper(strings.ReplaceAll(recoveryCodes[1], "-", "")))
+	if err != nil {
+		t.Errorf("Verify() with a normalized recovery code error = %v", err)
+	}
+
+	err = service.Verify(domain.UserId(2), recoveryCodes[2])
+	if !errors.Is(err, mfa.ErrInvalidCode) {
+		t.Errorf("Verify() with a recovery code of another user error = %v, want %v", err, mfa.ErrInvalidCode)
+	}
+
+	err = service.Verify(userId, "abcde-fghjk")
+	if !errors.Is(err, mfa.ErrInvalidCode) {
+		t.Errorf("Verify() with an unknown recovery code error = %v, want %v", err, mfa.ErrInvalidCode)
+	}
+}
+
+func TestEnrollTotpWhenEnabled(t *testing.T) {
+	service := newTestMfaService()
+	userId := domain.UserId(1)
+
+	enabled, err := service.IsTotpEnabled(userId)
+	if err != nil || enabled {
+		t.Fatalf("IsTotpEnabled() = %v, %v, want false", enabled, err)
+	}
+
+	enableTotp(t, service, userId)
+
+	enabled, err = service.IsTotpEnabled(userId)
+	if err != nil || !enabled {
+		t.Fatalf("IsTotpEnabled() = %v, %v, want true", enabled, err)
+	}
+
+	_, err = service.EnrollTotp(userId, "user@example.com")
+	if !errors.Is(err, mfa.ErrTotpAlreadyEnabled) {
+		t.Errorf("EnrollTotp() error = %v, want %v", err, mfa.ErrTotpAlreadyEnabled)
+	}
+}
@@ -0,0 +1,17 @@
+package mfa
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type MfaStorage interface {
+	// SaveTotp replaces a not confirmed authenticator of the user
+	SaveTotp(userId domain.UserId, encryptedSecret []byte) error
+	GetTotp(userId domain.UserId) (*Totp, error)
+	// ConfirmTotp confirms the authenticator accepting the time step and replaces recovery codes of the user
+	ConfirmTotp(userId domain.UserId, step int64, recoveryCodeHashes []string) error
+	// UseTotpStep reports whether the step is greater than the last accepted one and stores it
+	UseTotpStep(userId domain.UserId, step int64) (bool, error)
+	// UseRecoveryCode reports whether the code existed and deletes it
+	UseRecoveryCode(userId domain.UserId, recoveryCodeHash string) (bool, error)
+}
@@ -0,0 +1,6 @@
+package mfa
+
+type SecretCipher interface {
+	Encrypt(plaintext []byte, additionalData []byte) ([]byte, error)
+	Decrypt(ciphertext []byte, additionalData []byte) ([]byte, error)
+}
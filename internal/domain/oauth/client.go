@@ -0,0 +1,52 @@
+package oauth
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"slices"
+	"time"
+)
+
+// grant types of RFC 6749
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// Client is an application registered to obtain tokens with OAuth 2.0. ClientId is the public identifier
+// of the client, SecretHash is SHA-256 hex of the client secret and is empty for public clients,
+// e.g. single-page and mobile apps which can not keep a secret
+type Client struct {
+	Id           int64
+	ClientId     string
+	TenantId     domain.TenantId
+	Name         string
+	SecretHash   string
+	RedirectUris []string
+	GrantTypes   []string
+	Scopes       []string
+	CreatedAt    time.Time
+}
+
+func (c *Client) IsConfidential() bool {
+	return c.SecretHash != ""
+}
+
+func (c *Client) AllowsGrantType(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// AllowsRedirectUri compares the uri with the registered ones exactly, as OAuth 2.0 Security BCP requires
+func (c *Client) AllowsRedirectUri(redirectUri string) bool {
+	return slices.Contains(c.RedirectUris, redirectUri)
+}
+
+// AllowsScopes reports whether all the scopes are registered for the client
+func (c *Client) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,25 @@
+package oauth
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"slices"
+	"time"
+)
+
+// Consent records the scopes the user has granted to the client
+type Consent struct {
+	UserId    domain.UserId
+	ClientId  string
+	Scopes    []string
+	GrantedAt time.Time
+}
+
+// Covers reports whether the user has granted all the scopes
+func (c *Consent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
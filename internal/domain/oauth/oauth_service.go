@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+)
+
+var (
+	ErrClientNotFound      = errors.New("oauth client not found")
+	ErrClientAlreadyExists = errors.New("oauth client already exists")
+	ErrConsentNotFound     = errors.New("consent not found")
+	ErrUserNotFound        = errors.New("user not found")
+	ErrTenantNotFound      = errors.New("tenant not found")
+)
+
+type OAuthService interface {
+	CreateClient(client *Client) error
+	GetClient(clientId string) (*Client, error)
+	SaveConsent(userId domain.UserId, clientId string, scopes []string) error
+	GetConsent(userId domain.UserId, clientId string) (*Consent, error)
+}
+
+type oauthServiceImpl struct {
+	oauthStorage OAuthStorage
+
+	logger *slog.Logger
+}
+
+func NewOAuthService(oauthStorage OAuthStorage, logs *logs.Logs) OAuthService {
+	logger := logs.WithName("domain.oauth.service")
+	return &oauthServiceImpl{
+		oauthStorage: oauthStorage,
+		logger:       logger,
+	}
+}
+
+func (o *oauthServiceImpl) CreateClient(client *Client) error {
+	const operation = "CreateClient"
+
+	log := o.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", client.TenantId.String()),
+		slog.String("client_id", client.ClientId))
+
+	err := o.oauthStorage.CreateClient(client)
+	if err != nil {
+		log.Error("failed to create oauth client", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("oauth client created")
+
+	return nil
+}
+
+func (o *oauthServiceImpl) GetClient(clientId string) (*Client, error) {
+	const operation = "GetClient"
+
+	log := o.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", clientId))
+
+	client, err := o.oauthStorage.GetClientByClientId(clientId)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPostgresOAuthClientNotFound) {
+			log.Error("failed to get oauth client", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return client, nil
+}
+
+func (o *oauthServiceImpl) SaveConsent(userId domain.UserId, clientId string, scopes []string) error {
+	const operation = "SaveConsent"
+
+	log := o.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()),
+		slog.String("client_id", clientId))
+
+	err := o.oauthStorage.SaveConsent(userId, clientId, scopes)
+	if err != nil {
+		log.Error("failed to save consent", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("consent saved")
+
+	return nil
+}
+
+func (o *oauthServiceImpl) GetConsent(userId domain.UserId, clientId string) (*Consent, error) {
+	const operation = "GetConsent"
+
+	log := o.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()),
+		slog.String("client_id", clientId))
+
+	consent, err := o.oauthStorage.GetConsent(userId, clientId)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPostgresConsentNotFound) {
+			log.Error("failed to get consent", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return consent, nil
+}
+
+func toDomainError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrPostgresOAuthClientNotFound):
+		return ErrClientNotFound
+	case errors.Is(err, storage.ErrPostgresOAuthClientAlreadyExists):
+		return ErrClientAlreadyExists
+	case errors.Is(err, storage.ErrPostgresConsentNotFound):
+		return ErrConsentNotFound
+	case errors.Is(err, storage.ErrPostgresUserNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, storage.ErrPostgresTenantNotFound):
+		return ErrTenantNotFound
+	default:
+		return err
+	}
+}
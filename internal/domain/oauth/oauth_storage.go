@@ -0,0 +1,13 @@
+package oauth
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type OAuthStorage interface {
+	CreateClient(client *Client) error
+	GetClientByClientId(clientId string) (*Client, error)
+	// SaveConsent adds the scopes to the scopes already granted by the user to the client
+	SaveConsent(userId domain.UserId, clientId string, scopes []string) error
+	GetConsent(userId domain.UserId, clientId string) (*Consent, error)
+}
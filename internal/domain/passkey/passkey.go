@@ -0,0 +1,21 @@
+package passkey
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+// Passkey is a WebAuthn credential of the user. PublicKey is a CBOR encoded COSE_Key,
+// SignCount is the last signature counter reported by the authenticator
+type Passkey struct {
+	Id                []byte
+	UserId            domain.UserId
+	Name              string
+	PublicKey         []byte
+	SignCount         uint32
+	Aaguid            []byte
+	Transports        []string
+	AttestationFormat string
+	CreatedAt         time.Time
+	LastUsedAt        *time.Time
+}
@@ -0,0 +1,134 @@
+package passkey
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+)
+
+var (
+	ErrPasskeyNotFound       = errors.New("passkey not found")
+	ErrPasskeyAlreadyExists  = errors.New("passkey already exists")
+	ErrSignCountNotIncreased = errors.New("passkey sign count did not increase")
+	ErrUserNotFound          = errors.New("user not found")
+)
+
+type PasskeyService interface {
+	Create(passkey *Passkey) error
+	GetById(id []byte) (*Passkey, error)
+	ListByUserId(userId domain.UserId) ([]*Passkey, error)
+	UpdateSignCount(id []byte, signCount uint32) error
+}
+
+type passkeyServiceImpl struct {
+	passkeyStorage PasskeyStorage
+
+	logger *slog.Logger
+}
+
+func NewPasskeyService(passkeyStorage PasskeyStorage, logs *logs.Logs) PasskeyService {
+	logger := logs.WithName("domain.passkey.service")
+	return &passkeyServiceImpl{
+		passkeyStorage: passkeyStorage,
+		logger:         logger,
+	}
+}
+
+func (p *passkeyServiceImpl) Create(passkey *Passkey) error {
+	const operation = "Create"
+
+	log := p.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", passkey.UserId.String()),
+		slog.String("passkey_id", encodeId(passkey.Id)))
+
+	err := p.passkeyStorage.Create(passkey)
+	if err != nil {
+		log.Error("failed to create passkey", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("passkey created")
+
+	return nil
+}
+
+func (p *passkeyServiceImpl) GetById(id []byte) (*Passkey, error) {
+	const operation = "GetById"
+
+	log := p.logger.With(
+		slog.String("operation", operation),
+		slog.String("passkey_id", encodeId(id)))
+
+	passkey, err := p.passkeyStorage.GetById(id)
+	if err != nil {
+		log.Error("failed to get passkey", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return passkey, nil
+}
+
+func (p *passkeyServiceImpl) ListByUserId(userId domain.UserId) ([]*Passkey, error) {
+	const operation = "ListByUserId"
+
+	log := p.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	passkeys, err := p.passkeyStorage.ListByUserId(userId)
+	if err != nil {
+		log.Error("failed to list passkeys", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return passkeys, nil
+}
+
+// UpdateSignCount fails with ErrSignCountNotIncreased if a concurrent login has already used the sign count
+func (p *passkeyServiceImpl) UpdateSignCount(id []byte, signCount uint32) error {
+	const operation = "UpdateSignCount"
+
+	log := p.logger.With(
+		slog.String("operation", operation),
+		slog.String("passkey_id", encodeId(id)))
+
+	updated, err := p.passkeyStorage.UpdateSignCount(id, signCount)
+	if err != nil {
+		log.Error("failed to update sign count", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	if !updated {
+		log.Warn("sign count did not increase")
+
+		return fmt.Errorf("%s: %w", operation, ErrSignCountNotIncreased)
+	}
+
+	return nil
+}
+
+func encodeId(id []byte) string {
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+func toDomainError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrPostgresPasskeyNotFound):
+		return ErrPasskeyNotFound
+	case errors.Is(err, storage.ErrPostgresPasskeyAlreadyExists):
+		return ErrPasskeyAlreadyExists
+	case errors.Is(err, storage.ErrPostgresUserNotFound):
+		return ErrUserNotFound
+	default:
+		return err
+	}
+}
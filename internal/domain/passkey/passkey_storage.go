@@ -0,0 +1,14 @@
+package passkey
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type PasskeyStorage interface {
+	Create(passkey *Passkey) error
+	GetById(id []byte) (*Passkey, error)
+	ListByUserId(userId domain.UserId) ([]*Passkey, error)
+	// UpdateSignCount stores the sign count and reports whether it is greater than the stored one,
+	// sign counts which stay 0 are accepted as well
+	UpdateSignCount(id []byte, signCount uint32) (bool, error)
+}
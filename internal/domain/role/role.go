@@ -0,0 +1,14 @@
+package role
+
+// Role is a named set of permissions assigned to users
+type Role struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// UserPermissions are the roles of a user and the permissions they grant
+type UserPermissions struct {
+	Roles       []string
+	Permissions []string
+}
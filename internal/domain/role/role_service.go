@@ -0,0 +1,147 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+	"slices"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrUserNotFound = errors.New("user not found")
+)
+
+type RoleService interface {
+	AssignRole(userId domain.UserId, roleName string) error
+	RevokeRole(userId domain.UserId, roleName string) error
+	ListRoles() ([]*Role, error)
+	ListUserRoles(userId domain.UserId) ([]*Role, error)
+	GetUserPermissions(userId domain.UserId) (*UserPermissions, error)
+}
+
+type roleServiceImpl struct {
+	roleStorage RoleStorage
+
+	logger *slog.Logger
+}
+
+func NewRoleService(roleStorage RoleStorage, logs *logs.Logs) RoleService {
+	logger := logs.WithName("domain.role.service")
+	return &roleServiceImpl{roleStorage: roleStorage, logger: logger}
+}
+
+// AssignRole assigns the role to the user, assigning already assigned role is not an error.
+// Permissions of the role are put to tokens issued after the call
+func (r *roleServiceImpl) AssignRole(userId domain.UserId, roleName string) error {
+	const operation = "AssignRole"
+
+	log := r.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()),
+		slog.String("role", roleName))
+
+	log.Info("assigning a role")
+
+	err := r.roleStorage.AssignRole(userId, roleName)
+	if err != nil {
+		log.Error("failed to assign a role", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("role assigned")
+
+	return nil
+}
+
+// RevokeRole revokes the role from the user, revoking not assigned role is not an error
+func (r *roleServiceImpl) RevokeRole(userId domain.UserId, roleName string) error {
+	const operation = "RevokeRole"
+
+	log := r.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()),
+		slog.String("role", roleName))
+
+	log.Info("revoking a role")
+
+	err := r.roleStorage.RevokeRole(userId, roleName)
+	if err != nil {
+		log.Error("failed to revoke a role", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("role revoked")
+
+	return nil
+}
+
+func (r *roleServiceImpl) ListRoles() ([]*Role, error) {
+	const operation = "ListRoles"
+
+	log := r.logger.With(slog.String("operation", operation))
+
+	roles, err := r.roleStorage.ListRoles()
+	if err != nil {
+		log.Error("failed to list roles", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return roles, nil
+}
+
+func (r *roleServiceImpl) ListUserRoles(userId domain.UserId) ([]*Role, error) {
+	const operation = "ListUserRoles"
+
+	log := r.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", userId.String()))
+
+	roles, err := r.roleStorage.ListUserRoles(userId)
+	if err != nil {
+		log.Error("failed to list user roles", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	return roles, nil
+}
+
+// GetUserPermissions returns sorted role names of the user and sorted unique permissions they grant
+func (r *roleServiceImpl) GetUserPermissions(userId domain.UserId) (*UserPermissions, error) {
+	const operation = "GetUserPermissions"
+
+	roles, err := r.ListUserRoles(userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	userPermissions := &UserPermissions{}
+	for _, userRole := range roles {
+		userPermissions.Roles = append(userPermissions.Roles, userRole.Name)
+		userPermissions.Permissions = append(userPermissions.Permissions, userRole.Permissions...)
+	}
+
+	slices.Sort(userPermissions.Roles)
+	slices.Sort(userPermissions.Permissions)
+	userPermissions.Permissions = slices.Compact(userPermissions.Permissions)
+
+	return userPermissions, nil
+}
+
+func toDomainError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrPostgresRoleNotFound):
+		return ErrRoleNotFound
+	case errors.Is(err, storage.ErrPostgresUserNotFound):
+		return ErrUserNotFound
+	default:
+		return err
+	}
+}
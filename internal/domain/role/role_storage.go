@@ -0,0 +1,12 @@
+package role
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type RoleStorage interface {
+	AssignRole(userId domain.UserId, roleName string) error
+	RevokeRole(userId domain.UserId, roleName string) error
+	ListRoles() ([]*Role, error)
+	ListUserRoles(userId domain.UserId) ([]*Role, error)
+}
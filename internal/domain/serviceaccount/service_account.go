@@ -0,0 +1,32 @@
+package serviceaccount
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+// ServiceAccount is a non-human principal of the tenant. It authenticates with the client id and a secret
+// and gets access tokens limited to its scopes. DisabledAt is zero unless the account is disabled
+type ServiceAccount struct {
+	Id         int64
+	ClientId   string
+	TenantId   domain.TenantId
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	DisabledAt time.Time
+}
+
+func (s *ServiceAccount) Disabled() bool {
+	return !s.DisabledAt.IsZero()
+}
+
+// Secret of a service account, only its hash is stored. ExpiresAt is zero for the current secret,
+// previous secrets stay valid until ExpiresAt after a rotation
+type Secret struct {
+	Id               int64
+	ServiceAccountId int64
+	SecretHash       string
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+}
@@ -0,0 +1,167 @@
+package serviceaccount
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+	"time"
+)
+
+var (
+	ErrServiceAccountNotFound      = errors.New("service account not found")
+	ErrServiceAccountAlreadyExists = errors.New("service account already exists")
+	ErrTenantNotFound              = errors.New("tenant not found")
+)
+
+type ServiceAccountService interface {
+	Create(serviceAccount *ServiceAccount, secretHash string) error
+	GetByClientId(clientId string) (*ServiceAccount, error)
+	ListActiveSecrets(serviceAccountId int64) ([]*Secret, error)
+	RotateSecret(serviceAccountId int64, secretHash string, overlap time.Duration) error
+	Disable(serviceAccountId int64) error
+	Delete(serviceAccountId int64) error
+}
+
+type serviceAccountServiceImpl struct {
+	serviceAccountStorage ServiceAccountStorage
+
+	logger *slog.Logger
+}
+
+func NewServiceAccountService(serviceAccountStorage ServiceAccountStorage, logs *logs.Logs) ServiceAccountService {
+	logger := logs.WithName("domain.serviceaccount.service")
+	return &serviceAccountServiceImpl{
+		serviceAccountStorage: serviceAccountStorage,
+		logger:                logger,
+	}
+}
+
+// Create stores the service account along with its first secret
+func (s *serviceAccountServiceImpl) Create(serviceAccount *ServiceAccount, secretHash string) error {
+	const operation = "Create"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", serviceAccount.TenantId.String()),
+		slog.String("client_id", serviceAccount.ClientId))
+
+	err := s.serviceAccountStorage.Create(serviceAccount, secretHash)
+	if err != nil {
+		log.Error("failed to create service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("service account created")
+
+	return nil
+}
+
+func (s *serviceAccountServiceImpl) GetByClientId(clientId string) (*ServiceAccount, error) {
+	const operation = "GetByClientId"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.String("client_id", clientId))
+
+	serviceAccount, err := s.serviceAccountStorage.GetByClientId(clientId)
+	if err != nil {
+		if !errors.Is(err, storage.ErrPostgresServiceAccountNotFound) {
+			log.Error("failed to get service account", "error", err)
+		}
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return serviceAccount, nil
+}
+
+func (s *serviceAccountServiceImpl) ListActiveSecrets(serviceAccountId int64) ([]*Secret, error) {
+	const operation = "ListActiveSecrets"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.Int64("service_account_id", serviceAccountId))
+
+	secrets, err := s.serviceAccountStorage.ListActiveSecrets(serviceAccountId)
+	if err != nil {
+		log.Error("failed to list active secrets", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	return secrets, nil
+}
+
+func (s *serviceAccountServiceImpl) RotateSecret(serviceAccountId int64, secretHash string, overlap time.Duration) error {
+	const operation = "RotateSecret"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.Int64("service_account_id", serviceAccountId),
+		slog.Duration("overlap", overlap))
+
+	err := s.serviceAccountStorage.RotateSecret(serviceAccountId, secretHash, overlap)
+	if err != nil {
+		log.Error("failed to rotate secret", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("secret rotated")
+
+	return nil
+}
+
+func (s *serviceAccountServiceImpl) Disable(serviceAccountId int64) error {
+	const operation = "Disable"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.Int64("service_account_id", serviceAccountId))
+
+	err := s.serviceAccountStorage.Disable(serviceAccountId)
+	if err != nil {
+		log.Error("failed to disable service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("service account disabled")
+
+	return nil
+}
+
+func (s *serviceAccountServiceImpl) Delete(serviceAccountId int64) error {
+	const operation = "Delete"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.Int64("service_account_id", serviceAccountId))
+
+	err := s.serviceAccountStorage.Delete(serviceAccountId)
+	if err != nil {
+		log.Error("failed to delete service account", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, toDomainError(err))
+	}
+
+	log.Info("service account deleted")
+
+	return nil
+}
+
+func toDomainError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrPostgresServiceAccountNotFound):
+		return ErrServiceAccountNotFound
+	case errors.Is(err, storage.ErrPostgresServiceAccountAlreadyExists):
+		return ErrServiceAccountAlreadyExists
+	case errors.Is(err, storage.ErrPostgresTenantNotFound):
+		return ErrTenantNotFound
+	default:
+		return err
+	}
+}
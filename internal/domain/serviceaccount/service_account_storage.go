@@ -0,0 +1,16 @@
+package serviceaccount
+
+import (
+	"time"
+)
+
+type ServiceAccountStorage interface {
+	Create(serviceAccount *ServiceAccount, secretHash string) error
+	GetByClientId(clientId string) (*ServiceAccount, error)
+	ListActiveSecrets(serviceAccountId int64) ([]*Secret, error)
+	// RotateSecret adds a new secret, active secrets expire after the overlap. Zero overlap expires them immediately
+	RotateSecret(serviceAccountId int64, secretHash string, overlap time.Duration) error
+	Disable(serviceAccountId int64) error
+	// Delete removes the service account along with its secrets
+	Delete(serviceAccountId int64) error
+}
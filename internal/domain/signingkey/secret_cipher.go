@@ -0,0 +1,6 @@
+package signingkey
+
+type SecretCipher interface {
+	Encrypt(plaintext []byte, additionalData []byte) ([]byte, error)
+	Decrypt(ciphertext []byte, additionalData []byte) ([]byte, error)
+}
@@ -0,0 +1,17 @@
+package signingkey
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"time"
+)
+
+// SigningKey is a stored key of the token key ring, so rotations survive restarts and are shared by
+// all instances of the service. EncryptedPrivateKey is set for generated keys only, keys of the
+// configuration are stored with their lifetime
+type SigningKey struct {
+	Id                  string
+	Algorithm           accesstoken.Algorithm
+	EncryptedPrivateKey []byte
+	ActivateAt          time.Time
+	RetireAt            time.Time
+}
@@ -0,0 +1,141 @@
+package signingkey
+
+import (
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+)
+
+type SigningKeyService interface {
+	Load(keyRing *accesstoken.KeyRing) error
+	Save(keyRing *accesstoken.KeyRing, generatedKeyId string) error
+}
+
+type signingKeyServiceImpl struct {
+	signingKeyStorage SigningKeyStorage
+	secretCipher      SecretCipher
+
+	logger *slog.Logger
+}
+
+func NewSigningKeyService(signingKeyStorage SigningKeyStorage, secretCipher SecretCipher, logs *logs.Logs) SigningKeyService {
+	logger := logs.WithName("domain.signingkey.service")
+	return &signingKeyServiceImpl{
+		signingKeyStorage: signingKeyStorage,
+		secretCipher:      secretCipher,
+		logger:            logger,
+	}
+}
+
+// Load applies the stored keys to the ring. Keys of the ring get the stored lifetime, generated keys
+// are added to it. Keys configured only by other instances can not be loaded and are skipped
+func (s *signingKeyServiceImpl) Load(keyRing *accesstoken.KeyRing) error {
+	const operation = "Load"
+
+	log := s.logger.With(slog.String("operation", operation))
+
+	storedKeys, err := s.signingKeyStorage.List()
+	if err != nil {
+		log.Error("failed to list signing keys", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	ringKeys := make(map[string]*accesstoken.Key)
+	for _, ringKey := range keyRing.Keys() {
+		ringKeys[ringKey.Key.Id] = ringKey.Key
+	}
+
+	keys := make([]*accesstoken.RingKey, 0, len(storedKeys))
+	for _, storedKey := range storedKeys {
+		key, ok := ringKeys[storedKey.Id]
+		if !ok {
+			if storedKey.EncryptedPrivateKey == nil {
+				log.Warn("skipping signing key without private key", slog.String("key_id", storedKey.Id))
+				continue
+			}
+
+			key, err = s.decryptKey(storedKey)
+			if err != nil {
+				log.Error("failed to decrypt signing key", slog.String("key_id", storedKey.Id), "error", err)
+
+				return fmt.Errorf("%s: %w", operation, err)
+			}
+		}
+
+		keys = append(keys, &accesstoken.RingKey{
+			Key:        key,
+			ActivateAt: storedKey.ActivateAt,
+			RetireAt:   storedKey.RetireAt,
+		})
+	}
+
+	keyRing.Sync(keys)
+
+	return nil
+}
+
+// Save stores lifetimes of all keys of the ring and the private key of the generated key,
+// private keys of the configuration are never stored
+func (s *signingKeyServiceImpl) Save(keyRing *accesstoken.KeyRing, generatedKeyId string) error {
+	const operation = "Save"
+
+	log := s.logger.With(
+		slog.String("operation", operation),
+		slog.String("generated_key_id", generatedKeyId))
+
+	ringKeys := keyRing.Keys()
+
+	keys := make([]*SigningKey, len(ringKeys))
+	for i, ringKey := range ringKeys {
+		keys[i] = &SigningKey{
+			Id:         ringKey.Key.Id,
+			Algorithm:  ringKey.Key.Algorithm,
+			ActivateAt: ringKey.ActivateAt,
+			RetireAt:   ringKey.RetireAt,
+		}
+
+		if generatedKeyId != "" && ringKey.Key.Id == generatedKeyId {
+			encryptedPrivateKey, err := s.encryptKey(ringKey.Key)
+			if err != nil {
+				log.Error("failed to encrypt signing key", "error", err)
+
+				return fmt.Errorf("%s: %w", operation, err)
+			}
+			keys[i].EncryptedPrivateKey = encryptedPrivateKey
+		}
+	}
+
+	err := s.signingKeyStorage.Save(keys)
+	if err != nil {
+		log.Error("failed to save signing keys", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("signing keys saved")
+
+	return nil
+}
+
+func (s *signingKeyServiceImpl) encryptKey(key *accesstoken.Key) ([]byte, error) {
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return s.secretCipher.Encrypt(privateKey, keyAdditionalData(key.Id, key.Algorithm))
+}
+
+func (s *signingKeyServiceImpl) decryptKey(storedKey *SigningKey) (*accesstoken.Key, error) {
+	privateKey, err := s.secretCipher.Decrypt(storedKey.EncryptedPrivateKey, keyAdditionalData(storedKey.Id, storedKey.Algorithm))
+	if err != nil {
+		return nil, err
+	}
+	return accesstoken.ParsePrivateKey(storedKey.Id, storedKey.Algorithm, privateKey)
+}
+
+// keyAdditionalData binds an encrypted private key to its id and algorithm
+func keyAdditionalData(keyId string, algorithm accesstoken.Algorithm) []byte {
+	return []byte("signing_key:" + keyId + ":" + string(algorithm))
+}
@@ -0,0 +1,7 @@
+package signingkey
+
+type SigningKeyStorage interface {
+	List() ([]*SigningKey, error)
+	// Save inserts or updates the keys, a stored private key is kept if EncryptedPrivateKey is nil
+	Save(keys []*SigningKey) error
+}
@@ -0,0 +1,22 @@
+package tenant
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"time"
+)
+
+// Tenant is an organization users belong to. Name is a unique human-readable identifier
+type Tenant struct {
+	Id       domain.TenantId
+	Name     string
+	Settings Settings
+}
+
+// Settings override the config of the auth service for users of the tenant, zero values and nil
+// keep the service settings. PasswordPolicy replaces the configured policy as a whole
+type Settings struct {
+	TokenTtl        time.Duration
+	RefreshTokenTtl time.Duration
+	PasswordPolicy  *passwordpolicy.Config
+}
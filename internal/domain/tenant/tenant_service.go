@@ -0,0 +1,120 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTenantNotFound = errors.New("tenant not found")
+)
+
+// tenantCacheTtl bounds how long changed settings of a tenant may be ignored,
+// tenants are read on every login and token refresh
+const tenantCacheTtl = time.Minute
+
+type TenantService interface {
+	GetById(id domain.TenantId) (*Tenant, error)
+	GetByName(name string) (*Tenant, error)
+}
+
+type cachedTenant struct {
+	tenant    *Tenant
+	expiresAt time.Time
+}
+
+type tenantServiceImpl struct {
+	tenantStorage TenantStorage
+
+	cacheMu sync.Mutex
+	cache   map[domain.TenantId]cachedTenant
+
+	logger *slog.Logger
+}
+
+func NewTenantService(tenantStorage TenantStorage, logs *logs.Logs) TenantService {
+	logger := logs.WithName("domain.tenant.service")
+	return &tenantServiceImpl{
+		tenantStorage: tenantStorage,
+		cache:         make(map[domain.TenantId]cachedTenant),
+		logger:        logger,
+	}
+}
+
+func (t *tenantServiceImpl) GetById(id domain.TenantId) (*Tenant, error) {
+	const operation = "GetById"
+
+	log := t.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_id", id.String()))
+
+	if tenant, ok := t.cached(id); ok {
+		return tenant, nil
+	}
+
+	tenant, err := t.tenantStorage.GetById(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrPostgresTenantNotFound) {
+			log.Error("tenant with given id not found", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrTenantNotFound)
+		}
+
+		log.Error("unexpected error from tenant storage", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	t.cacheTenant(tenant)
+
+	return tenant, nil
+}
+
+func (t *tenantServiceImpl) GetByName(name string) (*Tenant, error) {
+	const operation = "GetByName"
+
+	log := t.logger.With(
+		slog.String("operation", operation),
+		slog.String("tenant_name", name))
+
+	tenant, err := t.tenantStorage.GetByName(name)
+	if err != nil {
+		if errors.Is(err, storage.ErrPostgresTenantNotFound) {
+			log.Error("tenant with given name not found", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrTenantNotFound)
+		}
+
+		log.Error("unexpected error from tenant storage", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	t.cacheTenant(tenant)
+
+	return tenant, nil
+}
+
+func (t *tenantServiceImpl) cached(id domain.TenantId) (*Tenant, bool) {
+	t.cacheMu.Lock()
+	defer t.cacheMu.Unlock()
+
+	cached, ok := t.cache[id]
+	if !ok || time.Now().After(cached.expiresAt) {
+		return nil, false
+	}
+	return cached.tenant, true
+}
+
+func (t *tenantServiceImpl) cacheTenant(tenant *Tenant) {
+	t.cacheMu.Lock()
+	defer t.cacheMu.Unlock()
+
+	t.cache[tenant.Id] = cachedTenant{tenant: tenant, expiresAt: time.Now().Add(tenantCacheTtl)}
+}
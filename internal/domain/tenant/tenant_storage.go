@@ -0,0 +1,10 @@
+package tenant
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type TenantStorage interface {
+	GetById(id domain.TenantId) (*Tenant, error)
+	GetByName(name string) (*Tenant, error)
+}
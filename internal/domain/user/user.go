@@ -4,6 +4,7 @@ import "github.com/vaberof/auth-grpc/pkg/domain"
 
 type User struct {
 	Id       domain.UserId
+	TenantId domain.TenantId
 	Email    domain.Email
 	Password domain.Password
 }
@@ -14,9 +14,11 @@ var (
 )
 
 type UserService interface {
-	Create(email domain.Email, password domain.Password) (domain.UserId, error)
-	GetByEmail(email domain.Email) (*User, error)
-	ExistsByEmail(email domain.Email) (bool, error)
+	Create(tenantId domain.TenantId, email domain.Email, password domain.Password) (domain.UserId, error)
+	GetByEmail(tenantId domain.TenantId, email domain.Email) (*User, error)
+	GetById(id domain.UserId) (*User, error)
+	ExistsByEmail(tenantId domain.TenantId, email domain.Email) (bool, error)
+	UpdatePassword(id domain.UserId, password domain.Password) error
 }
 
 type userServiceImpl struct {
@@ -30,16 +32,17 @@ func NewUserService(userStorage UserStorage, logs *logs.Logs) UserService {
 	return &userServiceImpl{userStorage: userStorage, logger: logger}
 }
 
-func (u *userServiceImpl) Create(email domain.Email, password domain.Password) (domain.UserId, error) {
+func (u *userServiceImpl) Create(tenantId domain.TenantId, email domain.Email, password domain.Password) (domain.UserId, error) {
 	const operation = "Create"
 
 	log := u.logger.With(
 		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
 		slog.String("email", string(email)))
 
 	log.Info("creating a user")
 
-	uid, err := u.userStorage.Create(email, password)
+	uid, err := u.userStorage.Create(tenantId, email, password)
 	if err != nil {
 		log.Error("failed to create a user", "error", err)
 
@@ -51,19 +54,20 @@ func (u *userServiceImpl) Create(email domain.Email, password domain.Password) (
 	return uid, nil
 }
 
-func (u *userServiceImpl) GetByEmail(email domain.Email) (*User, error) {
+func (u *userServiceImpl) GetByEmail(tenantId domain.TenantId, email domain.Email) (*User, error) {
 	const operation = "GetByEmail"
 
 	log := u.logger.With(
 		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
 		slog.String("email", string(email)))
 
-	domainUser, err := u.userStorage.GetByEmail(email)
+	domainUser, err := u.userStorage.GetByEmail(tenantId, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrPostgresUserNotFound) {
 			log.Error("user with given email not found", "error", err)
 
-			return nil, fmt.Errorf("%s: %w", operation, err)
+			return nil, fmt.Errorf("%s: %w", operation, ErrUserNotFound)
 		}
 
 		log.Error("unexpected error from user storage", "error", err)
@@ -76,14 +80,40 @@ func (u *userServiceImpl) GetByEmail(email domain.Email) (*User, error) {
 	return domainUser, nil
 }
 
-func (u *userServiceImpl) ExistsByEmail(email domain.Email) (bool, error) {
+func (u *userServiceImpl) GetById(id domain.UserId) (*User, error) {
+	const operation = "GetById"
+
+	log := u.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", id.String()))
+
+	domainUser, err := u.userStorage.GetById(id)
+	if err != nil {
+		if errors.Is(err, storage.ErrPostgresUserNotFound) {
+			log.Error("user with given id not found", "error", err)
+
+			return nil, fmt.Errorf("%s: %w", operation, ErrUserNotFound)
+		}
+
+		log.Error("unexpected error from user storage", "error", err)
+
+		return nil, fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("received user by id")
+
+	return domainUser, nil
+}
+
+func (u *userServiceImpl) ExistsByEmail(tenantId domain.TenantId, email domain.Email) (bool, error) {
 	const operation = "GetByEmail"
 
 	log := u.logger.With(
 		slog.String("operation", operation),
+		slog.String("tenant_id", tenantId.String()),
 		slog.String("email", string(email)))
 
-	exists, err := u.userStorage.ExistsByEmail(email)
+	exists, err := u.userStorage.ExistsByEmail(tenantId, email)
 	if err != nil {
 		log.Error("failed to get info about existing/non-existing email", "error", err)
 
@@ -92,3 +122,28 @@ func (u *userServiceImpl) ExistsByEmail(email domain.Email) (bool, error) {
 
 	return exists, nil
 }
+
+func (u *userServiceImpl) UpdatePassword(id domain.UserId, password domain.Password) error {
+	const operation = "UpdatePassword"
+
+	log := u.logger.With(
+		slog.String("operation", operation),
+		slog.String("user_id", id.String()))
+
+	err := u.userStorage.UpdatePassword(id, password)
+	if err != nil {
+		if errors.Is(err, storage.ErrPostgresUserNotFound) {
+			log.Error("user with given id not found", "error", err)
+
+			return fmt.Errorf("%s: %w", operation, ErrUserNotFound)
+		}
+
+		log.Error("failed to update password", "error", err)
+
+		return fmt.Errorf("%s: %w", operation, err)
+	}
+
+	log.Info("password updated")
+
+	return nil
+}
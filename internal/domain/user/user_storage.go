@@ -5,7 +5,9 @@ import (
 )
 
 type UserStorage interface {
-	Create(email domain.Email, password domain.Password) (domain.UserId, error)
-	GetByEmail(email domain.Email) (*User, error)
-	ExistsByEmail(email domain.Email) (bool, error)
+	Create(tenantId domain.TenantId, email domain.Email, password domain.Password) (domain.UserId, error)
+	GetByEmail(tenantId domain.TenantId, email domain.Email) (*User, error)
+	GetById(id domain.UserId) (*User, error)
+	ExistsByEmail(tenantId domain.TenantId, email domain.Email) (bool, error)
+	UpdatePassword(id domain.UserId, password domain.Password) error
 }
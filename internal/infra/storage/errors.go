@@ -3,7 +3,25 @@ package storage
 import "errors"
 
 var (
-	ErrPostgresUserNotFound = errors.New("user not found")
+	ErrPostgresUserNotFound   = errors.New("user not found")
+	ErrPostgresRoleNotFound   = errors.New("role not found")
+	ErrPostgresTenantNotFound = errors.New("tenant not found")
+
+	ErrPostgresTotpNotFound         = errors.New("totp not found")
+	ErrPostgresTotpAlreadyConfirmed = errors.New("totp is already confirmed")
+
+	ErrPostgresPasskeyNotFound      = errors.New("passkey not found")
+	ErrPostgresPasskeyAlreadyExists = errors.New("passkey already exists")
+
+	ErrPostgresIdentityNotFound      = errors.New("identity not found")
+	ErrPostgresIdentityAlreadyExists = errors.New("identity already exists")
+
+	ErrPostgresOAuthClientNotFound      = errors.New("oauth client not found")
+	ErrPostgresOAuthClientAlreadyExists = errors.New("oauth client already exists")
+	ErrPostgresConsentNotFound          = errors.New("consent not found")
+
+	ErrPostgresServiceAccountNotFound      = errors.New("service account not found")
+	ErrPostgresServiceAccountAlreadyExists = errors.New("service account already exists")
 
 	ErrRedisKeyNotFound = errors.New("key not found")
 )
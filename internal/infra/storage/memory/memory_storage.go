@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStorage is a process local stand-in for RedisStorage.
+// It is meant for local development and a single instance deployments only
+type MemoryStorage struct {
+	mu    sync.Mutex
+	items map[string]item
+}
+
+type item struct {
+	value    string
+	expireAt time.Time
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{items: make(map[string]item)}
+}
+
+func (ms *MemoryStorage) Set(key, value string, exp time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.items[key] = newItem(value, exp)
+
+	return nil
+}
+
+func (ms *MemoryStorage) SetIfNotExists(key, value string, exp time.Duration) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, ok := ms.get(key); ok {
+		return false, nil
+	}
+
+	ms.items[key] = newItem(value, exp)
+
+	return true, nil
+}
+
+func (ms *MemoryStorage) Get(key string) (string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	it, ok := ms.get(key)
+	if !ok {
+		return "", storage.ErrRedisKeyNotFound
+	}
+
+	return it.value, nil
+}
+
+func (ms *MemoryStorage) Delete(key string) (bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	_, ok := ms.get(key)
+	delete(ms.items, key)
+
+	return ok, nil
+}
+
+func (ms *MemoryStorage) Increment(key string, exp time.Duration) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	it, ok := ms.get(key)
+	if !ok {
+		it = newItem("0", exp)
+	}
+
+	value, err := strconv.ParseInt(it.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	value++
+
+	it.value = strconv.FormatInt(value, 10)
+	ms.items[key] = it
+
+	return value, nil
+}
+
+// get returns not expired item, expired one is removed. Must be called with mu held
+func (ms *MemoryStorage) get(key string) (item, bool) {
+	it, ok := ms.items[key]
+	if !ok {
+		return item{}, false
+	}
+
+	if !it.expireAt.IsZero() && time.Now().After(it.expireAt) {
+		delete(ms.items, key)
+		return item{}, false
+	}
+
+	return it, true
+}
+
+func newItem(value string, exp time.Duration) item {
+	it := item{value: value}
+	if exp > 0 {
+		it.expireAt = time.Now().Add(exp)
+	}
+	return it
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// RevocationListStorage is a process local stand-in for the redis revocation list storage
+type RevocationListStorage struct {
+	mu      sync.Mutex
+	entries []revocationListEntry
+}
+
+type revocationListEntry struct {
+	value     string
+	revokedAt time.Time
+}
+
+func NewRevocationListStorage() *RevocationListStorage {
+	return &RevocationListStorage{}
+}
+
+func (rs *RevocationListStorage) Add(entry string, revokedAt time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	i := sort.Search(len(rs.entries), func(i int) bool {
+		return rs.entries[i].revokedAt.After(revokedAt)
+	})
+
+	rs.entries = append(rs.entries, revocationListEntry{})
+	copy(rs.entries[i+1:], rs.entries[i:])
+	rs.entries[i] = revocationListEntry{value: entry, revokedAt: revokedAt}
+
+	return nil
+}
+
+func (rs *RevocationListStorage) Since(since time.Time) ([]string, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	i := sort.Search(len(rs.entries), func(i int) bool {
+		return !rs.entries[i].revokedAt.Before(since)
+	})
+
+	values := make([]string, 0, len(rs.entries)-i)
+	for _, entry := range rs.entries[i:] {
+		values = append(values, entry.value)
+	}
+
+	return values, nil
+}
+
+func (rs *RevocationListStorage) RemoveBefore(before time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	i := sort.Search(len(rs.entries), func(i int) bool {
+		return !rs.entries[i].revokedAt.Before(before)
+	})
+
+	rs.entries = append(rs.entries[:0], rs.entries[i:]...)
+
+	return nil
+}
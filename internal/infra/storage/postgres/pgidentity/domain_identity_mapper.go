@@ -0,0 +1,26 @@
+package pgidentity
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/identity"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+func toDomainIdentity(pgIdentity *Identity) *identity.Identity {
+	return &identity.Identity{
+		Id:        pgIdentity.Id,
+		UserId:    domain.UserId(pgIdentity.UserId),
+		TenantId:  domain.TenantId(pgIdentity.TenantId),
+		Provider:  pgIdentity.Provider,
+		Subject:   pgIdentity.Subject,
+		Email:     pgIdentity.Email,
+		CreatedAt: pgIdentity.CreatedAt,
+	}
+}
+
+func toDomainIdentities(pgIdentities []*Identity) []*identity.Identity {
+	domainIdentities := make([]*identity.Identity, len(pgIdentities))
+	for i, pgIdentity := range pgIdentities {
+		domainIdentities[i] = toDomainIdentity(pgIdentity)
+	}
+	return domainIdentities
+}
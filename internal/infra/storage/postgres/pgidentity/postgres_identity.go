@@ -0,0 +1,15 @@
+package pgidentity
+
+import (
+	"time"
+)
+
+type Identity struct {
+	Id        int64     `db:"id"`
+	UserId    int64     `db:"user_id"`
+	TenantId  int64     `db:"tenant_id"`
+	Provider  string    `db:"provider"`
+	Subject   string    `db:"subject"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
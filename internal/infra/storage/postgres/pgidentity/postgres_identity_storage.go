@@ -0,0 +1,101 @@
+package pgidentity
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vaberof/auth-grpc/internal/domain/identity"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+// postgres error codes
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type PgIdentityStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgIdentityStorage(db *sqlx.DB) *PgIdentityStorage {
+	return &PgIdentityStorage{
+		db: db,
+	}
+}
+
+func (is *PgIdentityStorage) Create(domainIdentity *identity.Identity) error {
+	query := `
+			INSERT INTO user_identities(
+			                            user_id,
+			                            tenant_id,
+			                            provider,
+			                            subject,
+			                            email
+			) VALUES ($1, $2, $3, $4, $5)
+			RETURNING id, created_at
+	`
+
+	err := is.db.QueryRow(
+		query,
+		domainIdentity.UserId,
+		domainIdentity.TenantId,
+		domainIdentity.Provider,
+		domainIdentity.Subject,
+		domainIdentity.Email,
+	).Scan(&domainIdentity.Id, &domainIdentity.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case foreignKeyViolation:
+				return storage.ErrPostgresUserNotFound
+			case uniqueViolation:
+				return storage.ErrPostgresIdentityAlreadyExists
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (is *PgIdentityStorage) GetByProviderSubject(tenantId domain.TenantId, provider string, subject string) (*identity.Identity, error) {
+	query := `
+			SELECT id, user_id, tenant_id, provider, subject, email, created_at
+			FROM user_identities
+			WHERE tenant_id=$1 AND provider=$2 AND subject=$3
+	`
+
+	var pgIdentity Identity
+
+	err := is.db.Get(&pgIdentity, query, tenantId, provider, subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainIdentity(&pgIdentity), nil
+}
+
+func (is *PgIdentityStorage) ListByUserId(userId domain.UserId) ([]*identity.Identity, error) {
+	query := `
+			SELECT id, user_id, tenant_id, provider, subject, email, created_at
+			FROM user_identities
+			WHERE user_id=$1
+			ORDER BY created_at
+	`
+
+	var pgIdentities []*Identity
+
+	err := is.db.Select(&pgIdentities, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainIdentities(pgIdentities), nil
+}
@@ -0,0 +1,14 @@
+package pgmfa
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/mfa"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+func toDomainTotp(pgTotp *Totp) *mfa.Totp {
+	return &mfa.Totp{
+		UserId:          domain.UserId(pgTotp.UserId),
+		EncryptedSecret: pgTotp.EncryptedSecret,
+		Confirmed:       pgTotp.Confirmed,
+	}
+}
@@ -0,0 +1,160 @@
+package pgmfa
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vaberof/auth-grpc/internal/domain/mfa"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+// foreignKeyViolation is the postgres error code returned when a referenced row does not exist
+const foreignKeyViolation = "23503"
+
+type PgMfaStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgMfaStorage(db *sqlx.DB) *PgMfaStorage {
+	return &PgMfaStorage{
+		db: db,
+	}
+}
+
+func (ms *PgMfaStorage) SaveTotp(userId domain.UserId, encryptedSecret []byte) error {
+	query := `
+			INSERT INTO user_totp(
+			                      user_id,
+			                      encrypted_secret
+			) VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE
+			SET encrypted_secret=EXCLUDED.encrypted_secret, last_used_step=NULL, created_at=NOW()
+			WHERE user_totp.confirmed=FALSE
+	`
+
+	result, err := ms.db.Exec(query, userId, encryptedSecret)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return storage.ErrPostgresUserNotFound
+		}
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrPostgresTotpAlreadyConfirmed
+	}
+
+	return nil
+}
+
+func (ms *PgMfaStorage) GetTotp(userId domain.UserId) (*mfa.Totp, error) {
+	query := `
+			SELECT user_id, encrypted_secret, confirmed FROM user_totp
+			WHERE user_id=$1
+	`
+
+	var pgTotp Totp
+
+	err := ms.db.Get(&pgTotp, query, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresTotpNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainTotp(&pgTotp), nil
+}
+
+func (ms *PgMfaStorage) ConfirmTotp(userId domain.UserId, step int64, recoveryCodeHashes []string) error {
+	confirmQuery := `
+			UPDATE user_totp
+			SET confirmed=TRUE, last_used_step=$2
+			WHERE user_id=$1 AND confirmed=FALSE
+	`
+
+	deleteQuery := `
+			DELETE FROM user_recovery_codes
+			WHERE user_id=$1
+	`
+
+	insertQuery := `
+			INSERT INTO user_recovery_codes(
+			                                user_id,
+			                                code_hash
+			) VALUES ($1, $2)
+	`
+
+	tx, err := ms.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(confirmQuery, userId, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrPostgresTotpAlreadyConfirmed
+	}
+
+	_, err = tx.Exec(deleteQuery, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, recoveryCodeHash := range recoveryCodeHashes {
+		_, err = tx.Exec(insertQuery, userId, recoveryCodeHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (ms *PgMfaStorage) UseTotpStep(userId domain.UserId, step int64) (bool, error) {
+	query := `
+			UPDATE user_totp
+			SET last_used_step=$2
+			WHERE user_id=$1 AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+
+	return ms.execAffected(query, userId, step)
+}
+
+func (ms *PgMfaStorage) UseRecoveryCode(userId domain.UserId, recoveryCodeHash string) (bool, error) {
+	query := `
+			DELETE FROM user_recovery_codes
+			WHERE user_id=$1 AND code_hash=$2
+	`
+
+	return ms.execAffected(query, userId, recoveryCodeHash)
+}
+
+func (ms *PgMfaStorage) execAffected(query string, args ...interface{}) (bool, error) {
+	result, err := ms.db.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
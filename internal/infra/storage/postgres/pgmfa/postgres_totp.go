@@ -0,0 +1,7 @@
+package pgmfa
+
+type Totp struct {
+	UserId          int64  `db:"user_id"`
+	EncryptedSecret []byte `db:"encrypted_secret"`
+	Confirmed       bool   `db:"confirmed"`
+}
@@ -0,0 +1,29 @@
+package pgoauth
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/oauth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+func toDomainClient(pgClient *Client) *oauth.Client {
+	return &oauth.Client{
+		Id:           pgClient.Id,
+		ClientId:     pgClient.ClientId,
+		TenantId:     domain.TenantId(pgClient.TenantId),
+		Name:         pgClient.Name,
+		SecretHash:   pgClient.SecretHash.String,
+		RedirectUris: pgClient.RedirectUris,
+		GrantTypes:   pgClient.GrantTypes,
+		Scopes:       pgClient.Scopes,
+		CreatedAt:    pgClient.CreatedAt,
+	}
+}
+
+func toDomainConsent(pgConsent *Consent) *oauth.Consent {
+	return &oauth.Consent{
+		UserId:    domain.UserId(pgConsent.UserId),
+		ClientId:  pgConsent.ClientId,
+		Scopes:    pgConsent.Scopes,
+		GrantedAt: pgConsent.GrantedAt,
+	}
+}
@@ -0,0 +1,26 @@
+package pgoauth
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Client struct {
+	Id           int64          `db:"id"`
+	ClientId     string         `db:"client_id"`
+	TenantId     int64          `db:"tenant_id"`
+	Name         string         `db:"name"`
+	SecretHash   sql.NullString `db:"secret_hash"`
+	RedirectUris pq.StringArray `db:"redirect_uris"`
+	GrantTypes   pq.StringArray `db:"grant_types"`
+	Scopes       pq.StringArray `db:"scopes"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+type Consent struct {
+	UserId    int64          `db:"user_id"`
+	ClientId  string         `db:"client_id"`
+	Scopes    pq.StringArray `db:"scopes"`
+	GrantedAt time.Time      `db:"granted_at"`
+}
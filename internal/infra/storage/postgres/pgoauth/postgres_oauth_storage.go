@@ -0,0 +1,138 @@
+package pgoauth
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vaberof/auth-grpc/internal/domain/oauth"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+// postgres error codes
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// foreign key constraints of oauth_consents
+const consentUserForeignKey = "oauth_consents_user_id_fkey"
+
+type PgOAuthStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgOAuthStorage(db *sqlx.DB) *PgOAuthStorage {
+	return &PgOAuthStorage{
+		db: db,
+	}
+}
+
+func (oas *PgOAuthStorage) CreateClient(domainClient *oauth.Client) error {
+	query := `
+			INSERT INTO oauth_clients(
+			                          client_id,
+			                          tenant_id,
+			                          name,
+			                          secret_hash,
+			                          redirect_uris,
+			                          grant_types,
+			                          scopes
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at
+	`
+
+	secretHash := sql.NullString{String: domainClient.SecretHash, Valid: domainClient.SecretHash != ""}
+
+	err := oas.db.QueryRow(
+		query,
+		domainClient.ClientId,
+		domainClient.TenantId,
+		domainClient.Name,
+		secretHash,
+		pq.StringArray(domainClient.RedirectUris),
+		pq.StringArray(domainClient.GrantTypes),
+		pq.StringArray(domainClient.Scopes),
+	).Scan(&domainClient.Id, &domainClient.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case foreignKeyViolation:
+				return storage.ErrPostgresTenantNotFound
+			case uniqueViolation:
+				return storage.ErrPostgresOAuthClientAlreadyExists
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (oas *PgOAuthStorage) GetClientByClientId(clientId string) (*oauth.Client, error) {
+	query := `
+			SELECT id, client_id, tenant_id, name, secret_hash, redirect_uris, grant_types, scopes, created_at
+			FROM oauth_clients
+			WHERE client_id=$1
+	`
+
+	var pgClient Client
+
+	err := oas.db.Get(&pgClient, query, clientId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresOAuthClientNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainClient(&pgClient), nil
+}
+
+func (oas *PgOAuthStorage) SaveConsent(userId domain.UserId, clientId string, scopes []string) error {
+	query := `
+			INSERT INTO oauth_consents(
+			                           user_id,
+			                           client_id,
+			                           scopes
+			) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, client_id) DO UPDATE
+			SET scopes=ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes)), granted_at=NOW()
+	`
+
+	_, err := oas.db.Exec(query, userId, clientId, pq.StringArray(scopes))
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			if pqErr.Constraint == consentUserForeignKey {
+				return storage.ErrPostgresUserNotFound
+			}
+			return storage.ErrPostgresOAuthClientNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (oas *PgOAuthStorage) GetConsent(userId domain.UserId, clientId string) (*oauth.Consent, error) {
+	query := `
+			SELECT user_id, client_id, scopes, granted_at
+			FROM oauth_consents
+			WHERE user_id=$1 AND client_id=$2
+	`
+
+	var pgConsent Consent
+
+	err := oas.db.Get(&pgConsent, query, userId, clientId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresConsentNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainConsent(&pgConsent), nil
+}
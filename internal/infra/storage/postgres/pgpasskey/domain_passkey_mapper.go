@@ -0,0 +1,34 @@
+package pgpasskey
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/passkey"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+func toDomainPasskey(pgPasskey *Passkey) *passkey.Passkey {
+	domainPasskey := &passkey.Passkey{
+		Id:                pgPasskey.Id,
+		UserId:            domain.UserId(pgPasskey.UserId),
+		Name:              pgPasskey.Name,
+		PublicKey:         pgPasskey.PublicKey,
+		SignCount:         uint32(pgPasskey.SignCount),
+		Aaguid:            pgPasskey.Aaguid,
+		Transports:        pgPasskey.Transports,
+		AttestationFormat: pgPasskey.AttestationFormat,
+		CreatedAt:         pgPasskey.CreatedAt,
+	}
+
+	if pgPasskey.LastUsedAt.Valid {
+		domainPasskey.LastUsedAt = &pgPasskey.LastUsedAt.Time
+	}
+
+	return domainPasskey
+}
+
+func toDomainPasskeys(pgPasskeys []*Passkey) []*passkey.Passkey {
+	domainPasskeys := make([]*passkey.Passkey, len(pgPasskeys))
+	for i, pgPasskey := range pgPasskeys {
+		domainPasskeys[i] = toDomainPasskey(pgPasskey)
+	}
+	return domainPasskeys
+}
@@ -0,0 +1,20 @@
+package pgpasskey
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+type Passkey struct {
+	Id                []byte         `db:"id"`
+	UserId            int64          `db:"user_id"`
+	Name              string         `db:"name"`
+	PublicKey         []byte         `db:"public_key"`
+	SignCount         int64          `db:"sign_count"`
+	Aaguid            []byte         `db:"aaguid"`
+	Transports        pq.StringArray `db:"transports"`
+	AttestationFormat string         `db:"attestation_format"`
+	CreatedAt         time.Time      `db:"created_at"`
+	LastUsedAt        sql.NullTime   `db:"last_used_at"`
+}
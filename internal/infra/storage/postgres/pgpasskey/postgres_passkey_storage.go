@@ -0,0 +1,127 @@
+package pgpasskey
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vaberof/auth-grpc/internal/domain/passkey"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+// postgres error codes
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type PgPasskeyStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgPasskeyStorage(db *sqlx.DB) *PgPasskeyStorage {
+	return &PgPasskeyStorage{
+		db: db,
+	}
+}
+
+func (ps *PgPasskeyStorage) Create(domainPasskey *passkey.Passkey) error {
+	query := `
+			INSERT INTO passkeys(
+			                     id,
+			                     user_id,
+			                     name,
+			                     public_key,
+			                     sign_count,
+			                     aaguid,
+			                     transports,
+			                     attestation_format
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING created_at
+	`
+
+	err := ps.db.QueryRow(
+		query,
+		domainPasskey.Id,
+		domainPasskey.UserId,
+		domainPasskey.Name,
+		domainPasskey.PublicKey,
+		int64(domainPasskey.SignCount),
+		domainPasskey.Aaguid,
+		pq.StringArray(domainPasskey.Transports),
+		domainPasskey.AttestationFormat,
+	).Scan(&domainPasskey.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case foreignKeyViolation:
+				return storage.ErrPostgresUserNotFound
+			case uniqueViolation:
+				return storage.ErrPostgresPasskeyAlreadyExists
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (ps *PgPasskeyStorage) GetById(id []byte) (*passkey.Passkey, error) {
+	query := `
+			SELECT id, user_id, name, public_key, sign_count, aaguid, transports, attestation_format, created_at, last_used_at
+			FROM passkeys
+			WHERE id=$1
+	`
+
+	var pgPasskey Passkey
+
+	err := ps.db.Get(&pgPasskey, query, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresPasskeyNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainPasskey(&pgPasskey), nil
+}
+
+func (ps *PgPasskeyStorage) ListByUserId(userId domain.UserId) ([]*passkey.Passkey, error) {
+	query := `
+			SELECT id, user_id, name, public_key, sign_count, aaguid, transports, attestation_format, created_at, last_used_at
+			FROM passkeys
+			WHERE user_id=$1
+			ORDER BY created_at
+	`
+
+	var pgPasskeys []*Passkey
+
+	err := ps.db.Select(&pgPasskeys, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainPasskeys(pgPasskeys), nil
+}
+
+func (ps *PgPasskeyStorage) UpdateSignCount(id []byte, signCount uint32) (bool, error) {
+	query := `
+			UPDATE passkeys
+			SET sign_count=$2, last_used_at=NOW()
+			WHERE id=$1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))
+	`
+
+	result, err := ps.db.Exec(query, id, int64(signCount))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAffected > 0, nil
+}
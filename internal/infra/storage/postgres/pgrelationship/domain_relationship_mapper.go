@@ -0,0 +1,22 @@
+package pgrelationship
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+)
+
+func toDomainRelationship(pgRelationship *Relationship) rebac.Relationship {
+	return rebac.Relationship{
+		Resource: rebac.Object{
+			Type: pgRelationship.ResourceType,
+			Id:   pgRelationship.ResourceId,
+		},
+		Relation: pgRelationship.Relation,
+		Subject: rebac.Subject{
+			Object: rebac.Object{
+				Type: pgRelationship.SubjectType,
+				Id:   pgRelationship.SubjectId,
+			},
+			Relation: pgRelationship.SubjectRelation,
+		},
+	}
+}
@@ -0,0 +1,10 @@
+package pgrelationship
+
+type Relationship struct {
+	ResourceType    string `db:"resource_type"`
+	ResourceId      string `db:"resource_id"`
+	Relation        string `db:"relation"`
+	SubjectType     string `db:"subject_type"`
+	SubjectId       string `db:"subject_id"`
+	SubjectRelation string `db:"subject_relation"`
+}
@@ -0,0 +1,116 @@
+package pgrelationship
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+)
+
+// PgRelationshipStorage is rebac.TupleStore keeping relationships in 'relationships' table
+type PgRelationshipStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgRelationshipStorage(db *sqlx.DB) *PgRelationshipStorage {
+	return &PgRelationshipStorage{
+		db: db,
+	}
+}
+
+func (rs *PgRelationshipStorage) WriteRelationships(writes []rebac.Relationship, deletes []rebac.Relationship) error {
+	insertQuery := `
+			INSERT INTO relationships(
+			                          resource_type,
+			                          resource_id,
+			                          relation,
+			                          subject_type,
+			                          subject_id,
+			                          subject_relation
+			) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT DO NOTHING
+	`
+
+	deleteQuery := `
+			DELETE FROM relationships
+			WHERE resource_type=$1 AND resource_id=$2 AND relation=$3
+			  AND subject_type=$4 AND subject_id=$5 AND subject_relation=$6
+	`
+
+	tx, err := rs.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, relationship := range writes {
+		_, err = tx.Exec(insertQuery, relationshipArgs(relationship)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, relationship := range deletes {
+		_, err = tx.Exec(deleteQuery, relationshipArgs(relationship)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (rs *PgRelationshipStorage) ReadSubjects(resource rebac.Object, relation string) ([]rebac.Subject, error) {
+	query := `
+			SELECT resource_type, resource_id, relation, subject_type, subject_id, subject_relation
+			FROM relationships
+			WHERE resource_type=$1 AND resource_id=$2 AND relation=$3
+	`
+
+	rows, err := rs.db.Queryx(query, resource.Type, resource.Id, relation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subjects []rebac.Subject
+
+	for rows.Next() {
+		var pgRelationship Relationship
+
+		err = rows.StructScan(&pgRelationship)
+		if err != nil {
+			return nil, err
+		}
+
+		subjects = append(subjects, toDomainRelationship(&pgRelationship).Subject)
+	}
+
+	return subjects, rows.Err()
+}
+
+func (rs *PgRelationshipStorage) ListResourceIds(resourceType string) ([]string, error) {
+	query := `
+			SELECT DISTINCT resource_id FROM relationships
+			WHERE resource_type=$1
+			ORDER BY resource_id
+	`
+
+	var ids []string
+
+	err := rs.db.Select(&ids, query, resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func relationshipArgs(relationship rebac.Relationship) []interface{} {
+	return []interface{}{
+		relationship.Resource.Type,
+		relationship.Resource.Id,
+		relationship.Relation,
+		relationship.Subject.Type,
+		relationship.Subject.Id,
+		relationship.Subject.Relation,
+	}
+}
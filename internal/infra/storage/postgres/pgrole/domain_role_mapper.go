@@ -0,0 +1,21 @@
+package pgrole
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+)
+
+func toDomainRole(pgRole *Role) *role.Role {
+	return &role.Role{
+		Name:        pgRole.Name,
+		Description: pgRole.Description,
+		Permissions: pgRole.Permissions,
+	}
+}
+
+func toDomainRoles(pgRoles []*Role) []*role.Role {
+	roles := make([]*role.Role, len(pgRoles))
+	for i, pgRole := range pgRoles {
+		roles[i] = toDomainRole(pgRole)
+	}
+	return roles
+}
@@ -0,0 +1,9 @@
+package pgrole
+
+import "github.com/lib/pq"
+
+type Role struct {
+	Name        string         `db:"name"`
+	Description string         `db:"description"`
+	Permissions pq.StringArray `db:"permissions"`
+}
@@ -0,0 +1,131 @@
+package pgrole
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vaberof/auth-grpc/internal/domain/role"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+// foreignKeyViolation is the postgres error code returned when a referenced row does not exist
+const foreignKeyViolation = "23503"
+
+type PgRoleStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgRoleStorage(db *sqlx.DB) *PgRoleStorage {
+	return &PgRoleStorage{
+		db: db,
+	}
+}
+
+func (rs *PgRoleStorage) AssignRole(userId domain.UserId, roleName string) error {
+	roleId, err := rs.getRoleId(roleName)
+	if err != nil {
+		return err
+	}
+
+	query := `
+			INSERT INTO user_roles(
+			                       user_id,
+			                       role_id
+			) VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+	`
+
+	_, err = rs.db.Exec(query, userId, roleId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return storage.ErrPostgresUserNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (rs *PgRoleStorage) RevokeRole(userId domain.UserId, roleName string) error {
+	roleId, err := rs.getRoleId(roleName)
+	if err != nil {
+		return err
+	}
+
+	query := `
+			DELETE FROM user_roles
+			WHERE user_id=$1 AND role_id=$2
+	`
+
+	_, err = rs.db.Exec(query, userId, roleId)
+
+	return err
+}
+
+func (rs *PgRoleStorage) ListRoles() ([]*role.Role, error) {
+	query := `
+			SELECT roles.name,
+			       roles.description,
+			       ARRAY_REMOVE(ARRAY_AGG(permissions.name ORDER BY permissions.name), NULL) AS permissions
+			FROM roles
+			LEFT JOIN role_permissions ON role_permissions.role_id = roles.id
+			LEFT JOIN permissions ON permissions.id = role_permissions.permission_id
+			GROUP BY roles.id
+			ORDER BY roles.name
+	`
+
+	var pgRoles []*Role
+
+	err := rs.db.Select(&pgRoles, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainRoles(pgRoles), nil
+}
+
+func (rs *PgRoleStorage) ListUserRoles(userId domain.UserId) ([]*role.Role, error) {
+	query := `
+			SELECT roles.name,
+			       roles.description,
+			       ARRAY_REMOVE(ARRAY_AGG(permissions.name ORDER BY permissions.name), NULL) AS permissions
+			FROM user_roles
+			JOIN roles ON roles.id = user_roles.role_id
+			LEFT JOIN role_permissions ON role_permissions.role_id = roles.id
+			LEFT JOIN permissions ON permissions.id = role_permissions.permission_id
+			WHERE user_roles.user_id=$1
+			GROUP BY roles.id
+			ORDER BY roles.name
+	`
+
+	var pgRoles []*Role
+
+	err := rs.db.Select(&pgRoles, query, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainRoles(pgRoles), nil
+}
+
+func (rs *PgRoleStorage) getRoleId(roleName string) (int64, error) {
+	query := `
+			SELECT id FROM roles
+			WHERE name=$1
+	`
+
+	var roleId int64
+
+	err := rs.db.QueryRow(query, roleName).Scan(&roleId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, storage.ErrPostgresRoleNotFound
+		}
+		return 0, err
+	}
+
+	return roleId, nil
+}
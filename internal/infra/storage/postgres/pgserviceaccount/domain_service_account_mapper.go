@@ -0,0 +1,32 @@
+package pgserviceaccount
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/serviceaccount"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+func toDomainServiceAccount(pgServiceAccount *ServiceAccount) *serviceaccount.ServiceAccount {
+	return &serviceaccount.ServiceAccount{
+		Id:         pgServiceAccount.Id,
+		ClientId:   pgServiceAccount.ClientId,
+		TenantId:   domain.TenantId(pgServiceAccount.TenantId),
+		Name:       pgServiceAccount.Name,
+		Scopes:     pgServiceAccount.Scopes,
+		CreatedAt:  pgServiceAccount.CreatedAt,
+		DisabledAt: pgServiceAccount.DisabledAt.Time,
+	}
+}
+
+func toDomainSecrets(pgSecrets []*Secret) []*serviceaccount.Secret {
+	domainSecrets := make([]*serviceaccount.Secret, len(pgSecrets))
+	for i, pgSecret := range pgSecrets {
+		domainSecrets[i] = &serviceaccount.Secret{
+			Id:               pgSecret.Id,
+			ServiceAccountId: pgSecret.ServiceAccountId,
+			SecretHash:       pgSecret.SecretHash,
+			CreatedAt:        pgSecret.CreatedAt,
+			ExpiresAt:        pgSecret.ExpiresAt.Time,
+		}
+	}
+	return domainSecrets
+}
@@ -0,0 +1,25 @@
+package pgserviceaccount
+
+import (
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+type ServiceAccount struct {
+	Id         int64          `db:"id"`
+	ClientId   string         `db:"client_id"`
+	TenantId   int64          `db:"tenant_id"`
+	Name       string         `db:"name"`
+	Scopes     pq.StringArray `db:"scopes"`
+	CreatedAt  time.Time      `db:"created_at"`
+	DisabledAt sql.NullTime   `db:"disabled_at"`
+}
+
+type Secret struct {
+	Id               int64        `db:"id"`
+	ServiceAccountId int64        `db:"service_account_id"`
+	SecretHash       string       `db:"secret_hash"`
+	CreatedAt        time.Time    `db:"created_at"`
+	ExpiresAt        sql.NullTime `db:"expires_at"`
+}
@@ -0,0 +1,203 @@
+package pgserviceaccount
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/vaberof/auth-grpc/internal/domain/serviceaccount"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"time"
+)
+
+// postgres error codes
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+type PgServiceAccountStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgServiceAccountStorage(db *sqlx.DB) *PgServiceAccountStorage {
+	return &PgServiceAccountStorage{
+		db: db,
+	}
+}
+
+func (sas *PgServiceAccountStorage) Create(domainServiceAccount *serviceaccount.ServiceAccount, secretHash string) error {
+	tx, err := sas.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+			INSERT INTO service_accounts(
+			                             client_id,
+			                             tenant_id,
+			                             name,
+			                             scopes
+			) VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(
+		query,
+		domainServiceAccount.ClientId,
+		domainServiceAccount.TenantId,
+		domainServiceAccount.Name,
+		pq.StringArray(domainServiceAccount.Scopes),
+	).Scan(&domainServiceAccount.Id, &domainServiceAccount.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case foreignKeyViolation:
+				return storage.ErrPostgresTenantNotFound
+			case uniqueViolation:
+				return storage.ErrPostgresServiceAccountAlreadyExists
+			}
+		}
+		return err
+	}
+
+	err = insertSecret(tx, domainServiceAccount.Id, secretHash)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (sas *PgServiceAccountStorage) GetByClientId(clientId string) (*serviceaccount.ServiceAccount, error) {
+	query := `
+			SELECT id, client_id, tenant_id, name, scopes, created_at, disabled_at
+			FROM service_accounts
+			WHERE client_id=$1
+	`
+
+	var pgServiceAccount ServiceAccount
+
+	err := sas.db.Get(&pgServiceAccount, query, clientId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresServiceAccountNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainServiceAccount(&pgServiceAccount), nil
+}
+
+func (sas *PgServiceAccountStorage) ListActiveSecrets(serviceAccountId int64) ([]*serviceaccount.Secret, error) {
+	query := `
+			SELECT id, service_account_id, secret_hash, created_at, expires_at
+			FROM service_account_secrets
+			WHERE service_account_id=$1 AND (expires_at IS NULL OR expires_at > NOW())
+			ORDER BY created_at DESC
+	`
+
+	var pgSecrets []*Secret
+
+	err := sas.db.Select(&pgSecrets, query, serviceAccountId)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainSecrets(pgSecrets), nil
+}
+
+func (sas *PgServiceAccountStorage) RotateSecret(serviceAccountId int64, secretHash string, overlap time.Duration) error {
+	tx, err := sas.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// secrets that already expire earlier than the overlap keep their expiration
+	query := `
+			UPDATE service_account_secrets
+			SET expires_at=LEAST(COALESCE(expires_at, 'infinity'), NOW() + make_interval(secs => $2))
+			WHERE service_account_id=$1 AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	_, err = tx.Exec(query, serviceAccountId, overlap.Seconds())
+	if err != nil {
+		return err
+	}
+
+	err = insertSecret(tx, serviceAccountId, secretHash)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Disable keeps the time the service account was disabled first
+func (sas *PgServiceAccountStorage) Disable(serviceAccountId int64) error {
+	query := `
+			UPDATE service_accounts
+			SET disabled_at=COALESCE(disabled_at, NOW())
+			WHERE id=$1
+	`
+
+	result, err := sas.db.Exec(query, serviceAccountId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrPostgresServiceAccountNotFound
+	}
+
+	return nil
+}
+
+func (sas *PgServiceAccountStorage) Delete(serviceAccountId int64) error {
+	query := `
+			DELETE FROM service_accounts
+			WHERE id=$1
+	`
+
+	result, err := sas.db.Exec(query, serviceAccountId)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return storage.ErrPostgresServiceAccountNotFound
+	}
+
+	return nil
+}
+
+func insertSecret(tx *sqlx.Tx, serviceAccountId int64, secretHash string) error {
+	query := `
+			INSERT INTO service_account_secrets(
+			                                    service_account_id,
+			                                    secret_hash
+			) VALUES ($1, $2)
+	`
+
+	_, err := tx.Exec(query, serviceAccountId, secretHash)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return storage.ErrPostgresServiceAccountNotFound
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package pgsigningkey
+
+import (
+	"github.com/vaberof/auth-grpc/internal/domain/signingkey"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+)
+
+func toDomainSigningKeys(pgSigningKeys []*SigningKey) []*signingkey.SigningKey {
+	domainSigningKeys := make([]*signingkey.SigningKey, len(pgSigningKeys))
+	for i, pgSigningKey := range pgSigningKeys {
+		domainSigningKeys[i] = &signingkey.SigningKey{
+			Id:                  pgSigningKey.Id,
+			Algorithm:           accesstoken.Algorithm(pgSigningKey.Algorithm),
+			EncryptedPrivateKey: pgSigningKey.EncryptedPrivateKey,
+			ActivateAt:          pgSigningKey.ActivateAt,
+			RetireAt:            pgSigningKey.RetireAt.Time,
+		}
+	}
+	return domainSigningKeys
+}
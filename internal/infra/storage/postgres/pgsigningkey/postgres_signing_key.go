@@ -0,0 +1,14 @@
+package pgsigningkey
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SigningKey struct {
+	Id                  string       `db:"id"`
+	Algorithm           string       `db:"algorithm"`
+	EncryptedPrivateKey []byte       `db:"encrypted_private_key"`
+	ActivateAt          time.Time    `db:"activate_at"`
+	RetireAt            sql.NullTime `db:"retire_at"`
+}
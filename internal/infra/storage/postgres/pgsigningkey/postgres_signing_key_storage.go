@@ -0,0 +1,77 @@
+package pgsigningkey
+
+import (
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/vaberof/auth-grpc/internal/domain/signingkey"
+)
+
+type PgSigningKeyStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgSigningKeyStorage(db *sqlx.DB) *PgSigningKeyStorage {
+	return &PgSigningKeyStorage{
+		db: db,
+	}
+}
+
+func (sks *PgSigningKeyStorage) List() ([]*signingkey.SigningKey, error) {
+	query := `
+			SELECT id, algorithm, encrypted_private_key, activate_at, retire_at
+			FROM signing_keys
+			ORDER BY activate_at
+	`
+
+	var pgSigningKeys []*SigningKey
+
+	err := sks.db.Select(&pgSigningKeys, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDomainSigningKeys(pgSigningKeys), nil
+}
+
+func (sks *PgSigningKeyStorage) Save(domainSigningKeys []*signingkey.SigningKey) error {
+	tx, err := sks.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// columns are TIMESTAMP without time zone, so times are stored in UTC.
+	// A nil private key is sent as an empty bytea, it is turned into NULL to keep the stored one
+	query := `
+			INSERT INTO signing_keys(
+			                         id,
+			                         algorithm,
+			                         encrypted_private_key,
+			                         activate_at,
+			                         retire_at
+			) VALUES ($1, $2, NULLIF($3::BYTEA, ''::BYTEA), $4, $5)
+			ON CONFLICT (id) DO UPDATE
+			SET activate_at=EXCLUDED.activate_at,
+			    retire_at=EXCLUDED.retire_at,
+			    encrypted_private_key=COALESCE(EXCLUDED.encrypted_private_key, signing_keys.encrypted_private_key),
+			    updated_at=NOW()
+	`
+
+	for _, domainSigningKey := range domainSigningKeys {
+		retireAt := sql.NullTime{Time: domainSigningKey.RetireAt.UTC(), Valid: !domainSigningKey.RetireAt.IsZero()}
+
+		_, err = tx.Exec(
+			query,
+			domainSigningKey.Id,
+			string(domainSigningKey.Algorithm),
+			domainSigningKey.EncryptedPrivateKey,
+			domainSigningKey.ActivateAt.UTC(),
+			retireAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
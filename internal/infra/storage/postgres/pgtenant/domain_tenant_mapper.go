@@ -0,0 +1,34 @@
+package pgtenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vaberof/auth-grpc/internal/domain/tenant"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"time"
+)
+
+func toDomainTenant(pgTenant *Tenant) (*tenant.Tenant, error) {
+	domainTenant := &tenant.Tenant{
+		Id:   domain.TenantId(pgTenant.Id),
+		Name: pgTenant.Name,
+		Settings: tenant.Settings{
+			TokenTtl:        time.Duration(pgTenant.TokenTtlSeconds.Int64) * time.Second,
+			RefreshTokenTtl: time.Duration(pgTenant.RefreshTokenTtlSeconds.Int64) * time.Second,
+		},
+	}
+
+	if pgTenant.PasswordPolicy.Valid {
+		var passwordPolicy passwordpolicy.Config
+
+		err := json.Unmarshal([]byte(pgTenant.PasswordPolicy.String), &passwordPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid password policy of tenant %d: %w", pgTenant.Id, err)
+		}
+
+		domainTenant.Settings.PasswordPolicy = &passwordPolicy
+	}
+
+	return domainTenant, nil
+}
@@ -0,0 +1,11 @@
+package pgtenant
+
+import "database/sql"
+
+type Tenant struct {
+	Id                     int64          `db:"id"`
+	Name                   string         `db:"name"`
+	TokenTtlSeconds        sql.NullInt64  `db:"token_ttl_seconds"`
+	RefreshTokenTtlSeconds sql.NullInt64  `db:"refresh_token_ttl_seconds"`
+	PasswordPolicy         sql.NullString `db:"password_policy"`
+}
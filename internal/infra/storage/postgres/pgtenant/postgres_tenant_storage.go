@@ -0,0 +1,52 @@
+package pgtenant
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/vaberof/auth-grpc/internal/domain/tenant"
+	"github.com/vaberof/auth-grpc/internal/infra/storage"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+)
+
+type PgTenantStorage struct {
+	db *sqlx.DB
+}
+
+func NewPgTenantStorage(db *sqlx.DB) *PgTenantStorage {
+	return &PgTenantStorage{
+		db: db,
+	}
+}
+
+func (ts *PgTenantStorage) GetById(id domain.TenantId) (*tenant.Tenant, error) {
+	query := `
+			SELECT id, name, token_ttl_seconds, refresh_token_ttl_seconds, password_policy FROM tenants
+			WHERE id=$1
+	`
+
+	return ts.get(query, id)
+}
+
+func (ts *PgTenantStorage) GetByName(name string) (*tenant.Tenant, error) {
+	query := `
+			SELECT id, name, token_ttl_seconds, refresh_token_ttl_seconds, password_policy FROM tenants
+			WHERE name=$1
+	`
+
+	return ts.get(query, name)
+}
+
+func (ts *PgTenantStorage) get(query string, args ...interface{}) (*tenant.Tenant, error) {
+	var pgTenant Tenant
+
+	err := ts.db.Get(&pgTenant, query, args...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresTenantNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainTenant(&pgTenant)
+}
@@ -8,6 +8,7 @@ import (
 func toDomainUser(pgUser *User) *user.User {
 	return &user.User{
 		Id:       domain.UserId(pgUser.Id),
+		TenantId: domain.TenantId(pgUser.TenantId),
 		Email:    domain.Email(pgUser.Email),
 		Password: domain.Password(pgUser.Password),
 	}
@@ -2,6 +2,7 @@ package pguser
 
 type User struct {
 	Id       int64
+	TenantId int64
 	Email    string
 	Password string
 }
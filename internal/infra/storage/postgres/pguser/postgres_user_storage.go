@@ -19,16 +19,17 @@ func NewPgUserStorage(db *sqlx.DB) *PgUserStorage {
 	}
 }
 
-func (us *PgUserStorage) Create(email domain.Email, password domain.Password) (domain.UserId, error) {
+func (us *PgUserStorage) Create(tenantId domain.TenantId, email domain.Email, password domain.Password) (domain.UserId, error) {
 	query := `
 			INSERT INTO users(
+			                  tenant_id,
 			                  email,
 			                  password
-			) VALUES ($1, $2)
+			) VALUES ($1, $2, $3)
 			RETURNING id
 	`
 
-	row := us.db.QueryRow(query, email.String(), password.String())
+	row := us.db.QueryRow(query, tenantId, email.String(), password.String())
 
 	var uid int64
 
@@ -40,18 +41,19 @@ func (us *PgUserStorage) Create(email domain.Email, password domain.Password) (d
 	return domain.UserId(uid), nil
 }
 
-func (us *PgUserStorage) GetByEmail(email domain.Email) (*user.User, error) {
+func (us *PgUserStorage) GetByEmail(tenantId domain.TenantId, email domain.Email) (*user.User, error) {
 	query := `
-			SELECT * FROM users
-			WHERE email=$1
+			SELECT id, tenant_id, email, password FROM users
+			WHERE tenant_id=$1 AND email=$2
 	`
 
-	row := us.db.QueryRow(query, email)
+	row := us.db.QueryRow(query, tenantId, email)
 
 	var pgUser User
 
 	err := row.Scan(
 		&pgUser.Id,
+		&pgUser.TenantId,
 		&pgUser.Email,
 		&pgUser.Password,
 	)
@@ -65,15 +67,41 @@ func (us *PgUserStorage) GetByEmail(email domain.Email) (*user.User, error) {
 	return toDomainUser(&pgUser), nil
 }
 
-func (us *PgUserStorage) ExistsByEmail(email domain.Email) (bool, error) {
+func (us *PgUserStorage) GetById(id domain.UserId) (*user.User, error) {
+	query := `
+			SELECT id, tenant_id, email, password FROM users
+			WHERE id=$1
+	`
+
+	row := us.db.QueryRow(query, id)
+
+	var pgUser User
+
+	err := row.Scan(
+		&pgUser.Id,
+		&pgUser.TenantId,
+		&pgUser.Email,
+		&pgUser.Password,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, storage.ErrPostgresUserNotFound
+		}
+		return nil, err
+	}
+
+	return toDomainUser(&pgUser), nil
+}
+
+func (us *PgUserStorage) ExistsByEmail(tenantId domain.TenantId, email domain.Email) (bool, error) {
 	query := `
 			SELECT id FROM users
-			WHERE email=$1
+			WHERE tenant_id=$1 AND email=$2
 	`
 
 	var uid int64
 
-	err := us.db.QueryRow(query, email).Scan(&uid)
+	err := us.db.QueryRow(query, tenantId, email).Scan(&uid)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
@@ -83,3 +111,27 @@ func (us *PgUserStorage) ExistsByEmail(email domain.Email) (bool, error) {
 
 	return true, nil
 }
+
+func (us *PgUserStorage) UpdatePassword(id domain.UserId, password domain.Password) error {
+	query := `
+			UPDATE users
+			SET password=$1
+			WHERE id=$2
+	`
+
+	result, err := us.db.Exec(query, password.String(), id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return storage.ErrPostgresUserNotFound
+	}
+
+	return nil
+}
@@ -24,6 +24,14 @@ func (rs *RedisStorage) Set(key, value string, exp time.Duration) error {
 	return nil
 }
 
+func (rs *RedisStorage) SetIfNotExists(key, value string, exp time.Duration) (bool, error) {
+	ok, err := rs.client.SetNX(context.Background(), key, value, exp).Result()
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
 func (rs *RedisStorage) Get(key string) (string, error) {
 	val, err := rs.client.Get(context.Background(), key).Result()
 	if err != nil {
@@ -34,3 +42,28 @@ func (rs *RedisStorage) Get(key string) (string, error) {
 	}
 	return val, nil
 }
+
+func (rs *RedisStorage) Delete(key string) (bool, error) {
+	deleted, err := rs.client.Del(context.Background(), key).Result()
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+// incrementScript sets the expiration atomically with the first increment, so a counter can not be left without it
+var incrementScript = redis.NewScript(`
+local value = redis.call('INCR', KEYS[1])
+if value == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return value
+`)
+
+func (rs *RedisStorage) Increment(key string, exp time.Duration) (int64, error) {
+	value, err := incrementScript.Run(context.Background(), rs.client, []string{key}, exp.Milliseconds()).Int64()
+	if err != nil {
+		return 0, err
+	}
+	return value, nil
+}
@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+const revocationListKey = "revocation_list"
+
+// RevocationListStorage keeps revocation entries in a sorted set scored by revocation time in nanoseconds
+type RevocationListStorage struct {
+	client *redis.Client
+}
+
+func NewRevocationListStorage(client *redis.Client) *RevocationListStorage {
+	return &RevocationListStorage{client: client}
+}
+
+func (rs *RevocationListStorage) Add(entry string, revokedAt time.Time) error {
+	return rs.client.ZAdd(context.Background(), revocationListKey, redis.Z{
+		Score:  float64(revokedAt.UnixNano()),
+		Member: entry,
+	}).Err()
+}
+
+func (rs *RevocationListStorage) Since(since time.Time) ([]string, error) {
+	return rs.client.ZRangeByScore(context.Background(), revocationListKey, &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixNano(), 10),
+		Max: "+inf",
+	}).Result()
+}
+
+func (rs *RevocationListStorage) RemoveBefore(before time.Time) error {
+	return rs.client.ZRemRangeByScore(context.Background(), revocationListKey,
+		"-inf", "("+strconv.FormatInt(before.UnixNano(), 10)).Err()
+}
@@ -0,0 +1,5 @@
+DELETE FROM permissions
+WHERE name = 'service_accounts.manage';
+
+DROP TABLE IF EXISTS service_account_secrets;
+DROP TABLE IF EXISTS service_accounts;
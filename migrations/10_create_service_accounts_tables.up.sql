@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS service_accounts
+(
+    id         SERIAL       PRIMARY KEY,
+    -- public identifier sent along with the secret to issue service tokens
+    client_id  VARCHAR(64)  NOT NULL UNIQUE,
+    tenant_id  INTEGER      NOT NULL REFERENCES tenants (id),
+    name       VARCHAR(128) NOT NULL,
+    scopes     TEXT[]       NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS service_account_secrets
+(
+    id                 SERIAL       PRIMARY KEY,
+    service_account_id INTEGER      NOT NULL REFERENCES service_accounts (id) ON DELETE CASCADE,
+    -- password hash of the secret
+    secret_hash        VARCHAR(255) NOT NULL,
+    created_at         TIMESTAMP    NOT NULL DEFAULT NOW(),
+    -- NULL for the current secret, set for previous secrets when the secret is rotated
+    expires_at         TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS service_account_secrets_service_account_id_idx ON service_account_secrets (service_account_id);
+
+INSERT INTO permissions (name)
+VALUES ('service_accounts.manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles,
+     permissions
+WHERE roles.name = 'admin'
+  AND permissions.name = 'service_accounts.manage'
+ON CONFLICT DO NOTHING;
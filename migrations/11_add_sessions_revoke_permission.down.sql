@@ -0,0 +1,2 @@
+DELETE FROM permissions
+WHERE name = 'sessions.revoke';
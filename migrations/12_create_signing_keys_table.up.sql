@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS signing_keys
+(
+    id                    VARCHAR(64) PRIMARY KEY,
+    algorithm             VARCHAR(16) NOT NULL,
+    -- private key encrypted with SIGNING_KEY_ENCRYPTION_KEY, NULL for keys of the configuration
+    encrypted_private_key BYTEA,
+    activate_at           TIMESTAMP   NOT NULL,
+    retire_at             TIMESTAMP,
+    updated_at            TIMESTAMP   NOT NULL DEFAULT NOW()
+);
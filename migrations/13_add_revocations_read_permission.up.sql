@@ -0,0 +1,11 @@
+INSERT INTO permissions (name)
+VALUES ('revocations.read')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles,
+     permissions
+WHERE roles.name = 'admin'
+  AND permissions.name = 'revocations.read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+ALTER TABLE service_accounts
+    DROP COLUMN IF EXISTS disabled_at;
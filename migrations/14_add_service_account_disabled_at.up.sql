@@ -0,0 +1,3 @@
+ALTER TABLE service_accounts
+    -- disabled service accounts can not issue tokens, NULL for active ones
+    ADD COLUMN IF NOT EXISTS disabled_at TIMESTAMP;
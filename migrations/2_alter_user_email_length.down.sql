@@ -0,0 +1 @@
+ALTER TABLE users ALTER COLUMN email TYPE VARCHAR(50);
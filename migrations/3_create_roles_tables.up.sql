@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS roles
+(
+    id          SERIAL PRIMARY KEY,
+    name        VARCHAR(64)  NOT NULL UNIQUE,
+    description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions
+(
+    id   SERIAL PRIMARY KEY,
+    name VARCHAR(128) NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions
+(
+    role_id       INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles
+(
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, role_id)
+);
+CREATE INDEX IF NOT EXISTS user_roles_role_id_idx ON user_roles (role_id);
+
+INSERT INTO roles (name, description)
+VALUES ('admin', 'Manages roles of users and signing keys')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name)
+VALUES ('roles.read'),
+       ('roles.assign'),
+       ('signing_keys.rotate')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles,
+     permissions
+WHERE roles.name = 'admin'
+ON CONFLICT DO NOTHING;
+
+-- the first admin has to be granted manually:
+-- INSERT INTO user_roles (user_id, role_id) SELECT <user id>, id FROM roles WHERE name = 'admin';
@@ -0,0 +1,4 @@
+DELETE FROM permissions
+WHERE name IN ('relationships.read', 'relationships.write');
+
+DROP TABLE IF EXISTS relationships;
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS relationships
+(
+    resource_type    VARCHAR(64)  NOT NULL,
+    resource_id      VARCHAR(128) NOT NULL,
+    relation         VARCHAR(64)  NOT NULL,
+    subject_type     VARCHAR(64)  NOT NULL,
+    subject_id       VARCHAR(128) NOT NULL,
+    -- empty if the subject is an object, not a set of objects having the relation with it
+    subject_relation VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at       TIMESTAMP    NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (resource_type, resource_id, relation, subject_type, subject_id, subject_relation)
+);
+CREATE INDEX IF NOT EXISTS relationships_subject_idx ON relationships (subject_type, subject_id, subject_relation);
+
+INSERT INTO permissions (name)
+VALUES ('relationships.read'),
+       ('relationships.write')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles,
+     permissions
+WHERE roles.name = 'admin'
+  AND permissions.name IN ('relationships.read', 'relationships.write')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS tenants
+(
+    id                        SERIAL PRIMARY KEY,
+    name                      VARCHAR(64) NOT NULL UNIQUE,
+    -- settings overriding app.auth-service config, NULL keeps the service setting
+    token_ttl_seconds         INTEGER CHECK (token_ttl_seconds > 0),
+    refresh_token_ttl_seconds INTEGER CHECK (refresh_token_ttl_seconds > 0),
+    -- e.g. {"min_length": 12, "min_character_classes": 3, "min_strength_score": 3}
+    password_policy           JSONB
+);
+
+-- existing users and requests without x-tenant-id metadata belong to the default tenant
+INSERT INTO tenants (id, name)
+VALUES (1, 'default')
+ON CONFLICT (id) DO NOTHING;
+SELECT setval('tenants_id_seq', (SELECT MAX(id) FROM tenants));
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id INTEGER NOT NULL DEFAULT 1 REFERENCES tenants (id);
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
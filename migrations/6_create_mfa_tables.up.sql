@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS user_totp
+(
+    user_id          INTEGER PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    -- AES-256-GCM encrypted base32 secret, see pkg/xcipher
+    encrypted_secret BYTEA     NOT NULL,
+    confirmed        BOOLEAN   NOT NULL DEFAULT FALSE,
+    -- the last accepted time step, codes of it and earlier steps are rejected
+    last_used_step   BIGINT,
+    created_at       TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes
+(
+    user_id   INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    -- SHA-256 hex of the normalized code
+    code_hash VARCHAR(64) NOT NULL,
+    PRIMARY KEY (user_id, code_hash)
+);
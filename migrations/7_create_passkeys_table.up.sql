@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS passkeys
+(
+    -- credential id chosen by the authenticator, at most 1023 bytes
+    id                 BYTEA        PRIMARY KEY,
+    user_id            INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name               VARCHAR(64)  NOT NULL,
+    -- CBOR encoded COSE_Key, see pkg/webauthn
+    public_key         BYTEA        NOT NULL,
+    sign_count         BIGINT       NOT NULL DEFAULT 0,
+    aaguid             BYTEA,
+    transports         TEXT[]       NOT NULL DEFAULT '{}',
+    attestation_format VARCHAR(32)  NOT NULL,
+    created_at         TIMESTAMP    NOT NULL DEFAULT NOW(),
+    last_used_at       TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    id         SERIAL       PRIMARY KEY,
+    user_id    INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    tenant_id  INTEGER      NOT NULL REFERENCES tenants (id),
+    -- name of the provider in app.auth-service.identity-providers
+    provider   VARCHAR(64)  NOT NULL,
+    -- account id within the provider, 'sub' claim of ID tokens
+    subject    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP    NOT NULL DEFAULT NOW(),
+    UNIQUE (tenant_id, provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
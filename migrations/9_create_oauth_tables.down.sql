@@ -0,0 +1,5 @@
+DELETE FROM permissions
+WHERE name = 'oauth_clients.manage';
+
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS oauth_clients
+(
+    id            SERIAL       PRIMARY KEY,
+    -- public identifier sent by the client in OAuth 2.0 requests
+    client_id     VARCHAR(64)  NOT NULL UNIQUE,
+    tenant_id     INTEGER      NOT NULL REFERENCES tenants (id),
+    name          VARCHAR(128) NOT NULL,
+    -- SHA-256 hex of the client secret, NULL for public clients
+    secret_hash   VARCHAR(64),
+    redirect_uris TEXT[]       NOT NULL DEFAULT '{}',
+    grant_types   TEXT[]       NOT NULL DEFAULT '{}',
+    scopes        TEXT[]       NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMP    NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS oauth_consents
+(
+    user_id    INTEGER     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id  VARCHAR(64) NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    scopes     TEXT[]      NOT NULL DEFAULT '{}',
+    granted_at TIMESTAMP   NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, client_id)
+);
+CREATE INDEX IF NOT EXISTS oauth_consents_client_id_idx ON oauth_consents (client_id);
+
+INSERT INTO permissions (name)
+VALUES ('oauth_clients.manage')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT roles.id, permissions.id
+FROM roles,
+     permissions
+WHERE roles.name = 'admin'
+  AND permissions.name = 'oauth_clients.manage'
+ON CONFLICT DO NOTHING;
@@ -5,6 +5,8 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/vaberof/auth-grpc/pkg/auth"
 	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"slices"
 	"strconv"
 	"time"
 )
@@ -13,79 +15,262 @@ var (
 	ErrInvalidToken         = errors.New("token is invalid")
 	ErrInvalidSigningMethod = errors.New("signing method is invalid")
 	ErrExpiredToken         = errors.New("token has expired")
+	ErrInvalidIssuer        = errors.New("token issuer is invalid")
+	ErrInvalidAudience      = errors.New("token audience is invalid")
+	ErrInvalidTokenUse      = errors.New("token use is invalid")
 )
 
-type SecretKey string
+const tokenIdLength = 16
 
-// Create returns JWT-token signed with specified secret key and
-// stores UserId, ExpireAt and IssuedAt in jwt payload
-func Create(userId domain.UserId, ttl time.Duration, secretKey SecretKey) (string, error) {
-	payload := auth.NewPayload(userId, ttl)
+type SecretKey string
 
-	jwtWithClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    payload.UserId.String(),
-		IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
-		ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
-	})
+// VerifyOptions configure validation of registered claims. Issuer and Audience are not checked if empty,
+// a token is accepted if it is intended for at least one of the audiences. TokenUses are the accepted uses
+// of 'token_use' claim, only tokens of the gRPC API are accepted if empty, so OAuth tokens of third-party
+// clients are not mistaken for them.
+// Legacy tokens storing user id in 'iss' claim instead of 'sub' are accepted until LegacyTokensAcceptedUntil
+type VerifyOptions struct {
+	Issuer                    string
+	Audience                  []string
+	TokenUses                 []string
+	Leeway                    time.Duration
+	LegacyTokensAcceptedUntil time.Time
+}
 
-	token, err := jwtWithClaims.SignedString([]byte(secretKey))
+// Create returns JWT-token signed with specified key and stores the payload in it.
+// Unique token id is generated if payload has no one
+func Create(payload *auth.JwtPayload, key *Key) (string, error) {
+	token, _, err := CreateWithExpirationTime(payload, key)
 
 	return token, err
 }
 
 // CreateWithExpirationTime is the same as Create, but additionally returns token expiration
-func CreateWithExpirationTime(userId domain.UserId, ttl time.Duration, secretKey SecretKey) (string, time.Time, error) {
-	payload := auth.NewPayload(userId, ttl)
+func CreateWithExpirationTime(payload *auth.JwtPayload, key *Key) (string, time.Time, error) {
+	if !key.CanSign() {
+		return "", time.Time{}, ErrVerificationOnlyKey
+	}
+
+	if payload.TokenId == "" {
+		tokenId, err := xrand.GenerateRandomToken(tokenIdLength)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		payload.TokenId = tokenId
+	}
 
-	jwtWithClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    payload.UserId.String(),
-		IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
-		ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+	subject := payload.UserId.String()
+	if payload.UserId == 0 && payload.ClientId != "" {
+		subject = payload.ClientId
+	}
+
+	jwtWithClaims := jwt.NewWithClaims(key.signingMethod(), &claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        payload.TokenId,
+			Subject:   subject,
+			Issuer:    payload.Issuer,
+			Audience:  payload.Audience,
+			IssuedAt:  jwt.NewNumericDate(payload.IssuedAt),
+			NotBefore: jwt.NewNumericDate(payload.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(payload.ExpiredAt),
+		},
+		TenantId:      int64(payload.TenantId),
+		Email:         payload.Email.String(),
+		Roles:         payload.Roles,
+		Permissions:   payload.Permissions,
+		Scope:         joinScopes(payload.Scopes),
+		ClientId:      payload.ClientId,
+		PrincipalType: payload.PrincipalType,
+		TokenUse:      payload.TokenUse,
 	})
+	jwtWithClaims.Header["kid"] = key.Id
 
-	token, err := jwtWithClaims.SignedString([]byte(secretKey))
+	token, err := jwtWithClaims.SignedString(key.signingKey)
 
 	return token, payload.ExpiredAt, err
 }
 
-func Verify(token string, secretKey SecretKey) (*auth.JwtPayload, error) {
-	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, ErrInvalidSigningMethod
-		}
-		return []byte(secretKey), nil
+// Verify checks that the token is signed with a key of the key set selected by 'kid' header,
+// validates its registered claims and returns its payload. Either a single Key or KeyRing may be
+// used as a key set. Options may be nil, then only signature and time claims are checked
+func Verify(token string, keys KeySet, options *VerifyOptions) (*auth.JwtPayload, error) {
+	if options == nil {
+		options = &VerifyOptions{}
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, keyFunc)
+	jwtToken, err := jwt.ParseWithClaims(token, &claims{}, keyFunc(keys),
+		jwt.WithLeeway(options.Leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
-		return nil, err
+		return nil, toVerifyError(err)
 	}
 
-	claims, ok := jwtToken.Claims.(*jwt.RegisteredClaims)
-	if !ok {
+	tokenClaims, ok := jwtToken.Claims.(*claims)
+	if !ok || tokenClaims.IssuedAt == nil {
 		return nil, ErrInvalidToken
 	}
 
-	if hasExpired(claims.ExpiresAt.Time) {
-		return nil, ErrExpiredToken
+	var userId domain.UserId
+
+	if tokenClaims.Subject == "" {
+		userId, err = legacyUserId(tokenClaims, options)
+		if err != nil {
+			return nil, err
+		}
+	} else if tokenClaims.ClientId != "" && tokenClaims.Subject == tokenClaims.ClientId {
+		// a token of a service account or of the client credentials grant
+		err = validateIssuerAndAudience(tokenClaims, options)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		userId, err = parseUserId(tokenClaims.Subject)
+		if err != nil {
+			return nil, err
+		}
+
+		err = validateIssuerAndAudience(tokenClaims, options)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	uid, err := strconv.Atoi(claims.Issuer)
+	tokenUse, err := validateTokenUse(tokenClaims, options)
 	if err != nil {
 		return nil, err
 	}
 
+	// tokens issued before tenants were introduced belong to the default tenant
+	tenantId := domain.TenantId(tokenClaims.TenantId)
+	if tenantId == 0 {
+		tenantId = domain.DefaultTenantId
+	}
+
+	principalType := tokenClaims.PrincipalType
+	if principalType == "" {
+		principalType = auth.PrincipalTypeUser
+		if userId == 0 {
+			principalType = auth.PrincipalTypeService
+		}
+	}
+
 	payload := &auth.JwtPayload{
-		UserId:    domain.UserId(uid),
-		IssuedAt:  claims.IssuedAt.Time,
-		ExpiredAt: claims.ExpiresAt.Time,
+		TokenId:       tokenClaims.ID,
+		PrincipalType: principalType,
+		TokenUse:      tokenUse,
+		UserId:        userId,
+		TenantId:      tenantId,
+		Email:         domain.Email(tokenClaims.Email),
+		Roles:         tokenClaims.Roles,
+		Permissions:   tokenClaims.Permissions,
+		Scopes:        splitScopes(tokenClaims.Scope),
+		ClientId:      tokenClaims.ClientId,
+		Issuer:        tokenClaims.Issuer,
+		Audience:      tokenClaims.Audience,
+		IssuedAt:      tokenClaims.IssuedAt.Time,
+		ExpiredAt:     tokenClaims.ExpiresAt.Time,
 	}
 
 	return payload, nil
 }
 
-func hasExpired(expireTime time.Time) bool {
-	currentTime := time.Now().UTC()
-	return currentTime.After(expireTime.UTC())
+// VerifyClaims checks that a token of any issuer is signed with a key of the key set selected by 'kid' header
+// and parses its claims into tokenClaims. Registered claims are validated as configured by the parser options,
+// expiration time is always required
+func VerifyClaims(token string, keys KeySet, tokenClaims jwt.Claims, options ...jwt.ParserOption) error {
+	options = append(options, jwt.WithExpirationRequired())
+
+	_, err := jwt.ParseWithClaims(token, tokenClaims, keyFunc(keys), options...)
+	if err != nil {
+		return toVerifyError(err)
+	}
+	return nil
+}
+
+// keyFunc selects the verification key by 'kid' header, the token must be signed with the algorithm of the key
+func keyFunc(keys KeySet) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := keys.LookupKey(kid)
+		if err != nil {
+			return nil, ErrInvalidToken
+		}
+
+		if token.Method.Alg() != string(key.Algorithm) {
+			return nil, ErrInvalidSigningMethod
+		}
+
+		return key.verificationKey, nil
+	}
+}
+
+func validateIssuerAndAudience(tokenClaims *claims, options *VerifyOptions) error {
+	if options.Issuer != "" && tokenClaims.Issuer != options.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if len(options.Audience) == 0 {
+		return nil
+	}
+
+	for _, audience := range tokenClaims.Audience {
+		if slices.Contains(options.Audience, audience) {
+			return nil
+		}
+	}
+
+	return ErrInvalidAudience
+}
+
+// validateTokenUse returns the use of the token. Tokens issued before 'token_use' claim was introduced
+// are tokens of the gRPC API, unlike ID tokens they have 'jti' claim unless they are legacy tokens without 'sub'
+func validateTokenUse(tokenClaims *claims, options *VerifyOptions) (string, error) {
+	tokenUse := tokenClaims.TokenUse
+	if tokenUse == "" {
+		if tokenClaims.ID == "" && tokenClaims.Subject != "" {
+			return "", ErrInvalidTokenUse
+		}
+		tokenUse = auth.TokenUseAccess
+	}
+
+	tokenUses := options.TokenUses
+	if len(tokenUses) == 0 {
+		tokenUses = []string{auth.TokenUseAccess}
+	}
+
+	if !slices.Contains(tokenUses, tokenUse) {
+		return "", ErrInvalidTokenUse
+	}
+	return tokenUse, nil
+}
+
+// legacyUserId returns user id stored in 'iss' claim by the previous versions of Create
+func legacyUserId(tokenClaims *claims, options *VerifyOptions) (domain.UserId, error) {
+	if time.Now().After(options.LegacyTokensAcceptedUntil) {
+		return 0, ErrInvalidToken
+	}
+	return parseUserId(tokenClaims.Issuer)
+}
+
+func parseUserId(value string) (domain.UserId, error) {
+	uid, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return domain.UserId(uid), nil
+}
+
+// toVerifyError converts errors of the jwt library to the errors of this package
+func toVerifyError(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidSigningMethod):
+		return ErrInvalidSigningMethod
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return ErrExpiredToken
+	default:
+		return ErrInvalidToken
+	}
 }
@@ -0,0 +1,298 @@
+package accesstoken_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "api"
+	testSecret   = "0123456789abcdef0123456789abcdef"
+)
+
+func testVerifyOptions() *accesstoken.VerifyOptions {
+	return &accesstoken.VerifyOptions{Issuer: testIssuer, Audience: []string{testAudience}}
+}
+
+func testPayload() *auth.JwtPayload {
+	payload := auth.NewPayload(42, time.Hour)
+	payload.TenantId = domain.DefaultTenantId
+	payload.Email = "user@example.com"
+	payload.Issuer = testIssuer
+	payload.Audience = []string{testAudience}
+	return payload
+}
+
+// signClaims signs arbitrary claims, so tokens which Create never issues can be verified
+func signClaims(t *testing.T, method jwt.SigningMethod, signingKey interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(signingKey)
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"jti":       "token-id",
+		"sub":       "42",
+		"iss":       testIssuer,
+		"aud":       testAudience,
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Hour).Unix(),
+		"token_use": auth.TokenUseAccess,
+	}
+}
+
+func TestCreateAndVerify(t *testing.T) {
+	for _, algorithm := range []accesstoken.Algorithm{
+		accesstoken.AlgorithmHS256,
+		accesstoken.AlgorithmRS256,
+		accesstoken.AlgorithmES256,
+		accesstoken.AlgorithmEdDSA,
+	} {
+		t.Run(string(algorithm), func(t *testing.T) {
+			key, err := accesstoken.GenerateKey("key", algorithm)
+			if err != nil {
+				t.Fatalf("GenerateKey() error = %v", err)
+			}
+
+			token, err := accesstoken.Create(testPayload(), key)
+			if err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+
+			payload, err := accesstoken.Verify(token, key, testVerifyOptions())
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+
+			if payload.UserId != 42 || payload.TenantId != domain.DefaultTenantId || payload.TokenId == "" ||
+				payload.TokenUse != auth.TokenUseAccess || payload.PrincipalType != auth.PrincipalTypeUser {
+				t.Errorf("Verify() payload = %+v", payload)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsAlgorithmNotMatchingKey(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	key, err := accesstoken.NewPrivateKey("es256", accesstoken.AlgorithmES256, ecdsaKey)
+	if err != nil {
+		t.Fatalf("NewPrivateKey() error = %v", err)
+	}
+
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(&ecdsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+	publicKeyPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer})
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// a public key must not be usable as a secret of HS256
+		{name: "HS256 signed with the public key", token: signClaims(t, jwt.SigningMethodHS256, publicKeyPem, key.Id, validClaims())},
+		{name: "ES384", token: es384Token(t, key.Id)},
+		{name: "none", token: signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, key.Id, validClaims())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := accesstoken.Verify(tt.token, key, testVerifyOptions())
+			if !errors.Is(err, accesstoken.ErrInvalidSigningMethod) {
+				t.Errorf("Verify() error = %v, want %v", err, accesstoken.ErrInvalidSigningMethod)
+			}
+		})
+	}
+}
+
+// es384Token returns a token signed with ES384, so its algorithm does not match ES256 key of the same id
+func es384Token(t *testing.T, kid string) string {
+	t.Helper()
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	return signClaims(t, jwt.SigningMethodES384, ecdsaKey, kid, validClaims())
+}
+
+func TestVerifyClaims(t *testing.T) {
+	key := accesstoken.NewSecretKey("key", testSecret)
+
+	withClaims := func(update func(claims jwt.MapClaims)) jwt.MapClaims {
+		claims := validClaims()
+		update(claims)
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		kid     string
+		claims  jwt.MapClaims
+		secret  string
+		wantErr error
+	}{
+		{name: "valid", kid: "key", claims: validClaims(), secret: testSecret},
+		{name: "other secret", kid: "key", claims: validClaims(), secret: "other-secret", wantErr: accesstoken.ErrInvalidToken},
+		{name: "unknown kid", kid: "unknown", claims: validClaims(), secret: testSecret, wantErr: accesstoken.ErrInvalidToken},
+		{name: "issuer mismatch", kid: "key", claims: withClaims(func(c jwt.MapClaims) { c["iss"] = "https://other.example.com" }), secret: testSecret, wantErr: accesstoken.ErrInvalidIssuer},
+		{name: "audience mismatch", kid: "key", claims: withClaims(func(c jwt.MapClaims) { c["aud"] = "other" }), secret: testSecret, wantErr: accesstoken.ErrInvalidAudience},
+		{name: "one of audiences", kid: "key", claims: withClaims(func(c jwt.MapClaims) { c["aud"] = []string{"other", testAudience} }), secret: testSecret},
+		{name: "missing exp", kid: "key", claims: withClaims(func(c jwt.MapClaims) { delete(c, "exp") }), secret: testSecret, wantErr: accesstoken.ErrInvalidToken},
+		{name: "missing iat", kid: "key", claims: withClaims(func(c jwt.MapClaims) { delete(c, "iat") }), secret: testSecret, wantErr: accesstoken.ErrInvalidToken},
+		{name: "expired", kid: "key", claims: withClaims(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), secret: testSecret, wantErr: accesstoken.ErrExpiredToken},
+		{name: "issued in the future", kid: "key", claims: withClaims(func(c jwt.MapClaims) { c["iat"] = time.Now().Add(time.Hour).Unix() }), secret: testSecret, wantErr: accesstoken.ErrInvalidToken},
+		{name: "not a user id", kid: "key", claims: withClaims(func(c jwt.MapClaims) { c["sub"] = "user" }), secret: testSecret, wantErr: accesstoken.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signClaims(t, jwt.SigningMethodHS256, []byte(tt.secret), tt.kid, tt.claims)
+
+			_, err := accesstoken.Verify(token, key, testVerifyOptions())
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyLegacyTokens(t *testing.T) {
+	key := accesstoken.NewSecretKey("key", testSecret)
+
+	// previous versions stored user id in 'iss' claim and issued tokens without 'kid' header, 'sub' and 'aud'
+	now := time.Now()
+	token := signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), "", jwt.MapClaims{
+		"iss": "42",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+
+	options := testVerifyOptions()
+	options.LegacyTokensAcceptedUntil = now.Add(time.Hour)
+
+	payload, err := accesstoken.Verify(token, key, options)
+	if err != nil {
+		t.Fatalf("Verify() before LegacyTokensAcceptedUntil error = %v", err)
+	}
+	if payload.UserId != 42 || payload.TenantId != domain.DefaultTenantId || payload.TokenUse != auth.TokenUseAccess {
+		t.Errorf("Verify() payload = %+v", payload)
+	}
+
+	options.LegacyTokensAcceptedUntil = now.Add(-time.Second)
+
+	_, err = accesstoken.Verify(token, key, options)
+	if !errors.Is(err, accesstoken.ErrInvalidToken) {
+		t.Errorf("Verify() after LegacyTokensAcceptedUntil error = %v, want %v", err, accesstoken.ErrInvalidToken)
+	}
+
+	_, err = accesstoken.Verify(token, key, testVerifyOptions())
+	if !errors.Is(err, accesstoken.ErrInvalidToken) {
+		t.Errorf("Verify() without LegacyTokensAcceptedUntil error = %v, want %v", err, accesstoken.ErrInvalidToken)
+	}
+}
+
+func TestVerifyTokenUse(t *testing.T) {
+	key, err := accesstoken.GenerateKey("key", accesstoken.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	idToken, err := accesstoken.CreateIdToken(&accesstoken.IdToken{
+		Issuer:    testIssuer,
+		UserId:    42,
+		ClientId:  testAudience,
+		IssuedAt:  time.Now(),
+		ExpiredAt: time.Now().Add(time.Hour),
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateIdToken() error = %v", err)
+	}
+
+	oauthPayload := testPayload()
+	oauthPayload.TokenUse = auth.TokenUseOAuth
+	oauthPayload.ClientId = "client"
+	oauthToken, err := accesstoken.Create(oauthPayload, key)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	accessToken, err := accesstoken.Create(testPayload(), key)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		tokenUses []string
+		wantErr   error
+	}{
+		{name: "access token", token: accessToken},
+		{name: "id token", token: idToken, wantErr: accesstoken.ErrInvalidTokenUse},
+		{name: "oauth token", token: oauthToken, wantErr: accesstoken.ErrInvalidTokenUse},
+		{name: "oauth token accepted", token: oauthToken, tokenUses: []string{auth.TokenUseOAuth}},
+		{name: "id token of oauth verifier", token: idToken, tokenUses: []string{auth.TokenUseAccess, auth.TokenUseOAuth}, wantErr: accesstoken.ErrInvalidTokenUse},
+		{name: "access token of oauth verifier", token: accessToken, tokenUses: []string{auth.TokenUseOAuth}, wantErr: accesstoken.ErrInvalidTokenUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := testVerifyOptions()
+			options.TokenUses = tt.tokenUses
+
+			_, err := accesstoken.Verify(tt.token, key, options)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenWithoutTokenUse(t *testing.T) {
+	key := accesstoken.NewSecretKey("key", testSecret)
+
+	// tokens issued before 'token_use' claim are access tokens if they have 'jti' claim
+	claims := validClaims()
+	delete(claims, "token_use")
+
+	payload, err := accesstoken.Verify(signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), "key", claims), key, testVerifyOptions())
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if payload.TokenUse != auth.TokenUseAccess {
+		t.Errorf("Verify() token use = %q, want %q", payload.TokenUse, auth.TokenUseAccess)
+	}
+
+	delete(claims, "jti")
+
+	_, err = accesstoken.Verify(signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), "key", claims), key, testVerifyOptions())
+	if !errors.Is(err, accesstoken.ErrInvalidTokenUse) {
+		t.Errorf("Verify() without jti error = %v, want %v", err, accesstoken.ErrInvalidTokenUse)
+	}
+}
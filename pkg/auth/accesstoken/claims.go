@@ -0,0 +1,30 @@
+package accesstoken
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"strings"
+)
+
+// claims are registered JWT claims extended with user identity and authorization data.
+// Scopes are stored space-delimited in 'scope' claim as defined by RFC 9068
+type claims struct {
+	jwt.RegisteredClaims
+	TenantId    int64    `json:"tid,omitempty"`
+	Email       string   `json:"email,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	ClientId    string   `json:"client_id,omitempty"`
+	// PrincipalType is absent in tokens issued before service accounts were introduced
+	PrincipalType string `json:"principal_type,omitempty"`
+	// TokenUse is absent in tokens issued before OAuth tokens were told apart from tokens of the gRPC API
+	TokenUse string `json:"token_use,omitempty"`
+}
+
+func joinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+func splitScopes(scope string) []string {
+	return strings.Fields(scope)
+}
@@ -0,0 +1,66 @@
+package accesstoken
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"time"
+)
+
+var ErrSymmetricIdTokenKey = errors.New("id tokens can not be signed with a symmetric key")
+
+// IdToken holds claims of an OpenID Connect ID token, the audience is the client the token is issued to.
+// Nonce and email claims are omitted if empty
+type IdToken struct {
+	Issuer        string
+	UserId        domain.UserId
+	ClientId      string
+	Nonce         string
+	Email         domain.Email
+	EmailVerified bool
+	IssuedAt      time.Time
+	ExpiredAt     time.Time
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	TokenUse      string `json:"token_use"`
+}
+
+// CreateIdToken returns ID token signed with specified key. Clients verify ID tokens with the published
+// keys, so only asymmetric keys can sign them. Their 'token_use' claim keeps Verify from accepting them
+// as access tokens
+func CreateIdToken(idToken *IdToken, key *Key) (string, error) {
+	if !key.CanSign() {
+		return "", ErrVerificationOnlyKey
+	}
+
+	if key.IsSymmetric() {
+		return "", ErrSymmetricIdTokenKey
+	}
+
+	tokenClaims := &idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   idToken.UserId.String(),
+			Issuer:    idToken.Issuer,
+			Audience:  jwt.ClaimStrings{idToken.ClientId},
+			IssuedAt:  jwt.NewNumericDate(idToken.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(idToken.ExpiredAt),
+		},
+		Nonce:    idToken.Nonce,
+		Email:    idToken.Email.String(),
+		TokenUse: auth.TokenUseId,
+	}
+	if idToken.Email != "" {
+		tokenClaims.EmailVerified = &idToken.EmailVerified
+	}
+
+	jwtWithClaims := jwt.NewWithClaims(key.signingMethod(), tokenClaims)
+	jwtWithClaims.Header["kid"] = key.Id
+
+	return jwtWithClaims.SignedString(key.signingKey)
+}
@@ -0,0 +1,174 @@
+package accesstoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+)
+
+// Jwk is a public key in JSON Web Key format (RFC 7517)
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA public key
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP public keys
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Jwks is a JSON Web Key Set
+type Jwks struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// Jwk returns public part of the key, false is returned for symmetric keys
+func (k *Key) Jwk() (*Jwk, bool) {
+	jwk := &Jwk{
+		Kid: k.Id,
+		Use: "sig",
+		Alg: string(k.Algorithm),
+	}
+
+	switch publicKey := k.verificationKey.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64Url(publicKey.N.Bytes())
+		jwk.E = encodeBase64Url(big.NewInt(int64(publicKey.E)).Bytes())
+	case *ecdsa.PublicKey:
+		byteLen := (publicKey.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Curve.Params().Name
+		jwk.X = encodeBase64Url(publicKey.X.FillBytes(make([]byte, byteLen)))
+		jwk.Y = encodeBase64Url(publicKey.Y.FillBytes(make([]byte, byteLen)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64Url(publicKey)
+	default:
+		return nil, false
+	}
+
+	return jwk, true
+}
+
+// Thumbprint returns JWK thumbprint (RFC 7638) computed with SHA-256
+func (jwk *Jwk) Thumbprint() (string, error) {
+	var members interface{}
+
+	// members are required to be in lexicographic order, struct fields are marshaled in the declared order
+	switch jwk.Kty {
+	case "RSA":
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.Kty, jwk.N}
+	case "EC":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y}
+	case "OKP":
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Crv, jwk.Kty, jwk.X}
+	default:
+		return "", errors.New("unsupported key type")
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+
+	return encodeBase64Url(sum[:]), nil
+}
+
+// Key returns a key which can only verify tokens. Id of the key is taken from 'kid' member,
+// JWK thumbprint is used if it is empty
+func (jwk *Jwk) Key() (*Key, error) {
+	var publicKey crypto.PublicKey
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBase64Url(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBase64Url(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+			return nil, errors.New("invalid RSA public exponent")
+		}
+		publicKey = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	case "EC":
+		if jwk.Crv != elliptic.P256().Params().Name {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		x, err := decodeBase64Url(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBase64Url(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		ecdsaKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !ecdsaKey.Curve.IsOnCurve(ecdsaKey.X, ecdsaKey.Y) {
+			return nil, errors.New("invalid EC public key")
+		}
+		publicKey = ecdsaKey
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, ErrUnsupportedAlgorithm
+		}
+		x, err := decodeBase64Url(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 public key")
+		}
+		publicKey = ed25519.PublicKey(x)
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	return NewPublicKey(jwk.Kid, Algorithm(jwk.Alg), publicKey)
+}
+
+func encodeBase64Url(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeBase64Url(value string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url value")
+	}
+	return data, nil
+}
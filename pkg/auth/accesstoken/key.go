@@ -0,0 +1,262 @@
+package accesstoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"os"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	AlgorithmHS256 Algorithm = "HS256"
+	AlgorithmRS256 Algorithm = "RS256"
+	AlgorithmES256 Algorithm = "ES256"
+	AlgorithmEdDSA Algorithm = "EdDSA"
+)
+
+const defaultSecretKeyId = "default"
+
+const (
+	secretKeyLength = 32
+	rsaKeyBits      = 2048
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+	ErrKeyAlgorithmMismatch = errors.New("key does not match signing algorithm")
+	ErrVerificationOnlyKey  = errors.New("key can only be used for verification")
+)
+
+// Key is used to sign and verify access tokens. Id of the key is put to the 'kid' header of a token.
+// A key created from a public key can only verify tokens
+type Key struct {
+	Id        string
+	Algorithm Algorithm
+
+	signingKey      interface{}
+	verificationKey interface{}
+}
+
+// KeyConfig describes a signing key, PEM encoded private key is required by asymmetric algorithms
+// and may be set either inline or as a path to a file. ActivateAt and RetireAt are used by KeyRing,
+// Legacy marks the key which verifies tokens issued without 'kid' header
+type KeyConfig struct {
+	Id             string    `yaml:"id"`
+	Algorithm      Algorithm `yaml:"algorithm"`
+	SecretKey      SecretKey `yaml:"secret-key"`
+	PrivateKey     string    `yaml:"private-key"`
+	PrivateKeyFile string    `yaml:"private-key-file"`
+	ActivateAt     time.Time `yaml:"activate-at"`
+	RetireAt       time.Time `yaml:"retire-at"`
+	Legacy         bool      `yaml:"legacy"`
+}
+
+// NewKeyFromConfig creates a signing key described by config
+func NewKeyFromConfig(config *KeyConfig) (*Key, error) {
+	if config.Algorithm == AlgorithmHS256 {
+		if config.SecretKey == "" {
+			return nil, errors.New("secret key must be set for HS256 algorithm")
+		}
+		return NewSecretKey(config.Id, config.SecretKey), nil
+	}
+
+	pemData := []byte(config.PrivateKey)
+	if len(pemData) == 0 {
+		if config.PrivateKeyFile == "" {
+			return nil, fmt.Errorf("private key must be set for %s algorithm", config.Algorithm)
+		}
+
+		data, err := os.ReadFile(config.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key file: %w", err)
+		}
+		pemData = data
+	}
+
+	return ParsePrivateKeyPEM(config.Id, config.Algorithm, pemData)
+}
+
+// GenerateKey returns a new random key for the algorithm
+func GenerateKey(id string, algorithm Algorithm) (*Key, error) {
+	var privateKey crypto.Signer
+	var err error
+
+	switch algorithm {
+	case AlgorithmHS256:
+		secretKey := make([]byte, secretKeyLength)
+		_, err = rand.Read(secretKey)
+		if err != nil {
+			return nil, err
+		}
+		return NewSecretKey(id, SecretKey(secretKey)), nil
+	case AlgorithmRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case AlgorithmES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgorithmEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPrivateKey(id, algorithm, privateKey)
+}
+
+// NewSecretKey returns a key for HS256 algorithm
+func NewSecretKey(id string, secretKey SecretKey) *Key {
+	if id == "" {
+		id = defaultSecretKeyId
+	}
+	return &Key{
+		Id:              id,
+		Algorithm:       AlgorithmHS256,
+		signingKey:      []byte(secretKey),
+		verificationKey: []byte(secretKey),
+	}
+}
+
+// NewPrivateKey returns a key for asymmetric algorithm. If id is empty,
+// JWK thumbprint of the public key is used as key id
+func NewPrivateKey(id string, algorithm Algorithm, privateKey crypto.Signer) (*Key, error) {
+	key, err := NewPublicKey(id, algorithm, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+	key.signingKey = privateKey
+	return key, nil
+}
+
+// NewPublicKey returns a key which can only verify tokens signed with asymmetric algorithm
+func NewPublicKey(id string, algorithm Algorithm, publicKey crypto.PublicKey) (*Key, error) {
+	err := checkPublicKey(algorithm, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{
+		Id:              id,
+		Algorithm:       algorithm,
+		verificationKey: publicKey,
+	}
+
+	if key.Id == "" {
+		jwk, _ := key.Jwk()
+		thumbprint, err := jwk.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		key.Id = thumbprint
+	}
+
+	return key, nil
+}
+
+// ParsePrivateKeyPEM parses PKCS#1, SEC 1 or PKCS#8 private key in PEM encoding
+func ParsePrivateKeyPEM(id string, algorithm Algorithm, pemData []byte) (*Key, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("failed to decode PEM private key")
+	}
+
+	var privateKey interface{}
+	var err error
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, ErrKeyAlgorithmMismatch
+	}
+
+	return NewPrivateKey(id, algorithm, signer)
+}
+
+// ParsePrivateKey parses a key encoded with MarshalPrivateKey
+func ParsePrivateKey(id string, algorithm Algorithm, data []byte) (*Key, error) {
+	if algorithm == AlgorithmHS256 {
+		return NewSecretKey(id, SecretKey(data)), nil
+	}
+	return ParsePrivateKeyPEM(id, algorithm, data)
+}
+
+// MarshalPrivateKey encodes the private key, the secret is returned as is for HS256 algorithm
+// and private keys of asymmetric algorithms are encoded as PKCS#8 PEM
+func (k *Key) MarshalPrivateKey() ([]byte, error) {
+	if !k.CanSign() {
+		return nil, ErrVerificationOnlyKey
+	}
+
+	if k.IsSymmetric() {
+		return k.signingKey.([]byte), nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(k.signingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
+
+// CanSign reports whether the key holds a private or a secret key
+func (k *Key) CanSign() bool {
+	return k.signingKey != nil
+}
+
+// IsSymmetric reports whether the key is a shared secret which must not be published
+func (k *Key) IsSymmetric() bool {
+	return k.Algorithm == AlgorithmHS256
+}
+
+func (k *Key) signingMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(string(k.Algorithm))
+}
+
+func checkPublicKey(algorithm Algorithm, publicKey crypto.PublicKey) error {
+	switch algorithm {
+	case AlgorithmRS256:
+		if _, ok := publicKey.(*rsa.PublicKey); !ok {
+			return ErrKeyAlgorithmMismatch
+		}
+	case AlgorithmES256:
+		ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || ecdsaKey.Curve != elliptic.P256() {
+			return ErrKeyAlgorithmMismatch
+		}
+	case AlgorithmEdDSA:
+		if _, ok := publicKey.(ed25519.PublicKey); !ok {
+			return ErrKeyAlgorithmMismatch
+		}
+	case AlgorithmHS256:
+		return ErrKeyAlgorithmMismatch
+	default:
+		return ErrUnsupportedAlgorithm
+	}
+	return nil
+}
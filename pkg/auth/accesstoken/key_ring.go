@@ -0,0 +1,265 @@
+package accesstoken
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrKeyNotFound    = errors.New("key not found")
+	ErrNoActiveKey    = errors.New("no active signing key")
+	ErrDuplicateKeyId = errors.New("key with the same id already exists")
+
+	ErrDuplicateLegacyKey = errors.New("only one key can be legacy")
+)
+
+// KeySet is a source of keys used to verify tokens, kid is empty for tokens without 'kid' header
+type KeySet interface {
+	LookupKey(kid string) (*Key, error)
+}
+
+// LookupKey makes a single key usable as a KeySet
+func (k *Key) LookupKey(kid string) (*Key, error) {
+	if kid != "" && kid != k.Id {
+		return nil, ErrKeyNotFound
+	}
+	return k, nil
+}
+
+// RingKey is a key of KeyRing with its lifetime. The key signs tokens from ActivateAt until a key
+// with a later activation time becomes active and verifies tokens until RetireAt
+type RingKey struct {
+	Key        *Key
+	ActivateAt time.Time
+	RetireAt   time.Time
+}
+
+func (rk *RingKey) isActive(now time.Time) bool {
+	return !rk.ActivateAt.After(now) && !rk.isRetired(now)
+}
+
+func (rk *RingKey) isRetired(now time.Time) bool {
+	return !rk.RetireAt.IsZero() && !rk.RetireAt.After(now)
+}
+
+// KeyRing holds one key which signs tokens and several keys which still verify them,
+// so signing keys can be rotated without invalidating outstanding tokens. Tokens without 'kid'
+// header were issued before the ring was introduced, they are verified with the legacy key only
+type KeyRing struct {
+	mu          sync.RWMutex
+	keys        []*RingKey
+	legacyKeyId string
+}
+
+// NewKeyRing creates a ring of the keys, the first key is the legacy key
+func NewKeyRing(keys ...*RingKey) (*KeyRing, error) {
+	ring := &KeyRing{}
+	for _, key := range keys {
+		err := ring.Add(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(keys) > 0 {
+		ring.legacyKeyId = keys[0].Key.Id
+	}
+	return ring, nil
+}
+
+// NewKeyRingFromConfig creates keys described by configs, at least one of them must be able to sign tokens now.
+// The key marked as legacy, or the first key if none is marked, verifies tokens without 'kid' header
+func NewKeyRingFromConfig(configs []KeyConfig) (*KeyRing, error) {
+	ring := &KeyRing{}
+	legacyMarked := false
+	for i := range configs {
+		key, err := NewKeyFromConfig(&configs[i])
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+
+		switch {
+		case configs[i].Legacy && legacyMarked:
+			return nil, fmt.Errorf("key %d: %w", i, ErrDuplicateLegacyKey)
+		case configs[i].Legacy:
+			legacyMarked = true
+			ring.legacyKeyId = key.Id
+		case i == 0:
+			ring.legacyKeyId = key.Id
+		}
+
+		err = ring.Add(&RingKey{
+			Key:        key,
+			ActivateAt: configs[i].ActivateAt,
+			RetireAt:   configs[i].RetireAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+	}
+
+	_, err := ring.SigningKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return ring, nil
+}
+
+func (r *KeyRing) Add(key *RingKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.Key.Id == key.Key.Id {
+			return ErrDuplicateKeyId
+		}
+	}
+
+	r.keys = append(r.keys, key)
+
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].ActivateAt.Before(r.keys[j].ActivateAt)
+	})
+
+	return nil
+}
+
+// SigningKey returns the most recently activated key which is not retired
+func (r *KeyRing) SigningKey() (*Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if r.keys[i].isActive(now) && r.keys[i].Key.CanSign() {
+			return r.keys[i].Key, nil
+		}
+	}
+
+	return nil, ErrNoActiveKey
+}
+
+// LookupKey returns not retired key by id. Tokens without 'kid' header are verified with the legacy key
+func (r *KeyRing) LookupKey(kid string) (*Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if kid == "" {
+		if r.legacyKeyId == "" {
+			return nil, ErrKeyNotFound
+		}
+		kid = r.legacyKeyId
+	}
+
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.Key.Id == kid && !key.isRetired(now) {
+			return key.Key, nil
+		}
+	}
+
+	return nil, ErrKeyNotFound
+}
+
+// VerificationKeys returns all not retired keys, including keys scheduled for activation,
+// so consumers can fetch a key before the first token is signed with it
+func (r *KeyRing) VerificationKeys() []*Key {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	now := time.Now()
+
+	keys := make([]*Key, 0, len(r.keys))
+	for _, key := range r.keys {
+		if !key.isRetired(now) {
+			keys = append(keys, key.Key)
+		}
+	}
+
+	return keys
+}
+
+// Rotate makes the key a signing key immediately. Previously active keys stop signing
+// and are retired after retireAfter, which should be not less than a token ttl.
+// If the key is already in the ring, it is activated instead of being added
+func (r *KeyRing) Rotate(key *Key, retireAfter time.Duration) error {
+	if !key.CanSign() {
+		return ErrVerificationOnlyKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	retireAt := now.Add(retireAfter)
+
+	var rotated *RingKey
+	for _, existing := range r.keys {
+		if existing.Key.Id == key.Id {
+			rotated = existing
+			continue
+		}
+		if existing.isActive(now) && (existing.RetireAt.IsZero() || existing.RetireAt.After(retireAt)) {
+			existing.RetireAt = retireAt
+		}
+	}
+
+	if rotated == nil {
+		rotated = &RingKey{Key: key}
+		r.keys = append(r.keys, rotated)
+	}
+
+	rotated.ActivateAt = now
+	rotated.RetireAt = time.Time{}
+
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].ActivateAt.Before(r.keys[j].ActivateAt)
+	})
+
+	return nil
+}
+
+// Keys returns copies of all keys of the ring with their lifetimes
+func (r *KeyRing) Keys() []*RingKey {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*RingKey, len(r.keys))
+	for i, key := range r.keys {
+		ringKey := *key
+		keys[i] = &ringKey
+	}
+
+	return keys
+}
+
+// Sync applies keys stored by other instances of the ring, e.g. after a rotation. Keys already
+// in the ring get the lifetime of the stored key, unknown keys are added
+func (r *KeyRing) Sync(keys []*RingKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing := make(map[string]*RingKey, len(r.keys))
+	for _, key := range r.keys {
+		existing[key.Key.Id] = key
+	}
+
+	for _, key := range keys {
+		ringKey, ok := existing[key.Key.Id]
+		if !ok {
+			ringKey = &RingKey{Key: key.Key}
+			existing[key.Key.Id] = ringKey
+			r.keys = append(r.keys, ringKey)
+		}
+
+		ringKey.ActivateAt = key.ActivateAt
+		ringKey.RetireAt = key.RetireAt
+	}
+
+	sort.SliceStable(r.keys, func(i, j int) bool {
+		return r.keys[i].ActivateAt.Before(r.keys[j].ActivateAt)
+	})
+}
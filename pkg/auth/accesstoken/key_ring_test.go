@@ -0,0 +1,135 @@
+package accesstoken_test
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"testing"
+	"time"
+)
+
+func TestKeyRingKidlessTokens(t *testing.T) {
+	legacyKey := accesstoken.NewSecretKey("legacy", "legacy-secret-legacy-secret-1234")
+	currentKey := accesstoken.NewSecretKey("current", testSecret)
+
+	ring, err := accesstoken.NewKeyRing(
+		&accesstoken.RingKey{Key: legacyKey, ActivateAt: time.Now().Add(-time.Hour)},
+		&accesstoken.RingKey{Key: currentKey, ActivateAt: time.Now()},
+	)
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+
+	// tokens without 'kid' header are verified with the legacy key only
+	_, err = accesstoken.Verify(signClaims(t, jwt.SigningMethodHS256, []byte("legacy-secret-legacy-secret-1234"), "", validClaims()), ring, testVerifyOptions())
+	if err != nil {
+		t.Errorf("Verify() of kid-less token of the legacy key error = %v", err)
+	}
+
+	_, err = accesstoken.Verify(signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), "", validClaims()), ring, testVerifyOptions())
+	if !errors.Is(err, accesstoken.ErrInvalidToken) {
+		t.Errorf("Verify() of kid-less token of another key error = %v, want %v", err, accesstoken.ErrInvalidToken)
+	}
+
+	_, err = accesstoken.Verify(signClaims(t, jwt.SigningMethodHS256, []byte(testSecret), "unknown", validClaims()), ring, testVerifyOptions())
+	if !errors.Is(err, accesstoken.ErrInvalidToken) {
+		t.Errorf("Verify() of token with unknown kid error = %v, want %v", err, accesstoken.ErrInvalidToken)
+	}
+}
+
+func TestNewKeyRingFromConfigLegacyKey(t *testing.T) {
+	ring, err := accesstoken.NewKeyRingFromConfig([]accesstoken.KeyConfig{
+		{Id: "first", Algorithm: accesstoken.AlgorithmHS256, SecretKey: "first-secret"},
+		{Id: "second", Algorithm: accesstoken.AlgorithmHS256, SecretKey: "second-secret", Legacy: true},
+	})
+	if err != nil {
+		t.Fatalf("NewKeyRingFromConfig() error = %v", err)
+	}
+
+	key, err := ring.LookupKey("")
+	if err != nil || key.Id != "second" {
+		t.Errorf("LookupKey(\"\") = %v, %v, want the key marked as legacy", key, err)
+	}
+
+	_, err = accesstoken.NewKeyRingFromConfig([]accesstoken.KeyConfig{
+		{Id: "first", Algorithm: accesstoken.AlgorithmHS256, SecretKey: "first-secret", Legacy: true},
+		{Id: "second", Algorithm: accesstoken.AlgorithmHS256, SecretKey: "second-secret", Legacy: true},
+	})
+	if !errors.Is(err, accesstoken.ErrDuplicateLegacyKey) {
+		t.Errorf("NewKeyRingFromConfig() error = %v, want %v", err, accesstoken.ErrDuplicateLegacyKey)
+	}
+}
+
+func TestKeyRingRotate(t *testing.T) {
+	previousKey, _ := accesstoken.GenerateKey("previous", accesstoken.AlgorithmES256)
+	nextKey, _ := accesstoken.GenerateKey("next", accesstoken.AlgorithmES256)
+
+	ring, err := accesstoken.NewKeyRing(&accesstoken.RingKey{Key: previousKey, ActivateAt: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("NewKeyRing() error = %v", err)
+	}
+
+	previousToken, err := accesstoken.Create(testPayload(), previousKey)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	err = ring.Rotate(nextKey, time.Hour)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	signingKey, err := ring.SigningKey()
+	if err != nil || signingKey.Id != nextKey.Id {
+		t.Fatalf("SigningKey() = %v, %v, want the rotated key", signingKey, err)
+	}
+
+	nextToken, err := accesstoken.Create(testPayload(), signingKey)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	// the previous key verifies outstanding tokens until it is retired
+	for name, token := range map[string]string{"previous": previousToken, "next": nextToken} {
+		_, err = accesstoken.Verify(token, ring, testVerifyOptions())
+		if err != nil {
+			t.Errorf("Verify() of token of the %s key error = %v", name, err)
+		}
+	}
+
+	if keys := ring.VerificationKeys(); len(keys) != 2 {
+		t.Errorf("VerificationKeys() returned %d keys, want 2", len(keys))
+	}
+
+	// rotating back without a grace period retires the current key immediately
+	err = ring.Rotate(previousKey, 0)
+	if err != nil {
+		t.Fatalf("Rotate() error = %v", err)
+	}
+
+	_, err = accesstoken.Verify(nextToken, ring, testVerifyOptions())
+	if !errors.Is(err, accesstoken.ErrInvalidToken) {
+		t.Errorf("Verify() of token of the retired key error = %v, want %v", err, accesstoken.ErrInvalidToken)
+	}
+
+	_, err = accesstoken.Verify(previousToken, ring, testVerifyOptions())
+	if err != nil {
+		t.Errorf("Verify() of token of the reactivated key error = %v", err)
+	}
+}
+
+func TestKeyRingRotateVerificationOnlyKey(t *testing.T) {
+	key, _ := accesstoken.GenerateKey("key", accesstoken.AlgorithmES256)
+	jwk, _ := key.Jwk()
+	publicKey, err := jwk.Key()
+	if err != nil {
+		t.Fatalf("Key() error = %v", err)
+	}
+
+	ring, _ := accesstoken.NewKeyRing()
+
+	err = ring.Rotate(publicKey, time.Hour)
+	if !errors.Is(err, accesstoken.ErrVerificationOnlyKey) {
+		t.Errorf("Rotate() error = %v, want %v", err, accesstoken.ErrVerificationOnlyKey)
+	}
+}
@@ -9,7 +9,11 @@ type contextKey struct {
 	name string
 }
 
-var authClientCtxKey = &contextKey{"AuthClient"}
+var (
+	authClientCtxKey = &contextKey{"AuthClient"}
+	claimsCtxKey     = &contextKey{"Claims"}
+	tenantIdCtxKey   = &contextKey{"TenantId"}
+)
 
 func UserIdFromContext(ctx context.Context) *domain.UserId {
 	v := ctx.Value(authClientCtxKey)
@@ -28,3 +32,39 @@ func UserIdFromContext(ctx context.Context) *domain.UserId {
 func UserIdToContext(ctx context.Context, userId *domain.UserId) context.Context {
 	return context.WithValue(ctx, authClientCtxKey, userId)
 }
+
+// ClaimsFromContext returns claims of the access token the request has been authenticated with
+func ClaimsFromContext(ctx context.Context) *JwtPayload {
+	v := ctx.Value(claimsCtxKey)
+	if v == nil {
+		return nil
+	}
+
+	claims, ok := v.(*JwtPayload)
+	if !ok {
+		return nil
+	}
+
+	return claims
+}
+
+// ClaimsToContext puts claims of the access token and the token owner to the context
+func ClaimsToContext(ctx context.Context, claims *JwtPayload) context.Context {
+	userId := claims.UserId
+	return context.WithValue(UserIdToContext(ctx, &userId), claimsCtxKey, claims)
+}
+
+// TenantIdFromContext returns the tenant the request has been resolved to, see TenantIdToContext.
+// DefaultTenantId is returned if the tenant has not been resolved
+func TenantIdFromContext(ctx context.Context) domain.TenantId {
+	tenantId, ok := ctx.Value(tenantIdCtxKey).(domain.TenantId)
+	if !ok {
+		return domain.DefaultTenantId
+	}
+
+	return tenantId
+}
+
+func TenantIdToContext(ctx context.Context, tenantId domain.TenantId) context.Context {
+	return context.WithValue(ctx, tenantIdCtxKey, tenantId)
+}
@@ -5,16 +5,49 @@ import (
 	"time"
 )
 
+// principal types of access tokens, tokens of service accounts and of the client credentials grant
+// belong to services
+const (
+	PrincipalTypeUser    = "user"
+	PrincipalTypeService = "service"
+)
+
+// token uses of access tokens. Tokens of the gRPC API are issued to users and services of the tenant,
+// OAuth tokens are delegated to third-party clients by users or issued to them by the client credentials grant.
+// ID tokens of OpenID Connect are signed with the same keys, but they are not access tokens
+const (
+	TokenUseAccess = "access"
+	TokenUseOAuth  = "oauth"
+	TokenUseId     = "id"
+)
+
+// JwtPayload holds claims of an access token. ClientId is set in tokens issued to OAuth clients and service accounts,
+// tokens of services have no user and the client is their subject
 type JwtPayload struct {
-	UserId    domain.UserId
-	IssuedAt  time.Time
-	ExpiredAt time.Time
+	TokenId       string
+	PrincipalType string
+	TokenUse      string
+	UserId        domain.UserId
+	ClientId      string
+	TenantId      domain.TenantId
+	Email         domain.Email
+	Roles         []string
+	Permissions   []string
+	Scopes        []string
+	Issuer        string
+	Audience      []string
+	IssuedAt      time.Time
+	ExpiredAt     time.Time
 }
 
+// NewPayload returns a payload of a user token of the gRPC API, the principal type is changed by issuers
+// of service tokens and the token use by issuers of OAuth tokens
 func NewPayload(userId domain.UserId, ttl time.Duration) *JwtPayload {
 	return &JwtPayload{
-		UserId:    userId,
-		IssuedAt:  time.Now().UTC(),
-		ExpiredAt: time.Now().UTC().Add(ttl),
+		PrincipalType: PrincipalTypeUser,
+		TokenUse:      TokenUseAccess,
+		UserId:        userId,
+		IssuedAt:      time.Now().UTC(),
+		ExpiredAt:     time.Now().UTC().Add(ttl),
 	}
 }
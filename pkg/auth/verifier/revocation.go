@@ -0,0 +1,118 @@
+package verifier
+
+import (
+	"context"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"time"
+)
+
+// PollRevocations fetches revocations since the previous poll. Polls overlap a little,
+// so revocations committed while the previous list was being generated are not missed
+func (v *Verifier) PollRevocations(ctx context.Context) error {
+	v.revocationsMu.RLock()
+	since := v.revocationsSince
+	v.revocationsMu.RUnlock()
+
+	if !since.IsZero() {
+		since = since.Add(-revocationPollOverlap)
+	}
+
+	revocationList, err := v.source.GetRevocationList(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	v.AddRevocations(revocationList)
+
+	return nil
+}
+
+// AddRevocations applies revocations pushed by the auth service, for example through a message broker
+func (v *Verifier) AddRevocations(revocationList *RevocationList) {
+	v.revocationsMu.Lock()
+	defer v.revocationsMu.Unlock()
+
+	now := time.Now()
+
+	for _, token := range revocationList.Tokens {
+		if token.ExpiresAt.After(now) {
+			v.revokedTokens[token.TokenId] = token.ExpiresAt
+		}
+	}
+
+	for _, user := range revocationList.Users {
+		if user.RevokedAt.After(v.revokedUsers[user.UserId]) {
+			v.revokedUsers[user.UserId] = user.RevokedAt
+		}
+	}
+
+	for _, client := range revocationList.Clients {
+		if client.RevokedAt.After(v.revokedClients[client.ClientId]) {
+			v.revokedClients[client.ClientId] = client.RevokedAt
+		}
+	}
+
+	if revocationList.GeneratedAt.After(v.revocationsSince) {
+		v.revocationsSince = revocationList.GeneratedAt
+	}
+	v.revocationsUpdatedAt = now
+
+	v.removeOutdatedRevocations(now)
+}
+
+// revocationsStale reports whether revocations have not been received for MaxRevocationStaleness
+func (v *Verifier) revocationsStale() bool {
+	if v.config.MaxRevocationStaleness < 0 {
+		return false
+	}
+
+	v.revocationsMu.RLock()
+	defer v.revocationsMu.RUnlock()
+
+	return time.Since(v.revocationsUpdatedAt) > v.config.MaxRevocationStaleness
+}
+
+// isRevoked uses the same rules as the auth service: 'iat' claim has a precision of seconds,
+// so a token issued within the same second as revocation of all user or service account tokens is considered valid
+func (v *Verifier) isRevoked(payload *auth.JwtPayload) bool {
+	v.revocationsMu.RLock()
+	defer v.revocationsMu.RUnlock()
+
+	if _, ok := v.revokedTokens[payload.TokenId]; ok {
+		return true
+	}
+
+	revokedAt, ok := v.revokedUsers[payload.UserId]
+	if ok && payload.IssuedAt.Before(revokedAt.Truncate(time.Second)) {
+		return true
+	}
+
+	if payload.ClientId == "" {
+		return false
+	}
+
+	revokedAt, ok = v.revokedClients[payload.ClientId]
+
+	return ok && payload.IssuedAt.Before(revokedAt.Truncate(time.Second))
+}
+
+// removeOutdatedRevocations must be called with revocationsMu held
+func (v *Verifier) removeOutdatedRevocations(now time.Time) {
+	for tokenId, expiresAt := range v.revokedTokens {
+		if !expiresAt.After(now) {
+			delete(v.revokedTokens, tokenId)
+		}
+	}
+
+	for userId, revokedAt := range v.revokedUsers {
+		if now.Sub(revokedAt) > v.config.RevocationRetention {
+			delete(v.revokedUsers, userId)
+		}
+	}
+
+	for clientId, revokedAt := range v.revokedClients {
+		if now.Sub(revokedAt) > v.config.RevocationRetention {
+			delete(v.revokedClients, clientId)
+		}
+	}
+}
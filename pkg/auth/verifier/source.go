@@ -0,0 +1,104 @@
+package verifier
+
+import (
+	"context"
+	pb "github.com/vaberof/auth-grpc/genproto/auth_service"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// RevokedToken is an access token revoked before its expiration
+type RevokedToken struct {
+	TokenId   string
+	ExpiresAt time.Time
+}
+
+// RevokedUser means that all the tokens of the user issued before RevokedAt are revoked
+type RevokedUser struct {
+	UserId    domain.UserId
+	RevokedAt time.Time
+}
+
+// RevokedClient means that all the tokens of the service account issued before RevokedAt are revoked
+type RevokedClient struct {
+	ClientId  string
+	RevokedAt time.Time
+}
+
+// RevocationList contains revocations since some time, GeneratedAt is the time of the next request
+type RevocationList struct {
+	Tokens      []RevokedToken
+	Users       []RevokedUser
+	Clients     []RevokedClient
+	GeneratedAt time.Time
+}
+
+// Source provides verification keys and revocations published by the auth service
+type Source interface {
+	GetJwks(ctx context.Context) (*accesstoken.Jwks, error)
+	GetRevocationList(ctx context.Context, since time.Time) (*RevocationList, error)
+}
+
+// GrpcSource fetches keys and revocations with GetJwks and GetRevocationList RPCs. GetRevocationList
+// requires credentials, e.g. authclient.PerRPCCredentials with a service token of 'revocations.read' scope
+type GrpcSource struct {
+	client pb.AuthServiceClient
+}
+
+func NewGrpcSource(conn grpc.ClientConnInterface) *GrpcSource {
+	return &GrpcSource{client: pb.NewAuthServiceClient(conn)}
+}
+
+func (s *GrpcSource) GetJwks(ctx context.Context) (*accesstoken.Jwks, error) {
+	resp, err := s.client.GetJwks(ctx, &emptypb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := &accesstoken.Jwks{Keys: make([]accesstoken.Jwk, len(resp.Keys))}
+	for i, jwk := range resp.Keys {
+		jwks.Keys[i] = accesstoken.Jwk{
+			Kty: jwk.Kty,
+			Kid: jwk.Kid,
+			Use: jwk.Use,
+			Alg: jwk.Alg,
+			N:   jwk.N,
+			E:   jwk.E,
+			Crv: jwk.Crv,
+			X:   jwk.X,
+			Y:   jwk.Y,
+		}
+	}
+
+	return jwks, nil
+}
+
+func (s *GrpcSource) GetRevocationList(ctx context.Context, since time.Time) (*RevocationList, error) {
+	resp, err := s.client.GetRevocationList(ctx, &pb.GetRevocationListRequest{Since: timestamppb.New(since)})
+	if err != nil {
+		return nil, err
+	}
+
+	revocationList := &RevocationList{
+		Tokens:      make([]RevokedToken, len(resp.Tokens)),
+		Users:       make([]RevokedUser, len(resp.Users)),
+		Clients:     make([]RevokedClient, len(resp.Clients)),
+		GeneratedAt: resp.GeneratedAt.AsTime(),
+	}
+
+	for i, token := range resp.Tokens {
+		revocationList.Tokens[i] = RevokedToken{TokenId: token.TokenId, ExpiresAt: token.ExpiresAt.AsTime()}
+	}
+	for i, user := range resp.Users {
+		revocationList.Users[i] = RevokedUser{UserId: domain.UserId(user.UserId), RevokedAt: user.RevokedAt.AsTime()}
+	}
+	for i, client := range resp.Clients {
+		revocationList.Clients[i] = RevokedClient{ClientId: client.ClientId, RevokedAt: client.RevokedAt.AsTime()}
+	}
+
+	return revocationList, nil
+}
@@ -0,0 +1,281 @@
+// Package verifier verifies access tokens of the auth service locally. Verification keys are fetched
+// from the service and cached, revoked tokens are rejected using revocation lists polled from the
+// service or pushed with AddRevocations. Tokens are rejected while the revocations are stale, so a service
+// which can not reach the auth service does not accept revoked tokens. Only tokens signed with asymmetric
+// keys can be verified, because HS256 secrets are never published
+package verifier
+
+import (
+	"context"
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Errors are the same as the errors of accesstoken.Verify, so callers may check either of them
+var (
+	ErrInvalidToken = accesstoken.ErrInvalidToken
+	ErrExpiredToken = accesstoken.ErrExpiredToken
+	ErrTokenRevoked = errors.New("token has been revoked")
+
+	ErrStaleRevocations = errors.New("revocation list is stale")
+)
+
+const (
+	defaultKeysRefreshInterval    = 5 * time.Minute
+	defaultRevocationPollInterval = 30 * time.Second
+	defaultRevocationRetention    = 24 * time.Hour
+	defaultMaxRevocationStaleness = 5 * time.Minute
+	defaultMinKeysRefreshInterval = 10 * time.Second
+	defaultSourceTimeout          = 5 * time.Second
+	revocationPollOverlap         = 5 * time.Second
+)
+
+// Config of the verifier, zero durations are replaced with defaults. RevocationRetention
+// should be not less than access token ttl of the auth service. Tokens are rejected with ErrStaleRevocations
+// if revocations have not been received for MaxRevocationStaleness, a negative value disables the check.
+// TokenUses are the accepted token uses, only tokens of the gRPC API are accepted if empty. Services
+// serving third-party OAuth clients add auth.TokenUseOAuth and must check the scopes of such tokens
+type Config struct {
+	Issuer                 string        `yaml:"issuer"`
+	Audience               []string      `yaml:"audience"`
+	TokenUses              []string      `yaml:"token-uses"`
+	Leeway                 time.Duration `yaml:"leeway"`
+	KeysRefreshInterval    time.Duration `yaml:"keys-refresh-interval"`
+	MinKeysRefreshInterval time.Duration `yaml:"min-keys-refresh-interval"`
+	RevocationPollInterval time.Duration `yaml:"revocation-poll-interval"`
+	RevocationRetention    time.Duration `yaml:"revocation-retention"`
+	MaxRevocationStaleness time.Duration `yaml:"max-revocation-staleness"`
+	SourceTimeout          time.Duration `yaml:"source-timeout"`
+}
+
+type Verifier struct {
+	source  Source
+	config  Config
+	options *accesstoken.VerifyOptions
+
+	keysMu          sync.RWMutex
+	keys            map[string]*accesstoken.Key
+	keysRefreshedAt time.Time
+	keysRefreshMu   sync.Mutex
+
+	revocationsMu    sync.RWMutex
+	revokedTokens    map[string]time.Time
+	revokedUsers     map[domain.UserId]time.Time
+	revokedClients   map[string]time.Time
+	revocationsSince time.Time
+	// revocationsUpdatedAt is the local time revocations were received at, unlike revocationsSince
+	// it does not depend on the clock of the auth service
+	revocationsUpdatedAt time.Time
+
+	logger *slog.Logger
+}
+
+func New(source Source, config *Config, logs *logs.Logs) *Verifier {
+	logger := logs.WithName("auth.verifier")
+
+	cfg := *config
+	cfg.KeysRefreshInterval = durationOrDefault(cfg.KeysRefreshInterval, defaultKeysRefreshInterval)
+	cfg.MinKeysRefreshInterval = durationOrDefault(cfg.MinKeysRefreshInterval, defaultMinKeysRefreshInterval)
+	cfg.RevocationPollInterval = durationOrDefault(cfg.RevocationPollInterval, defaultRevocationPollInterval)
+	cfg.RevocationRetention = durationOrDefault(cfg.RevocationRetention, defaultRevocationRetention)
+	cfg.MaxRevocationStaleness = durationOrDefault(cfg.MaxRevocationStaleness, defaultMaxRevocationStaleness)
+	cfg.SourceTimeout = durationOrDefault(cfg.SourceTimeout, defaultSourceTimeout)
+
+	return &Verifier{
+		source: source,
+		config: cfg,
+		options: &accesstoken.VerifyOptions{
+			Issuer:    cfg.Issuer,
+			Audience:  cfg.Audience,
+			TokenUses: cfg.TokenUses,
+			Leeway:    cfg.Leeway,
+		},
+		keys:           make(map[string]*accesstoken.Key),
+		revokedTokens:  make(map[string]time.Time),
+		revokedUsers:   make(map[domain.UserId]time.Time),
+		revokedClients: make(map[string]time.Time),
+		logger:         logger,
+	}
+}
+
+// Start fetches keys and revocations and keeps them up to date until the context is done
+func (v *Verifier) Start(ctx context.Context) error {
+	err := v.RefreshKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = v.PollRevocations(ctx)
+	if err != nil {
+		return err
+	}
+
+	go v.run(ctx)
+
+	return nil
+}
+
+// Verify checks the token signature, its claims and revocation and returns its payload
+func (v *Verifier) Verify(token string) (*auth.JwtPayload, error) {
+	if v.revocationsStale() {
+		return nil, ErrStaleRevocations
+	}
+
+	payload, err := accesstoken.Verify(token, v, v.options)
+	if err != nil {
+		if errors.Is(err, accesstoken.ErrExpiredToken) {
+			return nil, ErrExpiredToken
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if v.isRevoked(payload) {
+		return nil, ErrTokenRevoked
+	}
+
+	return payload, nil
+}
+
+// VerifyToken makes the verifier usable as grpcserver.TokenVerifier. Stale revocations are reported
+// as Unavailable, so clients retry the call instead of logging in again
+func (v *Verifier) VerifyToken(ctx context.Context, token string) (*auth.JwtPayload, error) {
+	payload, err := v.Verify(token)
+	if errors.Is(err, ErrStaleRevocations) {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return payload, err
+}
+
+// LookupKey returns a cached key, keys are refreshed if the key is unknown, so tokens signed
+// with a just rotated key are accepted. Refreshes are limited by MinKeysRefreshInterval
+func (v *Verifier) LookupKey(kid string) (*accesstoken.Key, error) {
+	if kid == "" {
+		return nil, accesstoken.ErrKeyNotFound
+	}
+
+	key, ok := v.cachedKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	// concurrent lookups of the same unknown key wait for a single refresh
+	v.keysRefreshMu.Lock()
+	defer v.keysRefreshMu.Unlock()
+
+	key, ok = v.cachedKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	v.keysMu.RLock()
+	refreshedAt := v.keysRefreshedAt
+	v.keysMu.RUnlock()
+
+	if time.Since(refreshedAt) < v.config.MinKeysRefreshInterval {
+		return nil, accesstoken.ErrKeyNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), v.config.SourceTimeout)
+	defer cancel()
+
+	err := v.refreshKeys(ctx)
+	if err != nil {
+		v.logger.Error("failed to refresh verification keys", "error", err)
+	}
+
+	key, ok = v.cachedKey(kid)
+	if !ok {
+		return nil, accesstoken.ErrKeyNotFound
+	}
+
+	return key, nil
+}
+
+// RefreshKeys replaces cached keys with the keys published by the source. Keys which
+// can not be parsed are skipped, so a new key type does not break verification
+func (v *Verifier) RefreshKeys(ctx context.Context) error {
+	v.keysRefreshMu.Lock()
+	defer v.keysRefreshMu.Unlock()
+
+	return v.refreshKeys(ctx)
+}
+
+// refreshKeys must be called with keysRefreshMu held
+func (v *Verifier) refreshKeys(ctx context.Context) error {
+	jwks, err := v.source.GetJwks(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*accesstoken.Key, len(jwks.Keys))
+	for i := range jwks.Keys {
+		key, err := jwks.Keys[i].Key()
+		if err != nil {
+			v.logger.Warn("skipping verification key", slog.String("kid", jwks.Keys[i].Kid), "error", err)
+			continue
+		}
+		keys[key.Id] = key
+	}
+
+	v.keysMu.Lock()
+	v.keys = keys
+	v.keysRefreshedAt = time.Now()
+	v.keysMu.Unlock()
+
+	return nil
+}
+
+func (v *Verifier) cachedKey(kid string) (*accesstoken.Key, bool) {
+	v.keysMu.RLock()
+	defer v.keysMu.RUnlock()
+
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+func (v *Verifier) run(ctx context.Context) {
+	keysTicker := time.NewTicker(v.config.KeysRefreshInterval)
+	defer keysTicker.Stop()
+
+	revocationsTicker := time.NewTicker(v.config.RevocationPollInterval)
+	defer revocationsTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keysTicker.C:
+			err := v.withTimeout(ctx, v.RefreshKeys)
+			if err != nil {
+				v.logger.Error("failed to refresh verification keys", "error", err)
+			}
+		case <-revocationsTicker.C:
+			err := v.withTimeout(ctx, v.PollRevocations)
+			if err != nil {
+				v.logger.Error("failed to poll revocation list", "error", err)
+			}
+		}
+	}
+}
+
+func (v *Verifier) withTimeout(ctx context.Context, f func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, v.config.SourceTimeout)
+	defer cancel()
+
+	return f(ctx)
+}
+
+func durationOrDefault(value time.Duration, defaultValue time.Duration) time.Duration {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
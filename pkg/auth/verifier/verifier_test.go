@@ -0,0 +1,209 @@
+package verifier_test
+
+import (
+	"context"
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/auth/verifier"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"io"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "api"
+)
+
+// fakeSource publishes the key and no revocations
+type fakeSource struct {
+	key *accesstoken.Key
+}
+
+func (s *fakeSource) GetJwks(context.Context) (*accesstoken.Jwks, error) {
+	jwk, _ := s.key.Jwk()
+	return &accesstoken.Jwks{Keys: []accesstoken.Jwk{*jwk}}, nil
+}
+
+func (s *fakeSource) GetRevocationList(context.Context, time.Time) (*verifier.RevocationList, error) {
+	return &verifier.RevocationList{GeneratedAt: time.Now()}, nil
+}
+
+func newTestVerifier(t *testing.T, key *accesstoken.Key, tokenUses []string) *verifier.Verifier {
+	t.Helper()
+
+	v := verifier.New(&fakeSource{key: key}, &verifier.Config{
+		Issuer:    testIssuer,
+		Audience:  []string{testAudience},
+		TokenUses: tokenUses,
+	}, logs.New(io.Discard, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	err := v.Start(ctx)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	return v
+}
+
+func createToken(t *testing.T, key *accesstoken.Key, payload *auth.JwtPayload) string {
+	t.Helper()
+
+	payload.Issuer = testIssuer
+	payload.Audience = []string{testAudience}
+
+	token, err := accesstoken.Create(payload, key)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return token
+}
+
+func TestVerifierTokenUses(t *testing.T) {
+	key, err := accesstoken.GenerateKey("key-1", accesstoken.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	userToken := func() *auth.JwtPayload {
+		return auth.NewPayload(1, time.Minute)
+	}
+	oauthToken := func() *auth.JwtPayload {
+		payload := auth.NewPayload(1, time.Minute)
+		payload.TokenUse = auth.TokenUseOAuth
+		payload.ClientId = "third-party-client"
+		payload.Scopes = []string{"openid"}
+		return payload
+	}
+	clientCredentialsToken := func() *auth.JwtPayload {
+		payload := auth.NewPayload(0, time.Minute)
+		payload.PrincipalType = auth.PrincipalTypeService
+		payload.TokenUse = auth.TokenUseOAuth
+		payload.ClientId = "third-party-client"
+		return payload
+	}
+	legacyToken := func() *auth.JwtPayload {
+		payload := auth.NewPayload(1, time.Minute)
+		payload.TokenUse = ""
+		return payload
+	}
+
+	tests := []struct {
+		name      string
+		tokenUses []string
+		payload   func() *auth.JwtPayload
+		wantUse   string
+		wantErr   error
+	}{
+		{name: "user token", payload: userToken, wantUse: auth.TokenUseAccess},
+		{name: "token without token use", payload: legacyToken, wantUse: auth.TokenUseAccess},
+		{name: "oauth token", payload: oauthToken, wantErr: verifier.ErrInvalidToken},
+		{name: "client credentials token", payload: clientCredentialsToken, wantErr: verifier.ErrInvalidToken},
+		{name: "oauth token accepted", tokenUses: []string{auth.TokenUseAccess, auth.TokenUseOAuth}, payload: oauthToken, wantUse: auth.TokenUseOAuth},
+		{name: "user token not accepted", tokenUses: []string{auth.TokenUseOAuth}, payload: userToken, wantErr: verifier.ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, key, tt.tokenUses)
+
+			payload, err := v.Verify(createToken(t, key, tt.payload()))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if payload.TokenUse != tt.wantUse {
+				t.Errorf("Verify() TokenUse = %q, want %q", payload.TokenUse, tt.wantUse)
+			}
+		})
+	}
+}
+
+func TestVerifierRejectsIdTokens(t *testing.T) {
+	key, err := accesstoken.GenerateKey("key-1", accesstoken.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	now := time.Now()
+
+	idToken, err := accesstoken.CreateIdToken(&accesstoken.IdToken{
+		Issuer:    testIssuer,
+		UserId:    1,
+		ClientId:  testAudience,
+		IssuedAt:  now,
+		ExpiredAt: now.Add(time.Minute),
+	}, key)
+	if err != nil {
+		t.Fatalf("CreateIdToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		tokenUses []string
+	}{
+		{name: "default token uses"},
+		{name: "oauth token uses", tokenUses: []string{auth.TokenUseAccess, auth.TokenUseOAuth}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVerifier(t, key, tt.tokenUses)
+
+			_, err := v.Verify(idToken)
+			if !errors.Is(err, verifier.ErrInvalidToken) {
+				t.Errorf("Verify() error = %v, want %v", err, verifier.ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestVerifierRevokedClients(t *testing.T) {
+	key, err := accesstoken.GenerateKey("key-1", accesstoken.AlgorithmES256)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+
+	revokedAt := time.Now()
+
+	serviceToken := func(clientId string, issuedAt time.Time) string {
+		payload := auth.NewPayload(0, time.Hour)
+		payload.PrincipalType = auth.PrincipalTypeService
+		payload.ClientId = clientId
+		payload.IssuedAt = issuedAt
+		return createToken(t, key, payload)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "token issued before revocation", token: serviceToken("service-1", revokedAt.Add(-time.Minute)), wantErr: verifier.ErrTokenRevoked},
+		{name: "token issued within the second of revocation", token: serviceToken("service-1", revokedAt.Truncate(time.Second))},
+		{name: "token of another service account", token: serviceToken("service-2", revokedAt.Add(-time.Minute))},
+		{name: "user token", token: createToken(t, key, auth.NewPayload(1, time.Minute))},
+	}
+
+	v := newTestVerifier(t, key, nil)
+	v.AddRevocations(&verifier.RevocationList{
+		Clients:     []verifier.RevokedClient{{ClientId: "service-1", RevokedAt: revokedAt}},
+		GeneratedAt: revokedAt,
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package authclienttest provides an in-process fake of the auth service for tests of its clients
+package authclienttest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	pb "github.com/vaberof/auth-grpc/genproto/auth_service"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/authclient"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	errorDomain      = "auth-grpc"
+	bufferSize       = 1024 * 1024
+	defaultTokenTtl  = 15 * time.Minute
+	refreshTokenSize = 32
+)
+
+// VerificationCode is sent to every registered email
+const VerificationCode = "123456"
+
+type user struct {
+	id       domain.UserId
+	email    string
+	password string
+	verified bool
+}
+
+// Server is a fake AuthService listening on an in-memory connection. It keeps users and tokens
+// in memory, passwords are compared as is and every verification code equals VerificationCode
+type Server struct {
+	pb.UnimplementedAuthServiceServer
+
+	key        *accesstoken.Key
+	grpcServer *grpc.Server
+	listener   *bufconn.Listener
+
+	mu            sync.Mutex
+	tokenTtl      time.Duration
+	users         map[string]*user
+	refreshTokens map[string]domain.UserId
+	revokedTokens map[string]struct{}
+	conns         []*grpc.ClientConn
+}
+
+// NewServer starts a fake server, it is stopped by Close
+func NewServer() *Server {
+	server := &Server{
+		key:           accesstoken.NewSecretKey("authclienttest", accesstoken.SecretKey(randomString())),
+		grpcServer:    grpc.NewServer(),
+		listener:      bufconn.Listen(bufferSize),
+		tokenTtl:      defaultTokenTtl,
+		users:         make(map[string]*user),
+		refreshTokens: make(map[string]domain.UserId),
+		revokedTokens: make(map[string]struct{}),
+	}
+
+	pb.RegisterAuthServiceServer(server.grpcServer, server)
+
+	go server.grpcServer.Serve(server.listener)
+
+	return server
+}
+
+// Dial returns a connection to the server
+func (s *Server) Dial(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, opts...)
+
+	return grpc.DialContext(ctx, "bufnet", dialOpts...)
+}
+
+// Client returns a client connected to the server, its connection is closed by Close
+func (s *Server) Client(ctx context.Context, opts ...grpc.DialOption) (*authclient.Client, error) {
+	conn, err := s.Dial(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, conn)
+	s.mu.Unlock()
+
+	return authclient.NewFromConn(conn), nil
+}
+
+// SetTokenTtl changes ttl of access tokens issued after the call
+func (s *Server) SetTokenTtl(tokenTtl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenTtl = tokenTtl
+}
+
+// AddUser adds a verified user, so tests can log in without registration
+func (s *Server) AddUser(email string, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[email] = &user{id: domain.UserId(len(s.users) + 1), email: email, password: password, verified: true}
+}
+
+func (s *Server) Close() {
+	s.mu.Lock()
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.grpcServer.Stop()
+}
+
+func (s *Server) Register(ctx context.Context, req *pb.RegisterRequest) (*emptypb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.users[req.Email]; ok && existing.verified {
+		return nil, newStatusError(codes.AlreadyExists, "USER_ALREADY_EXISTS", "user with specified email already exists")
+	}
+
+	s.users[req.Email] = &user{id: domain.UserId(len(s.users) + 1), email: req.Email, password: req.Password}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Verify(ctx context.Context, req *pb.VerifyRequest) (*emptypb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registered, ok := s.users[req.Email]
+	if !ok || registered.verified {
+		return nil, newStatusError(codes.FailedPrecondition, "VERIFICATION_CODE_EXPIRED", "verification code has expired")
+	}
+
+	if req.Code != VerificationCode {
+		return nil, newStatusError(codes.InvalidArgument, "INVALID_VERIFICATION_CODE", "invalid verification code")
+	}
+
+	registered.verified = true
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Login(ctx context.Context, req *pb.LoginRequest) (*pb.AuthResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	registered, ok := s.users[req.Email]
+	if !ok || !registered.verified || registered.password != req.Password {
+		return nil, newStatusError(codes.Unauthenticated, "INVALID_CREDENTIALS", "invalid email or password")
+	}
+
+	return s.issueTokens(registered)
+}
+
+func (s *Server) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*pb.TokenInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload, err := s.verifyAccessToken(req.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.TokenInfo{
+		UserId:        int64(payload.UserId),
+		Email:         payload.Email.String(),
+		IssuedAt:      timestamppb.New(payload.IssuedAt),
+		ExpiresAt:     timestamppb.New(payload.ExpiredAt),
+		TokenId:       payload.TokenId,
+		PrincipalType: payload.PrincipalType,
+	}, nil
+}
+
+func (s *Server) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.AuthResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userId, ok := s.refreshTokens[req.RefreshToken]
+	if !ok {
+		return nil, newStatusError(codes.Unauthenticated, "REFRESH_TOKEN_INVALID", "refresh token is invalid")
+	}
+	delete(s.refreshTokens, req.RefreshToken)
+
+	for _, registered := range s.users {
+		if registered.id == userId {
+			return s.issueTokens(registered)
+		}
+	}
+
+	return nil, newStatusError(codes.Unauthenticated, "REFRESH_TOKEN_INVALID", "refresh token is invalid")
+}
+
+func (s *Server) Logout(ctx context.Context, req *pb.LogoutRequest) (*emptypb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload, err := s.verifyAccessToken(req.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s.revokedTokens[payload.TokenId] = struct{}{}
+	delete(s.refreshTokens, req.RefreshToken)
+
+	return &emptypb.Empty{}, nil
+}
+
+// issueTokens must be called with mu held
+func (s *Server) issueTokens(registered *user) (*pb.AuthResponse, error) {
+	payload := auth.NewPayload(registered.id, s.tokenTtl)
+	payload.Email = domain.Email(registered.email)
+
+	accessToken, err := accesstoken.Create(payload, s.key)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	refreshToken := randomString()
+	s.refreshTokens[refreshToken] = registered.id
+
+	return &pb.AuthResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// verifyAccessToken must be called with mu held
+func (s *Server) verifyAccessToken(token string) (*auth.JwtPayload, error) {
+	payload, err := accesstoken.Verify(token, s.key, nil)
+	if err != nil {
+		if err == accesstoken.ErrExpiredToken {
+			return nil, newStatusError(codes.Unauthenticated, "TOKEN_EXPIRED", "token has expired")
+		}
+		return nil, newStatusError(codes.Unauthenticated, "TOKEN_INVALID", "token is invalid")
+	}
+
+	if _, ok := s.revokedTokens[payload.TokenId]; ok {
+		return nil, newStatusError(codes.Unauthenticated, "TOKEN_REVOKED", "token has been revoked")
+	}
+
+	return payload, nil
+}
+
+func newStatusError(code codes.Code, reason string, message string) error {
+	st, err := status.New(code, message).WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	if err != nil {
+		panic(fmt.Sprintf("authclienttest: %v", err))
+	}
+	return st.Err()
+}
+
+func randomString() string {
+	value := make([]byte, refreshTokenSize)
+	_, err := rand.Read(value)
+	if err != nil {
+		panic(fmt.Sprintf("authclienttest: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(value)
+}
@@ -0,0 +1,205 @@
+package authclient
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/vaberof/auth-grpc/genproto/auth_service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"time"
+)
+
+// Config of the client. Zero retry settings are replaced with defaults, negative MaxRetries disables retries
+type Config struct {
+	Host           string        `yaml:"host"`
+	Port           int           `yaml:"port"`
+	MaxRetries     int           `yaml:"max-retries"`
+	InitialBackoff time.Duration `yaml:"initial-backoff"`
+	MaxBackoff     time.Duration `yaml:"max-backoff"`
+}
+
+// principal types of TokenInfo
+const (
+	PrincipalTypeUser    = "user"
+	PrincipalTypeService = "service"
+)
+
+// Tokens issued by the auth service. ExpiresAt is read from the access token and is zero
+// if the token is not a JWT
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// ServiceToken is an access token issued to a service account
+type ServiceToken struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	Scopes      []string
+}
+
+// TokenInfo describes a verified access token. PrincipalType is PrincipalTypeUser or PrincipalTypeService,
+// UserId is not set and ClientId identifies the service in tokens of services
+type TokenInfo struct {
+	PrincipalType string
+	UserId        int64
+	ClientId      string
+	TenantId      int64
+	Email         string
+	Roles         []string
+	Permissions   []string
+	Scopes        []string
+	TokenId       string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+}
+
+// Client is a typed client of AuthService. Errors reported by the service are converted
+// to the errors of this package, which can be checked with errors.Is
+type Client struct {
+	conn    *grpc.ClientConn
+	service pb.AuthServiceClient
+}
+
+// New dials the auth service with retries of unavailable calls. Insecure transport is used
+// unless transport credentials are passed in opts
+func New(config *Config, opts ...grpc.DialOption) (*Client, error) {
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(UnaryRetryInterceptor(newRetryPolicy(config))),
+	}, opts...)
+
+	conn, err := grpc.DialContext(context.Background(), fmt.Sprintf("%s:%d", config.Host, config.Port), dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("auth service dial host=%s port=%d err=%v", config.Host, config.Port, err)
+	}
+
+	return &Client{conn: conn, service: pb.NewAuthServiceClient(conn)}, nil
+}
+
+// NewFromConn returns a client using the existing connection, the connection is not closed by Close
+func NewFromConn(conn grpc.ClientConnInterface) *Client {
+	return &Client{service: pb.NewAuthServiceClient(conn)}
+}
+
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+func (c *Client) Register(ctx context.Context, email string, password string) error {
+	_, err := c.service.Register(ctx, &pb.RegisterRequest{Email: email, Password: password})
+	return toError(err)
+}
+
+func (c *Client) Verify(ctx context.Context, email string, code string) error {
+	_, err := c.service.Verify(ctx, &pb.VerifyRequest{Email: email, Code: code})
+	return toError(err)
+}
+
+// Login returns *MfaRequiredError if the user has enabled MFA, it can be checked with errors.As
+func (c *Client) Login(ctx context.Context, email string, password string) (*Tokens, error) {
+	resp, err := c.service.Login(ctx, &pb.LoginRequest{Email: email, Password: password})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toLoginTokens(resp)
+}
+
+// StartEmailLogin sends a login code to the email, it succeeds for not registered emails as well
+func (c *Client) StartEmailLogin(ctx context.Context, email string) error {
+	_, err := c.service.StartEmailLogin(ctx, &pb.StartEmailLoginRequest{Email: email})
+	return toError(err)
+}
+
+// CompleteEmailLogin returns *MfaRequiredError like Login if the user has enabled MFA
+func (c *Client) CompleteEmailLogin(ctx context.Context, email string, code string) (*Tokens, error) {
+	resp, err := c.service.CompleteEmailLogin(ctx, &pb.CompleteEmailLoginRequest{Email: email, Code: code})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toLoginTokens(resp)
+}
+
+// CompleteMfa issues tokens for the MFA token returned by Login, code is a code of the authenticator app
+// or a recovery code
+func (c *Client) CompleteMfa(ctx context.Context, mfaToken string, code string) (*Tokens, error) {
+	resp, err := c.service.CompleteMfa(ctx, &pb.CompleteMfaRequest{MfaToken: mfaToken, Code: code})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toTokens(resp), nil
+}
+
+func (c *Client) VerifyToken(ctx context.Context, token string) (*TokenInfo, error) {
+	resp, err := c.service.VerifyToken(ctx, &pb.VerifyTokenRequest{Token: token})
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &TokenInfo{
+		PrincipalType: resp.PrincipalType,
+		UserId:        resp.UserId,
+		ClientId:      resp.ClientId,
+		TenantId:      resp.TenantId,
+		Email:         resp.Email,
+		Roles:         resp.Roles,
+		Permissions:   resp.Permissions,
+		Scopes:        resp.Scopes,
+		TokenId:       resp.TokenId,
+		IssuedAt:      resp.IssuedAt.AsTime(),
+		ExpiresAt:     resp.ExpiresAt.AsTime(),
+	}, nil
+}
+
+// IssueServiceToken issues an access token to the service account, all scopes of the account are granted
+// if scopes are empty. Service tokens are not refreshed, a new one is issued when the token expires
+func (c *Client) IssueServiceToken(ctx context.Context, clientId string, clientSecret string, scopes []string) (*ServiceToken, error) {
+	resp, err := c.service.IssueServiceToken(ctx, &pb.IssueServiceTokenRequest{
+		ClientId:     clientId,
+		ClientSecret: clientSecret,
+		Scopes:       scopes,
+	})
+	if err != nil {
+		return nil, toError(err)
+	}
+
+	return &ServiceToken{
+		AccessToken: resp.AccessToken,
+		ExpiresAt:   resp.ExpiresAt.AsTime(),
+		Scopes:      resp.Scopes,
+	}, nil
+}
+
+// Refresh exchanges the refresh token for new tokens, the refresh token can not be used again
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	resp, err := c.service.Refresh(ctx, &pb.RefreshRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return nil, toError(err)
+	}
+	return toTokens(resp), nil
+}
+
+// Logout revokes the access token and the session of the refresh token, refresh token is optional
+func (c *Client) Logout(ctx context.Context, tokens *Tokens) error {
+	_, err := c.service.Logout(ctx, &pb.LogoutRequest{AccessToken: tokens.AccessToken, RefreshToken: tokens.RefreshToken})
+	return toError(err)
+}
+
+func toLoginTokens(resp *pb.AuthResponse) (*Tokens, error) {
+	if resp.MfaRequired {
+		return nil, &MfaRequiredError{MfaToken: resp.MfaToken, ExpiresAt: resp.MfaTokenExpiresAt.AsTime()}
+	}
+	return toTokens(resp), nil
+}
+
+func toTokens(resp *pb.AuthResponse) *Tokens {
+	return &Tokens{
+		AccessToken:  resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		ExpiresAt:    accessTokenExpiration(resp.AccessToken),
+	}
+}
@@ -0,0 +1,47 @@
+package authclient
+
+import (
+	"context"
+)
+
+// AccessTokenSource returns a valid access token, it is implemented by TokenSource and ServiceTokenSource
+type AccessTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// PerRPCCredentials attaches 'authorization: Bearer <access token>' metadata to calls,
+// pass it with grpc.WithPerRPCCredentials or grpc.PerRPCCredentials
+type PerRPCCredentials struct {
+	source                   AccessTokenSource
+	requireTransportSecurity bool
+}
+
+// NewPerRPCCredentials returns credentials using tokens of the source. Tokens should only be sent
+// over insecure connections in tests and local environments
+func NewPerRPCCredentials(source AccessTokenSource, requireTransportSecurity bool) *PerRPCCredentials {
+	return &PerRPCCredentials{source: source, requireTransportSecurity: requireTransportSecurity}
+}
+
+// withoutCredentials marks calls which must be sent without the access token, e.g. the refresh
+// of the token source itself, which would wait for its own result otherwise
+func withoutCredentials(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutCredentialsKey{}, true)
+}
+
+type withoutCredentialsKey struct{}
+
+func (c *PerRPCCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	if ctx.Value(withoutCredentialsKey{}) != nil {
+		return nil, nil
+	}
+
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"authorization": "Bearer " + token}, nil
+}
+
+func (c *PerRPCCredentials) RequireTransportSecurity() bool {
+	return c.requireTransportSecurity
+}
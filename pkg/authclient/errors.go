@@ -0,0 +1,114 @@
+package authclient
+
+import (
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// errorDomain of google.rpc.ErrorInfo details sent by the auth service
+const errorDomain = "auth-grpc"
+
+var (
+	ErrInvalidArgument         = errors.New("invalid argument")
+	ErrUserAlreadyExists       = errors.New("user with specified email already exists")
+	ErrInvalidCredentials      = errors.New("invalid email or password")
+	ErrVerificationCodeExpired = errors.New("verification code has expired")
+	ErrInvalidVerificationCode = errors.New("invalid verification code")
+	ErrTooManyAttempts         = errors.New("too many attempts")
+	ErrTooManyRequests         = errors.New("too many requests")
+	ErrTokenExpired            = errors.New("token has expired")
+	ErrTokenInvalid            = errors.New("token is invalid")
+	ErrTokenRevoked            = errors.New("token has been revoked")
+	ErrRefreshTokenInvalid     = errors.New("refresh token is invalid")
+	ErrRefreshTokenReused      = errors.New("refresh token has already been used")
+	ErrMfaTokenInvalid         = errors.New("mfa token is invalid or has expired")
+	ErrInvalidMfaCode          = errors.New("invalid mfa code")
+	ErrInvalidServiceAccount   = errors.New("invalid client id or secret of service account")
+	ErrInvalidScope            = errors.New("requested scope is not allowed")
+	ErrPermissionDenied        = errors.New("permission denied")
+
+	// ErrMfaRequired is matched by *MfaRequiredError returned by Login
+	ErrMfaRequired = errors.New("mfa is required")
+)
+
+// reasonErrors maps reasons of google.rpc.ErrorInfo details to the errors
+var reasonErrors = map[string]error{
+	"INVALID_ARGUMENT":                    ErrInvalidArgument,
+	"USER_ALREADY_EXISTS":                 ErrUserAlreadyExists,
+	"INVALID_CREDENTIALS":                 ErrInvalidCredentials,
+	"VERIFICATION_CODE_EXPIRED":           ErrVerificationCodeExpired,
+	"INVALID_VERIFICATION_CODE":           ErrInvalidVerificationCode,
+	"TOO_MANY_ATTEMPTS":                   ErrTooManyAttempts,
+	"TOO_MANY_REQUESTS":                   ErrTooManyRequests,
+	"TOKEN_EXPIRED":                       ErrTokenExpired,
+	"TOKEN_INVALID":                       ErrTokenInvalid,
+	"TOKEN_REVOKED":                       ErrTokenRevoked,
+	"REFRESH_TOKEN_INVALID":               ErrRefreshTokenInvalid,
+	"REFRESH_TOKEN_REUSED":                ErrRefreshTokenReused,
+	"MFA_TOKEN_INVALID":                   ErrMfaTokenInvalid,
+	"INVALID_MFA_CODE":                    ErrInvalidMfaCode,
+	"SERVICE_ACCOUNT_CREDENTIALS_INVALID": ErrInvalidServiceAccount,
+	"INVALID_SCOPE":                       ErrInvalidScope,
+	"PERMISSION_DENIED":                   ErrPermissionDenied,
+}
+
+// Error is returned for known errors of the auth service, it matches one of the errors
+// of this package with errors.Is and keeps the gRPC status
+type Error struct {
+	err    error
+	status *status.Status
+}
+
+func (e *Error) Error() string {
+	return e.status.Err().Error()
+}
+
+func (e *Error) Is(target error) bool {
+	return e.err == target
+}
+
+// GRPCStatus makes status.FromError return the original status
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// MfaRequiredError is returned by Login if the user has enabled MFA,
+// MfaToken is passed to CompleteMfa along with a code before ExpiresAt
+type MfaRequiredError struct {
+	MfaToken  string
+	ExpiresAt time.Time
+}
+
+func (e *MfaRequiredError) Error() string {
+	return ErrMfaRequired.Error()
+}
+
+func (e *MfaRequiredError) Is(target error) bool {
+	return target == ErrMfaRequired
+}
+
+// toError converts a status error with a known reason to *Error, other errors are returned as is
+func toError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, detail := range st.Details() {
+		errorInfo, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || errorInfo.Domain != errorDomain {
+			continue
+		}
+		if reasonErr, ok := reasonErrors[errorInfo.Reason]; ok {
+			return &Error{err: reasonErr, status: st}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,76 @@
+package authclient
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"time"
+)
+
+const (
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 2 * time.Second
+)
+
+// RetryPolicy retries calls failed with codes which guarantee that the call has not been processed
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func newRetryPolicy(config *Config) *RetryPolicy {
+	policy := &RetryPolicy{
+		MaxRetries:     config.MaxRetries,
+		InitialBackoff: config.InitialBackoff,
+		MaxBackoff:     config.MaxBackoff,
+	}
+
+	if policy.MaxRetries == 0 {
+		policy.MaxRetries = defaultMaxRetries
+	}
+	if policy.InitialBackoff == 0 {
+		policy.InitialBackoff = defaultInitialBackoff
+	}
+	if policy.MaxBackoff == 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+
+	return policy
+}
+
+// UnaryRetryInterceptor retries unavailable calls with exponential backoff and full jitter
+func UnaryRetryInterceptor(policy *RetryPolicy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		for attempt := 0; attempt < policy.MaxRetries && isRetryable(err); attempt++ {
+			timer := time.NewTimer(policy.backoff(attempt))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return err
+	}
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff << attempt
+	if backoff <= 0 || backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(backoff) + 1))
+}
+
+func isRetryable(err error) bool {
+	return status.Code(err) == codes.Unavailable
+}
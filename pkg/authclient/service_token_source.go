@@ -0,0 +1,52 @@
+package authclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ServiceTokenSource issues access tokens of a service account with its secret and issues a new one
+// shortly before the token expires. It may be used as PerRPCCredentials of the connection of its own client
+type ServiceTokenSource struct {
+	client        *Client
+	clientId      string
+	clientSecret  string
+	scopes        []string
+	refreshBefore time.Duration
+
+	mu    sync.Mutex
+	token *ServiceToken
+}
+
+// NewServiceTokenSource returns a source of tokens with the scopes, all scopes of the account are granted
+// if scopes are empty
+func (c *Client) NewServiceTokenSource(clientId string, clientSecret string, scopes []string) *ServiceTokenSource {
+	return &ServiceTokenSource{
+		client:        c,
+		clientId:      clientId,
+		clientSecret:  clientSecret,
+		scopes:        scopes,
+		refreshBefore: defaultRefreshBefore,
+	}
+}
+
+// Token returns a valid access token of the service account issuing a new one if needed
+func (s *ServiceTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && time.Until(s.token.ExpiresAt) > s.refreshBefore {
+		return s.token.AccessToken, nil
+	}
+
+	// the call is authenticated with the secret, so it does not wait for credentials of this source
+	token, err := s.client.IssueServiceToken(withoutCredentials(ctx), s.clientId, s.clientSecret, s.scopes)
+	if err != nil {
+		return "", err
+	}
+
+	s.token = token
+
+	return token.AccessToken, nil
+}
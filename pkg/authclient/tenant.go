@@ -0,0 +1,14 @@
+package authclient
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+)
+
+const tenantMetadataKey = "x-tenant-id"
+
+// WithTenant returns a context making calls on behalf of the tenant given by its id or name,
+// calls without a tenant are made on behalf of the default tenant
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, tenantMetadataKey, tenant)
+}
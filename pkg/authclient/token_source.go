@@ -0,0 +1,163 @@
+package authclient
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"sync"
+	"time"
+)
+
+const defaultRefreshBefore = 30 * time.Second
+
+var ErrNoTokens = errors.New("no tokens, log in first")
+
+// TokenSource keeps tokens of a user and refreshes them shortly before the access token expires.
+// Refresh tokens are rotated by the auth service, so a token source must not share its tokens.
+// Concurrent callers wait for a single refresh, the lock is not held during the call to the service,
+// so the source may be used as PerRPCCredentials of the connection of its own client
+type TokenSource struct {
+	client        *Client
+	refreshBefore time.Duration
+	onRefresh     func(tokens *Tokens)
+
+	mu         sync.Mutex
+	tokens     *Tokens
+	refreshing *refreshCall
+}
+
+// refreshCall is a refresh in progress, done is closed when it finishes
+type refreshCall struct {
+	done   chan struct{}
+	tokens *Tokens
+	err    error
+}
+
+type TokenSourceOption func(*TokenSource)
+
+// WithRefreshBefore sets how long before expiration the access token is refreshed
+func WithRefreshBefore(refreshBefore time.Duration) TokenSourceOption {
+	return func(s *TokenSource) {
+		s.refreshBefore = refreshBefore
+	}
+}
+
+// WithOnRefresh sets a callback called with new tokens, it may be used to persist them
+func WithOnRefresh(onRefresh func(tokens *Tokens)) TokenSourceOption {
+	return func(s *TokenSource) {
+		s.onRefresh = onRefresh
+	}
+}
+
+func (c *Client) NewTokenSource(tokens *Tokens, opts ...TokenSourceOption) *TokenSource {
+	source := &TokenSource{
+		client:        c,
+		refreshBefore: defaultRefreshBefore,
+		tokens:        tokens,
+	}
+	for _, opt := range opts {
+		opt(source)
+	}
+	return source
+}
+
+// Token returns a valid access token refreshing tokens if needed
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	tokens := s.Tokens()
+	if tokens == nil {
+		return "", ErrNoTokens
+	}
+
+	if tokens.ExpiresAt.IsZero() || time.Until(tokens.ExpiresAt) > s.refreshBefore {
+		return tokens.AccessToken, nil
+	}
+
+	tokens, err := s.refresh(ctx, tokens)
+	if err != nil {
+		return "", err
+	}
+
+	return tokens.AccessToken, nil
+}
+
+// Refresh refreshes tokens regardless of the access token expiration
+func (s *TokenSource) Refresh(ctx context.Context) error {
+	tokens := s.Tokens()
+	if tokens == nil {
+		return ErrNoTokens
+	}
+
+	_, err := s.refresh(ctx, tokens)
+
+	return err
+}
+
+// Tokens returns the current tokens
+func (s *TokenSource) Tokens() *Tokens {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokens
+}
+
+// refresh replaces the stale tokens. If they have already been replaced by a concurrent caller,
+// the current tokens are returned, so a rotated refresh token is never sent twice
+func (s *TokenSource) refresh(ctx context.Context, stale *Tokens) (*Tokens, error) {
+	s.mu.Lock()
+
+	if s.tokens != stale {
+		tokens := s.tokens
+		s.mu.Unlock()
+		return tokens, nil
+	}
+
+	call := s.refreshing
+	if call != nil {
+		s.mu.Unlock()
+
+		select {
+		case <-call.done:
+			return call.tokens, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	call = &refreshCall{done: make(chan struct{})}
+	s.refreshing = call
+	s.mu.Unlock()
+
+	// the refresh token authenticates the call, access token of this source is not attached to it
+	call.tokens, call.err = s.client.Refresh(withoutCredentials(ctx), stale.RefreshToken)
+
+	if call.err == nil {
+		s.mu.Lock()
+		s.tokens = call.tokens
+		s.mu.Unlock()
+
+		// the next refresh waits for the callback, so callbacks get tokens in order
+		if s.onRefresh != nil {
+			s.onRefresh(call.tokens)
+		}
+	}
+
+	s.mu.Lock()
+	s.refreshing = nil
+	s.mu.Unlock()
+
+	close(call.done)
+
+	return call.tokens, call.err
+}
+
+// accessTokenExpiration reads 'exp' claim without verification, the token is verified by the service
+func accessTokenExpiration(accessToken string) time.Time {
+	var claims jwt.RegisteredClaims
+
+	_, _, err := jwt.NewParser().ParseUnverified(accessToken, &claims)
+	if err != nil || claims.ExpiresAt == nil {
+		return time.Time{}
+	}
+
+	return claims.ExpiresAt.Time
+}
@@ -0,0 +1,194 @@
+package authclient_test
+
+import (
+	"context"
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/authclient"
+	"github.com/vaberof/auth-grpc/pkg/authclient/authclienttest"
+	"google.golang.org/grpc"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testEmail    = "user@example.com"
+	testPassword = "password"
+	testTimeout  = 5 * time.Second
+)
+
+// sourceCredentials attaches tokens of a source created after the connection
+type sourceCredentials struct {
+	credentials *authclient.PerRPCCredentials
+}
+
+func (c *sourceCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return c.credentials.GetRequestMetadata(ctx, uri...)
+}
+
+func (c *sourceCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func newServer(t *testing.T) *authclienttest.Server {
+	t.Helper()
+
+	server := authclienttest.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddUser(testEmail, testPassword)
+
+	return server
+}
+
+func login(t *testing.T, ctx context.Context, client *authclient.Client) *authclient.Tokens {
+	t.Helper()
+
+	tokens, err := client.Login(ctx, testEmail, testPassword)
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	return tokens
+}
+
+func TestTokenSourceRefreshesOverOwnCredentials(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	server := newServer(t)
+
+	credentials := &sourceCredentials{}
+	client, err := server.Client(ctx, grpc.WithPerRPCCredentials(credentials))
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+
+	loginClient, err := server.Client(ctx)
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+
+	tokens := login(t, ctx, loginClient)
+
+	// every token is refreshed, so the refresh is sent over the connection with the credentials of the source
+	source := client.NewTokenSource(tokens, authclient.WithRefreshBefore(time.Hour))
+	credentials.credentials = authclient.NewPerRPCCredentials(source, false)
+
+	type result struct {
+		token string
+		err   error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		token, err := source.Token(ctx)
+		resultCh <- result{token: token, err: err}
+	}()
+
+	select {
+	case res := <-resultCh:
+		if res.err != nil {
+			t.Fatalf("Token() error = %v", res.err)
+		}
+		if res.token == tokens.AccessToken {
+			t.Errorf("Token() returned the stale access token")
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("Token() deadlocked refreshing tokens over its own credentials")
+	}
+}
+
+func TestTokenSourceConcurrentRefresh(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	server := newServer(t)
+
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+
+	// the login token expires within the refresh margin, refreshed tokens do not
+	server.SetTokenTtl(time.Second)
+	tokens := login(t, ctx, client)
+	server.SetTokenTtl(time.Hour)
+
+	var refreshes atomic.Int32
+	source := client.NewTokenSource(tokens, authclient.WithOnRefresh(func(*authclient.Tokens) {
+		refreshes.Add(1)
+	}))
+
+	const callers = 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	accessTokens := make(chan string, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := source.Token(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			accessTokens <- token
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	close(accessTokens)
+
+	// a second refresh would send the rotated refresh token and fail
+	for err := range errs {
+		t.Errorf("Token() error = %v", err)
+	}
+
+	if got := refreshes.Load(); got != 1 {
+		t.Errorf("refreshes = %d, want 1", got)
+	}
+
+	current := source.Tokens().AccessToken
+	for token := range accessTokens {
+		if token != current {
+			t.Errorf("Token() = %q, want the refreshed token %q", token, current)
+		}
+	}
+}
+
+func TestTokenSourceRefreshError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+	defer cancel()
+
+	server := newServer(t)
+
+	client, err := server.Client(ctx)
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+
+	tokens := login(t, ctx, client)
+	tokens.RefreshToken = "unknown"
+
+	source := client.NewTokenSource(tokens)
+
+	err = source.Refresh(ctx)
+	if !errors.Is(err, authclient.ErrRefreshTokenInvalid) {
+		t.Fatalf("Refresh() error = %v, want %v", err, authclient.ErrRefreshTokenInvalid)
+	}
+
+	if source.Tokens() != tokens {
+		t.Errorf("Tokens() changed after a failed refresh")
+	}
+
+	_, err = client.NewTokenSource(nil).Token(ctx)
+	if !errors.Is(err, authclient.ErrNoTokens) {
+		t.Errorf("Token() error = %v, want %v", err, authclient.ErrNoTokens)
+	}
+}
@@ -8,6 +8,16 @@ func (userId *UserId) String() string {
 	return strconv.FormatInt(int64(*userId), 10)
 }
 
+// TenantId identifies an organization users belong to, emails are unique within a tenant
+type TenantId int64
+
+// DefaultTenantId is the tenant of requests not specifying one and of users created before tenants
+const DefaultTenantId TenantId = 1
+
+func (tenantId *TenantId) String() string {
+	return strconv.FormatInt(int64(*tenantId), 10)
+}
+
 type Email string
 
 func (email *Email) String() string {
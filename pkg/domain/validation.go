@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxEmailLength          = 254
+	maxEmailLocalPartLength = 64
+)
+
+// FieldViolation describes why a single field of a request is invalid
+type FieldViolation struct {
+	Field       string
+	Description string
+}
+
+// ValidationError is returned when one or more fields of a request are invalid
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	descriptions := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		descriptions[i] = fmt.Sprintf("%s: %s", violation.Field, violation.Description)
+	}
+	return "validation failed: " + strings.Join(descriptions, "; ")
+}
+
+// Validator collects field violations of a request
+type Validator struct {
+	violations []FieldViolation
+}
+
+func NewValidator() *Validator {
+	return &Validator{}
+}
+
+// Field adds a violation of the field if err is not nil
+func (v *Validator) Field(field string, err error) *Validator {
+	if err != nil {
+		v.Violation(field, err.Error())
+	}
+	return v
+}
+
+// Violation adds a violation of the field with the description
+func (v *Validator) Violation(field string, description string) *Validator {
+	v.violations = append(v.violations, FieldViolation{Field: field, Description: description})
+	return v
+}
+
+// Err returns *ValidationError if any violation has been collected
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+// Validate checks that email is a bare RFC 5322 address without a display name
+func (email *Email) Validate() error {
+	value := email.String()
+	if value == "" {
+		return errors.New("must not be empty")
+	}
+
+	// RFC 5321 limits the length of addresses in octets
+	if len(value) > MaxEmailLength {
+		return fmt.Errorf("must not be longer than %d bytes", MaxEmailLength)
+	}
+
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Name != "" || address.Address != value {
+		return errors.New("must be a valid email address")
+	}
+
+	at := strings.LastIndex(value, "@")
+	if at > maxEmailLocalPartLength {
+		return fmt.Errorf("local part must not be longer than %d bytes", maxEmailLocalPartLength)
+	}
+
+	return nil
+}
+
+// Validate checks that code consists of exactly length digits
+func (code *Code) Validate(length int) error {
+	value := code.String()
+	if len(value) != length {
+		return fmt.Errorf("must be %d digits long", length)
+	}
+
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("must be %d digits long", length)
+		}
+	}
+
+	return nil
+}
+
+// ValidateLength checks that password length in characters is within the limits, zero limit is not checked
+func (password *Password) ValidateLength(minLength int, maxLength int) error {
+	length := utf8.RuneCountInString(password.String())
+
+	if length == 0 {
+		return errors.New("must not be empty")
+	}
+
+	if minLength > 0 && length < minLength {
+		return fmt.Errorf("must be at least %d characters long", minLength)
+	}
+
+	if maxLength > 0 && length > maxLength {
+		return fmt.Errorf("must not be longer than %d characters", maxLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package domain_test
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidator(t *testing.T) {
+	err := domain.NewValidator().
+		Field("email", nil).
+		Field("password", errors.New("must not be empty")).
+		Violation("code", "must be 6 digits long").
+		Err()
+
+	var validationErr *domain.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Err() = %v, want *domain.ValidationError", err)
+	}
+
+	want := []domain.FieldViolation{
+		{Field: "password", Description: "must not be empty"},
+		{Field: "code", Description: "must be 6 digits long"},
+	}
+	if !reflect.DeepEqual(validationErr.Violations, want) {
+		t.Errorf("Violations = %+v, want %+v", validationErr.Violations, want)
+	}
+
+	wantMessage := "validation failed: password: must not be empty; code: must be 6 digits long"
+	if validationErr.Error() != wantMessage {
+		t.Errorf("Error() = %q, want %q", validationErr.Error(), wantMessage)
+	}
+}
+
+func TestValidatorWithoutViolations(t *testing.T) {
+	err := domain.NewValidator().Field("email", nil).Err()
+	if err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+func TestEmailValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		email   domain.Email
+		wantErr bool
+	}{
+		{name: "valid", email: "user@example.com"},
+		{name: "valid with subaddress", email: "user+tag@example.com"},
+		{name: "empty", email: "", wantErr: true},
+		{name: "without at", email: "user.example.com", wantErr: true},
+		{name: "with display name", email: "User <user@example.com>", wantErr: true},
+		{name: "with surrounding spaces", email: " user@example.com", wantErr: true},
+		{name: "too long", email: domain.Email(strings.Repeat("a", 64) + "@" + strings.Repeat("b", domain.MaxEmailLength)), wantErr: true},
+		{name: "too long local part", email: domain.Email(strings.Repeat("a", 65) + "@example.com"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.email.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCodeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    domain.Code
+		wantErr bool
+	}{
+		{name: "valid", code: "012345"},
+		{name: "too short", code: "12345", wantErr: true},
+		{name: "too long", code: "1234567", wantErr: true},
+		{name: "not digits", code: "12a456", wantErr: true},
+		{name: "non ascii digits", code: "١٢٣٤٥٦", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.code.Validate(6)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordValidateLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		password domain.Password
+		wantErr  string
+	}{
+		{name: "within limits", password: "password"},
+		{name: "empty", password: "", wantErr: "must not be empty"},
+		{name: "too short", password: "pass", wantErr: "must be at least 5 characters long"},
+		{name: "too long", password: "password-1", wantErr: "must not be longer than 8 characters"},
+		// 8 characters take 16 bytes
+		{name: "multibyte within limits", password: "пароль12"},
+		{name: "multibyte too short", password: "пар", wantErr: "must be at least 5 characters long"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.password.ValidateLength(5, 8)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateLength() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateLength() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
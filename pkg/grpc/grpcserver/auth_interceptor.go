@@ -0,0 +1,160 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+const (
+	authorizationMetadataKey = "authorization"
+	bearerScheme             = "bearer "
+)
+
+// TokenVerifier returns claims of a valid access token. Status errors are returned
+// to the caller as is, other errors are reported as Unauthenticated
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string) (*auth.JwtPayload, error)
+}
+
+// AccessTokenVerifier verifies tokens offline with accesstoken.Verify, so services
+// trusting tokens of the auth service don't have to call it for every request
+type AccessTokenVerifier struct {
+	keys    accesstoken.KeySet
+	options *accesstoken.VerifyOptions
+}
+
+func NewAccessTokenVerifier(keys accesstoken.KeySet, options *accesstoken.VerifyOptions) *AccessTokenVerifier {
+	return &AccessTokenVerifier{keys: keys, options: options}
+}
+
+func (v *AccessTokenVerifier) VerifyToken(ctx context.Context, token string) (*auth.JwtPayload, error) {
+	payload, err := accesstoken.Verify(token, v.keys, v.options)
+	if err != nil {
+		if errors.Is(err, accesstoken.ErrExpiredToken) {
+			return nil, status.Error(codes.Unauthenticated, "token has expired")
+		}
+		return nil, status.Error(codes.Unauthenticated, "token is invalid")
+	}
+	return payload, nil
+}
+
+// WithAuthentication adds unary and stream interceptors authenticating all the methods except public ones
+func WithAuthentication(verifier TokenVerifier, publicMethods ...string) Option {
+	return func(opts *serverOptions) {
+		opts.unaryInterceptors = append(opts.unaryInterceptors, UnaryAuthInterceptor(verifier, publicMethods...))
+		opts.streamInterceptors = append(opts.streamInterceptors, StreamAuthInterceptor(verifier, publicMethods...))
+	}
+}
+
+// UnaryAuthInterceptor requires 'authorization: Bearer <token>' metadata for all the methods except
+// public ones and puts the token claims and owner to the context, see auth.ClaimsFromContext and
+// auth.UserIdFromContext. Public methods are full method names like '/package.Service/Method',
+// '/package.Service/*' makes all the methods of the service public
+func UnaryAuthInterceptor(verifier TokenVerifier, publicMethods ...string) grpc.UnaryServerInterceptor {
+	isPublic := publicMethodMatcher(publicMethods)
+
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublic(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, verifier)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor is the same as UnaryAuthInterceptor for streaming methods
+func StreamAuthInterceptor(verifier TokenVerifier, publicMethods ...string) grpc.StreamServerInterceptor {
+	isPublic := publicMethodMatcher(publicMethods)
+
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublic(info.FullMethod) {
+			return handler(srv, stream)
+		}
+
+		ctx, err := authenticate(stream.Context(), verifier)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, verifier TokenVerifier) (context.Context, error) {
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := verifier.VerifyToken(ctx, token)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+
+	return auth.ClaimsToContext(ctx, claims), nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get(authorizationMetadataKey)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "access token is required")
+	}
+
+	value := values[0]
+	if len(value) <= len(bearerScheme) || !strings.EqualFold(value[:len(bearerScheme)], bearerScheme) {
+		return "", status.Error(codes.Unauthenticated, "authorization metadata must use Bearer scheme")
+	}
+
+	return strings.TrimSpace(value[len(bearerScheme):]), nil
+}
+
+func publicMethodMatcher(publicMethods []string) func(fullMethod string) bool {
+	methods := make(map[string]struct{})
+	var services []string
+
+	for _, method := range publicMethods {
+		if service, ok := strings.CutSuffix(method, "/*"); ok {
+			services = append(services, service+"/")
+			continue
+		}
+		methods[method] = struct{}{}
+	}
+
+	return func(fullMethod string) bool {
+		if _, ok := methods[fullMethod]; ok {
+			return true
+		}
+		for _, service := range services {
+			if strings.HasPrefix(fullMethod, service) {
+				return true
+			}
+		}
+		return false
+	}
+}
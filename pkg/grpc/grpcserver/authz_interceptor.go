@@ -0,0 +1,143 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"slices"
+)
+
+// ReasonPermissionDenied is sent in google.rpc.ErrorInfo details of the errors returned by the rules,
+// the metadata holds the missing permission, role or scope
+const ReasonPermissionDenied = "PERMISSION_DENIED"
+
+// AuthorizationRule decides whether the owner of the claims may call a method,
+// it returns a status error if the call is not allowed
+type AuthorizationRule func(claims *auth.JwtPayload) error
+
+// RequirePermission allows a call if the access token carries the permission
+func RequirePermission(permission string) AuthorizationRule {
+	return func(claims *auth.JwtPayload) error {
+		if !slices.Contains(claims.Permissions, permission) {
+			return permissionDenied("permission", permission)
+		}
+		return nil
+	}
+}
+
+// RequireRole allows a call if the access token carries the role
+func RequireRole(role string) AuthorizationRule {
+	return func(claims *auth.JwtPayload) error {
+		if !slices.Contains(claims.Roles, role) {
+			return permissionDenied("role", role)
+		}
+		return nil
+	}
+}
+
+// RequireScope allows a call if the access token carries the scope, scopes are granted to service accounts
+func RequireScope(scope string) AuthorizationRule {
+	return func(claims *auth.JwtPayload) error {
+		if !slices.Contains(claims.Scopes, scope) {
+			return permissionDenied("scope", scope)
+		}
+		return nil
+	}
+}
+
+// RequireTenant allows a call if the access token belongs to the tenant, it restricts permissions which are
+// not scoped to a tenant, e.g. management of signing keys, to administrators of one tenant
+func RequireTenant(tenantId domain.TenantId) AuthorizationRule {
+	return func(claims *auth.JwtPayload) error {
+		if claims.TenantId != tenantId {
+			return permissionDenied("tenant", tenantId.String())
+		}
+		return nil
+	}
+}
+
+// RequireAll allows a call if all the rules allow it, the error of the first rule denying it is returned otherwise
+func RequireAll(rules ...AuthorizationRule) AuthorizationRule {
+	return func(claims *auth.JwtPayload) error {
+		for _, rule := range rules {
+			err := rule(claims)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// RequireAny allows a call if any of the rules allows it, the error of the first rule is returned otherwise
+func RequireAny(rules ...AuthorizationRule) AuthorizationRule {
+	return func(claims *auth.JwtPayload) error {
+		var firstErr error
+		for _, rule := range rules {
+			err := rule(claims)
+			if err == nil {
+				return nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+}
+
+// WithAuthorization adds unary and stream interceptors enforcing the rules per full method name,
+// it must be added after WithAuthentication, so the claims are already in the context
+func WithAuthorization(rules map[string]AuthorizationRule) Option {
+	return func(opts *serverOptions) {
+		opts.unaryInterceptors = append(opts.unaryInterceptors, UnaryAuthorizationInterceptor(rules))
+		opts.streamInterceptors = append(opts.streamInterceptors, StreamAuthorizationInterceptor(rules))
+	}
+}
+
+// UnaryAuthorizationInterceptor checks the rule of the called method against the claims put to the context
+// by UnaryAuthInterceptor. Methods without a rule are not checked
+func UnaryAuthorizationInterceptor(rules map[string]AuthorizationRule) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		err := authorize(ctx, rules, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthorizationInterceptor is the same as UnaryAuthorizationInterceptor for streaming methods
+func StreamAuthorizationInterceptor(rules map[string]AuthorizationRule) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := authorize(stream.Context(), rules, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+func authorize(ctx context.Context, rules map[string]AuthorizationRule, fullMethod string) error {
+	rule, ok := rules[fullMethod]
+	if !ok {
+		return nil
+	}
+
+	claims := auth.ClaimsFromContext(ctx)
+	if claims == nil {
+		return status.Error(codes.Unauthenticated, "access token is required")
+	}
+
+	return rule(claims)
+}
+
+func permissionDenied(kind string, value string) error {
+	return newStatusError(codes.PermissionDenied, ReasonPermissionDenied, fmt.Sprintf("%s %q is required", kind, value), map[string]string{kind: value})
+}
@@ -0,0 +1,61 @@
+package grpcserver
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is put to google.rpc.ErrorInfo details, so clients can tell errors
+// of the auth service from errors of other services with the same reasons
+const ErrorDomain = "auth-grpc"
+
+// NewStatusError returns gRPC status error with google.rpc.ErrorInfo details
+func NewStatusError(code codes.Code, reason string, message string) error {
+	return newStatusError(code, reason, message, nil)
+}
+
+// NewValidationStatusError returns InvalidArgument status error with google.rpc.ErrorInfo details
+// and google.rpc.BadRequest details with field violations of the validation error
+func NewValidationStatusError(reason string, validationErr *domain.ValidationError) error {
+	st := status.New(codes.InvalidArgument, validationErr.Error())
+
+	violations := make([]*errdetails.BadRequest_FieldViolation, len(validationErr.Violations))
+	for i, violation := range validationErr.Violations {
+		violations[i] = &errdetails.BadRequest_FieldViolation{
+			Field:       violation.Field,
+			Description: violation.Description,
+		}
+	}
+
+	stWithDetails, err := st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason: reason,
+			Domain: ErrorDomain,
+		},
+		&errdetails.BadRequest{
+			FieldViolations: violations,
+		},
+	)
+	if err != nil {
+		return st.Err()
+	}
+
+	return stWithDetails.Err()
+}
+
+func newStatusError(code codes.Code, reason string, message string, metadata map[string]string) error {
+	st := status.New(code, message)
+
+	stWithDetails, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   ErrorDomain,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return stWithDetails.Err()
+}
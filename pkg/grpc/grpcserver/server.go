@@ -21,16 +21,53 @@ type AppServer struct {
 	logger *slog.Logger
 }
 
-func New(config *ServerConfig, logs *logs.Logs) *AppServer {
+// Option configures AppServer
+type Option func(*serverOptions)
+
+type serverOptions struct {
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+}
+
+// WithUnaryInterceptors adds interceptors called after the recovery and logging ones
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(opts *serverOptions) {
+		opts.unaryInterceptors = append(opts.unaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors adds interceptors called after the recovery and logging ones
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(opts *serverOptions) {
+		opts.streamInterceptors = append(opts.streamInterceptors, interceptors...)
+	}
+}
+
+func New(config *ServerConfig, logs *logs.Logs, opts ...Option) *AppServer {
 	logger := logs.WithName("gRPC-server")
 
+	var options serverOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	loggingOpts := getLoggingOpts()
 	recoveryOpts := getRecoveryOpts(logger)
 
-	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+	unaryInterceptors := append([]grpc.UnaryServerInterceptor{
 		recovery.UnaryServerInterceptor(recoveryOpts...),
 		logging.UnaryServerInterceptor(InterceptorLogger(logs.GetLogger()), loggingOpts...),
-	))
+	}, options.unaryInterceptors...)
+
+	streamInterceptors := append([]grpc.StreamServerInterceptor{
+		recovery.StreamServerInterceptor(recoveryOpts...),
+		logging.StreamServerInterceptor(InterceptorLogger(logs.GetLogger()), loggingOpts...),
+	}, options.streamInterceptors...)
+
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
 
 	appServer := &AppServer{
 		Server:  grpcServer,
@@ -56,7 +93,7 @@ func (server *AppServer) StartAsync() <-chan error {
 	go func() {
 		err = server.Server.Serve(listener)
 		if err != nil {
-			server.logger.Error("Failed to start gRPC server", slog.Group("error", err))
+			server.logger.Error("Failed to start gRPC server", slog.String("error", err.Error()))
 
 			exitChannel <- err
 		} else {
@@ -0,0 +1,83 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/vaberof/auth-grpc/pkg/auth"
+	"github.com/vaberof/auth-grpc/pkg/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const tenantMetadataKey = "x-tenant-id"
+
+// TenantResolver returns the tenant identified by the value of 'x-tenant-id' metadata. Status errors
+// are returned to the caller as is, other errors are reported as Internal
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, tenant string) (domain.TenantId, error)
+}
+
+// WithTenantResolution adds unary and stream interceptors resolving tenants of the requests,
+// it must be added after WithAuthentication, so the claims are already in the context
+func WithTenantResolution(resolver TenantResolver) Option {
+	return func(opts *serverOptions) {
+		opts.unaryInterceptors = append(opts.unaryInterceptors, UnaryTenantInterceptor(resolver))
+		opts.streamInterceptors = append(opts.streamInterceptors, StreamTenantInterceptor(resolver))
+	}
+}
+
+// UnaryTenantInterceptor puts the tenant of the request to the context, see auth.TenantIdFromContext.
+// The tenant is resolved from 'x-tenant-id' metadata, requests without it belong to the tenant of
+// the access token they have been authenticated with or to the default tenant. Requests authenticated
+// with a token of another tenant than the one in the metadata are rejected
+func UnaryTenantInterceptor(resolver TenantResolver) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := resolveTenant(ctx, resolver)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamTenantInterceptor is the same as UnaryTenantInterceptor for streaming methods
+func StreamTenantInterceptor(resolver TenantResolver) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := resolveTenant(stream.Context(), resolver)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func resolveTenant(ctx context.Context, resolver TenantResolver) (context.Context, error) {
+	claims := auth.ClaimsFromContext(ctx)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	values := md.Get(tenantMetadataKey)
+	if len(values) == 0 || values[0] == "" {
+		if claims != nil {
+			return auth.TenantIdToContext(ctx, claims.TenantId), nil
+		}
+		return auth.TenantIdToContext(ctx, domain.DefaultTenantId), nil
+	}
+
+	tenantId, err := resolver.ResolveTenant(ctx, values[0])
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Error(codes.Internal, "failed to resolve tenant")
+	}
+
+	if claims != nil && claims.TenantId != tenantId {
+		return nil, status.Error(codes.PermissionDenied, "access token belongs to another tenant")
+	}
+
+	return auth.TenantIdToContext(ctx, tenantId), nil
+}
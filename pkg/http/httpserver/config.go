@@ -0,0 +1,7 @@
+package httpserver
+
+type ServerConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+}
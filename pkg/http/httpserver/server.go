@@ -0,0 +1,88 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/logging/logs"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+type AppServer struct {
+	Mux     *http.ServeMux
+	server  *http.Server
+	config  *ServerConfig
+	address string
+
+	logger *slog.Logger
+}
+
+func New(config *ServerConfig, logs *logs.Logs) *AppServer {
+	logger := logs.WithName("HTTP-server")
+
+	mux := http.NewServeMux()
+	address := fmt.Sprintf("%s:%d", config.Host, config.Port)
+
+	appServer := &AppServer{
+		Mux: mux,
+		server: &http.Server{
+			Addr:              address,
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+		},
+		config:  config,
+		logger:  logger,
+		address: address,
+	}
+
+	return appServer
+}
+
+func (server *AppServer) StartAsync() <-chan error {
+	server.logger.Info("Starting HTTP server")
+
+	exitChannel := make(chan error, 1)
+
+	listener, err := net.Listen("tcp", server.address)
+	if err != nil {
+		exitChannel <- err
+		return exitChannel
+	}
+
+	go func() {
+		err = server.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			server.logger.Error("Failed to start HTTP server", slog.String("error", err.Error()))
+
+			exitChannel <- err
+		} else {
+			exitChannel <- nil
+		}
+	}()
+
+	server.logger.Info("Started HTTP server", slog.Group("HTTP-server", "address", server.address))
+
+	return exitChannel
+}
+
+func (server *AppServer) Shutdown() {
+	server.logger.Info("Stopping HTTP server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := server.server.Shutdown(ctx)
+	if err != nil {
+		server.logger.Error("Failed to stop HTTP server gracefully", slog.String("error", err.Error()))
+	}
+
+	server.logger.Info("HTTP server is stopped")
+}
@@ -0,0 +1,102 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// provider types
+const (
+	// ProviderTypeOidc is any OpenID Connect provider supporting discovery, e.g. Google with
+	// issuer 'https://accounts.google.com'
+	ProviderTypeOidc = "oidc"
+	// ProviderTypeGithub is GitHub OAuth app, it does not issue ID tokens, so the user is read from its API
+	ProviderTypeGithub = "github"
+)
+
+const defaultHttpTimeout = 10 * time.Second
+
+var ErrInvalidConfig = errors.New("invalid identity provider config")
+
+// Config of an identity provider. Endpoints override endpoints of the discovery document,
+// GitHub endpoints are used by default for github providers. RedirectUri is the callback of the client
+// app which receives the authorization code and the state
+type Config struct {
+	Name                  string        `yaml:"name"`
+	Type                  string        `yaml:"type"`
+	Issuer                string        `yaml:"issuer"`
+	ClientId              string        `yaml:"client-id"`
+	ClientSecret          string        `yaml:"client-secret"`
+	RedirectUri           string        `yaml:"redirect-uri"`
+	Scopes                []string      `yaml:"scopes"`
+	AuthorizationEndpoint string        `yaml:"authorization-endpoint"`
+	TokenEndpoint         string        `yaml:"token-endpoint"`
+	UserinfoEndpoint      string        `yaml:"userinfo-endpoint"`
+	JwksUri               string        `yaml:"jwks-uri"`
+	HttpTimeout           time.Duration `yaml:"http-timeout"`
+}
+
+// GitHub endpoints, emails are read from '<userinfo endpoint>/emails'
+const (
+	githubAuthorizationEndpoint = "https://github.com/login/oauth/authorize"
+	githubTokenEndpoint         = "https://github.com/login/oauth/access_token"
+	githubUserEndpoint          = "https://api.github.com/user"
+)
+
+var (
+	defaultOidcScopes   = []string{"openid", "email", "profile"}
+	defaultGithubScopes = []string{"read:user", "user:email"}
+)
+
+func (c *Config) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidConfig)
+	}
+	if c.ClientId == "" || c.RedirectUri == "" {
+		return fmt.Errorf("%w: client id and redirect uri of %q are required", ErrInvalidConfig, c.Name)
+	}
+
+	switch c.Type {
+	case ProviderTypeOidc:
+		if c.Issuer == "" {
+			return fmt.Errorf("%w: issuer of %q is required", ErrInvalidConfig, c.Name)
+		}
+	case ProviderTypeGithub:
+	default:
+		return fmt.Errorf("%w: unknown type %q of %q", ErrInvalidConfig, c.Type, c.Name)
+	}
+	return nil
+}
+
+// withDefaults returns a copy of the config with default scopes and GitHub endpoints
+func (c *Config) withDefaults() Config {
+	config := *c
+
+	if config.HttpTimeout <= 0 {
+		config.HttpTimeout = defaultHttpTimeout
+	}
+
+	switch config.Type {
+	case ProviderTypeOidc:
+		if len(config.Scopes) == 0 {
+			config.Scopes = defaultOidcScopes
+		}
+	case ProviderTypeGithub:
+		if len(config.Scopes) == 0 {
+			config.Scopes = defaultGithubScopes
+		}
+		config.AuthorizationEndpoint = stringOrDefault(config.AuthorizationEndpoint, githubAuthorizationEndpoint)
+		config.TokenEndpoint = stringOrDefault(config.TokenEndpoint, githubTokenEndpoint)
+		config.UserinfoEndpoint = stringOrDefault(config.UserinfoEndpoint, githubUserEndpoint)
+	}
+
+	return config
+}
+
+func stringOrDefault(value string, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
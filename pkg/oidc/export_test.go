@@ -0,0 +1,11 @@
+package oidc
+
+import "time"
+
+// ExpireKeysRefreshInterval lets the next lookup of an unknown key fetch the keys of the provider again
+func ExpireKeysRefreshInterval(p *Provider) {
+	p.keys.mu.Lock()
+	defer p.keys.mu.Unlock()
+
+	p.keys.refreshedAt = time.Time{}
+}
@@ -0,0 +1,56 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+type githubUser struct {
+	Id    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// githubIdentity reads the user and the primary email. The public profile email is not verified,
+// it is used only if the emails can not be read
+func (p *Provider) githubIdentity(ctx context.Context, accessToken string) (*Identity, error) {
+	var user githubUser
+	err := p.getJson(ctx, p.config.UserinfoEndpoint, accessToken, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Id == 0 {
+		return nil, fmt.Errorf("%w: github user has no id", ErrProviderRequest)
+	}
+
+	identity := &Identity{
+		Subject: strconv.FormatInt(user.Id, 10),
+		Email:   user.Email,
+		Name:    stringOrDefault(user.Name, user.Login),
+	}
+
+	var emails []githubEmail
+	err = p.getJson(ctx, p.config.UserinfoEndpoint+"/emails", accessToken, &emails)
+	if err != nil {
+		return identity, nil
+	}
+
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			identity.Email = email.Email
+			identity.EmailVerified = true
+			break
+		}
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// idTokenLeeway tolerates clock skew between the service and providers
+const idTokenLeeway = time.Minute
+
+// minKeysRefreshInterval limits fetches of provider keys caused by tokens with unknown key ids
+const minKeysRefreshInterval = 10 * time.Second
+
+var idTokenAlgorithms = []string{
+	string(accesstoken.AlgorithmRS256),
+	string(accesstoken.AlgorithmES256),
+	string(accesstoken.AlgorithmEdDSA),
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce           string       `json:"nonce"`
+	AuthorizedParty string       `json:"azp"`
+	Email           string       `json:"email"`
+	EmailVerified   flexibleBool `json:"email_verified"`
+	Name            string       `json:"name"`
+}
+
+// flexibleBool accepts "true" and "false" strings sent by some providers as well
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = flexibleBool(v)
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*b = flexibleBool(parsed)
+	case nil:
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %v", value)
+	}
+	return nil
+}
+
+// verifyIdToken validates the ID token as OpenID Connect Core 3.1.3.7 requires
+func (p *Provider) verifyIdToken(idToken string, nonce string) (*Identity, error) {
+	var claims idTokenClaims
+
+	err := accesstoken.VerifyClaims(idToken, &p.keys, &claims,
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.ClientId),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(idTokenLeeway),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIdToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: subject is missing", ErrInvalidIdToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientId {
+		return nil, fmt.Errorf("%w: authorized party does not match the client", ErrInvalidIdToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIdToken)
+	}
+
+	return &Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// jwksCache is accesstoken.KeySet of the provider keys. Keys are refreshed when a token is signed
+// with an unknown key, so key rotations of the provider are picked up
+type jwksCache struct {
+	provider *Provider
+
+	mu          sync.Mutex
+	keys        map[string]*accesstoken.Key
+	refreshedAt time.Time
+}
+
+func (c *jwksCache) LookupKey(kid string) (*accesstoken.Key, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.cachedKey(kid)
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(c.refreshedAt) < minKeysRefreshInterval {
+		return nil, accesstoken.ErrKeyNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.provider.config.HttpTimeout)
+	defer cancel()
+
+	err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok = c.cachedKey(kid)
+	if !ok {
+		return nil, accesstoken.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// cachedKey must be called with mu held, a token without 'kid' header is accepted if the provider has a single key
+func (c *jwksCache) cachedKey(kid string) (*accesstoken.Key, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+// refresh must be called with mu held. Keys which can not be parsed or are not signing keys are skipped
+func (c *jwksCache) refresh(ctx context.Context) error {
+	endpoints, err := c.provider.endpoints(ctx)
+	if err != nil {
+		return err
+	}
+
+	var jwks accesstoken.Jwks
+	err = c.provider.getJson(ctx, endpoints.JwksUri, "", &jwks)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]*accesstoken.Key, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if jwk.Alg == "" {
+			jwk.Alg = defaultKeyAlgorithm(jwk.Kty)
+		}
+
+		key, err := jwk.Key()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	c.keys = keys
+	c.refreshedAt = time.Now()
+
+	return nil
+}
+
+// defaultKeyAlgorithm is used for keys without 'alg' member
+func defaultKeyAlgorithm(kty string) string {
+	switch kty {
+	case "RSA":
+		return string(accesstoken.AlgorithmRS256)
+	case "EC":
+		return string(accesstoken.AlgorithmES256)
+	case "OKP":
+		return string(accesstoken.AlgorithmEdDSA)
+	default:
+		return ""
+	}
+}
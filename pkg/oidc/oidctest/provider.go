@@ -0,0 +1,442 @@
+// Package oidctest provides a fake identity provider served by httptest.Server. It implements discovery, JWKS,
+// authorization code flow with PKCE, userinfo and GitHub user API, it is meant for tests and local development only
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/vaberof/auth-grpc/pkg/auth/accesstoken"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	keyId       = "oidctest"
+	rsaKeyBits  = 2048
+	tokenLength = 32
+	idTokenTtl  = 5 * time.Minute
+)
+
+var (
+	ErrInvalidAuthorizationUrl = errors.New("invalid authorization url")
+	ErrNoUser                  = errors.New("no user is signed in to the provider")
+)
+
+// User is the user signed in to the provider. Subject of GitHub users must be a number
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type authorization struct {
+	clientId      string
+	redirectUri   string
+	scopes        []string
+	nonce         string
+	codeChallenge string
+	user          User
+}
+
+// Provider is a fake provider of oidc.ProviderTypeOidc or oidc.ProviderTypeGithub type
+type Provider struct {
+	Type         string
+	ClientId     string
+	ClientSecret string
+	// EmailInUserinfoOnly leaves the email out of ID tokens, so it is read from the userinfo endpoint
+	EmailInUserinfoOnly bool
+	// IdTokenClaims override claims of issued ID tokens and a nil value removes the claim,
+	// so tests can issue tokens which must be rejected
+	IdTokenClaims map[string]interface{}
+
+	server *httptest.Server
+
+	mu             sync.Mutex
+	keyId          string
+	privateKey     *rsa.PrivateKey
+	jwk            *accesstoken.Jwk
+	keyRotations   int
+	jwksRequests   int
+	user           *User
+	authorizations map[string]*authorization
+	accessTokens   map[string]User
+}
+
+// NewProvider starts the fake provider, it must be closed with Close
+func NewProvider(providerType string, clientId string, clientSecret string) (*Provider, error) {
+	if providerType != oidc.ProviderTypeOidc && providerType != oidc.ProviderTypeGithub {
+		return nil, fmt.Errorf("unknown provider type %q", providerType)
+	}
+
+	privateKey, jwk, err := newKey(keyId)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := &Provider{
+		Type:           providerType,
+		ClientId:       clientId,
+		ClientSecret:   clientSecret,
+		keyId:          keyId,
+		privateKey:     privateKey,
+		jwk:            jwk,
+		authorizations: make(map[string]*authorization),
+		accessTokens:   make(map[string]User),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", provider.handleDiscovery)
+	mux.HandleFunc("GET /jwks", provider.handleJwks)
+	mux.HandleFunc("POST /token", provider.handleToken)
+	mux.HandleFunc("GET /userinfo", provider.handleUserinfo)
+	mux.HandleFunc("GET /user", provider.handleGithubUser)
+	mux.HandleFunc("GET /user/emails", provider.handleGithubEmails)
+
+	provider.server = httptest.NewServer(mux)
+
+	return provider, nil
+}
+
+func newKey(kid string) (*rsa.PrivateKey, *accesstoken.Jwk, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := accesstoken.NewPrivateKey(kid, accesstoken.AlgorithmRS256, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	jwk, _ := key.Jwk()
+
+	return privateKey, jwk, nil
+}
+
+func (p *Provider) Close() {
+	p.server.Close()
+}
+
+// Issuer is the url of the provider
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+// Config returns config of the provider for oidc.NewProvider
+func (p *Provider) Config(name string, redirectUri string) oidc.Config {
+	config := oidc.Config{
+		Name:         name,
+		Type:         p.Type,
+		ClientId:     p.ClientId,
+		ClientSecret: p.ClientSecret,
+		RedirectUri:  redirectUri,
+	}
+
+	if p.Type == oidc.ProviderTypeGithub {
+		config.AuthorizationEndpoint = p.server.URL + "/authorize"
+		config.TokenEndpoint = p.server.URL + "/token"
+		config.UserinfoEndpoint = p.server.URL + "/user"
+	} else {
+		config.Issuer = p.server.URL
+	}
+
+	return config
+}
+
+// RotateKey replaces the signing key with a new key of another key id, the old key is not published anymore
+func (p *Provider) RotateKey() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	kid := keyId + "-" + strconv.Itoa(p.keyRotations+1)
+
+	privateKey, jwk, err := newKey(kid)
+	if err != nil {
+		return err
+	}
+
+	p.keyRotations++
+	p.keyId = kid
+	p.privateKey = privateKey
+	p.jwk = jwk
+
+	return nil
+}
+
+// JwksRequests returns the number of served JWKS requests
+func (p *Provider) JwksRequests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.jwksRequests
+}
+
+// SetUser signs the user in to the provider
+func (p *Provider) SetUser(user User) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.user = &user
+}
+
+// Authorize handles the authorization request as if the signed in user consented and returns
+// the code and the state the user would be redirected with
+func (p *Provider) Authorize(authorizationUrl string) (code string, state string, err error) {
+	parsed, err := url.Parse(authorizationUrl)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrInvalidAuthorizationUrl, err)
+	}
+
+	query := parsed.Query()
+	switch {
+	case !strings.HasPrefix(authorizationUrl, p.server.URL+"/authorize"):
+		return "", "", fmt.Errorf("%w: unknown endpoint", ErrInvalidAuthorizationUrl)
+	case query.Get("response_type") != "code":
+		return "", "", fmt.Errorf("%w: response type must be code", ErrInvalidAuthorizationUrl)
+	case query.Get("client_id") != p.ClientId:
+		return "", "", fmt.Errorf("%w: unknown client", ErrInvalidAuthorizationUrl)
+	case query.Get("redirect_uri") == "":
+		return "", "", fmt.Errorf("%w: redirect uri is missing", ErrInvalidAuthorizationUrl)
+	case query.Get("code_challenge_method") != oidc.CodeChallengeMethod || query.Get("code_challenge") == "":
+		return "", "", fmt.Errorf("%w: S256 code challenge is required", ErrInvalidAuthorizationUrl)
+	}
+
+	scopes := strings.Fields(query.Get("scope"))
+	if p.Type == oidc.ProviderTypeOidc && !slices.Contains(scopes, "openid") {
+		return "", "", fmt.Errorf("%w: openid scope is required", ErrInvalidAuthorizationUrl)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.user == nil {
+		return "", "", ErrNoUser
+	}
+
+	code, err = xrand.GenerateRandomToken(tokenLength)
+	if err != nil {
+		return "", "", err
+	}
+
+	p.authorizations[code] = &authorization{
+		clientId:      p.ClientId,
+		redirectUri:   query.Get("redirect_uri"),
+		scopes:        scopes,
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          *p.user,
+	}
+
+	return code, query.Get("state"), nil
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	if p.Type != oidc.ProviderTypeOidc {
+		http.NotFound(w, r)
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"userinfo_endpoint":                     p.server.URL + "/userinfo",
+		"jwks_uri":                              p.server.URL + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{string(accesstoken.AlgorithmRS256)},
+		"code_challenge_methods_supported":      []string{oidc.CodeChallengeMethod},
+	})
+}
+
+func (p *Provider) handleJwks(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	p.jwksRequests++
+	jwk := *p.jwk
+	p.mu.Unlock()
+
+	writeJson(w, http.StatusOK, &accesstoken.Jwks{Keys: []accesstoken.Jwk{jwk}})
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		p.writeTokenError(w, "invalid_request")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		p.writeTokenError(w, "unsupported_grant_type")
+		return
+	}
+	if r.PostForm.Get("client_id") != p.ClientId ||
+		subtle.ConstantTimeCompare([]byte(r.PostForm.Get("client_secret")), []byte(p.ClientSecret)) != 1 {
+		p.writeTokenError(w, "invalid_client")
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostForm.Get("code")
+	authorization, ok := p.authorizations[code]
+	delete(p.authorizations, code)
+	p.mu.Unlock()
+
+	if !ok ||
+		authorization.redirectUri != r.PostForm.Get("redirect_uri") ||
+		oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != authorization.codeChallenge {
+		p.writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	accessToken, err := xrand.GenerateRandomToken(tokenLength)
+	if err != nil {
+		p.writeTokenError(w, "server_error")
+		return
+	}
+
+	p.mu.Lock()
+	p.accessTokens[accessToken] = authorization.user
+	p.mu.Unlock()
+
+	response := map[string]interface{}{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"scope":        strings.Join(authorization.scopes, " "),
+	}
+
+	if slices.Contains(authorization.scopes, "openid") {
+		idToken, err := p.idToken(authorization)
+		if err != nil {
+			p.writeTokenError(w, "server_error")
+			return
+		}
+		response["id_token"] = idToken
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+func (p *Provider) idToken(authorization *authorization) (string, error) {
+	now := time.Now()
+
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"sub":   authorization.user.Subject,
+		"aud":   authorization.clientId,
+		"iat":   now.Unix(),
+		"exp":   now.Add(idTokenTtl).Unix(),
+		"nonce": authorization.nonce,
+		"name":  authorization.user.Name,
+	}
+	if !p.EmailInUserinfoOnly {
+		claims["email"] = authorization.user.Email
+		claims["email_verified"] = authorization.user.EmailVerified
+	}
+	for name, value := range p.IdTokenClaims {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+
+	p.mu.Lock()
+	kid, privateKey := p.keyId, p.privateKey
+	p.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+
+	return token.SignedString(privateKey)
+}
+
+func (p *Provider) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	user, ok := p.authenticate(r)
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"sub":            user.Subject,
+		"email":          user.Email,
+		"email_verified": user.EmailVerified,
+		"name":           user.Name,
+	})
+}
+
+func (p *Provider) handleGithubUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := p.authenticate(r)
+	if !ok || p.Type != oidc.ProviderTypeGithub {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(user.Subject, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	// like GitHub the public profile has no email
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"id":    id,
+		"login": strings.ToLower(strings.ReplaceAll(user.Name, " ", "")),
+		"name":  user.Name,
+		"email": nil,
+	})
+}
+
+func (p *Provider) handleGithubEmails(w http.ResponseWriter, r *http.Request) {
+	user, ok := p.authenticate(r)
+	if !ok || p.Type != oidc.ProviderTypeGithub {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	writeJson(w, http.StatusOK, []map[string]interface{}{
+		{"email": "noreply-" + user.Subject + "@users.noreply.github.com", "primary": false, "verified": true},
+		{"email": user.Email, "primary": true, "verified": user.EmailVerified},
+	})
+}
+
+func (p *Provider) authenticate(r *http.Request) (User, bool) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return User{}, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	user, ok := p.accessTokens[accessToken]
+	return user, ok
+}
+
+// writeTokenError responds like GitHub with 200 status, other providers respond with 400
+func (p *Provider) writeTokenError(w http.ResponseWriter, code string) {
+	status := http.StatusBadRequest
+	if p.Type == oidc.ProviderTypeGithub {
+		status = http.StatusOK
+	}
+	writeJson(w, status, map[string]string{"error": code})
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
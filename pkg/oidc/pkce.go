@@ -0,0 +1,24 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"github.com/vaberof/auth-grpc/pkg/xrand"
+)
+
+// CodeChallengeMethod is the only PKCE method used, 'plain' does not protect a leaked authorization request
+const CodeChallengeMethod = "S256"
+
+// codeVerifierLength gives a 43 characters verifier, the minimum of RFC 7636
+const codeVerifierLength = 32
+
+// NewCodeVerifier generates a random PKCE code verifier
+func NewCodeVerifier() (string, error) {
+	return xrand.GenerateRandomToken(codeVerifierLength)
+}
+
+// CodeChallenge returns the S256 code challenge of the verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,344 @@
+// Package oidc signs users in with external identity providers using the authorization code flow with PKCE.
+// OpenID Connect providers are configured with discovery and their ID tokens are verified with the keys of
+// the provider JWKS, GitHub is supported as a plain OAuth 2.0 provider with its user API
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const discoveryPath = "/.well-known/openid-configuration"
+
+// maxResponseSize limits responses of providers read into memory
+const maxResponseSize = 1 << 20
+
+var (
+	ErrDuplicateProvider = errors.New("duplicate identity provider")
+	ErrDiscovery         = errors.New("identity provider discovery failed")
+	ErrProviderRequest   = errors.New("identity provider request failed")
+	ErrInvalidIdToken    = errors.New("invalid id token")
+)
+
+// Identity is the user authenticated by the provider, Subject is unique and stable within the provider
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IdToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Provider is safe for concurrent use, the discovery document and the keys are fetched on first use
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	metadataMu sync.Mutex
+	metadata   *metadata
+
+	keys jwksCache
+}
+
+func NewProvider(config *Config) (*Provider, error) {
+	err := config.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	providerConfig := config.withDefaults()
+
+	provider := &Provider{
+		config:     providerConfig,
+		httpClient: &http.Client{Timeout: providerConfig.HttpTimeout},
+	}
+	provider.keys.provider = provider
+
+	return provider, nil
+}
+
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthorizationUrl returns the url the user is redirected to. The nonce is put to the ID token,
+// it is not sent to providers without ID tokens
+func (p *Provider) AuthorizationUrl(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authorizationUrl, err := url.Parse(endpoints.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid authorization endpoint: %w", ErrInvalidConfig, err)
+	}
+
+	query := authorizationUrl.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientId)
+	query.Set("redirect_uri", p.config.RedirectUri)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", CodeChallengeMethod)
+	if p.config.Type == ProviderTypeOidc {
+		query.Set("nonce", nonce)
+	}
+	authorizationUrl.RawQuery = query.Encode()
+
+	return authorizationUrl.String(), nil
+}
+
+// Exchange redeems the authorization code and returns the authenticated user. ID tokens must carry the nonce
+// sent in the authorization request
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*Identity, error) {
+	endpoints, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := p.requestTokens(ctx, endpoints.TokenEndpoint, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.config.Type == ProviderTypeGithub {
+		return p.githubIdentity(ctx, tokens.AccessToken)
+	}
+
+	if tokens.IdToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id token", ErrInvalidIdToken)
+	}
+
+	identity, err := p.verifyIdToken(tokens.IdToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	// the email is often put to the userinfo only
+	if identity.Email == "" && endpoints.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		err = p.addUserinfo(ctx, endpoints.UserinfoEndpoint, tokens.AccessToken, identity)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *Provider) requestTokens(ctx context.Context, tokenEndpoint string, code string, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectUri},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.config.ClientId},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProviderRequest, err)
+	}
+	defer response.Body.Close()
+
+	var tokens tokenResponse
+	err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(&tokens)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token response: %w", ErrProviderRequest, err)
+	}
+
+	// GitHub reports errors with 200 status
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("%w: %s: %s", ErrProviderRequest, tokens.Error, tokens.ErrorDescription)
+	}
+	if response.StatusCode != http.StatusOK || tokens.AccessToken == "" {
+		return nil, fmt.Errorf("%w: token endpoint responded with status %d", ErrProviderRequest, response.StatusCode)
+	}
+
+	return &tokens, nil
+}
+
+func (p *Provider) addUserinfo(ctx context.Context, userinfoEndpoint string, accessToken string, identity *Identity) error {
+	var userinfo struct {
+		Subject       string       `json:"sub"`
+		Email         string       `json:"email"`
+		EmailVerified flexibleBool `json:"email_verified"`
+		Name          string       `json:"name"`
+	}
+
+	err := p.getJson(ctx, userinfoEndpoint, accessToken, &userinfo)
+	if err != nil {
+		return err
+	}
+
+	// userinfo of another user must not be trusted, OpenID Connect Core 5.3.2
+	if userinfo.Subject != identity.Subject {
+		return fmt.Errorf("%w: userinfo subject does not match the id token", ErrProviderRequest)
+	}
+
+	identity.Email = userinfo.Email
+	identity.EmailVerified = bool(userinfo.EmailVerified)
+	if identity.Name == "" {
+		identity.Name = userinfo.Name
+	}
+	return nil
+}
+
+// endpoints returns endpoints of the discovery document overridden by the configured ones
+func (p *Provider) endpoints(ctx context.Context) (*metadata, error) {
+	configured := &metadata{
+		Issuer:                p.config.Issuer,
+		AuthorizationEndpoint: p.config.AuthorizationEndpoint,
+		TokenEndpoint:         p.config.TokenEndpoint,
+		UserinfoEndpoint:      p.config.UserinfoEndpoint,
+		JwksUri:               p.config.JwksUri,
+	}
+
+	if p.config.Type == ProviderTypeGithub ||
+		configured.AuthorizationEndpoint != "" && configured.TokenEndpoint != "" && configured.JwksUri != "" {
+		return configured, nil
+	}
+
+	discovered, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metadata{
+		Issuer:                discovered.Issuer,
+		AuthorizationEndpoint: stringOrDefault(configured.AuthorizationEndpoint, discovered.AuthorizationEndpoint),
+		TokenEndpoint:         stringOrDefault(configured.TokenEndpoint, discovered.TokenEndpoint),
+		UserinfoEndpoint:      stringOrDefault(configured.UserinfoEndpoint, discovered.UserinfoEndpoint),
+		JwksUri:               stringOrDefault(configured.JwksUri, discovered.JwksUri),
+	}, nil
+}
+
+// discover fetches the discovery document once, a failed fetch is retried on the next call
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.metadataMu.Lock()
+	defer p.metadataMu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	var discovered metadata
+	err := p.getJson(ctx, strings.TrimSuffix(p.config.Issuer, "/")+discoveryPath, "", &discovered)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDiscovery, err)
+	}
+
+	if discovered.Issuer != p.config.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match the configured one", ErrDiscovery, discovered.Issuer)
+	}
+	if discovered.AuthorizationEndpoint == "" || discovered.TokenEndpoint == "" || discovered.JwksUri == "" {
+		return nil, fmt.Errorf("%w: required endpoints are missing", ErrDiscovery)
+	}
+
+	p.metadata = &discovered
+
+	return p.metadata, nil
+}
+
+func (p *Provider) getJson(ctx context.Context, endpoint string, accessToken string, body interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	if p.config.Type == ProviderTypeGithub {
+		// GitHub API rejects requests without a user agent
+		request.Header.Set("User-Agent", "auth-grpc")
+	}
+
+	response, err := p.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrProviderRequest, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s responded with status %d", ErrProviderRequest, endpoint, response.StatusCode)
+	}
+
+	err = json.NewDecoder(io.LimitReader(response.Body, maxResponseSize)).Decode(body)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrProviderRequest, endpoint, err)
+	}
+	return nil
+}
+
+// Providers are the configured identity providers by their names
+type Providers struct {
+	providers map[string]*Provider
+}
+
+func NewProviders(configs []Config) (*Providers, error) {
+	providers := make(map[string]*Provider, len(configs))
+
+	for i := range configs {
+		if _, ok := providers[configs[i].Name]; ok {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateProvider, configs[i].Name)
+		}
+
+		provider, err := NewProvider(&configs[i])
+		if err != nil {
+			return nil, err
+		}
+		providers[provider.Name()] = provider
+	}
+
+	return &Providers{providers: providers}, nil
+}
+
+func (p *Providers) Get(name string) (*Provider, bool) {
+	provider, ok := p.providers[name]
+	return provider, ok
+}
+
+// Names returns sorted names of the providers
+func (p *Providers) Names() []string {
+	names := make([]string, 0, len(p.providers))
+	for name := range p.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,240 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/oidc"
+	"github.com/vaberof/auth-grpc/pkg/oidc/oidctest"
+	"testing"
+	"time"
+)
+
+const (
+	testClientId     = "client"
+	testClientSecret = "secret"
+	testRedirectUri  = "https://auth.example.com/federated/callback"
+	testNonce        = "nonce"
+)
+
+var testUser = oidctest.User{
+	Subject:       "1234",
+	Email:         "user@example.com",
+	EmailVerified: true,
+	Name:          "Test User",
+}
+
+func newTestProvider(t *testing.T, providerType string) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+
+	fakeProvider, err := oidctest.NewProvider(providerType, testClientId, testClientSecret)
+	if err != nil {
+		t.Fatalf("oidctest.NewProvider() error = %v", err)
+	}
+	t.Cleanup(fakeProvider.Close)
+
+	config := fakeProvider.Config("test", testRedirectUri)
+	provider, err := oidc.NewProvider(&config)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	fakeProvider.SetUser(testUser)
+
+	return fakeProvider, provider
+}
+
+// signIn runs the authorization code flow with the nonce sent in the authorization request
+// and the nonce expected in the ID token
+func signIn(t *testing.T, fakeProvider *oidctest.Provider, provider *oidc.Provider, sentNonce string, expectedNonce string) (*oidc.Identity, error) {
+	t.Helper()
+
+	codeVerifier, err := oidc.NewCodeVerifier()
+	if err != nil {
+		t.Fatalf("NewCodeVerifier() error = %v", err)
+	}
+
+	authorizationUrl, err := provider.AuthorizationUrl(context.Background(), "state", sentNonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		t.Fatalf("AuthorizationUrl() error = %v", err)
+	}
+
+	code, state, err := fakeProvider.Authorize(authorizationUrl)
+	if err != nil {
+		t.Fatalf("Authorize() error = %v", err)
+	}
+	if state != "state" {
+		t.Fatalf("Authorize() state = %q, want %q", state, "state")
+	}
+
+	return provider.Exchange(context.Background(), code, codeVerifier, expectedNonce)
+}
+
+func TestProviderExchange(t *testing.T) {
+	tests := []struct {
+		name                string
+		emailInUserinfoOnly bool
+		user                oidctest.User
+		want                oidc.Identity
+	}{
+		{
+			name: "email in id token",
+			user: testUser,
+			want: oidc.Identity{Subject: "1234", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name:                "email in userinfo",
+			emailInUserinfoOnly: true,
+			user:                testUser,
+			want:                oidc.Identity{Subject: "1234", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			name: "unverified email",
+			user: oidctest.User{Subject: "1234", Email: "user@example.com", Name: "Test User"},
+			want: oidc.Identity{Subject: "1234", Email: "user@example.com", Name: "Test User"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProvider, provider := newTestProvider(t, oidc.ProviderTypeOidc)
+			fakeProvider.EmailInUserinfoOnly = tt.emailInUserinfoOnly
+			fakeProvider.SetUser(tt.user)
+
+			identity, err := signIn(t, fakeProvider, provider, testNonce, testNonce)
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if *identity != tt.want {
+				t.Errorf("Exchange() = %+v, want %+v", *identity, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderExchangeInvalidIdToken(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		claims        map[string]interface{}
+		expectedNonce string
+	}{
+		{name: "nonce mismatch", expectedNonce: "another nonce"},
+		{name: "missing nonce", claims: map[string]interface{}{"nonce": nil}},
+		{name: "wrong issuer", claims: map[string]interface{}{"iss": "https://idp.example.com"}},
+		{name: "missing issuer", claims: map[string]interface{}{"iss": nil}},
+		{name: "wrong audience", claims: map[string]interface{}{"aud": "another client"}},
+		{name: "multiple audiences without authorized party", claims: map[string]interface{}{"aud": []string{testClientId, "another client"}}},
+		{name: "multiple audiences with wrong authorized party", claims: map[string]interface{}{
+			"aud": []string{testClientId, "another client"},
+			"azp": "another client",
+		}},
+		{name: "expired", claims: map[string]interface{}{
+			"iat": now.Add(-time.Hour).Unix(),
+			"exp": now.Add(-10 * time.Minute).Unix(),
+		}},
+		{name: "issued in the future", claims: map[string]interface{}{"iat": now.Add(10 * time.Minute).Unix()}},
+		{name: "missing subject", claims: map[string]interface{}{"sub": nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProvider, provider := newTestProvider(t, oidc.ProviderTypeOidc)
+			fakeProvider.IdTokenClaims = tt.claims
+
+			expectedNonce := testNonce
+			if tt.expectedNonce != "" {
+				expectedNonce = tt.expectedNonce
+			}
+
+			_, err := signIn(t, fakeProvider, provider, testNonce, expectedNonce)
+			if !errors.Is(err, oidc.ErrInvalidIdToken) {
+				t.Errorf("Exchange() error = %v, want %v", err, oidc.ErrInvalidIdToken)
+			}
+		})
+	}
+}
+
+func TestProviderExchangeMultipleAudiences(t *testing.T) {
+	fakeProvider, provider := newTestProvider(t, oidc.ProviderTypeOidc)
+	fakeProvider.IdTokenClaims = map[string]interface{}{
+		"aud": []string{testClientId, "another client"},
+		"azp": testClientId,
+	}
+
+	_, err := signIn(t, fakeProvider, provider, testNonce, testNonce)
+	if err != nil {
+		t.Errorf("Exchange() error = %v", err)
+	}
+}
+
+func TestProviderExchangeRefreshesKeys(t *testing.T) {
+	fakeProvider, provider := newTestProvider(t, oidc.ProviderTypeOidc)
+
+	_, err := signIn(t, fakeProvider, provider, testNonce, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if got := fakeProvider.JwksRequests(); got != 1 {
+		t.Fatalf("JwksRequests() = %d, want 1", got)
+	}
+
+	err = fakeProvider.RotateKey()
+	if err != nil {
+		t.Fatalf("RotateKey() error = %v", err)
+	}
+
+	// keys were fetched just now, tokens signed with unknown keys must not make the provider fetch them on every request
+	_, err = signIn(t, fakeProvider, provider, testNonce, testNonce)
+	if !errors.Is(err, oidc.ErrInvalidIdToken) {
+		t.Fatalf("Exchange() error = %v, want %v", err, oidc.ErrInvalidIdToken)
+	}
+	if got := fakeProvider.JwksRequests(); got != 1 {
+		t.Fatalf("JwksRequests() = %d, want 1", got)
+	}
+
+	oidc.ExpireKeysRefreshInterval(provider)
+
+	_, err = signIn(t, fakeProvider, provider, testNonce, testNonce)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if got := fakeProvider.JwksRequests(); got != 2 {
+		t.Errorf("JwksRequests() = %d, want 2", got)
+	}
+}
+
+func TestProviderExchangeGithub(t *testing.T) {
+	tests := []struct {
+		name string
+		user oidctest.User
+		want oidc.Identity
+	}{
+		{
+			name: "verified primary email",
+			user: testUser,
+			want: oidc.Identity{Subject: "1234", Email: "user@example.com", EmailVerified: true, Name: "Test User"},
+		},
+		{
+			// the verified email which is not primary must not be used either
+			name: "no verified primary email",
+			user: oidctest.User{Subject: "1234", Email: "user@example.com", Name: "Test User"},
+			want: oidc.Identity{Subject: "1234", Name: "Test User"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeProvider, provider := newTestProvider(t, oidc.ProviderTypeGithub)
+			fakeProvider.SetUser(tt.user)
+
+			identity, err := signIn(t, fakeProvider, provider, "", "")
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if *identity != tt.want {
+				t.Errorf("Exchange() = %+v, want %+v", *identity, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// prefixLength is a length of SHA-1 hash prefix sent to a range source, as in Have I Been Pwned API
+const prefixLength = 5
+
+// BreachedChecker reports whether a password has appeared in a data breach
+type BreachedChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// RangeEntry is an uppercase hex suffix of SHA-1 hash of a breached password and the number of times
+// the password has appeared in breaches
+type RangeEntry struct {
+	Suffix string
+	Count  int
+}
+
+// RangeSource returns entries of breached passwords whose SHA-1 hashes start with the prefix
+type RangeSource interface {
+	Range(prefix string) ([]RangeEntry, error)
+}
+
+// KAnonymityChecker checks passwords by a hash prefix only, so the source never sees the full
+// hash of a password and may be a local file or a remote range API
+type KAnonymityChecker struct {
+	source   RangeSource
+	minCount int
+}
+
+// NewKAnonymityChecker returns a checker accepting passwords which have appeared in breaches
+// fewer than minCount times, minCount below 1 rejects all of them
+func NewKAnonymityChecker(source RangeSource, minCount int) *KAnonymityChecker {
+	return &KAnonymityChecker{source: source, minCount: max(minCount, 1)}
+}
+
+func (c *KAnonymityChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	entries, err := c.source.Range(prefix)
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		if entry.Suffix == suffix {
+			return entry.Count >= c.minCount, nil
+		}
+	}
+
+	return false, nil
+}
+
+// PrefixFile is a local range source loaded from a file in Have I Been Pwned format:
+// one 'HASH[:COUNT]' line per breached password, where HASH is uppercase hex SHA-1 of the password.
+// COUNT is 1 if it is omitted
+type PrefixFile struct {
+	ranges map[string][]RangeEntry
+}
+
+// LoadPrefixFile reads the whole file into memory grouping hashes by their prefixes
+func LoadPrefixFile(path string) (*PrefixFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached passwords file: %w", err)
+	}
+	defer file.Close()
+
+	ranges := make(map[string][]RangeEntry)
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, countValue, hasCount := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("breached passwords file line %d: invalid SHA-1 hash", lineNumber)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("breached passwords file line %d: invalid SHA-1 hash", lineNumber)
+		}
+
+		count := 1
+		if hasCount {
+			count, err = strconv.Atoi(countValue)
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("breached passwords file line %d: invalid count", lineNumber)
+			}
+		}
+
+		prefix := hash[:prefixLength]
+		ranges[prefix] = append(ranges[prefix], RangeEntry{Suffix: hash[prefixLength:], Count: count})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached passwords file: %w", err)
+	}
+
+	return &PrefixFile{ranges: ranges}, nil
+}
+
+func (f *PrefixFile) Range(prefix string) ([]RangeEntry, error) {
+	if len(prefix) != prefixLength {
+		return nil, errors.New("invalid hash prefix length")
+	}
+	return f.ranges[strings.ToUpper(prefix)], nil
+}
@@ -0,0 +1,251 @@
+package passwordpolicy_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// rangeServer serves a range API with the counts of breached passwords and records requested paths
+type rangeServer struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	paths []string
+}
+
+func newRangeServer(t *testing.T, counts map[string]int) *rangeServer {
+	t.Helper()
+
+	server := &rangeServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mu.Lock()
+		server.paths = append(server.paths, r.URL.Path)
+		server.mu.Unlock()
+
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+
+		var lines []string
+		for password, count := range counts {
+			hash := sha1Hex(password)
+			if strings.HasPrefix(hash, prefix) {
+				lines = append(lines, fmt.Sprintf("%s:%d", hash[len(prefix):], count))
+			}
+		}
+		if r.Header.Get("Add-Padding") == "true" {
+			lines = append(lines, strings.Repeat("0", 35)+":0")
+		}
+
+		fmt.Fprint(w, strings.Join(lines, "\r\n"))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestKAnonymityCheckerRangeApi(t *testing.T) {
+	server := newRangeServer(t, map[string]int{
+		"password":      3861493,
+		"rarely-leaked": 2,
+	})
+
+	tests := []struct {
+		name     string
+		minCount int
+		password string
+		want     bool
+	}{
+		{name: "breached", password: "password", want: true},
+		{name: "not breached", password: "not-breached-password", want: false},
+		{name: "breached once is enough by default", password: "rarely-leaked", want: true},
+		{name: "below min count", minCount: 10, password: "rarely-leaked", want: false},
+		{name: "above min count", minCount: 10, password: "password", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := passwordpolicy.NewKAnonymityChecker(passwordpolicy.NewRangeApi(server.URL+"/range/", time.Second), tt.minCount)
+
+			got, err := checker.IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKAnonymityCheckerSendsOnlyPrefix(t *testing.T) {
+	server := newRangeServer(t, nil)
+	checker := passwordpolicy.NewKAnonymityChecker(passwordpolicy.NewRangeApi(server.URL+"/range/", time.Second), 0)
+
+	_, err := checker.IsBreached("password")
+	if err != nil {
+		t.Fatalf("IsBreached() error = %v", err)
+	}
+
+	hash := sha1Hex("password")
+	want := []string{"/range/" + hash[:5]}
+	if len(server.paths) != 1 || server.paths[0] != want[0] {
+		t.Errorf("requested paths = %v, want %v", server.paths, want)
+	}
+}
+
+func TestKAnonymityCheckerMatchesWholeSuffix(t *testing.T) {
+	hash := sha1Hex("password")
+	// the same prefix and a suffix differing in the last character
+	otherSuffix := hash[5:len(hash)-1] + string("0123456789ABCDEF"[(strings.IndexByte("0123456789ABCDEF", hash[len(hash)-1])+1)%16])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s:100\n", strings.ToLower(otherSuffix))
+	}))
+	t.Cleanup(server.Close)
+
+	checker := passwordpolicy.NewKAnonymityChecker(passwordpolicy.NewRangeApi(server.URL+"/", time.Second), 0)
+
+	got, err := checker.IsBreached("password")
+	if err != nil {
+		t.Fatalf("IsBreached() error = %v", err)
+	}
+	if got {
+		t.Errorf("IsBreached() = true for a different suffix")
+	}
+}
+
+func TestRangeApiErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{name: "error status", handler: func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}},
+		{name: "malformed line", handler: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "not a range line")
+		}},
+		{name: "malformed count", handler: func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, strings.Repeat("0", 35)+":many")
+		}},
+		{name: "timeout", handler: func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			t.Cleanup(server.Close)
+
+			checker := passwordpolicy.NewKAnonymityChecker(passwordpolicy.NewRangeApi(server.URL+"/", 50*time.Millisecond), 0)
+
+			_, err := checker.IsBreached("password")
+			if !errors.Is(err, passwordpolicy.ErrRangeApiRequest) {
+				t.Errorf("IsBreached() error = %v, want %v", err, passwordpolicy.ErrRangeApiRequest)
+			}
+		})
+	}
+}
+
+func TestRangeApiNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL + "/"
+	server.Close()
+
+	policy := passwordpolicy.New(&passwordpolicy.Config{}, passwordpolicy.NewKAnonymityChecker(passwordpolicy.NewRangeApi(url, time.Second), 0))
+
+	// the password is not accepted if it can not be checked
+	violations, err := policy.Check("password", "")
+	if !errors.Is(err, passwordpolicy.ErrRangeApiRequest) {
+		t.Errorf("Check() = %v, %v, want error %v", violations, err, passwordpolicy.ErrRangeApiRequest)
+	}
+}
+
+func TestPrefixFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	content := strings.Join([]string{
+		"# comment",
+		"",
+		sha1Hex("password") + ":3861493",
+		strings.ToLower(sha1Hex("lowercase-hash")),
+		sha1Hex("rarely-leaked") + ":2",
+	}, "\n")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	prefixFile, err := passwordpolicy.LoadPrefixFile(path)
+	if err != nil {
+		t.Fatalf("LoadPrefixFile() error = %v", err)
+	}
+
+	tests := []struct {
+		minCount int
+		password string
+		want     bool
+	}{
+		{password: "password", want: true},
+		{password: "lowercase-hash", want: true},
+		{password: "rarely-leaked", want: true},
+		{password: "not-breached-password", want: false},
+		{minCount: 2, password: "lowercase-hash", want: false},
+		{minCount: 3, password: "rarely-leaked", want: false},
+		{minCount: 3, password: "password", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s min count %d", tt.password, tt.minCount), func(t *testing.T) {
+			got, err := passwordpolicy.NewKAnonymityChecker(prefixFile, tt.minCount).IsBreached(tt.password)
+			if err != nil {
+				t.Fatalf("IsBreached() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("IsBreached() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadPrefixFileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "short hash", line: "ABCDEF"},
+		{name: "not hex", line: strings.Repeat("Z", 40)},
+		{name: "invalid count", line: sha1Hex("password") + ":many"},
+		{name: "negative count", line: sha1Hex("password") + ":-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "breached.txt")
+			err := os.WriteFile(path, []byte(tt.line), 0o600)
+			if err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+
+			_, err = passwordpolicy.LoadPrefixFile(path)
+			if err == nil {
+				t.Errorf("LoadPrefixFile() error = nil, want error")
+			}
+		})
+	}
+}
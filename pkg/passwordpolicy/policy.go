@@ -0,0 +1,200 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	RuleMinLength           = "min_length"
+	RuleMaxLength           = "max_length"
+	RuleUppercase           = "uppercase"
+	RuleLowercase           = "lowercase"
+	RuleDigit               = "digit"
+	RuleSymbol              = "symbol"
+	RuleMinCharacterClasses = "min_character_classes"
+	RuleNotEmail            = "not_email"
+	RuleStrength            = "strength"
+	RuleNotBreached         = "not_breached"
+)
+
+// minEmailLocalPartMatchLength avoids rejecting passwords because of a short local part like 'a@example.com'
+const minEmailLocalPartMatchLength = 3
+
+// Config of a password policy, zero values disable corresponding rules.
+// MinStrengthScore is a score from 0 (too guessable) to 4 (very unguessable) as in zxcvbn.
+// Breached passwords are checked against BreachedPasswordsFile or, if it is not set, against a range API
+// at BreachedPasswordsUrl, passwords which have appeared in breaches fewer than BreachedPasswordsMinCount times (1 by default) are accepted.
+// JSON form is used to store policies overriding the configured one, it omits the breached passwords source
+type Config struct {
+	MinLength                 int           `yaml:"min-length" json:"min_length"`
+	MaxLength                 int           `yaml:"max-length" json:"max_length"`
+	RequireUppercase          bool          `yaml:"require-uppercase" json:"require_uppercase"`
+	RequireLowercase          bool          `yaml:"require-lowercase" json:"require_lowercase"`
+	RequireDigit              bool          `yaml:"require-digit" json:"require_digit"`
+	RequireSymbol             bool          `yaml:"require-symbol" json:"require_symbol"`
+	MinCharacterClasses       int           `yaml:"min-character-classes" json:"min_character_classes"`
+	DisallowEmail             bool          `yaml:"disallow-email" json:"disallow_email"`
+	MinStrengthScore          int           `yaml:"min-strength-score" json:"min_strength_score"`
+	BreachedPasswordsFile     string        `yaml:"breached-passwords-file" json:"-"`
+	BreachedPasswordsUrl      string        `yaml:"breached-passwords-url" json:"-"`
+	BreachedPasswordsMinCount int           `yaml:"breached-passwords-min-count" json:"-"`
+	BreachedPasswordsTimeout  time.Duration `yaml:"breached-passwords-timeout" json:"-"`
+}
+
+// Violation describes a single rule the password does not satisfy
+type Violation struct {
+	Rule        string
+	Description string
+}
+
+type Policy struct {
+	config          *Config
+	breachedChecker BreachedChecker
+}
+
+// New returns a policy, breached password check is skipped if breachedChecker is nil
+func New(config *Config, breachedChecker BreachedChecker) *Policy {
+	return &Policy{config: config, breachedChecker: breachedChecker}
+}
+
+// NewFromConfig returns a policy checking passwords against the breached passwords file or the range API
+// if one of them is configured
+func NewFromConfig(config *Config) (*Policy, error) {
+	switch {
+	case config.BreachedPasswordsFile != "":
+		prefixFile, err := LoadPrefixFile(config.BreachedPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return New(config, NewKAnonymityChecker(prefixFile, config.BreachedPasswordsMinCount)), nil
+	case config.BreachedPasswordsUrl != "":
+		rangeApi := NewRangeApi(config.BreachedPasswordsUrl, config.BreachedPasswordsTimeout)
+
+		return New(config, NewKAnonymityChecker(rangeApi, config.BreachedPasswordsMinCount)), nil
+	default:
+		return New(config, nil), nil
+	}
+}
+
+// Check returns all the rules the password violates. The email of the password owner is used
+// to reject passwords equal to or derived from the email
+func (p *Policy) Check(password string, email string) ([]Violation, error) {
+	return p.CheckWithConfig(p.config, password, email)
+}
+
+// CheckWithConfig is the same as Check, but the rules are taken from the config overriding the config
+// of the policy. Breached passwords are checked if the policy has a breached checker
+func (p *Policy) CheckWithConfig(config *Config, password string, email string) ([]Violation, error) {
+	var violations []Violation
+
+	addViolation := func(rule string, description string) {
+		violations = append(violations, Violation{Rule: rule, Description: description})
+	}
+
+	length := utf8.RuneCountInString(password)
+
+	if length == 0 || length < config.MinLength {
+		addViolation(RuleMinLength, fmt.Sprintf("must be at least %d characters long", max(config.MinLength, 1)))
+	}
+
+	if config.MaxLength > 0 && length > config.MaxLength {
+		addViolation(RuleMaxLength, fmt.Sprintf("must not be longer than %d characters", config.MaxLength))
+
+		// other checks are pointless and expensive for huge inputs
+		return violations, nil
+	}
+
+	classes := characterClassesOf(password)
+
+	if config.RequireUppercase && !classes.upper {
+		addViolation(RuleUppercase, "must contain an uppercase letter")
+	}
+	if config.RequireLowercase && !classes.lower {
+		addViolation(RuleLowercase, "must contain a lowercase letter")
+	}
+	if config.RequireDigit && !classes.digit {
+		addViolation(RuleDigit, "must contain a digit")
+	}
+	if config.RequireSymbol && !classes.symbol {
+		addViolation(RuleSymbol, "must contain a symbol")
+	}
+	if classes.count() < config.MinCharacterClasses {
+		addViolation(RuleMinCharacterClasses, fmt.Sprintf("must contain at least %d of uppercase letters, lowercase letters, digits and symbols", config.MinCharacterClasses))
+	}
+
+	if config.DisallowEmail && containsEmail(password, email) {
+		addViolation(RuleNotEmail, "must not contain the email")
+	}
+
+	if config.MinStrengthScore > 0 {
+		score := EstimateStrength(password, email).Score
+		if score < config.MinStrengthScore {
+			addViolation(RuleStrength, fmt.Sprintf("is too easy to guess, strength score %d of required %d", score, config.MinStrengthScore))
+		}
+	}
+
+	if p.breachedChecker != nil {
+		breached, err := p.breachedChecker.IsBreached(password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check breached password: %w", err)
+		}
+		if breached {
+			addViolation(RuleNotBreached, "has appeared in a data breach and must not be used")
+		}
+	}
+
+	return violations, nil
+}
+
+type characterClasses struct {
+	upper, lower, digit, symbol bool
+}
+
+func (c characterClasses) count() int {
+	count := 0
+	for _, present := range []bool{c.upper, c.lower, c.digit, c.symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+func characterClassesOf(password string) characterClasses {
+	var classes characterClasses
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			classes.upper = true
+		case unicode.IsLower(r):
+			classes.lower = true
+		case unicode.IsDigit(r):
+			classes.digit = true
+		default:
+			classes.symbol = true
+		}
+	}
+	return classes
+}
+
+// containsEmail reports whether the password contains the email or its local part
+func containsEmail(password string, email string) bool {
+	if email == "" {
+		return false
+	}
+
+	password = strings.ToLower(password)
+	email = strings.ToLower(email)
+
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	localPart, _, found := strings.Cut(email, "@")
+	return found && len(localPart) >= minEmailLocalPartMatchLength && strings.Contains(password, localPart)
+}
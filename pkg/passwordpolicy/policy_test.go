@@ -0,0 +1,134 @@
+package passwordpolicy_test
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func violatedRules(violations []passwordpolicy.Violation) []string {
+	rules := []string{}
+	for _, violation := range violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+func TestPolicyRules(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    passwordpolicy.Config
+		password  string
+		email     string
+		wantRules []string
+	}{
+		{name: "empty password", config: passwordpolicy.Config{}, password: "", wantRules: []string{passwordpolicy.RuleMinLength}},
+		{name: "too short", config: passwordpolicy.Config{MinLength: 8}, password: "short", wantRules: []string{passwordpolicy.RuleMinLength}},
+		{name: "min length", config: passwordpolicy.Config{MinLength: 8}, password: "12345678", wantRules: []string{}},
+		{name: "too long", config: passwordpolicy.Config{MaxLength: 8}, password: "123456789", wantRules: []string{passwordpolicy.RuleMaxLength}},
+		{name: "max length", config: passwordpolicy.Config{MaxLength: 8}, password: "12345678", wantRules: []string{}},
+		// 8 characters take 16 bytes, length is counted in characters
+		{name: "multibyte max length", config: passwordpolicy.Config{MinLength: 8, MaxLength: 8}, password: "пароль12", wantRules: []string{}},
+		{name: "multibyte too short", config: passwordpolicy.Config{MinLength: 8}, password: "пароль1", wantRules: []string{passwordpolicy.RuleMinLength}},
+		{name: "multibyte too long", config: passwordpolicy.Config{MaxLength: 8}, password: "пароль123", wantRules: []string{passwordpolicy.RuleMaxLength}},
+		{name: "emoji length", config: passwordpolicy.Config{MinLength: 4, MaxLength: 4}, password: "🔑🔑🔑🔑", wantRules: []string{}},
+		{
+			name:      "too long skips other rules",
+			config:    passwordpolicy.Config{MaxLength: 4, RequireDigit: true, MinStrengthScore: 4},
+			password:  "password",
+			wantRules: []string{passwordpolicy.RuleMaxLength},
+		},
+		{name: "missing uppercase", config: passwordpolicy.Config{RequireUppercase: true}, password: "password", wantRules: []string{passwordpolicy.RuleUppercase}},
+		{name: "missing lowercase", config: passwordpolicy.Config{RequireLowercase: true}, password: "PASSWORD", wantRules: []string{passwordpolicy.RuleLowercase}},
+		{name: "missing digit", config: passwordpolicy.Config{RequireDigit: true}, password: "password", wantRules: []string{passwordpolicy.RuleDigit}},
+		{name: "missing symbol", config: passwordpolicy.Config{RequireSymbol: true}, password: "password1", wantRules: []string{passwordpolicy.RuleSymbol}},
+		{name: "non latin letters", config: passwordpolicy.Config{RequireUppercase: true, RequireLowercase: true}, password: "Пароль", wantRules: []string{}},
+		{name: "all classes", config: passwordpolicy.Config{RequireUppercase: true, RequireLowercase: true, RequireDigit: true, RequireSymbol: true}, password: "Pass-word1", wantRules: []string{}},
+		{name: "too few classes", config: passwordpolicy.Config{MinCharacterClasses: 3}, password: "password1", wantRules: []string{passwordpolicy.RuleMinCharacterClasses}},
+		{name: "enough classes", config: passwordpolicy.Config{MinCharacterClasses: 3}, password: "Password1", wantRules: []string{}},
+		{name: "equal to email", config: passwordpolicy.Config{DisallowEmail: true}, password: "User@Example.com", email: "user@example.com", wantRules: []string{passwordpolicy.RuleNotEmail}},
+		{name: "contains local part", config: passwordpolicy.Config{DisallowEmail: true}, password: "johnsmith2024", email: "johnsmith@example.com", wantRules: []string{passwordpolicy.RuleNotEmail}},
+		{name: "short local part", config: passwordpolicy.Config{DisallowEmail: true}, password: "jo-password", email: "jo@example.com", wantRules: []string{}},
+		{name: "email not allowed without email", config: passwordpolicy.Config{DisallowEmail: true}, password: "password", wantRules: []string{}},
+		{name: "weak password", config: passwordpolicy.Config{MinStrengthScore: 3}, password: "password", wantRules: []string{passwordpolicy.RuleStrength}},
+		{name: "strong password", config: passwordpolicy.Config{MinStrengthScore: 3}, password: "violet-Tundra-58-kayak", wantRules: []string{}},
+		{
+			name:      "several violations",
+			config:    passwordpolicy.Config{MinLength: 10, RequireUppercase: true, RequireDigit: true},
+			password:  "short",
+			wantRules: []string{passwordpolicy.RuleMinLength, passwordpolicy.RuleUppercase, passwordpolicy.RuleDigit},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violations, err := passwordpolicy.New(&tt.config, nil).Check(tt.password, tt.email)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+
+			rules := violatedRules(violations)
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("Check() violated rules = %v, want %v", rules, tt.wantRules)
+			}
+
+			for _, violation := range violations {
+				if violation.Description == "" {
+					t.Errorf("violation of %s has no description", violation.Rule)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckWithConfigOverridesConfig(t *testing.T) {
+	policy := passwordpolicy.New(&passwordpolicy.Config{MinLength: 4}, nil)
+
+	violations, err := policy.CheckWithConfig(&passwordpolicy.Config{MinLength: 12}, "password", "")
+	if err != nil {
+		t.Fatalf("CheckWithConfig() error = %v", err)
+	}
+
+	rules := violatedRules(violations)
+	if !reflect.DeepEqual(rules, []string{passwordpolicy.RuleMinLength}) {
+		t.Errorf("CheckWithConfig() violated rules = %v, want [%s]", rules, passwordpolicy.RuleMinLength)
+	}
+	if !strings.Contains(violations[0].Description, "12") {
+		t.Errorf("description = %q, want the overridden min length", violations[0].Description)
+	}
+}
+
+type fakeBreachedChecker struct {
+	breached map[string]bool
+	err      error
+}
+
+func (c *fakeBreachedChecker) IsBreached(password string) (bool, error) {
+	return c.breached[password], c.err
+}
+
+func TestPolicyBreachedPasswords(t *testing.T) {
+	checker := &fakeBreachedChecker{breached: map[string]bool{"breached-password": true}}
+	policy := passwordpolicy.New(&passwordpolicy.Config{}, checker)
+
+	violations, err := policy.Check("breached-password", "")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if rules := violatedRules(violations); !reflect.DeepEqual(rules, []string{passwordpolicy.RuleNotBreached}) {
+		t.Errorf("Check() violated rules = %v, want [%s]", rules, passwordpolicy.RuleNotBreached)
+	}
+
+	violations, err = policy.Check("unique-password", "")
+	if err != nil || len(violations) != 0 {
+		t.Errorf("Check() = %v, %v, want no violations", violations, err)
+	}
+
+	checker.err = errors.New("source is unavailable")
+	_, err = policy.Check("unique-password", "")
+	if !errors.Is(err, checker.err) {
+		t.Errorf("Check() error = %v, want %v", err, checker.err)
+	}
+}
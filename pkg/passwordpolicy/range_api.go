@@ -0,0 +1,82 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultRangeApiTimeout = 5 * time.Second
+
+// maxRangeResponseSize limits responses read into memory, real ones are about 30 KiB with padding
+const maxRangeResponseSize = 1 << 20
+
+var ErrRangeApiRequest = errors.New("range api request failed")
+
+// RangeApi is a remote range source compatible with Have I Been Pwned range API:
+// 'GET <url><prefix>' responds with 'SUFFIX:COUNT' lines. Padding is requested, so the size
+// of responses does not reveal the prefix, padding entries have zero count
+type RangeApi struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewRangeApi returns a range source requesting url followed by the prefix, e.g. 'https://api.pwnedpasswords.com/range/'
+func NewRangeApi(url string, timeout time.Duration) *RangeApi {
+	if timeout <= 0 {
+		timeout = defaultRangeApiTimeout
+	}
+	return &RangeApi{url: url, httpClient: &http.Client{Timeout: timeout}}
+}
+
+func (a *RangeApi) Range(prefix string) ([]RangeEntry, error) {
+	if len(prefix) != prefixLength {
+		return nil, errors.New("invalid hash prefix length")
+	}
+
+	request, err := http.NewRequest(http.MethodGet, a.url+strings.ToUpper(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Add-Padding", "true")
+
+	response, err := a.httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRangeApiRequest, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: responded with status %d", ErrRangeApiRequest, response.StatusCode)
+	}
+
+	var entries []RangeEntry
+
+	scanner := bufio.NewScanner(io.LimitReader(response.Body, maxRangeResponseSize))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		suffix, countValue, found := strings.Cut(line, ":")
+		count, err := strconv.Atoi(countValue)
+		if !found || err != nil || len(suffix) != sha1.Size*2-prefixLength {
+			return nil, fmt.Errorf("%w: invalid line %q", ErrRangeApiRequest, line)
+		}
+
+		entries = append(entries, RangeEntry{Suffix: strings.ToUpper(suffix), Count: count})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRangeApiRequest, err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,192 @@
+package passwordpolicy
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsData string
+
+var commonPasswords = loadCommonPasswords(commonPasswordsData)
+
+// guessesLog2 thresholds of the scores, they match 10^3, 10^6, 10^8 and 10^10 guesses used by zxcvbn
+var scoreThresholds = []float64{10, 20, 26.6, 33.2}
+
+const (
+	// bits of a token found in the common passwords list or derived from the email
+	dictionaryTokenBits = 10
+	// bits of a character continuing a repeat, a sequence or a keyboard pattern
+	patternCharacterBits = 1
+	// minimal length of a dictionary token searched inside a password
+	minDictionaryTokenLength = 4
+)
+
+var keyboardRows = []string{
+	"`1234567890-=",
+	"qwertyuiop[]\\",
+	"asdfghjkl;'",
+	"zxcvbnm,./",
+}
+
+var leetReplacer = strings.NewReplacer(
+	"@", "a", "4", "a", "8", "b", "3", "e", "1", "l", "!", "i",
+	"0", "o", "$", "s", "5", "s", "7", "t", "+", "t", "2", "z",
+)
+
+// Strength is an estimation of how hard the password is to guess
+type Strength struct {
+	// Score is from 0 (too guessable) to 4 (very unguessable)
+	Score int
+	// GuessesLog2 is an estimated number of guesses needed to crack the password in bits
+	GuessesLog2 float64
+}
+
+// EstimateStrength estimates password strength in the spirit of zxcvbn: brute force entropy
+// of the password is reduced for common passwords, parts of the user inputs, repeats,
+// sequences and keyboard patterns
+func EstimateStrength(password string, userInputs ...string) Strength {
+	lowered := strings.ToLower(password)
+	normalized := leetReplacer.Replace(lowered)
+
+	dictionary := make(map[string]struct{}, len(userInputs))
+	for _, input := range userInputs {
+		for _, token := range strings.FieldsFunc(strings.ToLower(input), isSeparator) {
+			if len(token) >= minDictionaryTokenLength-1 {
+				dictionary[token] = struct{}{}
+			}
+		}
+	}
+
+	if isCommonPassword(lowered, dictionary) {
+		return newStrength(math.Log2(float64(len(commonPasswords))))
+	}
+
+	runes := []rune(normalized)
+	original := []rune(password)
+	poolBits := math.Log2(float64(poolSize(password)))
+
+	bits := 0.0
+	for i := 0; i < len(runes); {
+		tokenLength := dictionaryTokenAt(runes, i, dictionary)
+		if tokenLength > 0 {
+			bits += dictionaryTokenBits
+			i += tokenLength
+			continue
+		}
+
+		if i > 0 && isPatternContinuation(original[i-1], original[i]) {
+			bits += patternCharacterBits
+		} else {
+			bits += poolBits
+		}
+		i++
+	}
+
+	return newStrength(bits)
+}
+
+func newStrength(guessesLog2 float64) Strength {
+	score := 0
+	for _, threshold := range scoreThresholds {
+		if guessesLog2 >= threshold {
+			score++
+		}
+	}
+	return Strength{Score: score, GuessesLog2: guessesLog2}
+}
+
+// isCommonPassword also catches leet spelled common passwords and common passwords
+// with appended digits and symbols like 'p@ssword123!'
+func isCommonPassword(lowered string, dictionary map[string]struct{}) bool {
+	trimmed := strings.TrimRightFunc(lowered, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+
+	candidates := []string{lowered, trimmed, leetReplacer.Replace(lowered), leetReplacer.Replace(trimmed)}
+
+	for _, candidate := range candidates {
+		if _, ok := commonPasswords[candidate]; ok {
+			return true
+		}
+		if _, ok := dictionary[candidate]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dictionaryTokenAt returns length of the longest dictionary token starting at i, or zero
+func dictionaryTokenAt(runes []rune, i int, dictionary map[string]struct{}) int {
+	for end := len(runes); end-i >= minDictionaryTokenLength; end-- {
+		token := string(runes[i:end])
+		if _, ok := commonPasswords[token]; ok {
+			return end - i
+		}
+		if _, ok := dictionary[token]; ok {
+			return end - i
+		}
+	}
+	return 0
+}
+
+// isPatternContinuation reports whether the character repeats the previous one,
+// continues an alphabetical or numeric sequence, or is adjacent on a keyboard
+func isPatternContinuation(prev rune, cur rune) bool {
+	prev, cur = unicode.ToLower(prev), unicode.ToLower(cur)
+
+	if prev == cur || prev+1 == cur || prev-1 == cur {
+		return true
+	}
+
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		if i < 0 {
+			continue
+		}
+		j := strings.IndexRune(row, cur)
+		if j >= 0 && (j-i == 1 || i-j == 1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func poolSize(password string) int {
+	classes := characterClassesOf(password)
+
+	size := 0
+	if classes.lower {
+		size += 26
+	}
+	if classes.upper {
+		size += 26
+	}
+	if classes.digit {
+		size += 10
+	}
+	if classes.symbol {
+		size += 33
+	}
+
+	return max(size, 1)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func loadCommonPasswords(data string) map[string]struct{} {
+	passwords := make(map[string]struct{})
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			passwords[line] = struct{}{}
+		}
+	}
+	return passwords
+}
@@ -0,0 +1,53 @@
+package passwordpolicy_test
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/passwordpolicy"
+	"testing"
+)
+
+func TestEstimateStrengthCommonPasswords(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+	}{
+		{name: "common password", password: "password"},
+		{name: "common password in other case", password: "PassWord"},
+		{name: "common number", password: "123456789"},
+		{name: "common password with leet substitutions", password: "p@ssw0rd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strength := passwordpolicy.EstimateStrength(tt.password)
+			if strength.Score != 0 {
+				t.Errorf("EstimateStrength(%q) score = %d, want 0", tt.password, strength.Score)
+			}
+		})
+	}
+}
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		minScore   int
+		maxScore   int
+	}{
+		{name: "repeat", password: "aaaaaaaaaaaa", maxScore: 1},
+		{name: "sequence", password: "abcdefghijkl", maxScore: 1},
+		{name: "keyboard pattern", password: "qwertyuiopas", maxScore: 1},
+		{name: "derived from user input", password: "johnsmith1990", userInputs: []string{"johnsmith@example.com"}, maxScore: 2},
+		{name: "random characters", password: "x7#Qm9!vR2@kLp", minScore: 4, maxScore: 4},
+		{name: "passphrase", password: "violet-Tundra-58-kayak", minScore: 3, maxScore: 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strength := passwordpolicy.EstimateStrength(tt.password, tt.userInputs...)
+			if strength.Score < tt.minScore || strength.Score > tt.maxScore {
+				t.Errorf("EstimateStrength(%q) score = %d, want %d-%d", tt.password, strength.Score, tt.minScore, tt.maxScore)
+			}
+		})
+	}
+}
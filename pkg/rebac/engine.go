@@ -0,0 +1,278 @@
+package rebac
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrUnknownType           = errors.New("unknown object type")
+	ErrUnknownRelation       = errors.New("unknown relation or permission")
+	ErrSubjectTypeNotAllowed = errors.New("subject type is not allowed by the relation")
+	ErrMaxDepthExceeded      = errors.New("max check depth exceeded")
+	ErrTooManyRelationships  = errors.New("too many relationships in a single write")
+)
+
+const (
+	defaultMaxDepth     = 25
+	defaultMaxWriteSize = 1000
+)
+
+// Engine evaluates permissions of the schema over relationships of the tuple store,
+// it is safe for concurrent use
+type Engine struct {
+	schema       *Schema
+	store        TupleStore
+	maxDepth     int
+	maxWriteSize int
+}
+
+func NewEngine(schema *Schema, store TupleStore) *Engine {
+	return &Engine{
+		schema:       schema,
+		store:        store,
+		maxDepth:     defaultMaxDepth,
+		maxWriteSize: defaultMaxWriteSize,
+	}
+}
+
+// Schema returns the schema the engine evaluates
+func (e *Engine) Schema() *Schema {
+	return e.schema
+}
+
+// WriteRelationships validates the relationships against the schema and stores them
+func (e *Engine) WriteRelationships(writes []Relationship, deletes []Relationship) error {
+	if len(writes)+len(deletes) > e.maxWriteSize {
+		return fmt.Errorf("%w: at most %d are allowed", ErrTooManyRelationships, e.maxWriteSize)
+	}
+
+	for _, relationship := range writes {
+		err := e.validateRelationship(relationship)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, relationship := range deletes {
+		err := relationship.validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	return e.store.WriteRelationships(writes, deletes)
+}
+
+// Check reports whether the subject has the relation or the permission with the resource
+func (e *Engine) Check(resource Object, permission string, subject Subject) (bool, error) {
+	err := e.validateCheck(resource.Type, permission, subject)
+	if err != nil {
+		return false, err
+	}
+
+	err = resource.validate()
+	if err != nil {
+		return false, err
+	}
+
+	return e.check(resource, permission, subject, 0, make(visitedChecks))
+}
+
+// ListObjects returns sorted ids of the resources of the type the subject has the permission with.
+// Every resource of the type having any relationship is checked, so the call is linear in their count
+func (e *Engine) ListObjects(resourceType string, permission string, subject Subject) ([]string, error) {
+	err := e.validateCheck(resourceType, permission, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := e.store.ListResourceIds(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	allowedIds := make([]string, 0)
+	for _, id := range ids {
+		allowed, err := e.check(Object{Type: resourceType, Id: id}, permission, subject, 0, make(visitedChecks))
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			allowedIds = append(allowedIds, id)
+		}
+	}
+
+	return allowedIds, nil
+}
+
+func (e *Engine) validateCheck(resourceType string, permission string, subject Subject) error {
+	definition, ok := e.schema.Definition(resourceType)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownType, resourceType)
+	}
+	if !definition.hasMember(permission) {
+		return fmt.Errorf("%w: %q of type %q", ErrUnknownRelation, permission, resourceType)
+	}
+
+	err := subject.validate()
+	if err != nil {
+		return err
+	}
+
+	if _, ok = e.schema.Definition(subject.Type); !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownType, subject.Type)
+	}
+	return nil
+}
+
+func (e *Engine) validateRelationship(relationship Relationship) error {
+	err := relationship.validate()
+	if err != nil {
+		return err
+	}
+
+	definition, ok := e.schema.Definition(relationship.Resource.Type)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownType, relationship.Resource.Type)
+	}
+
+	relation, ok := definition.Relations[relationship.Relation]
+	if !ok {
+		return fmt.Errorf("%w: %q of type %q", ErrUnknownRelation, relationship.Relation, relationship.Resource.Type)
+	}
+
+	subject := relationship.Subject
+	for _, subjectType := range relation.Subjects {
+		if subjectType.Type != subject.Type {
+			continue
+		}
+		if subjectType.Wildcard && subject.Id == Wildcard {
+			return nil
+		}
+		if !subjectType.Wildcard && subject.Id != Wildcard && subjectType.Relation == subject.Relation {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q in %s#%s", ErrSubjectTypeNotAllowed, subject.String(), relationship.Resource.Type, relationship.Relation)
+}
+
+// checkKey identifies a check of the subject having the relation or the permission with the resource
+type checkKey struct {
+	resource Object
+	name     string
+	subject  Subject
+}
+
+// visitedChecks holds the checks being evaluated along the current path of a single request
+type visitedChecks map[checkKey]struct{}
+
+func (e *Engine) check(resource Object, name string, subject Subject, depth int, visited visitedChecks) (bool, error) {
+	if depth > e.maxDepth {
+		return false, ErrMaxDepthExceeded
+	}
+
+	if subject.Object == resource && subject.Relation == name {
+		return true, nil
+	}
+
+	// relationships may form cycles, e.g. groups being members of each other. A check depending on itself
+	// adds nothing to the result, so it is false. The key is removed after the check, because other branches,
+	// e.g. the right side of an intersection, may need the same check and its result is not kept
+	key := checkKey{resource: resource, name: name, subject: subject}
+	if _, ok := visited[key]; ok {
+		return false, nil
+	}
+	visited[key] = struct{}{}
+	defer delete(visited, key)
+
+	definition, ok := e.schema.Definition(resource.Type)
+	if !ok {
+		return false, nil
+	}
+
+	if permission, ok := definition.Permissions[name]; ok {
+		return e.evaluate(resource, permission.Expression, subject, depth, visited)
+	}
+
+	if _, ok := definition.Relations[name]; !ok {
+		return false, nil
+	}
+
+	subjects, err := e.store.ReadSubjects(resource, name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, relatedSubject := range subjects {
+		if relatedSubject == subject {
+			return true, nil
+		}
+		if relatedSubject.Id == Wildcard && relatedSubject.Type == subject.Type && subject.Relation == "" {
+			return true, nil
+		}
+	}
+
+	for _, relatedSubject := range subjects {
+		if relatedSubject.Relation == "" {
+			continue
+		}
+
+		allowed, err := e.check(relatedSubject.Object, relatedSubject.Relation, subject, depth+1, visited)
+		if err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	return false, nil
+}
+
+func (e *Engine) evaluate(resource Object, expression Expression, subject Subject, depth int, visited visitedChecks) (bool, error) {
+	switch expr := expression.(type) {
+	case *MemberExpression:
+		return e.check(resource, expr.Name, subject, depth+1, visited)
+	case *ArrowExpression:
+		relatedSubjects, err := e.store.ReadSubjects(resource, expr.Relation)
+		if err != nil {
+			return false, err
+		}
+
+		for _, relatedSubject := range relatedSubjects {
+			if relatedSubject.Id == Wildcard {
+				continue
+			}
+
+			allowed, err := e.check(relatedSubject.Object, expr.Name, subject, depth+1, visited)
+			if err != nil || allowed {
+				return allowed, err
+			}
+		}
+		return false, nil
+	case *OperationExpression:
+		left, err := e.evaluate(resource, expr.Left, subject, depth, visited)
+		if err != nil {
+			return false, err
+		}
+
+		switch expr.Operator {
+		case OperatorUnion:
+			if left {
+				return true, nil
+			}
+			return e.evaluate(resource, expr.Right, subject, depth, visited)
+		case OperatorIntersection:
+			if !left {
+				return false, nil
+			}
+			return e.evaluate(resource, expr.Right, subject, depth, visited)
+		case OperatorExclusion:
+			if !left {
+				return false, nil
+			}
+			right, err := e.evaluate(resource, expr.Right, subject, depth, visited)
+			return !right, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,297 @@
+package rebac_test
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+const testSchema = `
+type user {}
+
+type group {
+    relation member: user | group#member
+}
+
+type folder {
+    relation parent: folder
+    relation viewer: user | group#member
+    permission view = viewer + parent->view
+}
+
+type document {
+    relation parent: folder
+    relation owner: user
+    relation viewer: user | user:* | group#member
+    relation banned: user
+    permission edit = owner
+    permission view = viewer + edit + parent->view
+    permission comment = (viewer & parent->view) - banned
+}
+`
+
+func newEngine(t *testing.T, relationships ...string) *rebac.Engine {
+	t.Helper()
+
+	schema, err := rebac.ParseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("ParseSchema() error = %v", err)
+	}
+
+	writes := make([]rebac.Relationship, len(relationships))
+	for i, value := range relationships {
+		writes[i], err = rebac.ParseRelationship(value)
+		if err != nil {
+			t.Fatalf("ParseRelationship(%q) error = %v", value, err)
+		}
+	}
+
+	engine := rebac.NewEngine(schema, rebac.NewMemoryTupleStore())
+
+	err = engine.WriteRelationships(writes, nil)
+	if err != nil {
+		t.Fatalf("WriteRelationships() error = %v", err)
+	}
+
+	return engine
+}
+
+func mustParseObject(t *testing.T, value string) rebac.Object {
+	t.Helper()
+
+	object, err := rebac.ParseObject(value)
+	if err != nil {
+		t.Fatalf("ParseObject(%q) error = %v", value, err)
+	}
+	return object
+}
+
+func mustParseSubject(t *testing.T, value string) rebac.Subject {
+	t.Helper()
+
+	subject, err := rebac.ParseSubject(value)
+	if err != nil {
+		t.Fatalf("ParseSubject(%q) error = %v", value, err)
+	}
+	return subject
+}
+
+func TestEngineCheck(t *testing.T) {
+	tests := []struct {
+		name          string
+		relationships []string
+		resource      string
+		permission    string
+		subject       string
+		want          bool
+	}{
+		{
+			name:          "direct relation",
+			relationships: []string{"document:readme#viewer@user:alice"},
+			resource:      "document:readme",
+			permission:    "viewer",
+			subject:       "user:alice",
+			want:          true,
+		},
+		{
+			name:          "missing relation",
+			relationships: []string{"document:readme#viewer@user:alice"},
+			resource:      "document:readme",
+			permission:    "viewer",
+			subject:       "user:bob",
+			want:          false,
+		},
+		{
+			name:          "wildcard",
+			relationships: []string{"document:readme#viewer@user:*"},
+			resource:      "document:readme",
+			permission:    "view",
+			subject:       "user:bob",
+			want:          true,
+		},
+		{
+			name:          "permission of another relation",
+			relationships: []string{"document:readme#owner@user:alice"},
+			resource:      "document:readme",
+			permission:    "view",
+			subject:       "user:alice",
+			want:          true,
+		},
+		{
+			name: "nested groups",
+			relationships: []string{
+				"document:readme#viewer@group:staff#member",
+				"group:staff#member@group:engineers#member",
+				"group:engineers#member@user:bob",
+			},
+			resource:   "document:readme",
+			permission: "view",
+			subject:    "user:bob",
+			want:       true,
+		},
+		{
+			name: "arrow",
+			relationships: []string{
+				"document:readme#parent@folder:docs",
+				"folder:docs#parent@folder:root",
+				"folder:root#viewer@user:alice",
+			},
+			resource:   "document:readme",
+			permission: "view",
+			subject:    "user:alice",
+			want:       true,
+		},
+		{
+			name: "intersection and exclusion",
+			relationships: []string{
+				"document:readme#parent@folder:docs",
+				"document:readme#viewer@user:alice",
+				"document:readme#viewer@user:bob",
+				"document:readme#banned@user:bob",
+				"folder:docs#viewer@user:alice",
+				"folder:docs#viewer@user:bob",
+			},
+			resource:   "document:readme",
+			permission: "comment",
+			subject:    "user:alice",
+			want:       true,
+		},
+		{
+			name: "excluded subject",
+			relationships: []string{
+				"document:readme#parent@folder:docs",
+				"document:readme#viewer@user:bob",
+				"document:readme#banned@user:bob",
+				"folder:docs#viewer@user:bob",
+			},
+			resource:   "document:readme",
+			permission: "comment",
+			subject:    "user:bob",
+			want:       false,
+		},
+		{
+			name: "groups being members of each other",
+			relationships: []string{
+				"group:a#member@group:b#member",
+				"group:b#member@group:a#member",
+			},
+			resource:   "group:b",
+			permission: "member",
+			subject:    "user:bob",
+			want:       false,
+		},
+		{
+			name: "member of a group in a cycle",
+			relationships: []string{
+				"group:a#member@group:b#member",
+				"group:b#member@group:a#member",
+				"group:a#member@user:bob",
+			},
+			resource:   "group:b",
+			permission: "member",
+			subject:    "user:bob",
+			want:       true,
+		},
+		{
+			name: "group in a cycle as a subject",
+			relationships: []string{
+				"group:a#member@group:b#member",
+				"group:b#member@group:a#member",
+			},
+			resource:   "group:a",
+			permission: "member",
+			subject:    "group:b#member",
+			want:       true,
+		},
+		{
+			name: "folders being parents of each other",
+			relationships: []string{
+				"document:readme#parent@folder:a",
+				"folder:a#parent@folder:b",
+				"folder:b#parent@folder:a",
+			},
+			resource:   "document:readme",
+			permission: "view",
+			subject:    "user:alice",
+			want:       false,
+		},
+		{
+			name: "same check on both sides of an intersection",
+			relationships: []string{
+				"document:readme#parent@folder:docs",
+				"document:readme#viewer@group:staff#member",
+				"folder:docs#viewer@group:staff#member",
+				"group:staff#member@group:staff#member",
+				"group:staff#member@user:alice",
+			},
+			resource:   "document:readme",
+			permission: "comment",
+			subject:    "user:alice",
+			want:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := newEngine(t, tt.relationships...)
+
+			got, err := engine.Check(mustParseObject(t, tt.resource), tt.permission, mustParseSubject(t, tt.subject))
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngineCheckMaxDepth(t *testing.T) {
+	relationships := make([]string, 0, 30)
+	for i := 0; i < 30; i++ {
+		relationships = append(relationships, "group:g"+strconv.Itoa(i)+"#member@group:g"+strconv.Itoa(i+1)+"#member")
+	}
+
+	engine := newEngine(t, relationships...)
+
+	_, err := engine.Check(mustParseObject(t, "group:g0"), "member", mustParseSubject(t, "user:bob"))
+	if !errors.Is(err, rebac.ErrMaxDepthExceeded) {
+		t.Errorf("Check() error = %v, want %v", err, rebac.ErrMaxDepthExceeded)
+	}
+}
+
+func TestEngineListObjects(t *testing.T) {
+	engine := newEngine(t,
+		"group:a#member@group:b#member",
+		"group:b#member@group:a#member",
+		"group:c#member@user:bob",
+		"group:d#member@group:c#member",
+		"document:readme#viewer@group:a#member",
+		"document:guide#viewer@group:d#member",
+	)
+
+	tests := []struct {
+		resourceType string
+		permission   string
+		subject      string
+		want         []string
+	}{
+		{resourceType: "group", permission: "member", subject: "user:bob", want: []string{"c", "d"}},
+		{resourceType: "group", permission: "member", subject: "user:alice", want: []string{}},
+		{resourceType: "document", permission: "view", subject: "user:bob", want: []string{"guide"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resourceType+"#"+tt.permission+"@"+tt.subject, func(t *testing.T) {
+			got, err := engine.ListObjects(tt.resourceType, tt.permission, mustParseSubject(t, tt.subject))
+			if err != nil {
+				t.Fatalf("ListObjects() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ListObjects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
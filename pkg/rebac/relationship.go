@@ -0,0 +1,150 @@
+package rebac
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidRelationship = errors.New("invalid relationship")
+
+// Wildcard is the id of a subject standing for all the objects of its type
+const Wildcard = "*"
+
+const maxIdLength = 128
+
+var (
+	namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	idPattern   = regexp.MustCompile(`^[A-Za-z0-9_\-.=+/|]+$`)
+)
+
+// Object is written as 'type:id'
+type Object struct {
+	Type string
+	Id   string
+}
+
+func (o Object) String() string {
+	return o.Type + ":" + o.Id
+}
+
+// Subject is an object or a set of objects having the relation with it if Relation is set,
+// it is written as 'type:id' or 'type:id#relation'
+type Subject struct {
+	Object
+	Relation string
+}
+
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Object.String()
+	}
+	return s.Object.String() + "#" + s.Relation
+}
+
+// Relationship states that Subject has Relation with Resource,
+// it is written as 'type:id#relation@subject'
+type Relationship struct {
+	Resource Object
+	Relation string
+	Subject  Subject
+}
+
+func (r Relationship) String() string {
+	return r.Resource.String() + "#" + r.Relation + "@" + r.Subject.String()
+}
+
+// ParseObject parses 'type:id'
+func ParseObject(value string) (Object, error) {
+	object, err := cutObject(value)
+	if err != nil {
+		return Object{}, err
+	}
+
+	return object, object.validate()
+}
+
+// ParseSubject parses 'type:id' or 'type:id#relation'
+func ParseSubject(value string) (Subject, error) {
+	objectValue, relation, _ := strings.Cut(value, "#")
+
+	object, err := cutObject(objectValue)
+	if err != nil {
+		return Subject{}, err
+	}
+
+	subject := Subject{Object: object, Relation: relation}
+
+	return subject, subject.validate()
+}
+
+// ParseRelationship parses 'type:id#relation@subject'
+func ParseRelationship(value string) (Relationship, error) {
+	resourceValue, subjectValue, ok := strings.Cut(value, "@")
+	if !ok {
+		return Relationship{}, fmt.Errorf("%w: %q must be type:id#relation@subject", ErrInvalidRelationship, value)
+	}
+
+	resourceSubject, err := ParseSubject(resourceValue)
+	if err != nil {
+		return Relationship{}, err
+	}
+
+	subject, err := ParseSubject(subjectValue)
+	if err != nil {
+		return Relationship{}, err
+	}
+
+	relationship := Relationship{Resource: resourceSubject.Object, Relation: resourceSubject.Relation, Subject: subject}
+
+	return relationship, relationship.validate()
+}
+
+func cutObject(value string) (Object, error) {
+	objectType, id, ok := strings.Cut(value, ":")
+	if !ok {
+		return Object{}, fmt.Errorf("%w: %q must be type:id", ErrInvalidRelationship, value)
+	}
+	return Object{Type: objectType, Id: id}, nil
+}
+
+func (o Object) validate() error {
+	if !namePattern.MatchString(o.Type) {
+		return fmt.Errorf("%w: object type %q is invalid", ErrInvalidRelationship, o.Type)
+	}
+	if len(o.Id) > maxIdLength || !idPattern.MatchString(o.Id) {
+		return fmt.Errorf("%w: object id %q is invalid", ErrInvalidRelationship, o.Id)
+	}
+	return nil
+}
+
+func (s Subject) validate() error {
+	if s.Id != Wildcard {
+		err := s.Object.validate()
+		if err != nil {
+			return err
+		}
+	} else if !namePattern.MatchString(s.Type) {
+		return fmt.Errorf("%w: object type %q is invalid", ErrInvalidRelationship, s.Type)
+	}
+
+	if s.Relation != "" && !namePattern.MatchString(s.Relation) {
+		return fmt.Errorf("%w: relation %q is invalid", ErrInvalidRelationship, s.Relation)
+	}
+	if s.Id == Wildcard && s.Relation != "" {
+		return fmt.Errorf("%w: wildcard subject %q cannot have a relation", ErrInvalidRelationship, s.String())
+	}
+	return nil
+}
+
+func (r Relationship) validate() error {
+	err := r.Resource.validate()
+	if err != nil {
+		return err
+	}
+	if !namePattern.MatchString(r.Relation) {
+		return fmt.Errorf("%w: relation %q is invalid", ErrInvalidRelationship, r.Relation)
+	}
+	return r.Subject.validate()
+}
@@ -0,0 +1,599 @@
+// Package rebac answers "can subject X do action Y on resource Z" questions using relationships
+// between objects (Zanzibar-style relationship tuples) and a schema defining object types,
+// their relations and permissions computed from the relations.
+//
+// Schema example:
+//
+//	type user {}
+//
+//	type group {
+//	    relation member: user | group#member
+//	}
+//
+//	type document {
+//	    relation parent: folder
+//	    relation owner: user
+//	    relation viewer: user | user:* | group#member
+//	    permission edit = owner
+//	    permission view = viewer + edit + parent->view
+//	}
+//
+// A relation lists allowed subject types: 'user' allows user objects, 'group#member' allows
+// members of a group and 'user:*' allows all the users at once. A permission expression combines
+// relations and permissions of the same type with '+' (union), '&' (intersection) and '-' (exclusion),
+// operators have equal precedence and are applied from left to right, parentheses group them.
+// 'parent->view' is 'view' permission of the objects related by 'parent' relation.
+// Lines starting with '//' are comments
+package rebac
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// Schema is a parsed schema, it is immutable and safe for concurrent use
+type Schema struct {
+	definitions map[string]*Definition
+}
+
+// Definition describes an object type
+type Definition struct {
+	Name        string
+	Relations   map[string]*Relation
+	Permissions map[string]*Permission
+}
+
+// Relation is stored as relationships, Subjects are subject types allowed in them
+type Relation struct {
+	Name     string
+	Subjects []SubjectType
+}
+
+// SubjectType is 'type', 'type#relation' or 'type:*' if Wildcard is set
+type SubjectType struct {
+	Type     string
+	Relation string
+	Wildcard bool
+}
+
+func (st SubjectType) String() string {
+	switch {
+	case st.Wildcard:
+		return st.Type + ":*"
+	case st.Relation != "":
+		return st.Type + "#" + st.Relation
+	default:
+		return st.Type
+	}
+}
+
+// Permission is computed from relations and permissions by Expression
+type Permission struct {
+	Name       string
+	Expression Expression
+}
+
+// Expression is one of *MemberExpression, *ArrowExpression or *OperationExpression
+type Expression interface {
+	expression()
+}
+
+// MemberExpression refers to a relation or a permission of the same object
+type MemberExpression struct {
+	Name string
+}
+
+// ArrowExpression refers to Name of the objects related by Relation
+type ArrowExpression struct {
+	Relation string
+	Name     string
+}
+
+// Operator combines results of two expressions
+type Operator byte
+
+const (
+	OperatorUnion        Operator = '+'
+	OperatorIntersection Operator = '&'
+	OperatorExclusion    Operator = '-'
+)
+
+type OperationExpression struct {
+	Operator Operator
+	Left     Expression
+	Right    Expression
+}
+
+func (*MemberExpression) expression()    {}
+func (*ArrowExpression) expression()     {}
+func (*OperationExpression) expression() {}
+
+// ParseSchema parses and validates a schema source
+func ParseSchema(source string) (*Schema, error) {
+	p := &schemaParser{lexer: newLexer(source)}
+	p.next()
+
+	schema := &Schema{definitions: make(map[string]*Definition)}
+
+	for p.token.kind != tokenEOF {
+		definition, err := p.parseDefinition()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := schema.definitions[definition.Name]; ok {
+			return nil, fmt.Errorf("%w: type %q is defined twice", ErrInvalidSchema, definition.Name)
+		}
+		schema.definitions[definition.Name] = definition
+	}
+
+	err := schema.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// Definition returns the definition of the object type
+func (s *Schema) Definition(objectType string) (*Definition, bool) {
+	definition, ok := s.definitions[objectType]
+	return definition, ok
+}
+
+// Types returns sorted names of the object types
+func (s *Schema) Types() []string {
+	types := make([]string, 0, len(s.definitions))
+	for name := range s.definitions {
+		types = append(types, name)
+	}
+	slices.Sort(types)
+	return types
+}
+
+// hasMember reports whether the type has a relation or a permission with the name
+func (d *Definition) hasMember(name string) bool {
+	_, isRelation := d.Relations[name]
+	_, isPermission := d.Permissions[name]
+	return isRelation || isPermission
+}
+
+func (s *Schema) validate() error {
+	for _, definition := range s.definitions {
+		for _, relation := range definition.Relations {
+			for _, subjectType := range relation.Subjects {
+				subjectDefinition, ok := s.definitions[subjectType.Type]
+				if !ok {
+					return fmt.Errorf("%w: %s#%s refers to unknown type %q", ErrInvalidSchema, definition.Name, relation.Name, subjectType.Type)
+				}
+				if subjectType.Relation != "" && !subjectDefinition.hasMember(subjectType.Relation) {
+					return fmt.Errorf("%w: %s#%s refers to unknown %q", ErrInvalidSchema, definition.Name, relation.Name, subjectType.String())
+				}
+			}
+		}
+
+		for _, permission := range definition.Permissions {
+			err := s.validateExpression(definition, permission, permission.Expression)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := validatePermissionCycles(definition)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePermissionCycles rejects permissions of the type referring to each other, e.g. 'a = b' and 'b = a',
+// they can never be computed. Arrows are not followed, they refer to permissions of other objects
+func validatePermissionCycles(definition *Definition) error {
+	names := make([]string, 0, len(definition.Permissions))
+	for name := range definition.Permissions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	done := make(map[string]bool)
+	var path []string
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		permission, ok := definition.Permissions[name]
+		if !ok || done[name] {
+			return nil
+		}
+
+		if i := slices.Index(path, name); i >= 0 {
+			cycle := append(slices.Clone(path[i:]), name)
+			return fmt.Errorf("%w: permissions of type %q form a cycle %s", ErrInvalidSchema, definition.Name, strings.Join(cycle, " -> "))
+		}
+
+		path = append(path, name)
+		for _, member := range memberNames(permission.Expression) {
+			err := visit(member)
+			if err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+
+		done[name] = true
+		return nil
+	}
+
+	for _, name := range names {
+		err := visit(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memberNames returns names of the relations and the permissions of the same object the expression refers to
+func memberNames(expression Expression) []string {
+	switch expr := expression.(type) {
+	case *MemberExpression:
+		return []string{expr.Name}
+	case *OperationExpression:
+		return append(memberNames(expr.Left), memberNames(expr.Right)...)
+	}
+	return nil
+}
+
+func (s *Schema) validateExpression(definition *Definition, permission *Permission, expression Expression) error {
+	switch expr := expression.(type) {
+	case *MemberExpression:
+		if expr.Name == permission.Name {
+			return fmt.Errorf("%w: %s#%s refers to itself", ErrInvalidSchema, definition.Name, permission.Name)
+		}
+		if !definition.hasMember(expr.Name) {
+			return fmt.Errorf("%w: %s#%s refers to unknown %q", ErrInvalidSchema, definition.Name, permission.Name, expr.Name)
+		}
+	case *ArrowExpression:
+		relation, ok := definition.Relations[expr.Relation]
+		if !ok {
+			return fmt.Errorf("%w: %s#%s: %q of %q must be a relation", ErrInvalidSchema, definition.Name, permission.Name, expr.Relation, expr.Relation+"->"+expr.Name)
+		}
+		for _, subjectType := range relation.Subjects {
+			if s.definitions[subjectType.Type].hasMember(expr.Name) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s#%s: no subject type of %q has %q", ErrInvalidSchema, definition.Name, permission.Name, expr.Relation, expr.Name)
+	case *OperationExpression:
+		err := s.validateExpression(definition, permission, expr.Left)
+		if err != nil {
+			return err
+		}
+		return s.validateExpression(definition, permission, expr.Right)
+	}
+	return nil
+}
+
+type schemaParser struct {
+	lexer *lexer
+	token token
+}
+
+func (p *schemaParser) next() {
+	p.token = p.lexer.next()
+}
+
+func (p *schemaParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: line %d: %s", ErrInvalidSchema, p.token.line, fmt.Sprintf(format, args...))
+}
+
+func (p *schemaParser) expect(kind tokenKind, value string) error {
+	if p.token.kind != kind || (value != "" && p.token.value != value) {
+		if value == "" {
+			value = kind.String()
+		}
+		return p.errorf("expected %s, got %s", value, p.token)
+	}
+	p.next()
+	return nil
+}
+
+func (p *schemaParser) identifier() (string, error) {
+	if p.token.kind != tokenIdentifier {
+		return "", p.errorf("expected identifier, got %s", p.token)
+	}
+	value := p.token.value
+	p.next()
+	return value, nil
+}
+
+func (p *schemaParser) parseDefinition() (*Definition, error) {
+	err := p.expect(tokenIdentifier, "type")
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(tokenSymbol, "{")
+	if err != nil {
+		return nil, err
+	}
+
+	definition := &Definition{
+		Name:        name,
+		Relations:   make(map[string]*Relation),
+		Permissions: make(map[string]*Permission),
+	}
+
+	for !(p.token.kind == tokenSymbol && p.token.value == "}") {
+		line := p.token.line
+
+		switch {
+		case p.token.kind == tokenIdentifier && p.token.value == "relation":
+			relation, err := p.parseRelation()
+			if err != nil {
+				return nil, err
+			}
+			if definition.hasMember(relation.Name) {
+				return nil, fmt.Errorf("%w: line %d: %q is defined twice in type %q", ErrInvalidSchema, line, relation.Name, name)
+			}
+			definition.Relations[relation.Name] = relation
+		case p.token.kind == tokenIdentifier && p.token.value == "permission":
+			permission, err := p.parsePermission()
+			if err != nil {
+				return nil, err
+			}
+			if definition.hasMember(permission.Name) {
+				return nil, fmt.Errorf("%w: line %d: %q is defined twice in type %q", ErrInvalidSchema, line, permission.Name, name)
+			}
+			definition.Permissions[permission.Name] = permission
+		default:
+			return nil, p.errorf("expected relation, permission or }, got %s", p.token)
+		}
+	}
+	p.next()
+
+	return definition, nil
+}
+
+func (p *schemaParser) parseRelation() (*Relation, error) {
+	p.next()
+
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(tokenSymbol, ":")
+	if err != nil {
+		return nil, err
+	}
+
+	relation := &Relation{Name: name}
+
+	for {
+		subjectType, err := p.parseSubjectType()
+		if err != nil {
+			return nil, err
+		}
+		relation.Subjects = append(relation.Subjects, subjectType)
+
+		if !(p.token.kind == tokenSymbol && p.token.value == "|") {
+			return relation, nil
+		}
+		p.next()
+	}
+}
+
+func (p *schemaParser) parseSubjectType() (SubjectType, error) {
+	subjectTypeName, err := p.identifier()
+	if err != nil {
+		return SubjectType{}, err
+	}
+
+	subjectType := SubjectType{Type: subjectTypeName}
+
+	if p.token.kind == tokenSymbol && p.token.value == "#" {
+		p.next()
+
+		subjectType.Relation, err = p.identifier()
+		if err != nil {
+			return SubjectType{}, err
+		}
+	} else if p.token.kind == tokenSymbol && p.token.value == ":" {
+		p.next()
+
+		err = p.expect(tokenSymbol, "*")
+		if err != nil {
+			return SubjectType{}, err
+		}
+		subjectType.Wildcard = true
+	}
+
+	return subjectType, nil
+}
+
+func (p *schemaParser) parsePermission() (*Permission, error) {
+	p.next()
+
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.expect(tokenSymbol, "=")
+	if err != nil {
+		return nil, err
+	}
+
+	expression, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Permission{Name: name, Expression: expression}, nil
+}
+
+func (p *schemaParser) parseExpression() (Expression, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.token.kind == tokenSymbol && strings.Contains("+&-", p.token.value) {
+		operator := Operator(p.token.value[0])
+		p.next()
+
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &OperationExpression{Operator: operator, Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *schemaParser) parseTerm() (Expression, error) {
+	if p.token.kind == tokenSymbol && p.token.value == "(" {
+		p.next()
+
+		expression, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+
+		err = p.expect(tokenSymbol, ")")
+		if err != nil {
+			return nil, err
+		}
+
+		return expression, nil
+	}
+
+	name, err := p.identifier()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.token.kind == tokenSymbol && p.token.value == "->" {
+		p.next()
+
+		target, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+
+		return &ArrowExpression{Relation: name, Name: target}, nil
+	}
+
+	return &MemberExpression{Name: name}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenSymbol
+	tokenInvalid
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of schema"
+	case tokenIdentifier:
+		return "identifier"
+	case tokenSymbol:
+		return "symbol"
+	default:
+		return "invalid character"
+	}
+}
+
+type token struct {
+	kind  tokenKind
+	value string
+	line  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return t.kind.String()
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+type lexer struct {
+	source []rune
+	pos    int
+	line   int
+}
+
+func newLexer(source string) *lexer {
+	return &lexer{source: []rune(source), line: 1}
+}
+
+func (l *lexer) next() token {
+	l.skipSpacesAndComments()
+
+	if l.pos >= len(l.source) {
+		return token{kind: tokenEOF, line: l.line}
+	}
+
+	start := l.pos
+	r := l.source[l.pos]
+
+	if isIdentifierRune(r) {
+		for l.pos < len(l.source) && isIdentifierRune(l.source[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenIdentifier, value: string(l.source[start:l.pos]), line: l.line}
+	}
+
+	if r == '-' && l.pos+1 < len(l.source) && l.source[l.pos+1] == '>' {
+		l.pos += 2
+		return token{kind: tokenSymbol, value: "->", line: l.line}
+	}
+
+	l.pos++
+	if strings.ContainsRune("{}:|#*=+&-()", r) {
+		return token{kind: tokenSymbol, value: string(r), line: l.line}
+	}
+	return token{kind: tokenInvalid, value: string(r), line: l.line}
+}
+
+func (l *lexer) skipSpacesAndComments() {
+	for l.pos < len(l.source) {
+		r := l.source[l.pos]
+
+		switch {
+		case r == '\n':
+			l.line++
+			l.pos++
+		case unicode.IsSpace(r):
+			l.pos++
+		case r == '/' && l.pos+1 < len(l.source) && l.source[l.pos+1] == '/':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
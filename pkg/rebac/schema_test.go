@@ -0,0 +1,83 @@
+package rebac_test
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/rebac"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr bool
+	}{
+		{
+			name:   "valid schema",
+			source: testSchema,
+		},
+		{
+			name: "recursive arrow",
+			source: `
+type folder {
+    relation parent: folder
+    relation viewer: folder
+    permission view = viewer + parent->view
+}`,
+		},
+		{
+			name: "permission referring to itself",
+			source: `
+type document {
+    relation viewer: document
+    permission view = viewer + view
+}`,
+			wantErr: true,
+		},
+		{
+			name: "permissions referring to each other",
+			source: `
+type document {
+    relation viewer: document
+    permission a = viewer + b
+    permission b = a
+}`,
+			wantErr: true,
+		},
+		{
+			name: "longer permission cycle",
+			source: `
+type document {
+    relation viewer: document
+    relation owner: document
+    permission a = viewer & (owner - b)
+    permission b = c
+    permission c = owner + a
+}`,
+			wantErr: true,
+		},
+		{
+			name: "unknown member",
+			source: `
+type document {
+    permission view = viewer
+}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := rebac.ParseSchema(tt.source)
+			if tt.wantErr {
+				if !errors.Is(err, rebac.ErrInvalidSchema) {
+					t.Errorf("ParseSchema() error = %v, want %v", err, rebac.ErrInvalidSchema)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("ParseSchema() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package rebac
+
+import (
+	"slices"
+	"sync"
+)
+
+// TupleStore stores relationships. Writing an existing relationship and deleting
+// a missing one are not errors, writes and deletes of one call must be applied atomically
+type TupleStore interface {
+	WriteRelationships(writes []Relationship, deletes []Relationship) error
+	// ReadSubjects returns subjects having the relation with the resource
+	ReadSubjects(resource Object, relation string) ([]Subject, error)
+	// ListResourceIds returns sorted ids of the resources of the type having any relationship
+	ListResourceIds(resourceType string) ([]string, error)
+}
+
+// MemoryTupleStore keeps relationships in the process memory,
+// it is meant for tests and a local development
+type MemoryTupleStore struct {
+	mu            sync.RWMutex
+	relationships map[Relationship]struct{}
+}
+
+func NewMemoryTupleStore() *MemoryTupleStore {
+	return &MemoryTupleStore{relationships: make(map[Relationship]struct{})}
+}
+
+func (s *MemoryTupleStore) WriteRelationships(writes []Relationship, deletes []Relationship) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, relationship := range writes {
+		s.relationships[relationship] = struct{}{}
+	}
+	for _, relationship := range deletes {
+		delete(s.relationships, relationship)
+	}
+
+	return nil
+}
+
+func (s *MemoryTupleStore) ReadSubjects(resource Object, relation string) ([]Subject, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var subjects []Subject
+	for relationship := range s.relationships {
+		if relationship.Resource == resource && relationship.Relation == relation {
+			subjects = append(subjects, relationship.Subject)
+		}
+	}
+
+	return subjects, nil
+}
+
+func (s *MemoryTupleStore) ListResourceIds(resourceType string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var ids []string
+	for relationship := range s.relationships {
+		if relationship.Resource.Type == resourceType {
+			ids = append(ids, relationship.Resource.Id)
+		}
+	}
+
+	slices.Sort(ids)
+
+	return slices.Compact(ids), nil
+}
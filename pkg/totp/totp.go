@@ -0,0 +1,97 @@
+// Package totp implements time-based one-time passwords (RFC 6238) compatible with
+// common authenticator apps: HMAC-SHA1, 6 digits and 30 seconds period
+package totp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"image/png"
+	"time"
+)
+
+const (
+	period     = 30
+	secretSize = 20
+	digits     = otp.DigitsSix
+)
+
+// skew is the number of periods before and after the current one codes of which are accepted,
+// it tolerates clock drift of devices and a delay of typing the code
+const skew = 1
+
+// CodeLength is the number of digits of a code
+const CodeLength = 6
+
+var ErrInvalidUri = errors.New("invalid otpauth uri")
+
+// Key is a generated secret and the otpauth uri to be shown to the user as a QR code
+type Key struct {
+	// Secret is base32 encoded without padding
+	Secret string
+	Uri    string
+}
+
+// Generate generates a new secret, accountName is shown by authenticator apps along with the issuer
+func Generate(issuer string, accountName string) (*Key, error) {
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      issuer,
+		AccountName: accountName,
+		Period:      period,
+		SecretSize:  secretSize,
+		Digits:      digits,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Key{Secret: key.Secret(), Uri: key.URL()}, nil
+}
+
+// QrCode returns a PNG image of size x size pixels encoding the otpauth uri
+func QrCode(uri string, size int) ([]byte, error) {
+	key, err := otp.NewKeyFromURL(uri)
+	if err != nil || key.Type() != "totp" {
+		return nil, ErrInvalidUri
+	}
+
+	img, err := key.Image(size, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Validate checks the code against the secret at the time and returns the time step the code
+// belongs to. Callers should reject steps not greater than the last accepted one to prevent
+// replaying of codes within their validity window
+func Validate(secret string, code string, t time.Time) (int64, bool) {
+	if len(code) != CodeLength {
+		return 0, false
+	}
+
+	opts := hotp.ValidateOpts{Digits: digits, Algorithm: otp.AlgorithmSHA1}
+	current := t.Unix() / period
+
+	for step := current - skew; step <= current+skew; step++ {
+		ok, err := hotp.ValidateCustom(code, uint64(step), secret, opts)
+		if err != nil {
+			return 0, false
+		}
+		if ok {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,122 @@
+package totp_test
+
+import (
+	"github.com/vaberof/auth-grpc/pkg/totp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of RFC 6238 test vectors, ASCII "12345678901234567890" encoded in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateRfc6238Vectors(t *testing.T) {
+	// codes of RFC 6238 appendix B are 8 digits, 6 digit codes are their last digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step, ok := totp.Validate(rfc6238Secret, tt.code, time.Unix(tt.unix, 0))
+			if !ok {
+				t.Fatalf("Validate() ok = false, want true")
+			}
+			if step != tt.unix/30 {
+				t.Errorf("Validate() step = %d, want %d", step, tt.unix/30)
+			}
+		})
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	const code = "005924"
+	codeTime := time.Unix(1234567890, 0)
+	codeStep := codeTime.Unix() / 30
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{name: "same step", offset: 0, want: true},
+		{name: "one step later", offset: 30 * time.Second, want: true},
+		{name: "one step earlier", offset: -30 * time.Second, want: true},
+		{name: "two steps later", offset: 60 * time.Second, want: false},
+		{name: "two steps earlier", offset: -60 * time.Second, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := totp.Validate(rfc6238Secret, code, codeTime.Add(tt.offset))
+			if ok != tt.want {
+				t.Fatalf("Validate() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && step != codeStep {
+				t.Errorf("Validate() step = %d, want %d", step, codeStep)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidCodes(t *testing.T) {
+	at := time.Unix(1234567890, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{name: "wrong code", secret: rfc6238Secret, code: "005925"},
+		{name: "8 digit code", secret: rfc6238Secret, code: "89005924"},
+		{name: "short code", secret: rfc6238Secret, code: "05924"},
+		{name: "empty code", secret: rfc6238Secret, code: ""},
+		{name: "other secret", secret: "JBSWY3DPEHPK3PXP", code: "005924"},
+		{name: "invalid secret", secret: "not base32!", code: "005924"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := totp.Validate(tt.secret, tt.code, at)
+			if ok {
+				t.Errorf("Validate() ok = true, want false")
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	key, err := totp.Generate("auth-grpc", "user@example.com")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	// 20 bytes of the secret are 32 base32 characters
+	if len(key.Secret) != 32 {
+		t.Errorf("Generate() secret length = %d, want 32", len(key.Secret))
+	}
+	if !strings.HasPrefix(key.Uri, "otpauth://totp/") || !strings.Contains(key.Uri, "secret="+key.Secret) {
+		t.Errorf("Generate() uri = %q", key.Uri)
+	}
+
+	qrCode, err := totp.QrCode(key.Uri, 64)
+	if err != nil {
+		t.Fatalf("QrCode() error = %v", err)
+	}
+	if !strings.HasPrefix(string(qrCode), "\x89PNG") {
+		t.Errorf("QrCode() is not a PNG image")
+	}
+
+	_, err = totp.QrCode("otpauth://hotp/issuer:user?secret="+key.Secret, 64)
+	if err != totp.ErrInvalidUri {
+		t.Errorf("QrCode() error = %v, want %v", err, totp.ErrInvalidUri)
+	}
+}
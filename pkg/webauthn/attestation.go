@@ -0,0 +1,159 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// Attestation statement formats
+const (
+	AttestationFormatNone   = "none"
+	AttestationFormatPacked = "packed"
+)
+
+// attestationCertificateOu is required in the subject of packed attestation certificates
+const attestationCertificateOu = "Authenticator Attestation"
+
+// aaguidExtensionOid is id-fido-gen-ce-aaguid extension of attestation certificates
+var aaguidExtensionOid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+var (
+	ErrInvalidAttestation            = errors.New("invalid attestation")
+	ErrUnsupportedAttestationFormat  = errors.New("unsupported attestation format")
+	errInvalidAttestationCertificate = errors.New("invalid attestation certificate")
+)
+
+type attestationObject struct {
+	Format            string
+	Statement         map[interface{}]interface{}
+	AuthenticatorData []byte
+}
+
+func parseAttestationObject(data []byte) (*attestationObject, error) {
+	decoded, rest, err := decodeCbor(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidAttestation, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAttestation)
+	}
+
+	entries, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: must be a map", ErrInvalidAttestation)
+	}
+
+	format, _ := entries["fmt"].(string)
+	statement, _ := entries["attStmt"].(map[interface{}]interface{})
+	authenticatorData, _ := entries["authData"].([]byte)
+	if format == "" || statement == nil || authenticatorData == nil {
+		return nil, fmt.Errorf("%w: fmt, attStmt and authData are required", ErrInvalidAttestation)
+	}
+
+	return &attestationObject{Format: format, Statement: statement, AuthenticatorData: authenticatorData}, nil
+}
+
+// verifyAttestation checks the attestation statement signature. Certificate chains of packed
+// attestations are not validated against trusted roots, the verification is fully offline
+// and the relying party requests 'none' attestation conveyance anyway
+func verifyAttestation(object *attestationObject, authenticatorData *AuthenticatorData, credentialKey *PublicKey, clientDataHash []byte) error {
+	switch object.Format {
+	case AttestationFormatNone:
+		if len(object.Statement) != 0 {
+			return fmt.Errorf("%w: none attestation statement must be empty", ErrInvalidAttestation)
+		}
+		return nil
+	case AttestationFormatPacked:
+		return verifyPackedAttestation(object, authenticatorData, credentialKey, clientDataHash)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedAttestationFormat, object.Format)
+	}
+}
+
+func verifyPackedAttestation(object *attestationObject, authenticatorData *AuthenticatorData, credentialKey *PublicKey, clientDataHash []byte) error {
+	algorithm, ok := object.Statement["alg"].(int64)
+	if !ok {
+		return fmt.Errorf("%w: packed attestation alg is missing", ErrInvalidAttestation)
+	}
+
+	signature, ok := object.Statement["sig"].([]byte)
+	if !ok {
+		return fmt.Errorf("%w: packed attestation sig is missing", ErrInvalidAttestation)
+	}
+
+	signedData := append(append([]byte(nil), object.AuthenticatorData...), clientDataHash...)
+
+	x5c, ok := object.Statement["x5c"].([]interface{})
+	if !ok {
+		// self attestation is signed by the credential key itself
+		if algorithm != credentialKey.Algorithm {
+			return fmt.Errorf("%w: self attestation alg does not match the credential key", ErrInvalidAttestation)
+		}
+		err := credentialKey.Verify(signedData, signature)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidAttestation, err)
+		}
+		return nil
+	}
+
+	if len(x5c) == 0 {
+		return fmt.Errorf("%w: x5c is empty", ErrInvalidAttestation)
+	}
+	leafDer, ok := x5c[0].([]byte)
+	if !ok {
+		return fmt.Errorf("%w: x5c must contain certificates", ErrInvalidAttestation)
+	}
+
+	certificate, err := x509.ParseCertificate(leafDer)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAttestation, err)
+	}
+
+	err = checkAttestationCertificate(certificate, authenticatorData.Aaguid)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAttestation, err)
+	}
+
+	err = verifySignature(algorithm, certificate.PublicKey, signedData, signature)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAttestation, err)
+	}
+	return nil
+}
+
+// checkAttestationCertificate checks requirements of the WebAuthn spec to packed attestation certificates
+func checkAttestationCertificate(certificate *x509.Certificate, aaguid []byte) error {
+	if certificate.Version != 3 {
+		return fmt.Errorf("%w: version must be 3", errInvalidAttestationCertificate)
+	}
+
+	subject := certificate.Subject
+	if len(subject.Country) == 0 || len(subject.Organization) == 0 || subject.CommonName == "" ||
+		len(subject.OrganizationalUnit) == 0 || subject.OrganizationalUnit[0] != attestationCertificateOu {
+		return fmt.Errorf("%w: subject is invalid", errInvalidAttestationCertificate)
+	}
+
+	if certificate.IsCA {
+		return fmt.Errorf("%w: must not be a CA certificate", errInvalidAttestationCertificate)
+	}
+
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(aaguidExtensionOid) {
+			continue
+		}
+		if extension.Critical {
+			return fmt.Errorf("%w: aaguid extension must not be critical", errInvalidAttestationCertificate)
+		}
+
+		var certificateAaguid []byte
+		_, err := asn1.Unmarshal(extension.Value, &certificateAaguid)
+		if err != nil || !bytes.Equal(certificateAaguid, aaguid) {
+			return fmt.Errorf("%w: aaguid does not match the authenticator", errInvalidAttestationCertificate)
+		}
+	}
+
+	return nil
+}
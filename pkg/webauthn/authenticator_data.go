@@ -0,0 +1,92 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+	flagExtensionData          = 0x80
+)
+
+const (
+	rpIdHashLength = 32
+	aaguidLength   = 16
+	// minAuthenticatorDataLength is rpIdHash, flags and signCount
+	minAuthenticatorDataLength = rpIdHashLength + 1 + 4
+)
+
+// MaxCredentialIdLength is the limit of the WebAuthn spec
+const MaxCredentialIdLength = 1023
+
+var ErrInvalidAuthenticatorData = errors.New("invalid authenticator data")
+
+// AuthenticatorData is parsed authenticatorData of attestation and assertion responses
+type AuthenticatorData struct {
+	RpIdHash     []byte
+	UserPresent  bool
+	UserVerified bool
+	SignCount    uint32
+	// attested credential data is present in registration responses only
+	Aaguid              []byte
+	CredentialId        []byte
+	CredentialPublicKey []byte
+}
+
+func parseAuthenticatorData(data []byte) (*AuthenticatorData, error) {
+	if len(data) < minAuthenticatorDataLength {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidAuthenticatorData)
+	}
+
+	flags := data[rpIdHashLength]
+	authenticatorData := &AuthenticatorData{
+		RpIdHash:     data[:rpIdHashLength],
+		UserPresent:  flags&flagUserPresent != 0,
+		UserVerified: flags&flagUserVerified != 0,
+		SignCount:    binary.BigEndian.Uint32(data[rpIdHashLength+1:]),
+	}
+
+	rest := data[minAuthenticatorDataLength:]
+
+	if flags&flagAttestedCredentialData != 0 {
+		if len(rest) < aaguidLength+2 {
+			return nil, fmt.Errorf("%w: attested credential data is too short", ErrInvalidAuthenticatorData)
+		}
+
+		authenticatorData.Aaguid = rest[:aaguidLength]
+		credentialIdLength := int(binary.BigEndian.Uint16(rest[aaguidLength:]))
+		rest = rest[aaguidLength+2:]
+
+		if credentialIdLength > MaxCredentialIdLength || credentialIdLength > len(rest) {
+			return nil, fmt.Errorf("%w: invalid credential id length", ErrInvalidAuthenticatorData)
+		}
+		authenticatorData.CredentialId = rest[:credentialIdLength]
+		rest = rest[credentialIdLength:]
+
+		_, afterKey, err := decodeCbor(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: credential public key: %w", ErrInvalidAuthenticatorData, err)
+		}
+		authenticatorData.CredentialPublicKey = rest[:len(rest)-len(afterKey)]
+		rest = afterKey
+	}
+
+	if flags&flagExtensionData != 0 {
+		_, afterExtensions, err := decodeCbor(rest)
+		if err != nil {
+			return nil, fmt.Errorf("%w: extensions: %w", ErrInvalidAuthenticatorData, err)
+		}
+		rest = afterExtensions
+	}
+
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidAuthenticatorData)
+	}
+
+	return authenticatorData, nil
+}
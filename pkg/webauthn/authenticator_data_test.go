@@ -0,0 +1,134 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// testAuthenticatorData builds authenticator data of example.com, attested credential data is added
+// if credentialId is set and extensions if they are set
+func testAuthenticatorData(t testing.TB, flags byte, signCount uint32, credentialId []byte, extensions []byte) []byte {
+	t.Helper()
+
+	rpIdHash := sha256.Sum256([]byte("example.com"))
+	data := append(rpIdHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, signCount)
+
+	if credentialId != nil {
+		coseKey, err := encodeCbor(map[interface{}]interface{}{
+			int64(coseKeyType):      int64(coseKeyTypeOkp),
+			int64(coseKeyAlgorithm): int64(AlgorithmEdDSA),
+			int64(coseKeyCurve):     int64(coseCurveEd25519),
+			int64(coseKeyX):         make([]byte, 32),
+		})
+		if err != nil {
+			t.Fatalf("encodeCbor() error = %v", err)
+		}
+
+		data = append(data, make([]byte, aaguidLength)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(credentialId)))
+		data = append(data, credentialId...)
+		data = append(data, coseKey...)
+	}
+
+	return append(data, extensions...)
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	credentialId := []byte("credential-1")
+	data := testAuthenticatorData(t, flagUserPresent|flagUserVerified|flagAttestedCredentialData|flagExtensionData, 7, credentialId, []byte{0xa0})
+
+	authenticatorData, err := parseAuthenticatorData(data)
+	if err != nil {
+		t.Fatalf("parseAuthenticatorData() error = %v", err)
+	}
+
+	if !authenticatorData.UserPresent || !authenticatorData.UserVerified {
+		t.Errorf("UserPresent = %v, UserVerified = %v, want both set", authenticatorData.UserPresent, authenticatorData.UserVerified)
+	}
+	if authenticatorData.SignCount != 7 {
+		t.Errorf("SignCount = %d, want 7", authenticatorData.SignCount)
+	}
+	if !bytes.Equal(authenticatorData.CredentialId, credentialId) {
+		t.Errorf("CredentialId = %q, want %q", authenticatorData.CredentialId, credentialId)
+	}
+
+	_, err = ParsePublicKey(authenticatorData.CredentialPublicKey)
+	if err != nil {
+		t.Errorf("ParsePublicKey() error = %v", err)
+	}
+}
+
+func TestParseAuthenticatorDataErrors(t *testing.T) {
+	withCredential := testAuthenticatorData(t, flagUserPresent|flagAttestedCredentialData, 0, []byte("credential-1"), nil)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "too short", data: testAuthenticatorData(t, flagUserPresent, 0, nil, nil)[:minAuthenticatorDataLength-1]},
+		{name: "trailing data", data: append(testAuthenticatorData(t, flagUserPresent, 0, nil, nil), 0x00)},
+		{name: "truncated attested credential data", data: withCredential[:minAuthenticatorDataLength+aaguidLength+1]},
+		{name: "truncated credential public key", data: withCredential[:len(withCredential)-1]},
+		{name: "credential id longer than data", data: withCredential[:minAuthenticatorDataLength+aaguidLength+2+4]},
+		{name: "credential id longer than allowed", data: func() []byte {
+			data := bytes.Clone(withCredential)
+			binary.BigEndian.PutUint16(data[minAuthenticatorDataLength+aaguidLength:], MaxCredentialIdLength+1)
+			return append(data, make([]byte, MaxCredentialIdLength)...)
+		}()},
+		{name: "missing extensions", data: testAuthenticatorData(t, flagUserPresent|flagExtensionData, 0, nil, nil)},
+		{name: "invalid extensions", data: testAuthenticatorData(t, flagUserPresent|flagExtensionData, 0, nil, []byte{0xa1})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseAuthenticatorData(tt.data)
+			if !errors.Is(err, ErrInvalidAuthenticatorData) {
+				t.Errorf("parseAuthenticatorData() error = %v, want %v", err, ErrInvalidAuthenticatorData)
+			}
+		})
+	}
+}
+
+func FuzzParseAuthenticatorData(f *testing.F) {
+	f.Add(testAuthenticatorData(f, flagUserPresent, 1, nil, nil))
+	f.Add(testAuthenticatorData(f, flagUserPresent|flagUserVerified|flagAttestedCredentialData, 0, []byte("credential-1"), nil))
+	f.Add(testAuthenticatorData(f, flagUserPresent|flagExtensionData, 2, nil, []byte{0xa1, 0x63, 'c', 'r', 'p', 0xf5}))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		authenticatorData, err := parseAuthenticatorData(data)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidAuthenticatorData) {
+				t.Fatalf("parseAuthenticatorData() error = %v, want %v", err, ErrInvalidAuthenticatorData)
+			}
+			return
+		}
+
+		if len(authenticatorData.RpIdHash) != rpIdHashLength {
+			t.Fatalf("RpIdHash length = %d, want %d", len(authenticatorData.RpIdHash), rpIdHashLength)
+		}
+
+		flags := data[rpIdHashLength]
+		if flags&flagAttestedCredentialData == 0 {
+			if authenticatorData.CredentialId != nil || authenticatorData.CredentialPublicKey != nil {
+				t.Fatalf("attested credential data is parsed without the AT flag")
+			}
+			return
+		}
+
+		if len(authenticatorData.Aaguid) != aaguidLength {
+			t.Fatalf("Aaguid length = %d, want %d", len(authenticatorData.Aaguid), aaguidLength)
+		}
+		if len(authenticatorData.CredentialId) > MaxCredentialIdLength {
+			t.Fatalf("CredentialId length = %d, want at most %d", len(authenticatorData.CredentialId), MaxCredentialIdLength)
+		}
+
+		_, rest, err := decodeCbor(authenticatorData.CredentialPublicKey)
+		if err != nil || len(rest) != 0 {
+			t.Fatalf("CredentialPublicKey %x is not a single cbor item", authenticatorData.CredentialPublicKey)
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// CBOR (RFC 8949) subset used by WebAuthn: unsigned and negative integers, byte and text strings,
+// arrays, maps, booleans and null. Indefinite lengths, tags and floats are rejected,
+// authenticators encode attestation objects and COSE keys with definite lengths only
+
+const (
+	cborMajorUnsigned = 0
+	cborMajorNegative = 1
+	cborMajorBytes    = 2
+	cborMajorText     = 3
+	cborMajorArray    = 4
+	cborMajorMap      = 5
+	cborMajorSimple   = 7
+)
+
+const (
+	cborFalse = 20
+	cborTrue  = 21
+	cborNull  = 22
+)
+
+const cborMaxDepth = 16
+
+var ErrInvalidCbor = errors.New("invalid cbor")
+
+// decodeCbor decodes the first CBOR item of data and returns the rest of data. Integers are decoded
+// to int64, byte strings to []byte, text strings to string, arrays to []interface{}
+// and maps to map[interface{}]interface{}
+func decodeCbor(data []byte) (interface{}, []byte, error) {
+	return decodeCborItem(data, 0)
+}
+
+func decodeCborItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, fmt.Errorf("%w: max depth exceeded", ErrInvalidCbor)
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCbor)
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+
+	if major == cborMajorSimple {
+		switch info {
+		case cborFalse:
+			return false, data[1:], nil
+		case cborTrue:
+			return true, data[1:], nil
+		case cborNull:
+			return nil, data[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("%w: unsupported simple value %d", ErrInvalidCbor, info)
+		}
+	}
+
+	argument, data, err := decodeCborArgument(info, data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborMajorUnsigned:
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrInvalidCbor)
+		}
+		return int64(argument), data, nil
+	case cborMajorNegative:
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", ErrInvalidCbor)
+		}
+		return -1 - int64(argument), data, nil
+	case cborMajorBytes, cborMajorText:
+		if argument > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCbor)
+		}
+		value := data[:argument]
+		if major == cborMajorText {
+			return string(value), data[argument:], nil
+		}
+		return append([]byte(nil), value...), data[argument:], nil
+	case cborMajorArray:
+		// every item takes at least one byte
+		if argument > uint64(len(data)) {
+			return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCbor)
+		}
+		items := make([]interface{}, argument)
+		for i := range items {
+			items[i], data, err = decodeCborItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return items, data, nil
+	case cborMajorMap:
+		if argument > uint64(len(data))/2 {
+			return nil, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCbor)
+		}
+		entries := make(map[interface{}]interface{}, argument)
+		for i := uint64(0); i < argument; i++ {
+			var key, value interface{}
+			key, data, err = decodeCborItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: map key must be an integer or a text", ErrInvalidCbor)
+			}
+			if _, ok := entries[key]; ok {
+				return nil, nil, fmt.Errorf("%w: duplicate map key %v", ErrInvalidCbor, key)
+			}
+			value, data, err = decodeCborItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			entries[key] = value
+		}
+		return entries, data, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported major type %d", ErrInvalidCbor, major)
+	}
+}
+
+func decodeCborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	case info > 27:
+		return 0, nil, fmt.Errorf("%w: indefinite length is not supported", ErrInvalidCbor)
+	default:
+		return 0, nil, fmt.Errorf("%w: unexpected end of data", ErrInvalidCbor)
+	}
+}
+
+// encodeCbor encodes values of the types produced by decodeCbor, int and map[string]interface{}
+// are accepted as well. Map keys are sorted in the CTAP2 canonical order
+func encodeCbor(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return []byte{cborMajorSimple<<5 | cborNull}, nil
+	case bool:
+		if v {
+			return []byte{cborMajorSimple<<5 | cborTrue}, nil
+		}
+		return []byte{cborMajorSimple<<5 | cborFalse}, nil
+	case int:
+		return encodeCborInt(int64(v)), nil
+	case int64:
+		return encodeCborInt(v), nil
+	case []byte:
+		return append(encodeCborHead(cborMajorBytes, uint64(len(v))), v...), nil
+	case string:
+		return append(encodeCborHead(cborMajorText, uint64(len(v))), v...), nil
+	case []interface{}:
+		encoded := encodeCborHead(cborMajorArray, uint64(len(v)))
+		for _, item := range v {
+			encodedItem, err := encodeCbor(item)
+			if err != nil {
+				return nil, err
+			}
+			encoded = append(encoded, encodedItem...)
+		}
+		return encoded, nil
+	case map[string]interface{}:
+		entries := make(map[interface{}]interface{}, len(v))
+		for key, item := range v {
+			entries[key] = item
+		}
+		return encodeCbor(entries)
+	case map[interface{}]interface{}:
+		return encodeCborMap(v)
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %T", ErrInvalidCbor, value)
+	}
+}
+
+func encodeCborMap(entries map[interface{}]interface{}) ([]byte, error) {
+	type encodedEntry struct {
+		key   []byte
+		value []byte
+	}
+
+	encodedEntries := make([]encodedEntry, 0, len(entries))
+	for key, value := range entries {
+		encodedKey, err := encodeCbor(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := encodeCbor(value)
+		if err != nil {
+			return nil, err
+		}
+		encodedEntries = append(encodedEntries, encodedEntry{key: encodedKey, value: encodedValue})
+	}
+
+	// shorter keys first, keys of the same length in the bytewise lexical order
+	sort.Slice(encodedEntries, func(i, j int) bool {
+		a, b := encodedEntries[i].key, encodedEntries[j].key
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return string(a) < string(b)
+	})
+
+	encoded := encodeCborHead(cborMajorMap, uint64(len(entries)))
+	for _, entry := range encodedEntries {
+		encoded = append(encoded, entry.key...)
+		encoded = append(encoded, entry.value...)
+	}
+	return encoded, nil
+}
+
+func encodeCborInt(value int64) []byte {
+	if value < 0 {
+		return encodeCborHead(cborMajorNegative, uint64(-1-value))
+	}
+	return encodeCborHead(cborMajorUnsigned, uint64(value))
+}
+
+func encodeCborHead(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument <= math.MaxUint8:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+	case argument <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, argument)
+	}
+}
@@ -0,0 +1,146 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func mustDecodeHex(t testing.TB, value string) []byte {
+	t.Helper()
+
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatalf("DecodeString(%q) error = %v", value, err)
+	}
+	return decoded
+}
+
+func TestDecodeCbor(t *testing.T) {
+	// encoded values are taken from RFC 8949 Appendix A
+	tests := []struct {
+		name    string
+		encoded string
+		want    interface{}
+	}{
+		{name: "zero", encoded: "00", want: int64(0)},
+		{name: "small unsigned", encoded: "17", want: int64(23)},
+		{name: "one byte unsigned", encoded: "1818", want: int64(24)},
+		{name: "two bytes unsigned", encoded: "1903e8", want: int64(1000)},
+		{name: "eight bytes unsigned", encoded: "1b000000e8d4a51000", want: int64(1000000000000)},
+		{name: "negative", encoded: "20", want: int64(-1)},
+		{name: "two bytes negative", encoded: "3903e7", want: int64(-1000)},
+		{name: "empty bytes", encoded: "40", want: []byte(nil)},
+		{name: "bytes", encoded: "4401020304", want: []byte{1, 2, 3, 4}},
+		{name: "text", encoded: "6449455446", want: "IETF"},
+		{name: "false", encoded: "f4", want: false},
+		{name: "true", encoded: "f5", want: true},
+		{name: "null", encoded: "f6", want: nil},
+		{name: "array", encoded: "8301820203820405", want: []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{name: "map", encoded: "a201020304", want: map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{name: "map with text keys", encoded: "a26161016162820203", want: map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCbor(mustDecodeHex(t, tt.encoded))
+			if err != nil {
+				t.Fatalf("decodeCbor() error = %v", err)
+			}
+			if len(rest) != 0 {
+				t.Errorf("decodeCbor() rest = %x, want empty", rest)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCbor() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCborErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "empty", encoded: ""},
+		{name: "truncated argument", encoded: "19e8"},
+		{name: "truncated bytes", encoded: "440102"},
+		{name: "truncated array", encoded: "830102"},
+		{name: "truncated map", encoded: "a20102"},
+		{name: "huge bytes length", encoded: "5bffffffffffffffff00"},
+		{name: "huge array length", encoded: "9affffffff00"},
+		{name: "huge map length", encoded: "baffffffff0000"},
+		{name: "unsigned overflow", encoded: "1bffffffffffffffff"},
+		{name: "negative overflow", encoded: "3bffffffffffffffff"},
+		{name: "indefinite length", encoded: "5f42010243030405ff"},
+		{name: "tag", encoded: "c074323031332d30332d32315432303a30343a30305a"},
+		{name: "float", encoded: "f93c00"},
+		{name: "undefined", encoded: "f7"},
+		{name: "bytes map key", encoded: "a1410102"},
+		{name: "duplicate map key", encoded: "a201020103"},
+		{name: "too deep", encoded: "818181818181818181818181818181818101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeCbor(mustDecodeHex(t, tt.encoded))
+			if !errors.Is(err, ErrInvalidCbor) {
+				t.Errorf("decodeCbor() error = %v, want %v", err, ErrInvalidCbor)
+			}
+		})
+	}
+}
+
+func FuzzDecodeCbor(f *testing.F) {
+	for _, seed := range []string{
+		"00", "3903e7", "4401020304", "6449455446", "f5", "8301820203820405", "a26161016162820203",
+		// EC2 P-256 COSE_Key
+		"a5010203262001215820" + "0102030405060708091011121314151617181920212223242526272829303132" +
+			"225820" + "0102030405060708091011121314151617181920212223242526272829303132",
+		// {"fmt": "none", "attStmt": {}, "authData": h'00'}
+		"a363666d74646e6f6e656761747453746d74a068617574684461746141" + "00",
+	} {
+		f.Add(mustDecodeHex(f, seed))
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		decoded, rest, err := decodeCbor(data)
+		if err != nil {
+			if !errors.Is(err, ErrInvalidCbor) {
+				t.Fatalf("decodeCbor() error = %v, want %v", err, ErrInvalidCbor)
+			}
+			return
+		}
+		if len(rest) > len(data)-1 {
+			t.Fatalf("decodeCbor() consumed nothing of %x", data)
+		}
+
+		// decoded items are encoded canonically, so the encoding is decoded to the same item
+		encoded, err := encodeCbor(decoded)
+		if err != nil {
+			t.Fatalf("encodeCbor(%#v) error = %v", decoded, err)
+		}
+
+		redecoded, redecodedRest, err := decodeCbor(encoded)
+		if err != nil {
+			t.Fatalf("decodeCbor(%x) error = %v", encoded, err)
+		}
+		if len(redecodedRest) != 0 {
+			t.Fatalf("decodeCbor(%x) rest = %x, want empty", encoded, redecodedRest)
+		}
+		if !reflect.DeepEqual(decoded, redecoded) {
+			t.Fatalf("decodeCbor(encodeCbor(%#v)) = %#v", decoded, redecoded)
+		}
+
+		// canonical encoding is stable
+		reencoded, err := encodeCbor(redecoded)
+		if err != nil {
+			t.Fatalf("encodeCbor(%#v) error = %v", redecoded, err)
+		}
+		if !bytes.Equal(encoded, reencoded) {
+			t.Fatalf("encodeCbor() = %x, want %x", reencoded, encoded)
+		}
+	})
+}
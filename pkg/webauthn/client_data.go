@@ -0,0 +1,85 @@
+package webauthn
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// client data types
+const (
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+var ErrInvalidClientData = errors.New("invalid client data")
+
+// Bytes is encoded as unpadded base64url in JSON as the WebAuthn JSON serialization requires
+type Bytes []byte
+
+func (b Bytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (b *Bytes) UnmarshalJSON(data []byte) error {
+	var value string
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return err
+	}
+
+	*b = decoded
+	return nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// checkClientData parses clientDataJSON and checks its type, challenge and origin
+func checkClientData(clientDataJson []byte, expectedType string, challenge []byte, origins []string) error {
+	var data clientData
+	err := json.Unmarshal(clientDataJson, &data)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidClientData, err)
+	}
+
+	if data.Type != expectedType {
+		return fmt.Errorf("%w: type must be %q", ErrInvalidClientData, expectedType)
+	}
+
+	actualChallenge, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(data.Challenge, "="))
+	if err != nil || subtle.ConstantTimeCompare(actualChallenge, challenge) != 1 {
+		return fmt.Errorf("%w: challenge does not match", ErrInvalidClientData)
+	}
+
+	if !containsString(origins, data.Origin) {
+		return fmt.Errorf("%w: origin %q is not allowed", ErrInvalidClientData, data.Origin)
+	}
+
+	if data.CrossOrigin {
+		return fmt.Errorf("%w: cross-origin requests are not allowed", ErrInvalidClientData)
+	}
+
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithms (RFC 9053) supported for credentials
+const (
+	AlgorithmES256 = -7
+	AlgorithmEdDSA = -8
+	AlgorithmRS256 = -257
+)
+
+// SupportedAlgorithms are offered to authenticators in the order of preference
+var SupportedAlgorithms = []int64{AlgorithmES256, AlgorithmEdDSA, AlgorithmRS256}
+
+// COSE key parameters
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyRsaN      = -1
+	coseKeyRsaE      = -2
+)
+
+const (
+	coseKeyTypeOkp = 1
+	coseKeyTypeEc2 = 2
+	coseKeyTypeRsa = 3
+)
+
+const (
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+const minRsaKeyBits = 2048
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported credential algorithm")
+	ErrInvalidPublicKey     = errors.New("invalid credential public key")
+	ErrInvalidSignature     = errors.New("invalid signature")
+)
+
+// PublicKey is a credential public key decoded from COSE_Key
+type PublicKey struct {
+	Algorithm int64
+	Key       crypto.PublicKey
+}
+
+// ParsePublicKey decodes a CBOR encoded COSE_Key
+func ParsePublicKey(coseKey []byte) (*PublicKey, error) {
+	decoded, rest, err := decodeCbor(coseKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPublicKey, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", ErrInvalidPublicKey)
+	}
+
+	return parseCoseKey(decoded)
+}
+
+func parseCoseKey(decoded interface{}) (*PublicKey, error) {
+	params, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: must be a map", ErrInvalidPublicKey)
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	algorithm, ok := params[int64(coseKeyAlgorithm)].(int64)
+	if !ok {
+		return nil, fmt.Errorf("%w: algorithm is missing", ErrInvalidPublicKey)
+	}
+
+	switch algorithm {
+	case AlgorithmES256:
+		curve, _ := params[int64(coseKeyCurve)].(int64)
+		x, _ := params[int64(coseKeyX)].([]byte)
+		y, _ := params[int64(coseKeyY)].([]byte)
+		if keyType != coseKeyTypeEc2 || curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: ES256 key must be a P-256 EC2 key", ErrInvalidPublicKey)
+		}
+
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("%w: point is not on the curve", ErrInvalidPublicKey)
+		}
+		return &PublicKey{Algorithm: algorithm, Key: key}, nil
+	case AlgorithmEdDSA:
+		curve, _ := params[int64(coseKeyCurve)].(int64)
+		x, _ := params[int64(coseKeyX)].([]byte)
+		if keyType != coseKeyTypeOkp || curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: EdDSA key must be an Ed25519 OKP key", ErrInvalidPublicKey)
+		}
+		return &PublicKey{Algorithm: algorithm, Key: ed25519.PublicKey(x)}, nil
+	case AlgorithmRS256:
+		n, _ := params[int64(coseKeyRsaN)].([]byte)
+		e, _ := params[int64(coseKeyRsaE)].([]byte)
+		if keyType != coseKeyTypeRsa || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: RS256 key must be an RSA key", ErrInvalidPublicKey)
+		}
+
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < minRsaKeyBits || key.E < 3 {
+			return nil, fmt.Errorf("%w: RSA key is too weak", ErrInvalidPublicKey)
+		}
+		return &PublicKey{Algorithm: algorithm, Key: key}, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// Verify checks the signature of the data, ES256 signatures are ASN.1 DER encoded as WebAuthn requires
+func (k *PublicKey) Verify(data []byte, signature []byte) error {
+	return verifySignature(k.Algorithm, k.Key, data, signature)
+}
+
+func verifySignature(algorithm int64, key crypto.PublicKey, data []byte, signature []byte) error {
+	switch algorithm {
+	case AlgorithmES256:
+		ecdsaKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: ES256 requires an ECDSA key", ErrInvalidPublicKey)
+		}
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(ecdsaKey, digest[:], signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgorithmEdDSA:
+		ed25519Key, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: EdDSA requires an Ed25519 key", ErrInvalidPublicKey)
+		}
+		if !ed25519.Verify(ed25519Key, data, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgorithmRS256:
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%w: RS256 requires an RSA key", ErrInvalidPublicKey)
+		}
+		digest := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
@@ -0,0 +1,307 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChallengeLength is a length of random challenges in bytes
+const ChallengeLength = 32
+
+const defaultTimeout = 5 * time.Minute
+
+var (
+	ErrInvalidConfig         = errors.New("invalid webauthn config")
+	ErrInvalidResponse       = errors.New("invalid credential response")
+	ErrRpIdHashMismatch      = errors.New("rp id hash does not match")
+	ErrUserNotPresent        = errors.New("user presence is required")
+	ErrUserNotVerified       = errors.New("user verification is required")
+	ErrSignCountNotIncreased = errors.New("sign count did not increase, the authenticator may be cloned")
+	ErrCredentialIdMismatch  = errors.New("credential id does not match")
+	ErrMissingCredentialData = errors.New("attested credential data is missing")
+)
+
+// Config of a relying party. Origins are the allowed origins of client data,
+// e.g. 'https://example.com', RpId must be their registrable domain suffix
+type Config struct {
+	RpId             string        `yaml:"rp-id"`
+	RpName           string        `yaml:"rp-name"`
+	Origins          []string      `yaml:"origins"`
+	Timeout          time.Duration `yaml:"timeout"`
+	UserVerification string        `yaml:"user-verification"`
+}
+
+// Credential is a verified registered credential to be stored by the relying party
+type Credential struct {
+	Id                []byte
+	PublicKey         []byte
+	Algorithm         int64
+	SignCount         uint32
+	Aaguid            []byte
+	Transports        []string
+	AttestationFormat string
+	UserVerified      bool
+}
+
+// AssertionResult is a verified assertion, SignCount must be stored for the credential
+type AssertionResult struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+// RelyingParty creates options for the client and verifies credential responses, the verification
+// does not need any network access
+type RelyingParty struct {
+	id               string
+	name             string
+	idHash           []byte
+	origins          []string
+	timeout          time.Duration
+	userVerification string
+}
+
+func NewRelyingParty(config *Config) (*RelyingParty, error) {
+	if config.RpId == "" {
+		return nil, fmt.Errorf("%w: rp id is required", ErrInvalidConfig)
+	}
+	if len(config.Origins) == 0 {
+		return nil, fmt.Errorf("%w: at least 1 origin is required", ErrInvalidConfig)
+	}
+
+	userVerification := config.UserVerification
+	switch userVerification {
+	case "":
+		userVerification = UserVerificationPreferred
+	case UserVerificationRequired, UserVerificationPreferred, UserVerificationDiscouraged:
+	default:
+		return nil, fmt.Errorf("%w: unknown user verification %q", ErrInvalidConfig, userVerification)
+	}
+
+	name := config.RpName
+	if name == "" {
+		name = config.RpId
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	idHash := sha256.Sum256([]byte(config.RpId))
+
+	return &RelyingParty{
+		id:               config.RpId,
+		name:             name,
+		idHash:           idHash[:],
+		origins:          config.Origins,
+		timeout:          timeout,
+		userVerification: userVerification,
+	}, nil
+}
+
+// Timeout is how long the client is given to complete a ceremony, challenges should not outlive it
+func (rp *RelyingParty) Timeout() time.Duration {
+	return rp.timeout
+}
+
+// NewChallenge generates a random challenge of ChallengeLength bytes
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeLength)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// CreationOptions asks for a discoverable credential without attestation
+func (rp *RelyingParty) CreationOptions(challenge []byte, user UserEntity, excludeCredentials []CredentialDescriptor) *CreationOptions {
+	credentialParameters := make([]CredentialParameters, len(SupportedAlgorithms))
+	for i, algorithm := range SupportedAlgorithms {
+		credentialParameters[i] = CredentialParameters{Type: CredentialType, Algorithm: algorithm}
+	}
+
+	if excludeCredentials == nil {
+		excludeCredentials = []CredentialDescriptor{}
+	}
+
+	return &CreationOptions{
+		Rp:                 RpEntity{Id: rp.id, Name: rp.name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   credentialParameters,
+		Timeout:            rp.timeout.Milliseconds(),
+		ExcludeCredentials: excludeCredentials,
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: rp.userVerification,
+		},
+		Attestation: AttestationFormatNone,
+	}
+}
+
+// RequestOptions with empty allowCredentials lets the user pick any discoverable credential of the relying party
+func (rp *RelyingParty) RequestOptions(challenge []byte, allowCredentials []CredentialDescriptor) *RequestOptions {
+	if allowCredentials == nil {
+		allowCredentials = []CredentialDescriptor{}
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		Timeout:          rp.timeout.Milliseconds(),
+		RpId:             rp.id,
+		AllowCredentials: allowCredentials,
+		UserVerification: rp.userVerification,
+	}
+}
+
+// VerifyRegistration verifies the JSON serialized registration response to the challenge
+func (rp *RelyingParty) VerifyRegistration(challenge []byte, registrationJson []byte) (*Credential, error) {
+	var registration RegistrationResponse
+	err := json.Unmarshal(registrationJson, &registration)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	err = checkCredentialIds(registration.Type, registration.Id, registration.RawId)
+	if err != nil {
+		return nil, err
+	}
+
+	response := registration.Response
+	err = checkClientData(response.ClientDataJson, clientDataTypeCreate, challenge, rp.origins)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := parseAttestationObject(response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorData, err := parseAuthenticatorData(object.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rp.checkAuthenticatorData(authenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authenticatorData.CredentialId == nil {
+		return nil, ErrMissingCredentialData
+	}
+	if !bytes.Equal(authenticatorData.CredentialId, registration.RawId) {
+		return nil, ErrCredentialIdMismatch
+	}
+
+	publicKey, err := ParsePublicKey(authenticatorData.CredentialPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(response.ClientDataJson)
+	err = verifyAttestation(object, authenticatorData, publicKey, clientDataHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		Id:                authenticatorData.CredentialId,
+		PublicKey:         authenticatorData.CredentialPublicKey,
+		Algorithm:         publicKey.Algorithm,
+		SignCount:         authenticatorData.SignCount,
+		Aaguid:            authenticatorData.Aaguid,
+		Transports:        response.Transports,
+		AttestationFormat: object.Format,
+		UserVerified:      authenticatorData.UserVerified,
+	}, nil
+}
+
+// ParseAssertion parses the JSON serialized assertion response, so the credential can be found by its RawId
+func ParseAssertion(assertionJson []byte) (*AssertionResponse, error) {
+	var assertion AssertionResponse
+	err := json.Unmarshal(assertionJson, &assertion)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+
+	err = checkCredentialIds(assertion.Type, assertion.Id, assertion.RawId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &assertion, nil
+}
+
+// VerifyAssertion verifies the assertion to the challenge with the stored COSE public key of the credential.
+// A sign count which did not increase is rejected unless both the stored and the new one are 0
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, assertion *AssertionResponse, publicKey []byte, storedSignCount uint32) (*AssertionResult, error) {
+	response := assertion.Response
+	err := checkClientData(response.ClientDataJson, clientDataTypeGet, challenge, rp.origins)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorData, err := parseAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rp.checkAuthenticatorData(authenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(response.ClientDataJson)
+	signedData := append(append([]byte(nil), response.AuthenticatorData...), clientDataHash[:]...)
+	err = key.Verify(signedData, response.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	signCount := authenticatorData.SignCount
+	if (signCount != 0 || storedSignCount != 0) && signCount <= storedSignCount {
+		return nil, ErrSignCountNotIncreased
+	}
+
+	return &AssertionResult{SignCount: signCount, UserVerified: authenticatorData.UserVerified}, nil
+}
+
+func (rp *RelyingParty) checkAuthenticatorData(authenticatorData *AuthenticatorData) error {
+	if !bytes.Equal(authenticatorData.RpIdHash, rp.idHash) {
+		return ErrRpIdHashMismatch
+	}
+	if !authenticatorData.UserPresent {
+		return ErrUserNotPresent
+	}
+	if rp.userVerification == UserVerificationRequired && !authenticatorData.UserVerified {
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+func checkCredentialIds(credentialType string, id string, rawId []byte) error {
+	if credentialType != CredentialType {
+		return fmt.Errorf("%w: type must be %q", ErrInvalidResponse, CredentialType)
+	}
+	if len(rawId) == 0 || len(rawId) > MaxCredentialIdLength {
+		return fmt.Errorf("%w: invalid raw id", ErrInvalidResponse)
+	}
+	if id != base64.RawURLEncoding.EncodeToString(rawId) {
+		return ErrCredentialIdMismatch
+	}
+	return nil
+}
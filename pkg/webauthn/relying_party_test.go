@@ -0,0 +1,437 @@
+package webauthn_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/webauthn"
+	"github.com/vaberof/auth-grpc/pkg/webauthn/webauthntest"
+	"testing"
+)
+
+const (
+	testRpId   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+func newRelyingParty(t *testing.T, userVerification string) *webauthn.RelyingParty {
+	t.Helper()
+
+	rp, err := webauthn.NewRelyingParty(&webauthn.Config{
+		RpId:             testRpId,
+		Origins:          []string{testOrigin},
+		UserVerification: userVerification,
+	})
+	if err != nil {
+		t.Fatalf("NewRelyingParty() error = %v", err)
+	}
+	return rp
+}
+
+func newChallenge(t *testing.T) []byte {
+	t.Helper()
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge() error = %v", err)
+	}
+	return challenge
+}
+
+func mustMarshal(t *testing.T, value interface{}) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	return data
+}
+
+// registrationResponse answers creation options of the relying party, modifyOptions may change them before
+func registrationResponse(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator, challenge []byte, modifyOptions func(*webauthn.CreationOptions)) []byte {
+	t.Helper()
+
+	options := rp.CreationOptions(challenge, webauthn.UserEntity{Id: []byte("user-1"), Name: "alice", DisplayName: "Alice"}, nil)
+	if modifyOptions != nil {
+		modifyOptions(options)
+	}
+
+	registrationJson, err := authenticator.Register(mustMarshal(t, options))
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	return registrationJson
+}
+
+func register(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator) *webauthn.Credential {
+	t.Helper()
+
+	challenge := newChallenge(t)
+	credential, err := rp.VerifyRegistration(challenge, registrationResponse(t, rp, authenticator, challenge, nil))
+	if err != nil {
+		t.Fatalf("VerifyRegistration() error = %v", err)
+	}
+	return credential
+}
+
+func assertionResponse(t *testing.T, rp *webauthn.RelyingParty, authenticator *webauthntest.Authenticator, challenge []byte) *webauthn.AssertionResponse {
+	t.Helper()
+
+	assertionJson, err := authenticator.Login(mustMarshal(t, rp.RequestOptions(challenge, nil)))
+	if err != nil {
+		t.Fatalf("Login() error = %v", err)
+	}
+
+	assertion, err := webauthn.ParseAssertion(assertionJson)
+	if err != nil {
+		t.Fatalf("ParseAssertion() error = %v", err)
+	}
+	return assertion
+}
+
+// modifyRegistration changes the JSON serialized registration response
+func modifyRegistration(t *testing.T, registrationJson []byte, modify func(*webauthn.RegistrationResponse)) []byte {
+	t.Helper()
+
+	var registration webauthn.RegistrationResponse
+	err := json.Unmarshal(registrationJson, &registration)
+	if err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	modify(&registration)
+
+	return mustMarshal(t, registration)
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	tests := []struct {
+		name              string
+		algorithm         int64
+		attestationFormat string
+		selfAttestation   bool
+	}{
+		{name: "none ES256", algorithm: webauthn.AlgorithmES256, attestationFormat: webauthn.AttestationFormatNone},
+		{name: "none EdDSA", algorithm: webauthn.AlgorithmEdDSA, attestationFormat: webauthn.AttestationFormatNone},
+		{name: "none RS256", algorithm: webauthn.AlgorithmRS256, attestationFormat: webauthn.AttestationFormatNone},
+		{name: "packed ES256", algorithm: webauthn.AlgorithmES256, attestationFormat: webauthn.AttestationFormatPacked},
+		{name: "packed EdDSA", algorithm: webauthn.AlgorithmEdDSA, attestationFormat: webauthn.AttestationFormatPacked},
+		{name: "packed RS256", algorithm: webauthn.AlgorithmRS256, attestationFormat: webauthn.AttestationFormatPacked},
+		{name: "packed self ES256", algorithm: webauthn.AlgorithmES256, attestationFormat: webauthn.AttestationFormatPacked, selfAttestation: true},
+		{name: "packed self EdDSA", algorithm: webauthn.AlgorithmEdDSA, attestationFormat: webauthn.AttestationFormatPacked, selfAttestation: true},
+		{name: "packed self RS256", algorithm: webauthn.AlgorithmRS256, attestationFormat: webauthn.AttestationFormatPacked, selfAttestation: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty(t, webauthn.UserVerificationRequired)
+
+			authenticator := webauthntest.NewAuthenticator(testRpId, testOrigin)
+			authenticator.Algorithm = tt.algorithm
+			authenticator.AttestationFormat = tt.attestationFormat
+			authenticator.SelfAttestation = tt.selfAttestation
+			authenticator.Aaguid = []byte("0123456789abcdef")
+
+			credential := register(t, rp, authenticator)
+
+			if credential.Algorithm != tt.algorithm {
+				t.Errorf("Algorithm = %d, want %d", credential.Algorithm, tt.algorithm)
+			}
+			if credential.AttestationFormat != tt.attestationFormat {
+				t.Errorf("AttestationFormat = %q, want %q", credential.AttestationFormat, tt.attestationFormat)
+			}
+			if string(credential.Aaguid) != string(authenticator.Aaguid) {
+				t.Errorf("Aaguid = %x, want %x", credential.Aaguid, authenticator.Aaguid)
+			}
+			if !credential.UserVerified {
+				t.Errorf("UserVerified = false, want true")
+			}
+
+			signCount := credential.SignCount
+			for i := 0; i < 2; i++ {
+				challenge := newChallenge(t)
+				assertion := assertionResponse(t, rp, authenticator, challenge)
+
+				if string(assertion.RawId) != string(credential.Id) {
+					t.Fatalf("RawId = %x, want %x", assertion.RawId, credential.Id)
+				}
+
+				result, err := rp.VerifyAssertion(challenge, assertion, credential.PublicKey, signCount)
+				if err != nil {
+					t.Fatalf("VerifyAssertion() error = %v", err)
+				}
+				if result.SignCount != signCount+1 {
+					t.Errorf("SignCount = %d, want %d", result.SignCount, signCount+1)
+				}
+				signCount = result.SignCount
+			}
+		})
+	}
+}
+
+func TestVerifyRegistrationErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		userVerification string
+		// modifyAuthenticator changes the authenticator before the registration
+		modifyAuthenticator func(*webauthntest.Authenticator)
+		modifyOptions       func(*webauthn.CreationOptions)
+		modifyRegistration  func(*webauthn.RegistrationResponse)
+		wrongChallenge      bool
+		wantErr             error
+	}{
+		{
+			name: "wrong origin",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.Origin = "https://evil.example"
+			},
+			wantErr: webauthn.ErrInvalidClientData,
+		},
+		{
+			name:           "wrong challenge",
+			wrongChallenge: true,
+			wantErr:        webauthn.ErrInvalidClientData,
+		},
+		{
+			name: "wrong rp id hash",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.RpId = "evil.example"
+			},
+			modifyOptions: func(options *webauthn.CreationOptions) {
+				options.Rp.Id = "evil.example"
+			},
+			wantErr: webauthn.ErrRpIdHashMismatch,
+		},
+		{
+			name: "user not present",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.UserPresent = false
+			},
+			wantErr: webauthn.ErrUserNotPresent,
+		},
+		{
+			name:             "user not verified",
+			userVerification: webauthn.UserVerificationRequired,
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.UserVerified = false
+			},
+			wantErr: webauthn.ErrUserNotVerified,
+		},
+		{
+			name: "truncated attestation object",
+			modifyRegistration: func(r *webauthn.RegistrationResponse) {
+				r.Response.AttestationObject = r.Response.AttestationObject[:len(r.Response.AttestationObject)-1]
+			},
+			wantErr: webauthn.ErrInvalidAttestation,
+		},
+		{
+			name: "oversized attestation object",
+			modifyRegistration: func(r *webauthn.RegistrationResponse) {
+				r.Response.AttestationObject = append(r.Response.AttestationObject, 0x00)
+			},
+			wantErr: webauthn.ErrInvalidAttestation,
+		},
+		{
+			name: "attestation object with huge length",
+			modifyRegistration: func(r *webauthn.RegistrationResponse) {
+				// map of 2^32-1 entries
+				r.Response.AttestationObject = []byte{0xba, 0xff, 0xff, 0xff, 0xff, 0x00}
+			},
+			wantErr: webauthn.ErrInvalidAttestation,
+		},
+		{
+			name: "packed attestation of other client data",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.AttestationFormat = webauthn.AttestationFormatPacked
+			},
+			modifyRegistration: func(r *webauthn.RegistrationResponse) {
+				r.Response.ClientDataJson = append(r.Response.ClientDataJson, ' ')
+			},
+			wantErr: webauthn.ErrInvalidAttestation,
+		},
+		{
+			name: "self attestation of other client data",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.AttestationFormat = webauthn.AttestationFormatPacked
+				a.SelfAttestation = true
+			},
+			modifyRegistration: func(r *webauthn.RegistrationResponse) {
+				r.Response.ClientDataJson = append(r.Response.ClientDataJson, ' ')
+			},
+			wantErr: webauthn.ErrInvalidAttestation,
+		},
+		{
+			name: "credential id mismatch",
+			modifyRegistration: func(r *webauthn.RegistrationResponse) {
+				r.RawId = append(r.RawId[:len(r.RawId):len(r.RawId)], 0x00)
+			},
+			wantErr: webauthn.ErrCredentialIdMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty(t, tt.userVerification)
+
+			authenticator := webauthntest.NewAuthenticator(testRpId, testOrigin)
+			if tt.modifyAuthenticator != nil {
+				tt.modifyAuthenticator(authenticator)
+			}
+
+			challenge := newChallenge(t)
+			registrationJson := registrationResponse(t, rp, authenticator, challenge, tt.modifyOptions)
+			if tt.modifyRegistration != nil {
+				registrationJson = modifyRegistration(t, registrationJson, tt.modifyRegistration)
+			}
+			if tt.wrongChallenge {
+				challenge = newChallenge(t)
+			}
+
+			_, err := rp.VerifyRegistration(challenge, registrationJson)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyRegistration() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionErrors(t *testing.T) {
+	tests := []struct {
+		name             string
+		userVerification string
+		// modifyAuthenticator changes the authenticator after the registration
+		modifyAuthenticator func(*webauthntest.Authenticator)
+		modifyAssertion     func(*webauthn.AssertionResponse)
+		wrongChallenge      bool
+		otherCredential     bool
+		storedSignCount     uint32
+		wantErr             error
+	}{
+		{
+			name: "wrong origin",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.Origin = "https://evil.example"
+			},
+			wantErr: webauthn.ErrInvalidClientData,
+		},
+		{
+			name:           "wrong challenge",
+			wrongChallenge: true,
+			wantErr:        webauthn.ErrInvalidClientData,
+		},
+		{
+			name: "wrong rp id hash",
+			modifyAssertion: func(a *webauthn.AssertionResponse) {
+				a.Response.AuthenticatorData[0] ^= 0xff
+			},
+			wantErr: webauthn.ErrRpIdHashMismatch,
+		},
+		{
+			name: "user not present",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.UserPresent = false
+			},
+			wantErr: webauthn.ErrUserNotPresent,
+		},
+		{
+			name:             "user not verified",
+			userVerification: webauthn.UserVerificationRequired,
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.UserVerified = false
+			},
+			wantErr: webauthn.ErrUserNotVerified,
+		},
+		{
+			name:            "sign count regression",
+			storedSignCount: 10,
+			wantErr:         webauthn.ErrSignCountNotIncreased,
+		},
+		{
+			name:            "same sign count",
+			storedSignCount: 1,
+			wantErr:         webauthn.ErrSignCountNotIncreased,
+		},
+		{
+			name: "zero sign count after a non-zero one",
+			modifyAuthenticator: func(a *webauthntest.Authenticator) {
+				a.SignCountStep = 0
+			},
+			storedSignCount: 1,
+			wantErr:         webauthn.ErrSignCountNotIncreased,
+		},
+		{
+			name: "modified authenticator data",
+			modifyAssertion: func(a *webauthn.AssertionResponse) {
+				a.Response.AuthenticatorData[len(a.Response.AuthenticatorData)-1] ^= 0x01
+			},
+			wantErr: webauthn.ErrInvalidSignature,
+		},
+		{
+			name:            "signature of another credential",
+			otherCredential: true,
+			wantErr:         webauthn.ErrInvalidSignature,
+		},
+		{
+			name: "truncated authenticator data",
+			modifyAssertion: func(a *webauthn.AssertionResponse) {
+				a.Response.AuthenticatorData = a.Response.AuthenticatorData[:len(a.Response.AuthenticatorData)-1]
+			},
+			wantErr: webauthn.ErrInvalidAuthenticatorData,
+		},
+		{
+			name: "oversized authenticator data",
+			modifyAssertion: func(a *webauthn.AssertionResponse) {
+				a.Response.AuthenticatorData = append(a.Response.AuthenticatorData, 0x00)
+			},
+			wantErr: webauthn.ErrInvalidAuthenticatorData,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := newRelyingParty(t, tt.userVerification)
+
+			authenticator := webauthntest.NewAuthenticator(testRpId, testOrigin)
+			credential := register(t, rp, authenticator)
+
+			if tt.otherCredential {
+				credential = register(t, rp, webauthntest.NewAuthenticator(testRpId, testOrigin))
+			}
+			if tt.modifyAuthenticator != nil {
+				tt.modifyAuthenticator(authenticator)
+			}
+
+			challenge := newChallenge(t)
+			assertion := assertionResponse(t, rp, authenticator, challenge)
+			if tt.modifyAssertion != nil {
+				tt.modifyAssertion(assertion)
+			}
+			if tt.wrongChallenge {
+				challenge = newChallenge(t)
+			}
+
+			_, err := rp.VerifyAssertion(challenge, assertion, credential.PublicKey, tt.storedSignCount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyAssertion() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertionZeroSignCount(t *testing.T) {
+	rp := newRelyingParty(t, "")
+
+	authenticator := webauthntest.NewAuthenticator(testRpId, testOrigin)
+	authenticator.SignCountStep = 0
+	credential := register(t, rp, authenticator)
+
+	// authenticators without a counter always send 0
+	challenge := newChallenge(t)
+	result, err := rp.VerifyAssertion(challenge, assertionResponse(t, rp, authenticator, challenge), credential.PublicKey, 0)
+	if err != nil {
+		t.Fatalf("VerifyAssertion() error = %v", err)
+	}
+	if result.SignCount != 0 {
+		t.Errorf("SignCount = %d, want 0", result.SignCount)
+	}
+}
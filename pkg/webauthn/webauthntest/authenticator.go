@@ -0,0 +1,435 @@
+// Package webauthntest provides a software authenticator producing WebAuthn registration and
+// assertion responses, it is meant for tests and local development only
+package webauthntest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vaberof/auth-grpc/pkg/webauthn"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const credentialIdLength = 32
+
+const rsaKeyBits = 2048
+
+// authenticator data flags
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+// aaguidExtensionOid is id-fido-gen-ce-aaguid extension of attestation certificates
+var aaguidExtensionOid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+var (
+	ErrRpIdMismatch         = errors.New("rp id does not match the authenticator")
+	ErrUnsupportedAlgorithm = errors.New("algorithm of the authenticator is not offered by the relying party")
+	ErrNoCredentials        = errors.New("no credentials for the relying party")
+)
+
+type credential struct {
+	id         []byte
+	algorithm  int64
+	privateKey crypto.Signer
+	userHandle []byte
+	signCount  uint32
+}
+
+// Authenticator creates discoverable credentials of Algorithm with AttestationFormat attestation
+type Authenticator struct {
+	RpId   string
+	Origin string
+	Aaguid []byte
+	// Algorithm of created credentials, one of webauthn.SupportedAlgorithms
+	Algorithm int64
+	// AttestationFormat is webauthn.AttestationFormatNone or webauthn.AttestationFormatPacked. Packed
+	// attestations are signed by a generated attestation certificate, or by the credential key if SelfAttestation is set
+	AttestationFormat string
+	SelfAttestation   bool
+	// UserPresent and UserVerified set the UP and UV flags of responses
+	UserPresent  bool
+	UserVerified bool
+	// SignCountStep is added to the sign count of a credential on every assertion, 0 keeps it 0
+	SignCountStep uint32
+
+	mu                     sync.Mutex
+	credentials            []*credential
+	attestationKey         *ecdsa.PrivateKey
+	attestationCertificate []byte
+}
+
+func NewAuthenticator(rpId string, origin string) *Authenticator {
+	return &Authenticator{
+		RpId:              rpId,
+		Origin:            origin,
+		Aaguid:            make([]byte, 16),
+		Algorithm:         webauthn.AlgorithmES256,
+		AttestationFormat: webauthn.AttestationFormatNone,
+		UserPresent:       true,
+		UserVerified:      true,
+		SignCountStep:     1,
+	}
+}
+
+// Register answers the JSON serialized creation options with a JSON serialized registration response
+func (a *Authenticator) Register(optionsJson []byte) ([]byte, error) {
+	var options webauthn.CreationOptions
+	err := json.Unmarshal(optionsJson, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Rp.Id != a.RpId {
+		return nil, ErrRpIdMismatch
+	}
+
+	if !offersAlgorithm(options.PubKeyCredParams, a.Algorithm) {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	privateKey, err := generateKey(a.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, credentialIdLength)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &credential{id: id, algorithm: a.Algorithm, privateKey: privateKey, userHandle: options.User.Id}
+
+	coseKey, err := encodeCoseKey(a.Algorithm, privateKey.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorData := a.authenticatorData(flagAttestedCredentialData, 0)
+	authenticatorData = append(authenticatorData, a.Aaguid...)
+	authenticatorData = binary.BigEndian.AppendUint16(authenticatorData, uint16(len(id)))
+	authenticatorData = append(authenticatorData, id...)
+	authenticatorData = append(authenticatorData, coseKey...)
+
+	clientDataJson, err := a.clientData("webauthn.create", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	attestationStatement, err := a.attestationStatement(cred, authenticatorData, clientDataJson)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.credentials = append(a.credentials, cred)
+	a.mu.Unlock()
+
+	return json.Marshal(webauthn.RegistrationResponse{
+		Id:    base64.RawURLEncoding.EncodeToString(id),
+		RawId: id,
+		Type:  webauthn.CredentialType,
+		Response: webauthn.AttestationResponse{
+			ClientDataJson:    clientDataJson,
+			AttestationObject: encodeAttestationObject(a.AttestationFormat, attestationStatement, authenticatorData),
+			Transports:        []string{"internal"},
+		},
+	})
+}
+
+// Login answers the JSON serialized request options with a JSON serialized assertion response
+// of the first allowed credential, or of the last registered one if allowCredentials is empty
+func (a *Authenticator) Login(optionsJson []byte) ([]byte, error) {
+	var options webauthn.RequestOptions
+	err := json.Unmarshal(optionsJson, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.RpId != a.RpId {
+		return nil, ErrRpIdMismatch
+	}
+
+	a.mu.Lock()
+	cred := a.findCredential(options.AllowCredentials)
+	if cred != nil {
+		cred.signCount += a.SignCountStep
+	}
+	a.mu.Unlock()
+
+	if cred == nil {
+		return nil, ErrNoCredentials
+	}
+
+	authenticatorData := a.authenticatorData(0, cred.signCount)
+
+	clientDataJson, err := a.clientData("webauthn.get", options.Challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := sign(cred.algorithm, cred.privateKey, signedData(authenticatorData, clientDataJson))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(webauthn.AssertionResponse{
+		Id:    base64.RawURLEncoding.EncodeToString(cred.id),
+		RawId: cred.id,
+		Type:  webauthn.CredentialType,
+		Response: webauthn.AuthenticatorAssertionResponse{
+			ClientDataJson:    clientDataJson,
+			AuthenticatorData: authenticatorData,
+			Signature:         signature,
+			UserHandle:        cred.userHandle,
+		},
+	})
+}
+
+func (a *Authenticator) findCredential(allowCredentials []webauthn.CredentialDescriptor) *credential {
+	if len(allowCredentials) == 0 {
+		if len(a.credentials) == 0 {
+			return nil
+		}
+		return a.credentials[len(a.credentials)-1]
+	}
+
+	for _, allowed := range allowCredentials {
+		for _, cred := range a.credentials {
+			if string(cred.id) == string(allowed.Id) {
+				return cred
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) authenticatorData(flags byte, signCount uint32) []byte {
+	if a.UserPresent {
+		flags |= flagUserPresent
+	}
+	if a.UserVerified {
+		flags |= flagUserVerified
+	}
+
+	rpIdHash := sha256.Sum256([]byte(a.RpId))
+	data := append(rpIdHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func (a *Authenticator) clientData(clientDataType string, challenge []byte) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"type":        clientDataType,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+}
+
+// attestationStatement returns CBOR encoded attStmt of the attestation format
+func (a *Authenticator) attestationStatement(cred *credential, authenticatorData []byte, clientDataJson []byte) ([]byte, error) {
+	switch a.AttestationFormat {
+	case webauthn.AttestationFormatNone:
+		return cborHead(cborMajorMap, 0), nil
+	case webauthn.AttestationFormatPacked:
+		data := signedData(authenticatorData, clientDataJson)
+
+		if a.SelfAttestation {
+			signature, err := sign(cred.algorithm, cred.privateKey, data)
+			if err != nil {
+				return nil, err
+			}
+
+			statement := cborHead(cborMajorMap, 2)
+			statement = append(statement, cborText("alg")...)
+			statement = append(statement, cborInt(cred.algorithm)...)
+			statement = append(statement, cborText("sig")...)
+			return append(statement, cborBytes(signature)...), nil
+		}
+
+		attestationKey, certificate, err := a.attestationCertificateKey()
+		if err != nil {
+			return nil, err
+		}
+
+		signature, err := sign(webauthn.AlgorithmES256, attestationKey, data)
+		if err != nil {
+			return nil, err
+		}
+
+		statement := cborHead(cborMajorMap, 3)
+		statement = append(statement, cborText("alg")...)
+		statement = append(statement, cborInt(webauthn.AlgorithmES256)...)
+		statement = append(statement, cborText("sig")...)
+		statement = append(statement, cborBytes(signature)...)
+		statement = append(statement, cborText("x5c")...)
+		statement = append(statement, cborHead(cborMajorArray, 1)...)
+		return append(statement, cborBytes(certificate)...), nil
+	default:
+		return nil, fmt.Errorf("unsupported attestation format %q", a.AttestationFormat)
+	}
+}
+
+// attestationCertificateKey generates an ES256 attestation key and a certificate meeting the requirements
+// of the WebAuthn spec to packed attestation certificates once
+func (a *Authenticator) attestationCertificateKey() (*ecdsa.PrivateKey, []byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.attestationKey != nil {
+		return a.attestationKey, a.attestationCertificate, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aaguid, err := asn1.Marshal(a.Aaguid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"US"},
+			Organization:       []string{"webauthntest"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "webauthntest attestation",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions:       []pkix.Extension{{Id: aaguidExtensionOid, Value: aaguid}},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a.attestationKey = key
+	a.attestationCertificate = certificate
+
+	return key, certificate, nil
+}
+
+func offersAlgorithm(parameters []webauthn.CredentialParameters, algorithm int64) bool {
+	for _, parameter := range parameters {
+		if parameter.Type == webauthn.CredentialType && parameter.Algorithm == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+func generateKey(algorithm int64) (crypto.Signer, error) {
+	switch algorithm {
+	case webauthn.AlgorithmES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case webauthn.AlgorithmEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	case webauthn.AlgorithmRS256:
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %d", algorithm)
+	}
+}
+
+// signedData is authenticatorData || sha256(clientDataJSON) signed by assertions and packed attestations
+func signedData(authenticatorData []byte, clientDataJson []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJson)
+	return append(append([]byte(nil), authenticatorData...), clientDataHash[:]...)
+}
+
+// sign signs the data as the COSE algorithm requires, ES256 signatures are ASN.1 DER encoded
+func sign(algorithm int64, privateKey crypto.Signer, data []byte) ([]byte, error) {
+	if algorithm == webauthn.AlgorithmEdDSA {
+		return privateKey.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+
+	digest := sha256.Sum256(data)
+	return privateKey.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+// encodeCoseKey encodes the public key to COSE_Key in the canonical order: EC2 P-256 {1: 2, 3: -7, -1: 1, -2: x, -3: y},
+// OKP Ed25519 {1: 1, 3: -8, -1: 6, -2: x} or RSA {1: 3, 3: -257, -1: n, -2: e}
+func encodeCoseKey(algorithm int64, publicKey crypto.PublicKey) ([]byte, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		ecdhKey, err := key.ECDH()
+		if err != nil {
+			return nil, err
+		}
+
+		// uncompressed point is 0x04 || x || y
+		point := ecdhKey.Bytes()
+		if len(point) != 65 {
+			return nil, fmt.Errorf("unexpected point length %d", len(point))
+		}
+
+		encoded := cborHead(cborMajorMap, 5)
+		encoded = append(encoded, cborInt(1)...)
+		encoded = append(encoded, cborInt(2)...)
+		encoded = append(encoded, cborInt(3)...)
+		encoded = append(encoded, cborInt(algorithm)...)
+		encoded = append(encoded, cborInt(-1)...)
+		encoded = append(encoded, cborInt(1)...)
+		encoded = append(encoded, cborInt(-2)...)
+		encoded = append(encoded, cborBytes(point[1:33])...)
+		encoded = append(encoded, cborInt(-3)...)
+		return append(encoded, cborBytes(point[33:])...), nil
+	case ed25519.PublicKey:
+		encoded := cborHead(cborMajorMap, 4)
+		encoded = append(encoded, cborInt(1)...)
+		encoded = append(encoded, cborInt(1)...)
+		encoded = append(encoded, cborInt(3)...)
+		encoded = append(encoded, cborInt(algorithm)...)
+		encoded = append(encoded, cborInt(-1)...)
+		encoded = append(encoded, cborInt(6)...)
+		encoded = append(encoded, cborInt(-2)...)
+		return append(encoded, cborBytes(key)...), nil
+	case *rsa.PublicKey:
+		encoded := cborHead(cborMajorMap, 4)
+		encoded = append(encoded, cborInt(1)...)
+		encoded = append(encoded, cborInt(3)...)
+		encoded = append(encoded, cborInt(3)...)
+		encoded = append(encoded, cborInt(algorithm)...)
+		encoded = append(encoded, cborInt(-1)...)
+		encoded = append(encoded, cborBytes(key.N.Bytes())...)
+		encoded = append(encoded, cborInt(-2)...)
+		return append(encoded, cborBytes(big.NewInt(int64(key.E)).Bytes())...), nil
+	default:
+		return nil, fmt.Errorf("unsupported public key %T", publicKey)
+	}
+}
+
+// encodeAttestationObject encodes {"fmt": format, "attStmt": statement, "authData": authenticatorData}
+func encodeAttestationObject(format string, statement []byte, authenticatorData []byte) []byte {
+	encoded := cborHead(cborMajorMap, 3)
+	encoded = append(encoded, cborText("fmt")...)
+	encoded = append(encoded, cborText(format)...)
+	encoded = append(encoded, cborText("attStmt")...)
+	encoded = append(encoded, statement...)
+	encoded = append(encoded, cborText("authData")...)
+	return append(encoded, cborBytes(authenticatorData)...)
+}
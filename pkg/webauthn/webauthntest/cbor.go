@@ -0,0 +1,46 @@
+package webauthntest
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// CBOR (RFC 8949) major types written by the authenticator
+const (
+	cborMajorUnsigned = 0
+	cborMajorNegative = 1
+	cborMajorBytes    = 2
+	cborMajorText     = 3
+	cborMajorArray    = 4
+	cborMajorMap      = 5
+)
+
+func cborInt(value int64) []byte {
+	if value < 0 {
+		return cborHead(cborMajorNegative, uint64(-1-value))
+	}
+	return cborHead(cborMajorUnsigned, uint64(value))
+}
+
+func cborBytes(value []byte) []byte {
+	return append(cborHead(cborMajorBytes, uint64(len(value))), value...)
+}
+
+func cborText(value string) []byte {
+	return append(cborHead(cborMajorText, uint64(len(value))), value...)
+}
+
+func cborHead(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument <= math.MaxUint8:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+	case argument <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, argument)
+	}
+}
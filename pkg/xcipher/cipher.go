@@ -0,0 +1,76 @@
+package xcipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// KeyLength is the length of AES-256 keys
+const KeyLength = 32
+
+var (
+	ErrInvalidKey        = errors.New("invalid encryption key")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Cipher encrypts small secrets with AES-256-GCM. A ciphertext is a random nonce followed
+// by the sealed data, additional data binds it to a context, e.g. an owner of the secret
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+func New(key []byte) (*Cipher, error) {
+	if len(key) != KeyLength {
+		return nil, fmt.Errorf("%w: must be %d bytes long, got %d", ErrInvalidKey, KeyLength, len(key))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// NewFromBase64 returns a cipher using the standard base64 encoded key
+func NewFromBase64(key string) (*Cipher, error) {
+	decodedKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: must be base64 encoded", ErrInvalidKey)
+	}
+	return New(decodedKey)
+}
+
+func (c *Cipher) Encrypt(plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(plaintext)+c.aead.Overhead())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt returns ErrInvalidCiphertext if the ciphertext was not encrypted by the key
+// with the same additional data or was modified
+func (c *Cipher) Decrypt(ciphertext []byte, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < c.aead.NonceSize()+c.aead.Overhead() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, sealed := ciphertext[:c.aead.NonceSize()], ciphertext[c.aead.NonceSize():]
+
+	plaintext, err := c.aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,173 @@
+package xpassword
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+// Defaults follow OWASP recommendation for argon2id: 19 MiB of memory, 2 iterations, 1 degree of parallelism
+const (
+	defaultArgon2idMemory      = 19 * 1024
+	defaultArgon2idTime        = 2
+	defaultArgon2idParallelism = 1
+	defaultArgon2idSaltLength  = 16
+	defaultArgon2idKeyLength   = 32
+)
+
+// Parameters of argon2id are limited, so a tampered stored hash can not make a check exhaust memory or CPU
+const (
+	maxArgon2idMemory      = 1024 * 1024
+	maxArgon2idTime        = 32
+	maxArgon2idParallelism = 16
+	minArgon2idSaltLength  = 8
+	maxArgon2idSaltLength  = 64
+	minArgon2idKeyLength   = 16
+	maxArgon2idKeyLength   = 128
+)
+
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idConfig of argon2id algorithm. Memory is in KiB, Time is a number of iterations.
+// Memory is limited to 1 GiB, Time to 32, Parallelism to 16, SaltLength to 8-64 and KeyLength to 16-128 bytes
+type Argon2idConfig struct {
+	Memory      uint32 `yaml:"memory"`
+	Time        uint32 `yaml:"time"`
+	Parallelism uint8  `yaml:"parallelism"`
+	SaltLength  uint32 `yaml:"salt-length"`
+	KeyLength   uint32 `yaml:"key-length"`
+}
+
+type argon2idParams struct {
+	memory      uint32
+	time        uint32
+	parallelism uint8
+}
+
+// Argon2idHasher creates argon2id hashes in PHC string format:
+// '$argon2id$v=19$m=<memory>,t=<time>,p=<parallelism>$<salt>$<hash>' with unpadded base64 salt and hash
+type Argon2idHasher struct {
+	params     argon2idParams
+	saltLength uint32
+	keyLength  uint32
+}
+
+func NewArgon2idHasher(config *Argon2idConfig) *Argon2idHasher {
+	return &Argon2idHasher{
+		params: argon2idParams{
+			memory:      valueOrDefault(config.Memory, defaultArgon2idMemory),
+			time:        valueOrDefault(config.Time, defaultArgon2idTime),
+			parallelism: valueOrDefault(config.Parallelism, defaultArgon2idParallelism),
+		},
+		saltLength: valueOrDefault(config.SaltLength, defaultArgon2idSaltLength),
+		keyLength:  valueOrDefault(config.KeyLength, defaultArgon2idKeyLength),
+	}
+}
+
+func (h *Argon2idHasher) validate() error {
+	if !h.params.valid() ||
+		h.saltLength < minArgon2idSaltLength || h.saltLength > maxArgon2idSaltLength ||
+		h.keyLength < minArgon2idKeyLength || h.keyLength > maxArgon2idKeyLength {
+		return ErrInvalidParameters
+	}
+	return nil
+}
+
+func (p argon2idParams) valid() bool {
+	return p.memory > 0 && p.memory <= maxArgon2idMemory &&
+		p.time > 0 && p.time <= maxArgon2idTime &&
+		p.parallelism > 0 && p.parallelism <= maxArgon2idParallelism
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.saltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.time, h.params.memory, h.params.parallelism, h.keyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.params.memory,
+		h.params.time,
+		h.params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Check(password string, hashedPassword string) error {
+	params, salt, key, err := parseArgon2idHash(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	otherKey := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.parallelism, uint32(len(key)))
+
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+func (h *Argon2idHasher) Supports(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, argon2idPrefix)
+}
+
+func (h *Argon2idHasher) MaxPasswordLength() int {
+	return 0
+}
+
+func (h *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, salt, key, err := parseArgon2idHash(hashedPassword)
+	if err != nil {
+		return true
+	}
+	return params != h.params || uint32(len(salt)) != h.saltLength || uint32(len(key)) != h.keyLength
+}
+
+func parseArgon2idHash(hashedPassword string) (argon2idParams, []byte, []byte, error) {
+	var params argon2idParams
+
+	// '', 'argon2id', 'v=19', 'm=..,t=..,p=..', salt, hash
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	if parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	// parameters are formatted back, so trailing garbage and non-canonical numbers are rejected
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.parallelism)
+	if err != nil || parts[3] != fmt.Sprintf("m=%d,t=%d,p=%d", params.memory, params.time, params.parallelism) || !params.valid() {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.Strict().DecodeString(parts[4])
+	if err != nil || len(salt) < minArgon2idSaltLength || len(salt) > maxArgon2idSaltLength {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.Strict().DecodeString(parts[5])
+	if err != nil || len(key) < minArgon2idKeyLength || len(key) > maxArgon2idKeyLength {
+		return params, nil, nil, ErrInvalidHash
+	}
+
+	return params, salt, key, nil
+}
+
+func valueOrDefault[T uint8 | uint32](value T, defaultValue T) T {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
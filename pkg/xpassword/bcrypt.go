@@ -0,0 +1,74 @@
+package xpassword
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// bcryptMaxPasswordLength is the number of password bytes bcrypt uses
+const bcryptMaxPasswordLength = 72
+
+// BcryptConfig of bcrypt algorithm, bcrypt.DefaultCost is used if Cost is zero
+type BcryptConfig struct {
+	Cost int `yaml:"cost"`
+}
+
+// BcryptHasher creates bcrypt hashes in modular crypt format like '$2a$10$...'.
+// Passwords longer than 72 bytes are rejected with ErrPasswordTooLong
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(config *BcryptConfig) *BcryptHasher {
+	cost := config.Cost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) validate() error {
+	if h.cost < bcrypt.MinCost || h.cost > bcrypt.MaxCost {
+		return ErrInvalidParameters
+	}
+	return nil
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	if len(password) > bcryptMaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+func (h *BcryptHasher) Check(password string, hashedPassword string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatchedHashAndPassword
+	}
+	if err != nil {
+		return ErrInvalidHash
+	}
+	return nil
+}
+
+func (h *BcryptHasher) Supports(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+func (h *BcryptHasher) MaxPasswordLength() int {
+	return bcryptMaxPasswordLength
+}
+
+func (h *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.cost
+}
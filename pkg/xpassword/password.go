@@ -1,20 +1,125 @@
 package xpassword
 
 import (
+	"errors"
 	"fmt"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// Hash returns the bcrypt hash of the password
-func Hash(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+type Algorithm string
+
+const (
+	AlgorithmArgon2id Algorithm = "argon2id"
+	AlgorithmBcrypt   Algorithm = "bcrypt"
+)
+
+var (
+	ErrMismatchedHashAndPassword = errors.New("hashed password is not the hash of the given password")
+	ErrUnknownHashFormat         = errors.New("unknown password hash format")
+	ErrUnsupportedAlgorithm      = errors.New("unsupported password hashing algorithm")
+	ErrInvalidHash               = errors.New("password hash is malformed")
+	ErrPasswordTooLong           = errors.New("password is longer than the algorithm supports")
+	ErrInvalidParameters         = errors.New("invalid password hashing parameters")
+)
+
+// Hasher hashes passwords with a single algorithm
+type Hasher interface {
+	// Hash returns a hash of the password encoded with its algorithm, parameters and salt
+	Hash(password string) (string, error)
+	// Check returns ErrMismatchedHashAndPassword if the password does not match the hash
+	Check(password string, hashedPassword string) error
+	// Supports reports whether the hash has been created by the algorithm of the hasher
+	Supports(hashedPassword string) bool
+	// NeedsRehash reports whether the hash has been created with other parameters than configured
+	NeedsRehash(hashedPassword string) bool
+	// MaxPasswordLength is the maximum length of passwords in bytes, zero means that the length is not limited
+	MaxPasswordLength() int
+}
+
+// Config selects an algorithm used to hash new passwords, hashes of all the algorithms
+// are still checked. Zero parameters of the algorithms are replaced with defaults
+type Config struct {
+	Algorithm Algorithm      `yaml:"algorithm"`
+	Argon2id  Argon2idConfig `yaml:"argon2id"`
+	Bcrypt    BcryptConfig   `yaml:"bcrypt"`
+}
+
+// PasswordHasher hashes new passwords with the preferred algorithm and checks hashes of any
+// supported algorithm, so stored hashes may be upgraded when users log in
+type PasswordHasher struct {
+	preferred Hasher
+	hashers   []Hasher
+}
+
+// New returns a hasher hashing passwords with preferred and checking hashes of preferred and others
+func New(preferred Hasher, others ...Hasher) *PasswordHasher {
+	return &PasswordHasher{
+		preferred: preferred,
+		hashers:   append([]Hasher{preferred}, others...),
+	}
+}
+
+// NewFromConfig returns a hasher supporting argon2id and bcrypt, argon2id is preferred by default
+func NewFromConfig(config *Config) (*PasswordHasher, error) {
+	argon2idHasher := NewArgon2idHasher(&config.Argon2id)
+	err := argon2idHasher.validate()
+	if err != nil {
+		return nil, fmt.Errorf("argon2id: %w", err)
+	}
+
+	bcryptHasher := NewBcryptHasher(&config.Bcrypt)
+	err = bcryptHasher.validate()
+	if err != nil {
+		return nil, fmt.Errorf("bcrypt: %w", err)
+	}
+
+	switch config.Algorithm {
+	case "", AlgorithmArgon2id:
+		return New(argon2idHasher, bcryptHasher), nil
+	case AlgorithmBcrypt:
+		return New(bcryptHasher, argon2idHasher), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, config.Algorithm)
+	}
+}
+
+// Hash returns the hash of the password created by the preferred algorithm
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	hashedPassword, err := h.preferred.Hash(password)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
-	return string(hashedPassword), nil
+	return hashedPassword, nil
+}
+
+// MaxPasswordLength is the maximum length in bytes of passwords hashed by the preferred algorithm,
+// zero means that the length is not limited
+func (h *PasswordHasher) MaxPasswordLength() int {
+	return h.preferred.MaxPasswordLength()
 }
 
 // Check checks if the provided password is correct or not
-func Check(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func (h *PasswordHasher) Check(password string, hashedPassword string) error {
+	hasher, err := h.hasherOf(hashedPassword)
+	if err != nil {
+		return err
+	}
+	return hasher.Check(password, hashedPassword)
+}
+
+// NeedsRehash reports whether the hash should be replaced with a hash created by the preferred
+// algorithm with the configured parameters. It should be called after the password is checked
+func (h *PasswordHasher) NeedsRehash(hashedPassword string) bool {
+	if !h.preferred.Supports(hashedPassword) {
+		return true
+	}
+	return h.preferred.NeedsRehash(hashedPassword)
+}
+
+func (h *PasswordHasher) hasherOf(hashedPassword string) (Hasher, error) {
+	for _, hasher := range h.hashers {
+		if hasher.Supports(hashedPassword) {
+			return hasher, nil
+		}
+	}
+	return nil, ErrUnknownHashFormat
 }
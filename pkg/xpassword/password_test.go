@@ -0,0 +1,261 @@
+package xpassword_test
+
+import (
+	"errors"
+	"github.com/vaberof/auth-grpc/pkg/xpassword"
+	"strings"
+	"testing"
+)
+
+// cheap parameters keep the tests fast
+var (
+	testArgon2idConfig = xpassword.Argon2idConfig{Memory: 64, Time: 1}
+	testBcryptConfig   = xpassword.BcryptConfig{Cost: 4}
+)
+
+func newTestHasher(t *testing.T, algorithm xpassword.Algorithm) *xpassword.PasswordHasher {
+	t.Helper()
+
+	hasher, err := xpassword.NewFromConfig(&xpassword.Config{
+		Algorithm: algorithm,
+		Argon2id:  testArgon2idConfig,
+		Bcrypt:    testBcryptConfig,
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+	return hasher
+}
+
+func TestHashAndCheck(t *testing.T) {
+	tests := []struct {
+		algorithm  xpassword.Algorithm
+		wantPrefix string
+	}{
+		{algorithm: xpassword.AlgorithmArgon2id, wantPrefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+		{algorithm: xpassword.AlgorithmBcrypt, wantPrefix: "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			hasher := newTestHasher(t, tt.algorithm)
+
+			hashedPassword, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if !strings.HasPrefix(hashedPassword, tt.wantPrefix) {
+				t.Errorf("Hash() = %q, want prefix %q", hashedPassword, tt.wantPrefix)
+			}
+
+			otherHash, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if otherHash == hashedPassword {
+				t.Errorf("Hash() returned the same hash twice, salt is not random")
+			}
+
+			err = hasher.Check("correct horse", hashedPassword)
+			if err != nil {
+				t.Errorf("Check() error = %v", err)
+			}
+
+			err = hasher.Check("wrong horse", hashedPassword)
+			if !errors.Is(err, xpassword.ErrMismatchedHashAndPassword) {
+				t.Errorf("Check() error = %v, want %v", err, xpassword.ErrMismatchedHashAndPassword)
+			}
+
+			if hasher.NeedsRehash(hashedPassword) {
+				t.Errorf("NeedsRehash() = true for a hash with the configured parameters")
+			}
+		})
+	}
+}
+
+func TestCheckArgon2idHash(t *testing.T) {
+	hasher := newTestHasher(t, xpassword.AlgorithmArgon2id)
+
+	hashedPassword, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	// '', 'argon2id', 'v=19', 'm=..,t=..,p=..', salt, hash
+	parts := strings.Split(hashedPassword, "$")
+	withPart := func(i int, value string) string {
+		tampered := append([]string{}, parts...)
+		tampered[i] = value
+		return strings.Join(tampered, "$")
+	}
+	// flipping the first character keeps the base64 encoding valid
+	flipFirst := func(value string) string {
+		if value[0] == 'A' {
+			return "B" + value[1:]
+		}
+		return "A" + value[1:]
+	}
+
+	tests := []struct {
+		name           string
+		hashedPassword string
+		wantErr        error
+	}{
+		{name: "tampered salt", hashedPassword: withPart(4, flipFirst(parts[4])), wantErr: xpassword.ErrMismatchedHashAndPassword},
+		{name: "tampered hash", hashedPassword: withPart(5, flipFirst(parts[5])), wantErr: xpassword.ErrMismatchedHashAndPassword},
+		{name: "tampered parameters", hashedPassword: withPart(3, "m=64,t=2,p=1"), wantErr: xpassword.ErrMismatchedHashAndPassword},
+		{name: "wrong version", hashedPassword: withPart(2, "v=16"), wantErr: xpassword.ErrInvalidHash},
+		{name: "version with trailing data", hashedPassword: withPart(2, "v=19x"), wantErr: xpassword.ErrInvalidHash},
+		{name: "missing part", hashedPassword: strings.Join(parts[:5], "$"), wantErr: xpassword.ErrInvalidHash},
+		{name: "extra part", hashedPassword: hashedPassword + "$extra", wantErr: xpassword.ErrInvalidHash},
+		{name: "parameters with trailing data", hashedPassword: withPart(3, "m=64,t=1,p=1,x=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "parameters in other order", hashedPassword: withPart(3, "t=1,m=64,p=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "zero memory", hashedPassword: withPart(3, "m=0,t=1,p=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "oversized memory", hashedPassword: withPart(3, "m=4194304,t=1,p=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "memory overflow", hashedPassword: withPart(3, "m=99999999999,t=1,p=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "zero time", hashedPassword: withPart(3, "m=64,t=0,p=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "oversized time", hashedPassword: withPart(3, "m=64,t=1000,p=1"), wantErr: xpassword.ErrInvalidHash},
+		{name: "zero parallelism", hashedPassword: withPart(3, "m=64,t=1,p=0"), wantErr: xpassword.ErrInvalidHash},
+		{name: "oversized parallelism", hashedPassword: withPart(3, "m=64,t=1,p=200"), wantErr: xpassword.ErrInvalidHash},
+		{name: "invalid salt encoding", hashedPassword: withPart(4, "!!!!"), wantErr: xpassword.ErrInvalidHash},
+		{name: "short salt", hashedPassword: withPart(4, "AAAA"), wantErr: xpassword.ErrInvalidHash},
+		{name: "padded hash", hashedPassword: withPart(5, parts[5]+"="), wantErr: xpassword.ErrInvalidHash},
+		{name: "short hash", hashedPassword: withPart(5, "AAAA"), wantErr: xpassword.ErrInvalidHash},
+		{name: "empty hash", hashedPassword: withPart(5, ""), wantErr: xpassword.ErrInvalidHash},
+		{name: "unknown algorithm", hashedPassword: withPart(1, "argon2i"), wantErr: xpassword.ErrUnknownHashFormat},
+		{name: "plain text", hashedPassword: "password", wantErr: xpassword.ErrUnknownHashFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := hasher.Check("password", tt.hashedPassword)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	argon2idHasher := newTestHasher(t, xpassword.AlgorithmArgon2id)
+	bcryptHasher := newTestHasher(t, xpassword.AlgorithmBcrypt)
+
+	strongerArgon2idHasher, err := xpassword.NewFromConfig(&xpassword.Config{
+		Argon2id: xpassword.Argon2idConfig{Memory: 128, Time: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig() error = %v", err)
+	}
+
+	argon2idHash, err := argon2idHasher.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	bcryptHash, err := bcryptHasher.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		hasher         *xpassword.PasswordHasher
+		hashedPassword string
+		want           bool
+	}{
+		{name: "argon2id with configured parameters", hasher: argon2idHasher, hashedPassword: argon2idHash, want: false},
+		{name: "argon2id with weaker parameters", hasher: strongerArgon2idHasher, hashedPassword: argon2idHash, want: true},
+		{name: "bcrypt with argon2id preferred", hasher: argon2idHasher, hashedPassword: bcryptHash, want: true},
+		{name: "argon2id with bcrypt preferred", hasher: bcryptHasher, hashedPassword: argon2idHash, want: true},
+		{name: "bcrypt with other cost", hasher: bcryptHasher, hashedPassword: strings.Replace(bcryptHash, "$04$", "$05$", 1), want: true},
+		{name: "malformed argon2id", hasher: argon2idHasher, hashedPassword: "$argon2id$v=19$m=64,t=1,p=1$", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.hasher.NeedsRehash(tt.hashedPassword)
+			if got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRehashBcryptToArgon2id(t *testing.T) {
+	bcryptHash, err := newTestHasher(t, xpassword.AlgorithmBcrypt).Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+
+	hasher := newTestHasher(t, xpassword.AlgorithmArgon2id)
+
+	// hashes of other algorithms are still checked
+	err = hasher.Check("password", bcryptHash)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !hasher.NeedsRehash(bcryptHash) {
+		t.Fatalf("NeedsRehash() = false, want true")
+	}
+
+	rehashed, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if !strings.HasPrefix(rehashed, "$argon2id$") {
+		t.Errorf("Hash() = %q, want argon2id hash", rehashed)
+	}
+	if hasher.NeedsRehash(rehashed) {
+		t.Errorf("NeedsRehash() = true after rehashing")
+	}
+	err = hasher.Check("password", rehashed)
+	if err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+}
+
+func TestBcryptMaxPasswordLength(t *testing.T) {
+	hasher := newTestHasher(t, xpassword.AlgorithmBcrypt)
+
+	if hasher.MaxPasswordLength() != 72 {
+		t.Errorf("MaxPasswordLength() = %d, want 72", hasher.MaxPasswordLength())
+	}
+
+	_, err := hasher.Hash(strings.Repeat("a", 72))
+	if err != nil {
+		t.Errorf("Hash() of 72 bytes error = %v", err)
+	}
+
+	// 37 characters take 74 bytes
+	_, err = hasher.Hash(strings.Repeat("я", 37))
+	if !errors.Is(err, xpassword.ErrPasswordTooLong) {
+		t.Errorf("Hash() of 74 bytes error = %v, want %v", err, xpassword.ErrPasswordTooLong)
+	}
+
+	if length := newTestHasher(t, xpassword.AlgorithmArgon2id).MaxPasswordLength(); length != 0 {
+		t.Errorf("argon2id MaxPasswordLength() = %d, want 0", length)
+	}
+}
+
+func TestNewFromConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  xpassword.Config
+		wantErr error
+	}{
+		{name: "unknown algorithm", config: xpassword.Config{Algorithm: "scrypt"}, wantErr: xpassword.ErrUnsupportedAlgorithm},
+		{name: "oversized argon2id memory", config: xpassword.Config{Argon2id: xpassword.Argon2idConfig{Memory: 4 * 1024 * 1024}}, wantErr: xpassword.ErrInvalidParameters},
+		{name: "oversized argon2id time", config: xpassword.Config{Argon2id: xpassword.Argon2idConfig{Time: 1000}}, wantErr: xpassword.ErrInvalidParameters},
+		{name: "short argon2id salt", config: xpassword.Config{Argon2id: xpassword.Argon2idConfig{SaltLength: 4}}, wantErr: xpassword.ErrInvalidParameters},
+		{name: "short argon2id key", config: xpassword.Config{Argon2id: xpassword.Argon2idConfig{KeyLength: 4}}, wantErr: xpassword.ErrInvalidParameters},
+		{name: "oversized bcrypt cost", config: xpassword.Config{Bcrypt: xpassword.BcryptConfig{Cost: 40}}, wantErr: xpassword.ErrInvalidParameters},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := xpassword.NewFromConfig(&tt.config)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("NewFromConfig() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package xrand
 
 import (
 	"crypto/rand"
+	"fmt"
 	"math"
 	"math/big"
 )
@@ -14,5 +15,5 @@ func GenerateRandomCode(maxDigits int) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return bi.String(), nil
+	return fmt.Sprintf("%0*d", maxDigits, bi.Int64()), nil
 }
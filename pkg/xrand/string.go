@@ -0,0 +1,21 @@
+package xrand
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// GenerateRandomString returns a string of n characters picked uniformly from the alphabet
+func GenerateRandomString(n int, alphabet string) (string, error) {
+	max := big.NewInt(int64(len(alphabet)))
+
+	b := make([]byte, n)
+	for i := range b {
+		bi, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = alphabet[bi.Int64()]
+	}
+	return string(b), nil
+}